@@ -0,0 +1,127 @@
+// Command backtest replays a transaction dump against a candidate rule set and
+// reports how that rule set would have decided.
+//
+// Usage:
+//
+//	go run ./cmd/backtest [flags]
+//
+// Flags:
+//
+//	-dump     Transaction dump to replay: seed format or a store export (default: data/seed.json)
+//	-rules    Candidate rule file (default: data/rules.json)
+//	-fx-rates Path to the FX rate table; empty scores raw amounts (default: data/fx_rates.json)
+//	-json     Print the result as JSON instead of tables
+//
+// Records may carry an "is_fraud" boolean, or outcomes recorded through the
+// API (a chargeback or confirmed fraud labels a record fraudulent, a false
+// positive labels it legitimate). When any do, precision and recall against
+// those labels are reported as well.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"lumina/fraud-api/internal/backtest"
+	"lumina/fraud-api/internal/domain"
+	"lumina/fraud-api/internal/fx"
+	"lumina/fraud-api/internal/scoring"
+)
+
+func main() {
+	dumpFile := flag.String("dump", "data/seed.json", "transaction dump to replay")
+	rulesFile := flag.String("rules", "data/rules.json", "candidate rule file")
+	fxFile := flag.String("fx-rates", "data/fx_rates.json", "path to FX rate table (empty: no normalisation)")
+	asJSON := flag.Bool("json", false, "print the result as JSON")
+	flag.Parse()
+
+	records, err := backtest.Load(*dumpFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load dump: %v\n", err)
+		os.Exit(1)
+	}
+
+	rules, err := scoring.LoadRuleSet(*rulesFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load rules: %v\n", err)
+		os.Exit(1)
+	}
+
+	var rates *fx.Table
+	if *fxFile != "" {
+		if rates, err = fx.Load(*fxFile); err != nil {
+			fmt.Fprintf(os.Stderr, "load fx rates: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	res := backtest.Run(records, rules, rates)
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(res); err != nil {
+			fmt.Fprintf(os.Stderr, "encode error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	printResult(os.Stdout, res)
+}
+
+func printResult(out io.Writer, res backtest.Result) {
+	fmt.Fprintf(out, "Rule set:     %s (%s)\n", res.RuleSetVersion, res.RuleSetHash[:12])
+	fmt.Fprintf(out, "Transactions: %d scored, %d rejected\n\n", res.Transactions, res.Rejected)
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(out, "Decisions")
+	for _, d := range []string{domain.ActionApprove, domain.ActionReview, domain.ActionDecline} {
+		fmt.Fprintf(w, "  %s\t%d\t%s\n", d, res.Decisions[d], percent(res.Decisions[d], res.Transactions))
+	}
+	w.Flush()
+
+	fmt.Fprintln(out, "\nScore histogram")
+	for _, b := range res.Histogram {
+		fmt.Fprintf(w, "  %d–%d\t%d\t%s\n", b.From, b.To, b.Count, bar(b.Count, res.Transactions))
+	}
+	w.Flush()
+
+	fmt.Fprintln(out, "\nRule fire rates")
+	for _, f := range res.RuleFires {
+		fmt.Fprintf(w, "  %s\t%d\t%s\n", f.Rule, f.Count, percent(f.Count, res.Transactions))
+	}
+	w.Flush()
+
+	if res.Labelled == 0 {
+		fmt.Fprintln(out, "\nNo labelled records (is_fraud or outcomes); precision/recall not computed.")
+		return
+	}
+	fmt.Fprintf(out, "\nAgainst %d labelled records\n", res.Labelled)
+	fmt.Fprintf(w, "  flagged as fraud\tTP\tFP\tFN\tprecision\trecall\n")
+	for _, m := range res.Metrics {
+		fmt.Fprintf(w, "  %s\t%d\t%d\t%d\t%.3f\t%.3f\n",
+			strings.Join(m.Flagged, "+"), m.TruePositives, m.FalsePositives, m.FalseNegatives, m.Precision, m.Recall)
+	}
+	w.Flush()
+}
+
+func percent(n, total int) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(n)/float64(total))
+}
+
+// bar renders n/total as a bar of up to 40 characters.
+func bar(n, total int) string {
+	if total == 0 {
+		return ""
+	}
+	return strings.Repeat("█", n*40/total)
+}
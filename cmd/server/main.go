@@ -8,6 +8,29 @@
 //
 //	-port  HTTP port to listen on (default: 8080)
 //	-seed  Path to a seed data JSON file to load on startup (default: data/seed.json)
+//	-rules Path to the scoring rule file (default: data/rules.json)
+//	-fx-rates Path to the FX rate table (default: data/fx_rates.json)
+//	-shadow-rules Path to a challenger rule file scored in shadow (default: none)
+//	-settings-refresh   Time between checks for rule sets and FX rates stored by other replicas (default: 10s)
+//	-review-sla   Target time from queueing to a manual review decision (default: 4h)
+//	-review-claim-ttl How long an analyst's claim on a review case holds (default: 30m)
+//	-idempotency-ttl  How long an Idempotency-Key replays the original result (default: 24h)
+//	-store    Storage backend: memory, sqlite or redis (default: memory)
+//	-sqlite-path Database file with -store=sqlite (default: data/fraud.db)
+//	-redis-addr  Redis server address with -store=redis (default: localhost:6379)
+//	-retention   How long transaction history is kept; zero or negative keeps it forever (default: 2160h, 90 days)
+//	-compact-interval   Time between retention passes on the memory and sqlite stores (default: 1h)
+//	-data-dir Directory for the write-ahead log and snapshots with -store=memory (default: none, memory only)
+//	-wal-sync WAL fsync policy: always, interval or never (default: interval)
+//	-wal-sync-interval  Time between fsyncs with -wal-sync=interval (default: 1s)
+//	-snapshot-every     WAL records between snapshots; negative disables (default: 10000)
+//	-snapshot-interval  Time between snapshots; negative disables (default: 10m)
+//
+// Environment:
+//
+//	API_ADMIN_KEY  Bootstrap admin API key. When unset and the store holds no
+//	               active admin key, one is generated and its token printed
+//	               once to stderr, outside the structured log.
 package main
 
 import (
@@ -20,13 +43,21 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"reflect"
 	"sort"
 	"strconv"
 	"syscall"
 	"time"
 
+	"github.com/redis/go-redis/v9"
+
 	"lumina/fraud-api/internal/api"
+	"lumina/fraud-api/internal/audit"
+	"lumina/fraud-api/internal/auth"
 	"lumina/fraud-api/internal/domain"
+	"lumina/fraud-api/internal/fx"
+	"lumina/fraud-api/internal/retention"
+	"lumina/fraud-api/internal/review"
 	"lumina/fraud-api/internal/scoring"
 	"lumina/fraud-api/internal/store"
 	"lumina/fraud-api/internal/webhook"
@@ -35,6 +66,23 @@ import (
 func main() {
 	port := flag.Int("port", 8080, "HTTP port")
 	seedFile := flag.String("seed", "data/seed.json", "path to seed data JSON file")
+	rulesFile := flag.String("rules", "data/rules.json", "path to scoring rule file")
+	fxFile := flag.String("fx-rates", "data/fx_rates.json", "path to FX rate table")
+	shadowFile := flag.String("shadow-rules", "", "path to a challenger rule file scored in shadow mode")
+	settingsRefresh := flag.Duration("settings-refresh", 10*time.Second, "time between checks for rule sets and FX rates stored by other replicas")
+	reviewSLA := flag.Duration("review-sla", review.DefaultSLA, "target time from queueing to a review decision")
+	claimTTL := flag.Duration("review-claim-ttl", review.DefaultClaimTTL, "how long a claim on a review case holds")
+	idempotencyTTL := flag.Duration("idempotency-ttl", api.DefaultIdempotencyTTL, "how long an Idempotency-Key replays the original result of POST /transactions")
+	backend := flag.String("store", storeMemory, "storage backend: memory, sqlite or redis")
+	sqlitePath := flag.String("sqlite-path", "data/fraud.db", "database file with -store=sqlite")
+	redisAddr := flag.String("redis-addr", "localhost:6379", "Redis server address with -store=redis")
+	retentionHorizon := flag.Duration("retention", retention.DefaultHorizon, "how long transaction history is kept; zero or negative keeps it forever")
+	compactInterval := flag.Duration("compact-interval", retention.DefaultInterval, "time between retention passes on the memory and sqlite stores")
+	dataDir := flag.String("data-dir", "", "directory for the write-ahead log and snapshots with -store=memory; empty keeps state in memory only")
+	walSync := flag.String("wal-sync", string(store.SyncInterval), "WAL fsync policy: always, interval or never")
+	walSyncInterval := flag.Duration("wal-sync-interval", store.DefaultSyncInterval, "time between fsyncs with -wal-sync=interval")
+	snapshotEvery := flag.Int("snapshot-every", store.DefaultSnapshotEvery, "WAL records between snapshots; negative disables")
+	snapshotInterval := flag.Duration("snapshot-interval", store.DefaultSnapshotInterval, "time between snapshots; negative disables")
 	flag.Parse()
 
 	// Railway (and most PaaS platforms) inject PORT as an env var.
@@ -50,19 +98,126 @@ func main() {
 		Level: slog.LevelInfo,
 	})))
 
+	// ── Load scoring rules ────────────────────────────────────────────────────
+	rules, err := loadRules(*rulesFile)
+	if err != nil {
+		// Fatal: scoring with a half-understood rule file is worse than not starting.
+		slog.Error("invalid rule file", "file", *rulesFile, "error", err)
+		os.Exit(1)
+	}
+	slog.Info("scoring rules loaded", "file", *rulesFile, "version", rules.Version)
+
+	// ── Load FX rates ─────────────────────────────────────────────────────────
+	rates, err := fx.Load(*fxFile)
+	if err != nil {
+		// Non-fatal: without rates the engine scores raw amounts, as before
+		// normalisation existed. Rates can still be supplied via the admin API.
+		slog.Warn("fx rates not loaded; amounts will not be normalised", "file", *fxFile, "reason", err.Error())
+		rates = fx.New("USD")
+	} else {
+		slog.Info("fx rates loaded", "file", *fxFile, "reporting_currency", rates.ReportingCurrency())
+	}
+
+	// ── Open the store ────────────────────────────────────────────────────────
+	syncPolicy, err := store.ParseSyncPolicy(*walSync)
+	if err != nil {
+		slog.Error("invalid -wal-sync", "error", err)
+		os.Exit(1)
+	}
+	s, closeStore, err := openStore(storeConfig{
+		backend:    *backend,
+		sqlitePath: *sqlitePath,
+		redisAddr:  *redisAddr,
+		retention:  *retentionHorizon,
+		dataDir:    *dataDir,
+		durable: store.DurableOptions{
+			Sync:             syncPolicy,
+			SyncInterval:     *walSyncInterval,
+			SnapshotEvery:    *snapshotEvery,
+			SnapshotInterval: *snapshotInterval,
+		},
+	})
+	if err != nil {
+		// Fatal: starting empty over data we cannot read would silently fork
+		// history.
+		slog.Error("store open failed", "store", *backend, "error", err)
+		os.Exit(1)
+	}
+
 	// ── Wire dependencies ─────────────────────────────────────────────────────
-	s := store.New()
-	engine := scoring.New(s)
+	engine := scoring.New(s, rules, rates)
 	notifier := webhook.New(s)
-	handler := api.NewHandler(s, engine, notifier)
+
+	// ── Load shadow (challenger) rules ────────────────────────────────────────
+	if *shadowFile != "" {
+		challenger, err := scoring.LoadRuleSet(*shadowFile)
+		if err != nil {
+			// Non-fatal: the champion decides regardless; shadow mode is diagnostic.
+			slog.Warn("shadow rules not loaded; shadow mode off", "file", *shadowFile, "reason", err.Error())
+		} else {
+			engine.SetChallenger(challenger)
+			slog.Info("shadow rules loaded", "file", *shadowFile, "version", challenger.Version)
+		}
+	}
+
+	// ── Apply stored settings ─────────────────────────────────────────────────
+	// Rule sets and FX rates changed through the admin API, or reloaded, are
+	// kept in the store and win over the files; replicas keep polling for
+	// new ones.
+	refreshSettings(engine)
+	if *settingsRefresh > 0 {
+		go func() {
+			for range time.Tick(*settingsRefresh) {
+				refreshSettings(engine)
+			}
+		}()
+	}
+	reviews := review.New(s, *claimTTL, *reviewSLA)
+	auditLog, err := audit.New(s)
+	if err != nil {
+		slog.Error("audit log unavailable", "error", err)
+		os.Exit(1)
+	}
+	// A broken chain is evidence of tampering, not a reason to stop serving:
+	// new entries link after the last stored one, and GET /audit/verify
+	// keeps reporting the break.
+	if err := auditLog.Verify(); err != nil {
+		slog.Error("stored audit chain failed verification", "error", err)
+	} else {
+		slog.Info("audit log loaded", "entries", len(auditLog.Entries()))
+	}
+
+	// ── Bootstrap admin API key ───────────────────────────────────────────────
+	keys := auth.New(s)
+	if err := bootstrapAdminKey(keys, os.Getenv("API_ADMIN_KEY")); err != nil {
+		slog.Error("invalid API_ADMIN_KEY", "error", err)
+		os.Exit(1)
+	}
+
+	handler := api.NewHandler(s, engine, notifier, reviews, auditLog, keys)
+	handler.SetIdempotencyTTL(*idempotencyTTL)
 	router := api.NewRouter(handler)
 
 	// ── Load seed data ────────────────────────────────────────────────────────
-	if err := loadSeedData(s, engine, *seedFile); err != nil {
+	if n := len(s.GetAllTransactions(time.Time{})); n > 0 {
+		slog.Info("seed data skipped; store already holds transactions", "transactions", n)
+	} else if err := loadSeedData(s, engine, *seedFile); err != nil {
 		// Non-fatal: the API works fine without seed data.
 		slog.Warn("seed data not loaded", "file", *seedFile, "reason", err.Error())
 	}
 
+	// ── Start retention ───────────────────────────────────────────────────────
+	// Redis expires history itself through key TTLs; the other backends are
+	// pruned in the background.
+	var compactor *retention.Compactor
+	if p, ok := s.(store.Pruner); ok && *retentionHorizon > 0 {
+		compactor = retention.New(p, *retentionHorizon, *compactInterval)
+		compactor.Start()
+		slog.Info("retention compactor started", "horizon", compactor.Horizon(), "interval", *compactInterval)
+	} else if *retentionHorizon <= 0 {
+		slog.Info("retention disabled; history is kept forever")
+	}
+
 	// ── Start HTTP server ─────────────────────────────────────────────────────
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%d", *port),
@@ -72,6 +227,32 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Reload scoring rules (and shadow rules, if any) on SIGHUP without
+	// dropping in-flight requests.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			before := engine.ActiveRules()
+			if rs, err := engine.ReloadRules(); err != nil {
+				slog.Error("rule reload failed; keeping current rules", "error", err)
+			} else {
+				info := engine.ActiveRules()
+				slog.Info("scoring rules reloaded", "file", rs.Source, "version", info.Version, "hash", info.Hash)
+				recordSignalReload(auditLog, "rules.reload", "rules", before, info)
+			}
+
+			shadowBefore, _ := engine.ChallengerRules()
+			if rs, err := engine.ReloadChallenger(); err == nil {
+				info, _ := engine.ChallengerRules()
+				slog.Info("shadow rules reloaded", "file", rs.Source, "version", info.Version, "hash", info.Hash)
+				recordSignalReload(auditLog, "shadow_rules.reload", "rules/shadow", shadowBefore, info)
+			} else if !errors.Is(err, scoring.ErrNoRuleSource) {
+				slog.Error("shadow rule reload failed; keeping current shadow rules", "error", err)
+			}
+		}
+	}()
+
 	// Graceful shutdown on SIGINT / SIGTERM.
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -93,12 +274,167 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		slog.Error("shutdown error", "error", err)
 	}
+	if compactor != nil {
+		compactor.Stop()
+	}
+	if err := closeStore(); err != nil {
+		slog.Error("store close error", "error", err)
+	}
 	slog.Info("server stopped")
 }
 
+// refreshSettings applies rule sets and FX rates other replicas stored,
+// logging any change.
+func refreshSettings(engine *scoring.Engine) {
+	before := engine.ActiveRules()
+	shadowBefore, _ := engine.ChallengerRules()
+	ratesBefore := engine.Rates().Snapshot()
+	if err := engine.Refresh(); err != nil {
+		slog.Error("stored settings not applied", "error", err)
+	}
+	if info := engine.ActiveRules(); info.Hash != before.Hash {
+		slog.Info("stored scoring rules applied", "version", info.Version, "hash", info.Hash)
+	}
+	if info, on := engine.ChallengerRules(); info.Hash != shadowBefore.Hash {
+		slog.Info("stored shadow rules applied", "on", on, "version", info.Version, "hash", info.Hash)
+	}
+	if rates := engine.Rates().Snapshot(); !reflect.DeepEqual(rates, ratesBefore) {
+		slog.Info("stored fx rates applied", "rates", len(rates.Rates))
+	}
+}
+
+// Storage backends selectable with -store.
+const (
+	storeMemory = "memory"
+	storeSQLite = "sqlite"
+	storeRedis  = "redis"
+)
+
+// storeConfig gathers the storage flags.
+type storeConfig struct {
+	backend    string
+	sqlitePath string
+	redisAddr  string
+	retention  time.Duration
+	dataDir    string
+	durable    store.DurableOptions
+}
+
+// openStore returns the selected backend. The in-memory store is made durable
+// with a WAL and snapshots when dataDir is set. The returned func flushes and
+// closes the store.
+func openStore(cfg storeConfig) (store.Store, func() error, error) {
+	if cfg.backend != storeMemory && cfg.dataDir != "" {
+		return nil, nil, errors.New("-data-dir applies only to -store=memory")
+	}
+	switch cfg.backend {
+	case storeMemory:
+	case storeSQLite:
+		db, err := store.OpenSQLite(cfg.sqlitePath)
+		if err != nil {
+			return nil, nil, err
+		}
+		slog.Info("sqlite store opened", "path", cfg.sqlitePath)
+		return db, db.Close, nil
+	case storeRedis:
+		client := redis.NewClient(&redis.Options{Addr: cfg.redisAddr})
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := client.Ping(ctx).Err(); err != nil {
+			client.Close()
+			return nil, nil, fmt.Errorf("redis %s: %w", cfg.redisAddr, err)
+		}
+		// The retention horizon doubles as the key TTL; RedisOptions treats a
+		// negative TTL as never expiring.
+		ttl := cfg.retention
+		if ttl <= 0 {
+			ttl = -1
+		}
+		slog.Info("redis store connected", "addr", cfg.redisAddr, "ttl", ttl)
+		return store.NewRedis(client, store.RedisOptions{TTL: ttl}), client.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown -store %q: must be memory, sqlite or redis", cfg.backend)
+	}
+
+	dataDir, opts := cfg.dataDir, cfg.durable
+	if dataDir == "" {
+		slog.Info("no -data-dir; state is kept in memory only")
+		return store.New(), func() error { return nil }, nil
+	}
+	opts.Dir = dataDir
+	d, err := store.OpenDurable(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.Info("durable store opened", "dir", dataDir, "wal_sync", opts.Sync)
+	return d, d.Close, nil
+}
+
+// recordSignalReload audits a reload triggered by SIGHUP rather than the API.
+func recordSignalReload(log *audit.Log, action, resource string, before, after scoring.RuleSetInfo) {
+	if _, err := log.Record("signal:SIGHUP", "", action, resource, "", before, after); err != nil {
+		slog.Error("audit record failed", "action", action, "error", err)
+	}
+}
+
+// bootstrapAdminKey registers the first admin key, from which every other key
+// is issued through the API. Keys are kept in the store, so a configured key
+// already registered there, e.g. by an earlier run, is reused. Without
+// one configured, a fresh key is generated and its token printed once to
+// stderr, unless the store already holds an active admin key. The token never
+// goes through the structured logger, so it does not end up in log shipping;
+// only its hash is stored, so copy it from the terminal.
+func bootstrapAdminKey(keys *auth.Keyring, token string) error {
+	scopes := []string{auth.ScopeAdmin}
+	if token != "" {
+		key, err := keys.Authenticate(token)
+		if err != nil {
+			key, _, err = keys.Add("bootstrap-admin", token, scopes, "env:API_ADMIN_KEY")
+		}
+		if errors.Is(err, auth.ErrTokenTaken) {
+			// Registered by another process since, or revoked.
+			if key, err = keys.Authenticate(token); err != nil {
+				return errors.New("the key has been revoked; set a new API_ADMIN_KEY")
+			}
+		}
+		if err != nil {
+			return err
+		}
+		slog.Info("admin API key loaded", "id", key.ID, "prefix", key.Prefix)
+		return nil
+	}
+	for _, key := range keys.List() {
+		if key.RevokedAt == nil && key.Allows(auth.ScopeAdmin) {
+			slog.Info("API_ADMIN_KEY not set; using the stored admin key", "id", key.ID, "prefix", key.Prefix)
+			return nil
+		}
+	}
+	key, token, err := keys.Create("bootstrap-admin", scopes, "startup")
+	if err != nil {
+		return err
+	}
+	slog.Warn("API_ADMIN_KEY not set; generated an admin key, token printed to stderr", "id", key.ID, "prefix", key.Prefix)
+	fmt.Fprintf(os.Stderr, "\nGenerated admin API key (shown once, store it now):\n\n    %s\n\n", token)
+	return nil
+}
+
+// loadRules loads the scoring rule file. A missing file falls back to the
+// built-in defaults so the server still starts from a bare checkout; the path
+// is kept as the source so a SIGHUP picks the file up once it exists.
+func loadRules(filePath string) (*scoring.RuleSet, error) {
+	rules, err := scoring.LoadRuleSet(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		slog.Warn("rule file not found, using built-in rules", "file", filePath)
+		rules = scoring.DefaultRuleSet()
+		rules.Source = filePath
+		return rules, nil
+	}
+	return rules, err
+}
+
 // loadSeedData reads a JSON file of TransactionRequests, scores each one,
 // and persists them to the store so the API starts with historical context.
-func loadSeedData(s *store.Store, e *scoring.Engine, filePath string) error {
+func loadSeedData(s store.Store, e *scoring.Engine, filePath string) error {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return err
@@ -117,18 +453,11 @@ func loadSeedData(s *store.Store, e *scoring.Engine, filePath string) error {
 
 	var loaded, skipped int
 	for i := range requests {
-		req := &requests[i]
-		score, factors, explanation := e.Score(req)
-		recommendation, riskLevel := scoring.Recommend(score)
-
-		tx := &domain.Transaction{
-			TransactionRequest: *req,
-			RiskScore:          score,
-			RiskLevel:          riskLevel,
-			Recommendation:     recommendation,
-			Factors:            factors,
-			Explanation:        explanation,
-			ProcessedAt:        time.Now().UTC(),
+		tx, err := e.Assess(&requests[i])
+		if err != nil {
+			slog.Warn("seed transaction rejected", "transaction_id", requests[i].TransactionID, "error", err)
+			skipped++
+			continue
 		}
 		if err := s.SaveTransaction(tx); err != nil {
 			skipped++
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"lumina/fraud-api/internal/auth"
+)
+
+// authenticate resolves the caller's API key from either
+// "Authorization: Bearer <token>" or "X-API-Key: <token>" and stores it in the
+// request context. Requests without a valid key are rejected with 401.
+func authenticate(keys *auth.Keyring) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := tokenFrom(r)
+			if token == "" {
+				unauthorized(w, "an API key is required: send 'Authorization: Bearer <key>'")
+				return
+			}
+			key, err := keys.Authenticate(token)
+			if err != nil {
+				unauthorized(w, err.Error())
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(auth.WithKey(r.Context(), key)))
+		})
+	}
+}
+
+// requireScope rejects requests whose key does not grant scope with 403.
+// It must run after authenticate.
+func requireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, found := auth.FromContext(r.Context())
+			if !found {
+				unauthorized(w, "an API key is required")
+				return
+			}
+			if !key.Allows(scope) {
+				forbidden(w, fmt.Sprintf("API key '%s' lacks the '%s' scope", key.Name, scope))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func tokenFrom(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); h != "" {
+		scheme, token, found := strings.Cut(h, " ")
+		if found && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
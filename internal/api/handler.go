@@ -1,8 +1,15 @@
 package api
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"math"
 	"net/http"
 	"net/url"
 	"sort"
@@ -11,9 +18,15 @@ import (
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/google/uuid"
 
+	"lumina/fraud-api/internal/audit"
+	"lumina/fraud-api/internal/auth"
 	"lumina/fraud-api/internal/domain"
+	"lumina/fraud-api/internal/fx"
+	"lumina/fraud-api/internal/graph"
+	"lumina/fraud-api/internal/review"
 	"lumina/fraud-api/internal/scoring"
 	"lumina/fraud-api/internal/store"
 	"lumina/fraud-api/internal/webhook"
@@ -21,20 +34,40 @@ import (
 
 // Handler holds the dependencies shared across all HTTP handlers.
 type Handler struct {
-	store    *store.Store
+	store    store.Store
 	engine   *scoring.Engine
 	notifier *webhook.Notifier
+	reviews  *review.Queue
+	auditLog *audit.Log
+	keys     *auth.Keyring
+
+	idempotencyTTL time.Duration
 }
 
 // NewHandler creates a Handler wired to the given dependencies.
-func NewHandler(s *store.Store, e *scoring.Engine, n *webhook.Notifier) *Handler {
-	return &Handler{store: s, engine: e, notifier: n}
+func NewHandler(s store.Store, e *scoring.Engine, n *webhook.Notifier, q *review.Queue, a *audit.Log, k *auth.Keyring) *Handler {
+	return &Handler{store: s, engine: e, notifier: n, reviews: q, auditLog: a, keys: k, idempotencyTTL: DefaultIdempotencyTTL}
+}
+
+// SetIdempotencyTTL sets how long an Idempotency-Key replays its original
+// result. Non-positive values keep the current TTL.
+func (h *Handler) SetIdempotencyTTL(ttl time.Duration) {
+	if ttl > 0 {
+		h.idempotencyTTL = ttl
+	}
 }
 
 // ─── POST /api/v1/transactions ────────────────────────────────────────────────
 
 // SubmitTransaction accepts a transaction payload, scores it, saves it, and
 // returns the full risk analysis result synchronously.
+//
+// A request carrying an Idempotency-Key header can be retried safely: while
+// the key is live, resending the same payload returns the stored transaction
+// with 200 instead of a duplicate error, and a different payload under the
+// same key is rejected with 422. The key is reserved before scoring, so of
+// concurrent requests sharing a key only one is scored; it is released again
+// if that request fails.
 func (h *Handler) SubmitTransaction(w http.ResponseWriter, r *http.Request) {
 	var req domain.TransactionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -47,25 +80,42 @@ func (h *Handler) SubmitTransaction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Score the transaction before saving so historical lookups exclude it.
-	score, factors, explanation := h.engine.Score(&req)
-	recommendation, riskLevel := scoring.Recommend(score)
+	idem, err := idempotencyKeyFrom(r)
+	if err != nil {
+		badRequest(w, "INVALID_IDEMPOTENCY_KEY", err.Error())
+		return
+	}
+	var reservation domain.IdempotencyKey
+	if idem != "" {
+		var done bool
+		if reservation, done = h.reserveIdempotencyKey(w, idem, &req); done {
+			return
+		}
+	}
 
-	tx := &domain.Transaction{
-		TransactionRequest: req,
-		RiskScore:          score,
-		RiskLevel:          riskLevel,
-		Recommendation:     recommendation,
-		Factors:            factors,
-		Explanation:        explanation,
-		ProcessedAt:        time.Now().UTC(),
+	// Score the transaction before saving so historical lookups exclude it.
+	tx, err := h.engine.Assess(&req)
+	if err != nil {
+		h.releaseIdempotencyKey(reservation)
+		badRequest(w, "UNSUPPORTED_CURRENCY", err.Error())
+		return
 	}
 
 	if err := h.store.SaveTransaction(tx); err != nil {
 		if err == store.ErrDuplicateTransaction {
+			// The same request may have been saved earlier without its key;
+			// the key now points at it, so hand back its result.
+			if idem != "" {
+				if stored, found := h.store.GetTransaction(req.TransactionID); found && requestFingerprint(&stored.TransactionRequest) == reservation.Fingerprint {
+					replayed(w, stored)
+					return
+				}
+			}
+			h.releaseIdempotencyKey(reservation)
 			conflict(w, fmt.Sprintf("transaction '%s' already exists", req.TransactionID))
 			return
 		}
+		h.releaseIdempotencyKey(reservation)
 		internalError(w)
 		return
 	}
@@ -76,6 +126,916 @@ func (h *Handler) SubmitTransaction(w http.ResponseWriter, r *http.Request) {
 	created(w, tx)
 }
 
+// ─── Idempotency keys ─────────────────────────────────────────────────────────
+
+// DefaultIdempotencyTTL is how long an Idempotency-Key is remembered unless
+// SetIdempotencyTTL says otherwise.
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// maxIdempotencyKeyLen bounds the header so keys stay cheap to store.
+const maxIdempotencyKeyLen = 255
+
+// idempotencyKeyFrom returns the request's Idempotency-Key, or "" when it
+// sent none. Keys are namespaced by API key, so two clients choosing the
+// same key never see each other's transactions.
+func idempotencyKeyFrom(r *http.Request) (string, error) {
+	k := strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+	if k == "" {
+		return "", nil
+	}
+	if len(k) > maxIdempotencyKeyLen {
+		return "", fmt.Errorf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLen)
+	}
+	if key, found := auth.FromContext(r.Context()); found {
+		return key.ID + ":" + k, nil
+	}
+	return k, nil
+}
+
+// requestFingerprint hashes a transaction payload so a replay can be told
+// apart from a different request reusing its key. Times are compared as
+// instants, whatever offset the client wrote them in.
+func requestFingerprint(req *domain.TransactionRequest) string {
+	canonical := *req
+	canonical.Timestamp = canonical.Timestamp.UTC()
+	canonical.AccountCreatedAt = canonical.AccountCreatedAt.UTC()
+	data, _ := json.Marshal(canonical) // a TransactionRequest always encodes
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// replayIdempotent answers a request whose key is already bound: with the
+// stored transaction if the payload matches, with 422 if it does not.
+func (h *Handler) replayIdempotent(w http.ResponseWriter, bound domain.IdempotencyKey, fingerprint string) {
+	if bound.Fingerprint != fingerprint {
+		writeJSON(w, http.StatusUnprocessableEntity, envelope{Error: &apiError{
+			Code:    "IDEMPOTENCY_KEY_MISMATCH",
+			Message: fmt.Sprintf("this Idempotency-Key was already used for transaction '%s' with a different payload", bound.TransactionID),
+		}})
+		return
+	}
+	tx, found := h.store.GetTransaction(bound.TransactionID)
+	if !found {
+		// The request holding the key is still being scored, or its
+		// transaction has since been pruned.
+		conflict(w, fmt.Sprintf("transaction '%s' for this Idempotency-Key is still being processed or no longer stored", bound.TransactionID))
+		return
+	}
+	replayed(w, tx)
+}
+
+// reserveIdempotencyKey binds key to the request's transaction ID before it
+// is scored. If the key is already bound, or cannot be reserved, the
+// response is written and done is true.
+func (h *Handler) reserveIdempotencyKey(w http.ResponseWriter, key string, req *domain.TransactionRequest) (k domain.IdempotencyKey, done bool) {
+	now := time.Now()
+	k = domain.IdempotencyKey{
+		Key:           key,
+		TransactionID: req.TransactionID,
+		Fingerprint:   requestFingerprint(req),
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(h.idempotencyTTL),
+	}
+	bound, err := h.store.SaveIdempotencyKey(k)
+	switch {
+	case errors.Is(err, store.ErrIdempotencyKeyExists):
+		h.replayIdempotent(w, bound, k.Fingerprint)
+		return k, true
+	case err != nil:
+		slog.Error("idempotency key not reserved", "transaction_id", req.TransactionID, "error", err)
+		internalError(w)
+		return k, true
+	}
+	return k, false
+}
+
+// releaseIdempotencyKey frees a key reserved by a request that failed, so the
+// client can retry under it. A zero k, from a request without a key, is
+// ignored.
+func (h *Handler) releaseIdempotencyKey(k domain.IdempotencyKey) {
+	if k.Key == "" {
+		return
+	}
+	if err := h.store.ReleaseIdempotencyKey(k); err != nil {
+		slog.Warn("idempotency key not released", "transaction_id", k.TransactionID, "error", err)
+	}
+}
+
+// replayed writes the stored result of an earlier identical request.
+func replayed(w http.ResponseWriter, tx *domain.Transaction) {
+	w.Header().Set("Idempotent-Replayed", "true")
+	ok(w, tx)
+}
+
+// ─── POST /api/v1/transactions/batch ──────────────────────────────────────────
+
+// Batch modes.
+const (
+	batchBestEffort   = "best_effort"    // every valid item is saved
+	batchAllOrNothing = "all_or_nothing" // nothing is saved unless every item is valid
+)
+
+// Batch item statuses.
+const (
+	batchScored   = "scored"
+	batchRejected = "rejected"
+	batchSkipped  = "skipped" // valid, but the batch was rejected
+)
+
+// Batch limits. Larger imports are split by the caller.
+const (
+	maxBatchItems = 10000
+	maxBatchBytes = 32 << 20
+)
+
+// ndjsonContentType, when accepted by the client, streams batch results.
+const ndjsonContentType = "application/x-ndjson"
+
+type batchRequest struct {
+	Mode         string                      `json:"mode"`
+	Transactions []domain.TransactionRequest `json:"transactions"`
+}
+
+// batchItem is the result for one transaction of a batch. Items are scored
+// in time order, so Index ties a result back to its position in the request.
+type batchItem struct {
+	Index         int                 `json:"index"`
+	TransactionID string              `json:"transaction_id"`
+	Status        string              `json:"status"`
+	Transaction   *domain.Transaction `json:"transaction,omitempty"`
+	Error         *apiError           `json:"error,omitempty"`
+}
+
+func (it *batchItem) reject(code, message string) {
+	it.Status = batchRejected
+	it.Error = &apiError{Code: code, Message: message}
+}
+
+type batchSummary struct {
+	Mode     string `json:"mode"`
+	Total    int    `json:"total"`
+	Scored   int    `json:"scored"`
+	Rejected int    `json:"rejected"`
+	Skipped  int    `json:"skipped"`
+}
+
+func (s *batchSummary) count(it *batchItem) {
+	switch it.Status {
+	case batchScored:
+		s.Scored++
+	case batchRejected:
+		s.Rejected++
+	case batchSkipped:
+		s.Skipped++
+	}
+}
+
+type batchResponse struct {
+	batchSummary
+	Results []batchItem `json:"results"`
+}
+
+// SubmitTransactionBatch scores and saves many transactions in one request.
+//
+// Body: {"mode": "best_effort", "transactions": [ ...TransactionRequest ]}
+//
+// Items are scored in timestamp order, each seeing the ones before it in its
+// velocity windows, exactly as if they had been submitted one by one. Every
+// item gets a result: its scored transaction, or the error that rejected it.
+//
+//   - best_effort (default) saves every valid item.
+//   - all_or_nothing validates every item first, including duplicate IDs,
+//     and saves nothing if any fails (422). Valid batches are then saved in
+//     one atomic store write; if another request saved one of its IDs in the
+//     meantime, nothing is saved and the batch fails with 409.
+//
+// With "Accept: application/x-ndjson" results are streamed one JSON object
+// per line as they are scored, followed by a {"summary": ...} line.
+func (h *Handler) SubmitTransactionBatch(w http.ResponseWriter, r *http.Request) {
+	var req batchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBytes)).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeJSON(w, http.StatusRequestEntityTooLarge, envelope{Error: &apiError{
+				Code: "BATCH_TOO_LARGE", Message: fmt.Sprintf("body must not exceed %d bytes", maxBatchBytes),
+			}})
+			return
+		}
+		badRequest(w, "INVALID_JSON", "body must be a JSON object with a transactions array")
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = batchBestEffort
+	}
+	if req.Mode != batchBestEffort && req.Mode != batchAllOrNothing {
+		badRequest(w, "INVALID_PARAM", "mode must be best_effort or all_or_nothing")
+		return
+	}
+	n := len(req.Transactions)
+	if n == 0 {
+		badRequest(w, "VALIDATION_ERROR", "transactions must not be empty")
+		return
+	}
+	if n > maxBatchItems {
+		badRequest(w, "BATCH_TOO_LARGE", fmt.Sprintf("a batch holds at most %d transactions", maxBatchItems))
+		return
+	}
+
+	items := make([]batchItem, n)
+	for i := range items {
+		items[i] = batchItem{Index: i, TransactionID: req.Transactions[i].TransactionID}
+	}
+	summary := batchSummary{Mode: req.Mode, Total: n}
+	stream := strings.Contains(r.Header.Get("Accept"), ndjsonContentType)
+
+	if req.Mode == batchAllOrNothing && !h.precheckBatch(req.Transactions, items) {
+		rejectBatch(w, stream, http.StatusUnprocessableEntity, items, summary, "are invalid")
+		return
+	}
+
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return req.Transactions[order[a]].Timestamp.Before(req.Transactions[order[b]].Timestamp)
+	})
+
+	if req.Mode == batchAllOrNothing {
+		err := h.saveBatch(req.Transactions, items, order)
+		switch {
+		case errors.Is(err, store.ErrDuplicateTransaction):
+			rejectBatch(w, stream, http.StatusConflict, items, summary, "already exist")
+			return
+		case errors.Is(err, errBatchRejected):
+			rejectBatch(w, stream, http.StatusUnprocessableEntity, items, summary, "are invalid")
+			return
+		case err != nil:
+			slog.Error("batch not saved", "error", err)
+			internalError(w)
+			return
+		}
+	}
+
+	var out *ndjsonWriter
+	if stream {
+		out = newNDJSONWriter(w, http.StatusOK)
+	}
+	for _, i := range order {
+		if req.Mode == batchBestEffort {
+			h.scoreBatchItem(&req.Transactions[i], &items[i])
+		}
+		summary.count(&items[i])
+		if out != nil {
+			out.write(&items[i])
+		}
+	}
+	if out != nil {
+		out.write(map[string]any{"summary": summary})
+		return
+	}
+	ok(w, batchResponse{batchSummary: summary, Results: items})
+}
+
+// rejectBatch answers an all_or_nothing batch that saved nothing. Items
+// without a result of their own are marked skipped; reason completes "N of M
+// transactions ...".
+func rejectBatch(w http.ResponseWriter, stream bool, status int, items []batchItem, summary batchSummary, reason string) {
+	for i := range items {
+		if items[i].Status != batchRejected {
+			items[i].Status, items[i].Transaction = batchSkipped, nil
+		}
+		summary.count(&items[i])
+	}
+	rejected := &apiError{
+		Code:    "BATCH_REJECTED",
+		Message: fmt.Sprintf("%d of %d transactions %s; none were saved", summary.Rejected, summary.Total, reason),
+	}
+	if stream {
+		out := newNDJSONWriter(w, status)
+		for i := range items {
+			out.write(&items[i])
+		}
+		out.write(map[string]any{"summary": summary, "error": rejected})
+		return
+	}
+	writeJSON(w, status, envelope{
+		Data:  batchResponse{batchSummary: summary, Results: items},
+		Error: rejected,
+	})
+}
+
+// errBatchRejected means an all_or_nothing item failed while being scored,
+// e.g. its currency was dropped from the FX table after the precheck.
+var errBatchRejected = errors.New("batch item rejected")
+
+// saveBatch scores an all_or_nothing batch in order, each item seeing the
+// live store and the items before it, then saves it with one
+// SaveTransactions call, so the store holds all of it or none of it.
+// Webhooks fire only once the whole batch is saved. If another request saved
+// one of the IDs after the precheck, that item is rejected and
+// store.ErrDuplicateTransaction returned.
+func (h *Handler) saveBatch(reqs []domain.TransactionRequest, items []batchItem, order []int) error {
+	view := &batchOverlay{live: h.store, pending: store.New()}
+	engine := h.engine.WithStore(view)
+	txs := make([]*domain.Transaction, 0, len(order))
+	for _, i := range order {
+		tx, err := engine.Assess(&reqs[i])
+		if err != nil {
+			items[i].reject("UNSUPPORTED_CURRENCY", err.Error())
+			return errBatchRejected
+		}
+		if err := view.SaveTransaction(tx); err != nil {
+			return err
+		}
+		txs = append(txs, tx)
+	}
+	if err := h.store.SaveTransactions(txs); err != nil {
+		if errors.Is(err, store.ErrDuplicateTransaction) {
+			for i := range reqs {
+				if _, exists := h.store.GetTransaction(reqs[i].TransactionID); exists {
+					items[i].reject("CONFLICT", fmt.Sprintf("transaction '%s' already exists", reqs[i].TransactionID))
+				}
+			}
+		}
+		return err
+	}
+	for j, i := range order {
+		h.notifier.NotifyAsync(txs[j])
+		items[i].Status, items[i].Transaction = batchScored, txs[j]
+	}
+	return nil
+}
+
+// scoreBatchItem validates, scores and saves one batch item, recording the
+// result on it.
+func (h *Handler) scoreBatchItem(req *domain.TransactionRequest, it *batchItem) {
+	if err := validateTransactionRequest(req); err != nil {
+		it.reject("VALIDATION_ERROR", err.Error())
+		return
+	}
+	tx, err := h.engine.Assess(req)
+	if err != nil {
+		it.reject("UNSUPPORTED_CURRENCY", err.Error())
+		return
+	}
+	if err := h.store.SaveTransaction(tx); err != nil {
+		if errors.Is(err, store.ErrDuplicateTransaction) {
+			it.reject("CONFLICT", fmt.Sprintf("transaction '%s' already exists", req.TransactionID))
+		} else {
+			it.reject("INTERNAL_ERROR", "an unexpected error occurred")
+		}
+		return
+	}
+	h.notifier.NotifyAsync(tx)
+	it.Status = batchScored
+	it.Transaction = tx
+}
+
+// precheckBatch rejects every item that scoreBatchItem would reject, without
+// saving anything. It reports whether all items passed.
+func (h *Handler) precheckBatch(reqs []domain.TransactionRequest, items []batchItem) bool {
+	valid := true
+	seen := make(map[string]bool, len(reqs))
+	for i := range reqs {
+		req, it := &reqs[i], &items[i]
+		if err := validateTransactionRequest(req); err != nil {
+			it.reject("VALIDATION_ERROR", err.Error())
+		} else if err := h.engine.CheckCurrency(req); err != nil {
+			it.reject("UNSUPPORTED_CURRENCY", err.Error())
+		} else if seen[req.TransactionID] {
+			it.reject("CONFLICT", fmt.Sprintf("transaction '%s' appears more than once in the batch", req.TransactionID))
+		} else if _, exists := h.store.GetTransaction(req.TransactionID); exists {
+			it.reject("CONFLICT", fmt.Sprintf("transaction '%s' already exists", req.TransactionID))
+		}
+		seen[req.TransactionID] = true
+		if it.Status == batchRejected {
+			valid = false
+		}
+	}
+	return valid
+}
+
+// ndjsonWriter streams newline-delimited JSON, flushing after every line so
+// the client sees results while the rest of the batch is scored.
+type ndjsonWriter struct {
+	enc *json.Encoder
+	rc  *http.ResponseController
+}
+
+func newNDJSONWriter(w http.ResponseWriter, status int) *ndjsonWriter {
+	w.Header().Set("Content-Type", ndjsonContentType)
+	w.WriteHeader(status)
+	return &ndjsonWriter{enc: json.NewEncoder(w), rc: http.NewResponseController(w)}
+}
+
+func (nw *ndjsonWriter) write(v any) {
+	// Headers are sent; a client that went away just stops receiving lines.
+	if err := nw.enc.Encode(v); err == nil {
+		_ = nw.rc.Flush()
+	}
+}
+
+// ─── POST /api/v1/score/simulate ──────────────────────────────────────────────
+
+// maxSimulationHistory caps the synthetic history of one simulation.
+const maxSimulationHistory = 1000
+
+type simulateRequest struct {
+	Transaction domain.TransactionRequest   `json:"transaction"`
+	Rules       json.RawMessage             `json:"rules,omitempty"`
+	History     []domain.TransactionRequest `json:"history,omitempty"`
+}
+
+// simulation is a dry-run result: the transaction as it would be saved,
+// and where the rules and history it was scored with came from.
+type simulation struct {
+	Transaction *domain.Transaction `json:"transaction"`
+	Rules       scoring.RuleSetInfo `json:"rules"`
+	RulesSource string              `json:"rules_source"`   // "active" or "request"
+	History     string              `json:"history_source"` // "live" or "request"
+}
+
+// SimulateScore answers "what would this transaction score?" without saving
+// it or notifying webhooks. It runs the same pipeline as SubmitTransaction.
+//
+// Body: {"transaction": {...}, "rules": {...}, "history": [...]}
+//
+//   - rules (optional) is a rule document, as for PUT /admin/rules, used
+//     instead of the active rule set for this request only.
+//   - history (optional) replaces the live entity history: the transaction is
+//     scored as if these requests, scored oldest first, were all the store
+//     held. Lists and threshold overrides still come from the live store.
+//
+// transaction_id may be omitted.
+func (h *Handler) SimulateScore(w http.ResponseWriter, r *http.Request) {
+	var req simulateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "INVALID_JSON", "request body must be valid JSON")
+		return
+	}
+	if req.Transaction.TransactionID == "" {
+		req.Transaction.TransactionID = "simulation"
+	}
+	if err := validateTransactionRequest(&req.Transaction); err != nil {
+		badRequest(w, "VALIDATION_ERROR", "transaction: "+err.Error())
+		return
+	}
+	if len(req.History) > maxSimulationHistory {
+		badRequest(w, "VALIDATION_ERROR", fmt.Sprintf("history holds at most %d transactions", maxSimulationHistory))
+		return
+	}
+
+	result := simulation{RulesSource: "active", History: "live"}
+	rules := h.engine.RuleSet()
+	if len(req.Rules) > 0 && string(req.Rules) != "null" {
+		rs, err := scoring.ParseRuleSet(req.Rules)
+		if err != nil {
+			badRequest(w, "INVALID_RULES", flattenError(err))
+			return
+		}
+		rules, result.RulesSource = rs, "request"
+	}
+
+	engine := h.engine
+	if result.RulesSource != "active" || req.History != nil {
+		var s scoring.Store = h.store
+		if req.History != nil {
+			overlay := &historyOverlay{live: h.store, history: store.New()}
+			if err := replayHistory(scoring.New(overlay, rules, h.engine.Rates()), overlay, req.History); err != nil {
+				badRequest(w, "INVALID_HISTORY", err.Error())
+				return
+			}
+			s, result.History = overlay, "request"
+		}
+		engine = scoring.New(s, rules, h.engine.Rates())
+	}
+
+	tx, err := engine.Assess(&req.Transaction)
+	if err != nil {
+		badRequest(w, "UNSUPPORTED_CURRENCY", err.Error())
+		return
+	}
+	result.Transaction = tx
+	result.Rules = engine.ActiveRules()
+	ok(w, result)
+}
+
+// replayHistory scores synthetic history oldest first and saves it to the
+// overlay, the way the seed loader builds real history.
+func replayHistory(e *scoring.Engine, s *historyOverlay, history []domain.TransactionRequest) error {
+	order := make([]int, len(history))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return history[order[a]].Timestamp.Before(history[order[b]].Timestamp)
+	})
+	for _, i := range order {
+		req := &history[i]
+		if req.TransactionID == "" {
+			req.TransactionID = fmt.Sprintf("history-%d", i)
+		}
+		if err := validateTransactionRequest(req); err != nil {
+			return fmt.Errorf("history[%d]: %w", i, err)
+		}
+		tx, err := e.Assess(req)
+		if err != nil {
+			return fmt.Errorf("history[%d]: %w", i, err)
+		}
+		if err := s.SaveTransaction(tx); err != nil {
+			return fmt.Errorf("history[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// historyOverlay is what simulations score against: entity history,
+// velocity counts, fraud labels and entity links come from a scratch store;
+// block entries and thresholds from the live one. Writes go to the scratch
+// store.
+type historyOverlay struct {
+	live    scoring.Store
+	history *store.Memory
+}
+
+func (o *historyOverlay) SaveTransaction(tx *domain.Transaction) error {
+	return o.history.SaveTransaction(tx)
+}
+
+func (o *historyOverlay) GetTransactionsByEmail(email string, since time.Time) []*domain.Transaction {
+	return o.history.GetTransactionsByEmail(email, since)
+}
+
+func (o *historyOverlay) GetTransactionsByIP(ip string, since time.Time) []*domain.Transaction {
+	return o.history.GetTransactionsByIP(ip, since)
+}
+
+func (o *historyOverlay) GetTransactionsByDevice(device string, since time.Time) []*domain.Transaction {
+	return o.history.GetTransactionsByDevice(device, since)
+}
+
+func (o *historyOverlay) GetTransactionsByBIN(bin string, since time.Time) []*domain.Transaction {
+	return o.history.GetTransactionsByBIN(bin, since)
+}
+
+func (o *historyOverlay) GetUniqueCardsByIP(ip string) int {
+	return o.history.GetUniqueCardsByIP(ip)
+}
+
+func (o *historyOverlay) FirstFraudLabelled(emails []string) (int, string, bool) {
+	return o.history.FirstFraudLabelled(emails)
+}
+
+func (o *historyOverlay) CountTransactions(entityType, value string, since time.Time) int {
+	return o.history.CountTransactions(entityType, value, since)
+}
+
+func (o *historyOverlay) CountDistinctEmails(entityType, value string, since time.Time) int {
+	return o.history.CountDistinctEmails(entityType, value, since)
+}
+
+func (o *historyOverlay) GetEntityLinks(entityType, value string) []domain.EntityLink {
+	return o.history.GetEntityLinks(entityType, value)
+}
+
+func (o *historyOverlay) CheckBlocklist(entityType, value string) (*domain.BlocklistEntry, bool) {
+	return o.live.CheckBlocklist(entityType, value)
+}
+
+func (o *historyOverlay) FirstBlocked(nodes []domain.EntityNode) (int, *domain.BlocklistEntry, bool) {
+	return o.live.FirstBlocked(nodes)
+}
+
+func (o *historyOverlay) ResolveThresholds(merchantID, merchantCountry string) (domain.Thresholds, string) {
+	return o.live.ResolveThresholds(merchantID, merchantCountry)
+}
+
+// batchOverlay is what an all_or_nothing batch is scored against: the live
+// store plus the batch items scored so far, which are only saved together at
+// the end. Velocity reads combine both; fraud labels, which the new items
+// cannot have yet, block entries and thresholds come from the live store.
+// Writes go to the pending items.
+type batchOverlay struct {
+	live    scoring.Store
+	pending *store.Memory
+}
+
+func (o *batchOverlay) SaveTransaction(tx *domain.Transaction) error {
+	return o.pending.SaveTransaction(tx)
+}
+
+func (o *batchOverlay) GetTransactionsByEmail(email string, since time.Time) []*domain.Transaction {
+	return append(o.live.GetTransactionsByEmail(email, since), o.pending.GetTransactionsByEmail(email, since)...)
+}
+
+func (o *batchOverlay) GetTransactionsByIP(ip string, since time.Time) []*domain.Transaction {
+	return append(o.live.GetTransactionsByIP(ip, since), o.pending.GetTransactionsByIP(ip, since)...)
+}
+
+func (o *batchOverlay) GetTransactionsByDevice(device string, since time.Time) []*domain.Transaction {
+	return append(o.live.GetTransactionsByDevice(device, since), o.pending.GetTransactionsByDevice(device, since)...)
+}
+
+func (o *batchOverlay) GetTransactionsByBIN(bin string, since time.Time) []*domain.Transaction {
+	return append(o.live.GetTransactionsByBIN(bin, since), o.pending.GetTransactionsByBIN(bin, since)...)
+}
+
+func (o *batchOverlay) FirstFraudLabelled(emails []string) (int, string, bool) {
+	return o.live.FirstFraudLabelled(emails)
+}
+
+func (o *batchOverlay) CountTransactions(entityType, value string, since time.Time) int {
+	return o.live.CountTransactions(entityType, value, since) + o.pending.CountTransactions(entityType, value, since)
+}
+
+// CountDistinctEmails only loads the live window when the batch has items
+// for the entity; otherwise the live count is already exact.
+func (o *batchOverlay) CountDistinctEmails(entityType, value string, since time.Time) int {
+	if o.pending.CountTransactions(entityType, value, since) == 0 {
+		return o.live.CountDistinctEmails(entityType, value, since)
+	}
+	if entityType == domain.EntityEmail {
+		return 1
+	}
+	var window []*domain.Transaction
+	switch entityType {
+	case domain.EntityIP:
+		window = o.GetTransactionsByIP(value, since)
+	case domain.EntityDevice:
+		window = o.GetTransactionsByDevice(value, since)
+	case domain.EntityBIN:
+		window = o.GetTransactionsByBIN(value, since)
+	}
+	emails := make(map[string]bool, len(window))
+	for _, tx := range window {
+		emails[tx.UserEmail] = true
+	}
+	return len(emails)
+}
+
+// GetUniqueCardsByIP adds the batch's BINs the IP's stored transactions have
+// not used.
+func (o *batchOverlay) GetUniqueCardsByIP(ip string) int {
+	pending := o.pending.GetTransactionsByIP(ip, time.Time{})
+	if len(pending) == 0 {
+		return o.live.GetUniqueCardsByIP(ip)
+	}
+	seen := make(map[string]bool)
+	for _, tx := range o.live.GetTransactionsByIP(ip, time.Time{}) {
+		seen[tx.CardBIN] = true
+	}
+	n := o.live.GetUniqueCardsByIP(ip)
+	for _, tx := range pending {
+		if !seen[tx.CardBIN] {
+			seen[tx.CardBIN] = true
+			n++
+		}
+	}
+	return n
+}
+
+func (o *batchOverlay) GetEntityLinks(entityType, value string) []domain.EntityLink {
+	links := o.live.GetEntityLinks(entityType, value)
+	at := make(map[domain.EntityNode]int, len(links))
+	for i, l := range links {
+		at[l.Node] = i
+	}
+	for _, l := range o.pending.GetEntityLinks(entityType, value) {
+		i, ok := at[l.Node]
+		if !ok {
+			links = append(links, l)
+			continue
+		}
+		links[i].Weight += l.Weight
+		if l.FirstSeen.Before(links[i].FirstSeen) {
+			links[i].FirstSeen = l.FirstSeen
+		}
+		if l.LastSeen.After(links[i].LastSeen) {
+			links[i].LastSeen = l.LastSeen
+		}
+	}
+	return links
+}
+
+func (o *batchOverlay) CheckBlocklist(entityType, value string) (*domain.BlocklistEntry, bool) {
+	return o.live.CheckBlocklist(entityType, value)
+}
+
+func (o *batchOverlay) FirstBlocked(nodes []domain.EntityNode) (int, *domain.BlocklistEntry, bool) {
+	return o.live.FirstBlocked(nodes)
+}
+
+func (o *batchOverlay) ResolveThresholds(merchantID, merchantCountry string) (domain.Thresholds, string) {
+	return o.live.ResolveThresholds(merchantID, merchantCountry)
+}
+
+// ─── GET /api/v1/transactions ─────────────────────────────────────────────────
+
+// maxSearchLimit caps the page size of GET /transactions.
+const maxSearchLimit = 500
+
+// transactionList is the response body of GET /transactions.
+type transactionList struct {
+	Transactions []*domain.Transaction `json:"transactions"`
+	NextCursor   string                `json:"next_cursor,omitempty"` // absent on the last page
+}
+
+// searchCursor is the decoded form of next_cursor. It carries the order it
+// was issued for, so it cannot be replayed against a different sort.
+type searchCursor struct {
+	Sort  string       `json:"sort"`
+	Desc  bool         `json:"desc"`
+	After store.Cursor `json:"after"`
+}
+
+var (
+	validRiskLevels      = map[string]bool{domain.RiskLow: true, domain.RiskMedium: true, domain.RiskHigh: true}
+	validRecommendations = map[string]bool{domain.ActionApprove: true, domain.ActionReview: true, domain.ActionDecline: true}
+	validSearchSorts     = map[string]bool{store.SortByTimestamp: true, store.SortByScore: true, store.SortByAmount: true}
+)
+
+// ListTransactions searches stored transactions, newest first unless asked
+// otherwise, one page at a time. To read the next page, repeat the request
+// with cursor set to the previous page's next_cursor.
+//
+// Query params (all optional; lists are comma-separated and match any value):
+//
+//	since, until           — RFC 3339; since inclusive, until exclusive
+//	risk_level             — low, medium, high
+//	recommendation         — approve, review, decline
+//	currency               — ISO 4217 codes
+//	ip_country             — ISO 3166-1 alpha-2 codes; likewise card_country
+//	                         and merchant_country
+//	factor                 — name of a risk factor that fired
+//	min_score, max_score   — 0–100, inclusive
+//	min_amount, max_amount — in the reporting currency, inclusive
+//	sort                   — timestamp (default), risk_score or amount
+//	order                  — desc (default) or asc
+//	limit                  — page size (default: 50, max: 500)
+//	cursor                 — next_cursor of the previous page
+func (h *Handler) ListTransactions(w http.ResponseWriter, r *http.Request) {
+	q, err := parseTransactionQuery(r.URL.Query())
+	if err != nil {
+		badRequest(w, "INVALID_PARAM", err.Error())
+		return
+	}
+	page, err := h.store.SearchTransactions(q)
+	if err != nil {
+		slog.Error("transaction search failed", "error", err)
+		internalError(w)
+		return
+	}
+	result := transactionList{Transactions: page.Transactions}
+	if result.Transactions == nil {
+		result.Transactions = []*domain.Transaction{}
+	}
+	if page.More {
+		last := page.Transactions[len(page.Transactions)-1]
+		result.NextCursor = encodeSearchCursor(searchCursor{Sort: q.Sort, Desc: q.Desc, After: store.CursorAt(last)})
+	}
+	ok(w, result)
+}
+
+func parseTransactionQuery(v url.Values) (store.TransactionQuery, error) {
+	q := store.TransactionQuery{Sort: store.SortByTimestamp, Desc: true, Limit: store.DefaultSearchLimit}
+	for name, dst := range map[string]*time.Time{"since": &q.Since, "until": &q.Until} {
+		if s := v.Get(name); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return q, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+			}
+			*dst = t
+		}
+	}
+
+	var err error
+	if q.RiskLevels, err = listParam(v, "risk_level", strings.ToLower, validRiskLevels); err != nil {
+		return q, err
+	}
+	if q.Recommendations, err = listParam(v, "recommendation", strings.ToLower, validRecommendations); err != nil {
+		return q, err
+	}
+	for name, dst := range map[string]*[]string{
+		"currency":         &q.Currencies,
+		"ip_country":       &q.IPCountries,
+		"card_country":     &q.CardCountries,
+		"merchant_country": &q.MerchantCountries,
+	} {
+		if *dst, err = listParam(v, name, strings.ToUpper, nil); err != nil {
+			return q, err
+		}
+	}
+	q.Factor = strings.TrimSpace(v.Get("factor"))
+
+	if q.MinScore, err = intParam(v, "min_score", 0, 100); err != nil {
+		return q, err
+	}
+	if q.MaxScore, err = intParam(v, "max_score", 0, 100); err != nil {
+		return q, err
+	}
+	if q.MinScore != nil && q.MaxScore != nil && *q.MinScore > *q.MaxScore {
+		return q, fmt.Errorf("min_score must not exceed max_score")
+	}
+	if q.MinAmount, err = amountParam(v, "min_amount"); err != nil {
+		return q, err
+	}
+	if q.MaxAmount, err = amountParam(v, "max_amount"); err != nil {
+		return q, err
+	}
+	if q.MinAmount != nil && q.MaxAmount != nil && *q.MinAmount > *q.MaxAmount {
+		return q, fmt.Errorf("min_amount must not exceed max_amount")
+	}
+
+	if s := v.Get("sort"); s != "" {
+		if !validSearchSorts[s] {
+			return q, fmt.Errorf("sort must be one of: timestamp, risk_score, amount")
+		}
+		q.Sort = s
+	}
+	switch v.Get("order") {
+	case "", "desc":
+	case "asc":
+		q.Desc = false
+	default:
+		return q, fmt.Errorf("order must be asc or desc")
+	}
+	if l := v.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			return q, fmt.Errorf("limit must be an integer between 1 and %d", maxSearchLimit)
+		}
+		q.Limit = n
+	}
+	if c := v.Get("cursor"); c != "" {
+		cur, err := decodeSearchCursor(c)
+		if err != nil || cur.Sort != q.Sort || cur.Desc != q.Desc {
+			return q, fmt.Errorf("cursor is invalid or was issued for a different sort or order")
+		}
+		q.After = &cur.After
+	}
+	return q, nil
+}
+
+// listParam splits a comma-separated parameter, normalising each value and,
+// when allowed is set, rejecting values outside it.
+func listParam(v url.Values, name string, normalize func(string) string, allowed map[string]bool) ([]string, error) {
+	raw := v.Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	var values []string
+	for _, s := range strings.Split(raw, ",") {
+		s = normalize(strings.TrimSpace(s))
+		if s == "" {
+			continue
+		}
+		if allowed != nil && !allowed[s] {
+			return nil, fmt.Errorf("%s contains unknown value '%s'", name, s)
+		}
+		values = append(values, s)
+	}
+	return values, nil
+}
+
+func intParam(v url.Values, name string, min, max int) (*int, error) {
+	s := v.Get(name)
+	if s == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < min || n > max {
+		return nil, fmt.Errorf("%s must be an integer between %d and %d", name, min, max)
+	}
+	return &n, nil
+}
+
+func amountParam(v url.Values, name string) (*float64, error) {
+	s := v.Get(name)
+	if s == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f < 0 || math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, fmt.Errorf("%s must be a non-negative number", name)
+	}
+	return &f, nil
+}
+
+func encodeSearchCursor(c searchCursor) string {
+	data, _ := json.Marshal(c) // plain values always encode
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSearchCursor(s string) (searchCursor, error) {
+	var c searchCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	return c, json.Unmarshal(data, &c)
+}
+
 // ─── GET /api/v1/transactions/{id} ───────────────────────────────────────────
 
 // GetTransaction retrieves a previously scored transaction by its ID.
@@ -89,29 +1049,258 @@ func (h *Handler) GetTransaction(w http.ResponseWriter, r *http.Request) {
 	ok(w, tx)
 }
 
+// ─── Outcomes ─────────────────────────────────────────────────────────────────
+
+var validOutcomeTypes = map[string]bool{
+	domain.OutcomeChargeback:     true,
+	domain.OutcomeConfirmedFraud: true,
+	domain.OutcomeFalsePositive:  true,
+	domain.OutcomeRefund:         true,
+}
+
+// RecordOutcome attaches what happened after the decision — a chargeback,
+// confirmed fraud, false positive or refund — to a scored transaction.
+//
+// Body: {"type": "chargeback", "source": "acquirer", "occurred_at": "...",
+// "reason_code": "10.4", "amount": 120.5, "note": "..."}
+func (h *Handler) RecordOutcome(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req struct {
+		Type       string     `json:"type"`
+		Source     string     `json:"source"`
+		ReasonCode string     `json:"reason_code"`
+		Amount     float64    `json:"amount"`
+		Note       string     `json:"note"`
+		OccurredAt *time.Time `json:"occurred_at,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "INVALID_JSON", "request body must be valid JSON")
+		return
+	}
+
+	if !validOutcomeTypes[req.Type] {
+		badRequest(w, "INVALID_OUTCOME_TYPE",
+			"type must be one of: chargeback, confirmed_fraud, false_positive, refund")
+		return
+	}
+	if strings.TrimSpace(req.Source) == "" {
+		badRequest(w, "MISSING_SOURCE", "source is required")
+		return
+	}
+
+	tx, exists := h.store.GetTransaction(id)
+	if !exists {
+		notFound(w, fmt.Sprintf("transaction '%s' not found", id))
+		return
+	}
+	if req.Amount < 0 || req.Amount > tx.Amount {
+		badRequest(w, "INVALID_AMOUNT", "amount must be between 0 and the transaction amount")
+		return
+	}
+
+	now := time.Now().UTC()
+	occurredAt := now
+	if req.OccurredAt != nil {
+		occurredAt = req.OccurredAt.UTC()
+		if occurredAt.After(now) {
+			badRequest(w, "INVALID_PARAM", "occurred_at must not be in the future")
+			return
+		}
+	}
+
+	outcome := domain.Outcome{
+		ID:            uuid.NewString(),
+		TransactionID: id,
+		Type:          req.Type,
+		Source:        req.Source,
+		ReasonCode:    req.ReasonCode,
+		Amount:        req.Amount,
+		Note:          req.Note,
+		OccurredAt:    occurredAt,
+		RecordedAt:    now,
+	}
+	if _, err := h.store.AddOutcome(outcome); err != nil {
+		if errors.Is(err, store.ErrTransactionNotFound) {
+			notFound(w, fmt.Sprintf("transaction '%s' not found", id))
+			return
+		}
+		internalError(w)
+		return
+	}
+	h.audit(r, "outcome.record", "transactions/"+id, nil, outcome)
+	created(w, outcome)
+}
+
+// ListTransactionOutcomes returns the outcomes recorded for one transaction,
+// oldest first.
+func (h *Handler) ListTransactionOutcomes(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	tx, exists := h.store.GetTransaction(id)
+	if !exists {
+		notFound(w, fmt.Sprintf("transaction '%s' not found", id))
+		return
+	}
+	outcomes := tx.Outcomes
+	if outcomes == nil {
+		outcomes = []domain.Outcome{}
+	}
+	ok(w, outcomes)
+}
+
+// ListOutcomes returns recorded outcomes across all transactions, newest first.
+//
+// Query params:
+//
+//	type — only this outcome type (default: all)
+//	days — look-back window on occurred_at in days (default: 30, max: 365)
+func (h *Handler) ListOutcomes(w http.ResponseWriter, r *http.Request) {
+	outcomeType := r.URL.Query().Get("type")
+	if outcomeType != "" && !validOutcomeTypes[outcomeType] {
+		badRequest(w, "INVALID_OUTCOME_TYPE",
+			"type must be one of: chargeback, confirmed_fraud, false_positive, refund")
+		return
+	}
+
+	days := 30
+	if d := r.URL.Query().Get("days"); d != "" {
+		parsed, err := strconv.Atoi(d)
+		if err != nil || parsed < 1 || parsed > 365 {
+			badRequest(w, "INVALID_PARAM", "days must be an integer between 1 and 365")
+			return
+		}
+		days = parsed
+	}
+
+	since := time.Now().UTC().Add(-time.Duration(days) * 24 * time.Hour)
+	ok(w, h.store.ListOutcomes(outcomeType, since))
+}
+
+// ─── Review queue ─────────────────────────────────────────────────────────────
+
+// ListReviews returns the cases waiting for manual review.
+//
+// Query params:
+//
+//	sort — score (default, highest first) | amount (largest first) | age (oldest first)
+func (h *Handler) ListReviews(w http.ResponseWriter, r *http.Request) {
+	cases, err := h.reviews.Pending(r.URL.Query().Get("sort"))
+	if err != nil {
+		badRequest(w, "INVALID_PARAM", err.Error())
+		return
+	}
+	ok(w, cases)
+}
+
+// ClaimReview assigns a case to the calling analyst (the API key's name).
+func (h *Handler) ClaimReview(w http.ResponseWriter, r *http.Request) {
+	analyst, present := analystFrom(w, r)
+	if !present {
+		return
+	}
+	id := chi.URLParam(r, "id")
+	claim, err := h.reviews.Claim(id, analyst)
+	if err != nil {
+		reviewError(w, err)
+		return
+	}
+	h.audit(r, "review.claim", "transactions/"+id, nil, claim)
+	ok(w, claim)
+}
+
+// ReleaseReview returns a case claimed by the calling analyst to the pool.
+func (h *Handler) ReleaseReview(w http.ResponseWriter, r *http.Request) {
+	analyst, present := analystFrom(w, r)
+	if !present {
+		return
+	}
+	id := chi.URLParam(r, "id")
+	if err := h.reviews.Release(id, analyst); err != nil {
+		reviewError(w, err)
+		return
+	}
+	h.audit(r, "review.release", "transactions/"+id, nil, nil)
+	noContent(w)
+}
+
+// DecideReview records the calling analyst's decision on a case they claimed.
+//
+// Body: {"decision": "approve|decline", "notes": "...", "blocklist": ["email", "device"]}
+func (h *Handler) DecideReview(w http.ResponseWriter, r *http.Request) {
+	analyst, present := analystFrom(w, r)
+	if !present {
+		return
+	}
+	var d review.Decision
+	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+		badRequest(w, "INVALID_JSON", "request body must be valid JSON")
+		return
+	}
+	id := chi.URLParam(r, "id")
+	tx, err := h.reviews.Decide(id, analyst, d)
+	if err != nil {
+		reviewError(w, err)
+		return
+	}
+	h.audit(r, "review.decide", "transactions/"+id,
+		map[string]string{"final_status": domain.StatusPendingReview},
+		map[string]any{"final_status": tx.FinalStatus, "review": tx.Review})
+	ok(w, tx)
+}
+
+// GetReviewSLA reports queue ageing against the review SLA.
+func (h *Handler) GetReviewSLA(w http.ResponseWriter, r *http.Request) {
+	ok(w, h.reviews.SLA())
+}
+
+// analystFrom identifies the analyst working a case by the authenticated
+// API key's name, so each analyst needs a key of their own. X-Actor is a
+// label the client chooses and is only ever recorded as on_behalf_of; it
+// never decides who holds a claim.
+func analystFrom(w http.ResponseWriter, r *http.Request) (string, bool) {
+	key, found := auth.FromContext(r.Context())
+	if !found {
+		unauthorized(w, "an API key is required to work the review queue")
+		return "", false
+	}
+	return key.Name, true
+}
+
+func reviewError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, store.ErrTransactionNotFound):
+		notFound(w, err.Error())
+	case errors.Is(err, store.ErrNotInReview),
+		errors.Is(err, store.ErrReviewClaimed),
+		errors.Is(err, store.ErrReviewNotClaimed):
+		conflict(w, err.Error())
+	case errors.Is(err, review.ErrInvalidDecision):
+		badRequest(w, "INVALID_DECISION", err.Error())
+	case errors.Is(err, review.ErrInvalidBlocklist):
+		badRequest(w, "INVALID_BLOCKLIST", err.Error())
+	default:
+		internalError(w)
+	}
+}
+
 // ─── GET /api/v1/entities/{type}/{value} ─────────────────────────────────────
 
 // GetEntitySummary returns aggregated activity for a tracked entity
 // (email, ip, bin, or device) over a configurable look-back window.
 //
+// Aggregates cover the whole window; the transactions themselves are paged,
+// newest first.
+//
 // Query params:
-//   days — look-back window in days (default: 7, max: 90)
+//   days   — look-back window in days (default: 7, max: 90)
+//   limit  — transactions per page (default: 50, max: 500)
+//   cursor — next_cursor of the previous page
 func (h *Handler) GetEntitySummary(w http.ResponseWriter, r *http.Request) {
-	entityType := strings.ToLower(chi.URLParam(r, "type"))
-	rawValue := chi.URLParam(r, "value")
-	entityValue, _ := url.PathUnescape(rawValue)
-
-	validTypes := map[string]bool{
-		domain.EntityEmail:  true,
-		domain.EntityIP:     true,
-		domain.EntityBIN:    true,
-		domain.EntityDevice: true,
-	}
-	if !validTypes[entityType] {
-		badRequest(w, "INVALID_ENTITY_TYPE",
-			"entity type must be one of: email, ip, bin, device")
+	entity, valid := entityFromPath(w, r)
+	if !valid {
 		return
 	}
+	entityType, entityValue := entity.Type, entity.Value
 
 	days := 7
 	if d := r.URL.Query().Get("days"); d != "" {
@@ -123,7 +1312,27 @@ func (h *Handler) GetEntitySummary(w http.ResponseWriter, r *http.Request) {
 		days = parsed
 	}
 
-	since := time.Now().UTC().Add(-time.Duration(days) * 24 * time.Hour)
+	limit := store.DefaultSearchLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		parsed, err := strconv.Atoi(l)
+		if err != nil || parsed < 1 || parsed > maxSearchLimit {
+			badRequest(w, "INVALID_PARAM", fmt.Sprintf("limit must be an integer between 1 and %d", maxSearchLimit))
+			return
+		}
+		limit = parsed
+	}
+	var after *store.Cursor
+	if c := r.URL.Query().Get("cursor"); c != "" {
+		cur, err := decodeSearchCursor(c)
+		if err != nil || cur.Sort != store.SortByTimestamp || !cur.Desc {
+			badRequest(w, "INVALID_PARAM", "cursor is invalid")
+			return
+		}
+		after = &cur.After
+	}
+
+	now := time.Now().UTC()
+	since := now.Add(-time.Duration(days) * 24 * time.Hour)
 
 	var txns []*domain.Transaction
 	switch entityType {
@@ -137,27 +1346,127 @@ func (h *Handler) GetEntitySummary(w http.ResponseWriter, r *http.Request) {
 		txns = h.store.GetTransactionsByDevice(entityValue, since)
 	}
 
-	// Sort newest first for readability.
+	// Sort newest first for readability; ties by ID give pages a stable order.
 	sort.Slice(txns, func(i, j int) bool {
-		return txns[i].Timestamp.After(txns[j].Timestamp)
+		if !txns[i].Timestamp.Equal(txns[j].Timestamp) {
+			return txns[i].Timestamp.After(txns[j].Timestamp)
+		}
+		return txns[i].TransactionID > txns[j].TransactionID
 	})
 
-	summary := buildEntitySummary(entityType, entityValue, days, txns)
+	summary := buildEntitySummary(entityType, entityValue, days, since, now, txns)
+	summary.ReportingCurrency = h.reportingCurrency()
+	page, more := pageEntityTransactions(txns, after, limit)
+	summary.Transactions = make([]domain.Transaction, len(page))
+	for i, tx := range page {
+		summary.Transactions[i] = *tx
+	}
+	if more {
+		last := store.CursorAt(page[len(page)-1])
+		summary.NextCursor = encodeSearchCursor(searchCursor{Sort: store.SortByTimestamp, Desc: true, After: last})
+	}
 	ok(w, summary)
 }
 
-func buildEntitySummary(entityType, entityValue string, days int, txns []*domain.Transaction) domain.EntitySummary {
+// entityFromPath reads the {type}/{value} path parameters, writing a 400 and
+// reporting false when the type is unknown.
+func entityFromPath(w http.ResponseWriter, r *http.Request) (domain.EntityNode, bool) {
+	entityType := strings.ToLower(chi.URLParam(r, "type"))
+	rawValue := chi.URLParam(r, "value")
+	entityValue, _ := url.PathUnescape(rawValue)
+
+	validTypes := map[string]bool{
+		domain.EntityEmail:  true,
+		domain.EntityIP:     true,
+		domain.EntityBIN:    true,
+		domain.EntityDevice: true,
+	}
+	if !validTypes[entityType] {
+		badRequest(w, "INVALID_ENTITY_TYPE",
+			"entity type must be one of: email, ip, bin, device")
+		return domain.EntityNode{}, false
+	}
+	return domain.EntityNode{Type: entityType, Value: entityValue}, true
+}
+
+// reportingCurrency is the currency aggregated amounts are expressed in, or
+// empty when no FX table is configured and amounts are summed raw.
+func (h *Handler) reportingCurrency() string {
+	if rates := h.engine.Rates(); rates != nil && !rates.Empty() {
+		return rates.ReportingCurrency()
+	}
+	return ""
+}
+
+// entitySummaryTop caps the ranked counterpart and factor lists.
+const entitySummaryTop = 10
+
+// counterpartOf names, for each entity type, the entity summarised alongside
+// it and how to read that entity off a transaction.
+var counterpartOf = map[string]struct {
+	entityType string
+	value      func(tx *domain.Transaction) string
+}{
+	domain.EntityIP:     {domain.EntityEmail, func(tx *domain.Transaction) string { return tx.UserEmail }},
+	domain.EntityBIN:    {domain.EntityEmail, func(tx *domain.Transaction) string { return tx.UserEmail }},
+	domain.EntityEmail:  {domain.EntityDevice, func(tx *domain.Transaction) string { return tx.DeviceFingerprint }},
+	domain.EntityDevice: {domain.EntityBIN, func(tx *domain.Transaction) string { return tx.CardBIN }},
+}
+
+// buildEntitySummary aggregates every transaction in the window [since, now].
+// The caller fills in the page of transactions.
+func buildEntitySummary(entityType, entityValue string, days int, since, now time.Time, txns []*domain.Transaction) domain.EntitySummary {
 	var totalScore int
 	var totalAmount float64
 	var highRisk int
-	derefd := make([]domain.Transaction, len(txns))
+	var outcomes domain.OutcomeCounts
+	var firstSeen, lastSeen *time.Time
+	counterpart := counterpartOf[entityType]
+	counterparts := make(map[string]int)
+	factors := make(map[string]int)
+
+	activity := []domain.DayActivity{}
+	dayIndex := make(map[string]int)
+	for day := since.Truncate(24 * time.Hour); !day.After(now); day = day.Add(24 * time.Hour) {
+		date := day.Format(time.DateOnly)
+		dayIndex[date] = len(activity)
+		activity = append(activity, domain.DayActivity{Date: date})
+	}
+
+	for _, tx := range txns {
+		outcomes.Add(tx)
+		totalScore += tx.RiskScore
+		totalAmount += tx.ReportingAmount()
+		if tx.RiskLevel == domain.RiskHigh {
+			highRisk++
+		}
+
+		ts := tx.Timestamp
+		if firstSeen == nil || ts.Before(*firstSeen) {
+			firstSeen = &ts
+		}
+		if lastSeen == nil || ts.After(*lastSeen) {
+			lastSeen = &ts
+		}
+
+		if v := counterpart.value(tx); v != "" {
+			counterparts[v]++
+		}
+		fired := make(map[string]bool, len(tx.Factors))
+		for _, f := range tx.Factors {
+			if !fired[f.Name] {
+				fired[f.Name] = true
+				factors[f.Name]++
+			}
+		}
 
-	for i, tx := range txns {
-		derefd[i] = *tx
-		totalScore += tx.RiskScore
-		totalAmount += tx.Amount
-		if tx.RiskScore > domain.ThresholdReview {
-			highRisk++
+		if i, ok := dayIndex[tx.Timestamp.UTC().Format(time.DateOnly)]; ok {
+			day := &activity[i]
+			day.Count++
+			day.Amount += tx.ReportingAmount()
+			if tx.RiskLevel == domain.RiskHigh {
+				day.HighRiskCount++
+			}
 		}
 	}
 
@@ -166,6 +1475,34 @@ func buildEntitySummary(entityType, entityValue string, days int, txns []*domain
 		avg = float64(totalScore) / float64(len(txns))
 	}
 
+	topCounterparts := []domain.EntityCount{}
+	for value, n := range counterparts {
+		topCounterparts = append(topCounterparts, domain.EntityCount{Value: value, Count: n})
+	}
+	sort.Slice(topCounterparts, func(i, j int) bool {
+		if topCounterparts[i].Count != topCounterparts[j].Count {
+			return topCounterparts[i].Count > topCounterparts[j].Count
+		}
+		return topCounterparts[i].Value < topCounterparts[j].Value
+	})
+	if len(topCounterparts) > entitySummaryTop {
+		topCounterparts = topCounterparts[:entitySummaryTop]
+	}
+
+	topFactors := []domain.FactorCount{}
+	for name, n := range factors {
+		topFactors = append(topFactors, domain.FactorCount{Name: name, Count: n})
+	}
+	sort.Slice(topFactors, func(i, j int) bool {
+		if topFactors[i].Count != topFactors[j].Count {
+			return topFactors[i].Count > topFactors[j].Count
+		}
+		return topFactors[i].Name < topFactors[j].Name
+	})
+	if len(topFactors) > entitySummaryTop {
+		topFactors = topFactors[:entitySummaryTop]
+	}
+
 	return domain.EntitySummary{
 		EntityType:    entityType,
 		EntityValue:   entityValue,
@@ -174,8 +1511,72 @@ func buildEntitySummary(entityType, entityValue string, days int, txns []*domain
 		HighRiskCount: highRisk,
 		AvgRiskScore:  avg,
 		TotalAmount:   totalAmount,
-		Transactions:  derefd,
+		Outcomes:      outcomes,
+		FirstSeen:     firstSeen,
+		LastSeen:      lastSeen,
+		Counterparts: domain.Counterparts{
+			EntityType: counterpart.entityType,
+			Distinct:   len(counterparts),
+			Top:        topCounterparts,
+		},
+		Activity:   activity,
+		TopFactors: topFactors,
+	}
+}
+
+// pageEntityTransactions returns up to limit transactions that follow after
+// in txns, which must be sorted newest first with ties by descending ID, and
+// whether more remain.
+func pageEntityTransactions(txns []*domain.Transaction, after *store.Cursor, limit int) ([]*domain.Transaction, bool) {
+	if after != nil {
+		txns = txns[sort.Search(len(txns), func(i int) bool {
+			ts := txns[i].Timestamp
+			return ts.Before(after.Timestamp) || ts.Equal(after.Timestamp) && txns[i].TransactionID < after.ID
+		}):]
+	}
+	if len(txns) > limit {
+		return txns[:limit], true
+	}
+	return txns, false
+}
+
+// ─── GET /api/v1/entities/{type}/{value}/graph ───────────────────────────────
+
+// GetEntityGraph returns the entities linked to one by shared transactions,
+// up to a number of hops, with the weight and first and last time of each
+// link. The investigator sees the accounts, devices, IPs and cards of a ring
+// from any one of them.
+//
+// Query params:
+//   depth     — hops from the entity (default: 2, max: 4)
+//   max_nodes — node cap; the graph is marked truncated when hit
+//               (default: 200, max: 1000)
+func (h *Handler) GetEntityGraph(w http.ResponseWriter, r *http.Request) {
+	root, valid := entityFromPath(w, r)
+	if !valid {
+		return
+	}
+
+	depth := graph.DefaultDepth
+	if d := r.URL.Query().Get("depth"); d != "" {
+		parsed, err := strconv.Atoi(d)
+		if err != nil || parsed < 1 || parsed > graph.MaxDepth {
+			badRequest(w, "INVALID_PARAM", fmt.Sprintf("depth must be an integer between 1 and %d", graph.MaxDepth))
+			return
+		}
+		depth = parsed
+	}
+	maxNodes := graph.DefaultMaxNodes
+	if n := r.URL.Query().Get("max_nodes"); n != "" {
+		parsed, err := strconv.Atoi(n)
+		if err != nil || parsed < 1 || parsed > graph.MaxNodes {
+			badRequest(w, "INVALID_PARAM", fmt.Sprintf("max_nodes must be an integer between 1 and %d", graph.MaxNodes))
+			return
+		}
+		maxNodes = parsed
 	}
+
+	ok(w, graph.Explore(h.store, root, depth, maxNodes))
 }
 
 // ─── Blocklist ────────────────────────────────────────────────────────────────
@@ -232,16 +1633,19 @@ func (h *Handler) AddBlocklistEntry(w http.ResponseWriter, r *http.Request) {
 	}
 
 	h.store.SaveBlocklistEntry(entry)
+	h.audit(r, "blocklist.add", "blocklist/"+entry.ID, nil, entry)
 	created(w, entry)
 }
 
 // DeleteBlocklistEntry removes an entry from the blocklist/allowlist.
 func (h *Handler) DeleteBlocklistEntry(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
+	before, _ := h.store.GetBlocklistEntry(id)
 	if !h.store.DeleteBlocklistEntry(id) {
 		notFound(w, fmt.Sprintf("blocklist entry '%s' not found", id))
 		return
 	}
+	h.audit(r, "blocklist.delete", "blocklist/"+id, before, nil)
 	noContent(w)
 }
 
@@ -253,9 +1657,68 @@ func (h *Handler) GetFraudReport(w http.ResponseWriter, r *http.Request) {
 	allTxns := h.store.GetAllTransactions(since)
 
 	report := buildFraudReport(allTxns)
+	report.Summary.ReportingCurrency = h.reportingCurrency()
+
+	// Chargebacks arrive weeks after the payment, so outcomes are measured
+	// over a longer window than the 24-hour pattern scan.
+	outcomeSince := time.Now().UTC().Add(-outcomeReportDays * 24 * time.Hour)
+	report.Outcomes = buildOutcomeReport(h.store.GetAllTransactions(outcomeSince))
 	ok(w, report)
 }
 
+// outcomeReportDays is the look-back window of the report's outcome section.
+const outcomeReportDays = 90
+
+// outcomeReportTop caps how many entities are listed per dimension.
+const outcomeReportTop = 10
+
+func buildOutcomeReport(txns []*domain.Transaction) domain.OutcomeReport {
+	report := domain.OutcomeReport{Period: fmt.Sprintf("last_%d_days", outcomeReportDays)}
+
+	byBIN := make(map[string]*domain.OutcomeCounts)
+	byEmail := make(map[string]*domain.OutcomeCounts)
+	for _, tx := range txns {
+		report.Totals.Add(tx)
+		if byBIN[tx.CardBIN] == nil {
+			byBIN[tx.CardBIN] = &domain.OutcomeCounts{}
+		}
+		byBIN[tx.CardBIN].Add(tx)
+		if byEmail[tx.UserEmail] == nil {
+			byEmail[tx.UserEmail] = &domain.OutcomeCounts{}
+		}
+		byEmail[tx.UserEmail].Add(tx)
+	}
+
+	report.ByBIN = rankOutcomes(domain.EntityBIN, byBIN)
+	report.ByEmail = rankOutcomes(domain.EntityEmail, byEmail)
+	return report
+}
+
+// rankOutcomes lists the entities with at least one chargeback or confirmed
+// fraud, highest chargeback rate first.
+func rankOutcomes(entityType string, counts map[string]*domain.OutcomeCounts) []domain.EntityOutcomes {
+	ranked := []domain.EntityOutcomes{}
+	for value, c := range counts {
+		if c.Chargebacks == 0 && c.ConfirmedFraud == 0 {
+			continue
+		}
+		ranked = append(ranked, domain.EntityOutcomes{EntityType: entityType, EntityValue: value, OutcomeCounts: *c})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].ChargebackRate != ranked[j].ChargebackRate {
+			return ranked[i].ChargebackRate > ranked[j].ChargebackRate
+		}
+		if ranked[i].Chargebacks != ranked[j].Chargebacks {
+			return ranked[i].Chargebacks > ranked[j].Chargebacks
+		}
+		return ranked[i].EntityValue < ranked[j].EntityValue
+	})
+	if len(ranked) > outcomeReportTop {
+		ranked = ranked[:outcomeReportTop]
+	}
+	return ranked
+}
+
 func buildFraudReport(txns []*domain.Transaction) domain.FraudReport {
 	var highRisk, medRisk, lowRisk int
 	var totalScore int
@@ -273,11 +1736,13 @@ func buildFraudReport(txns []*domain.Transaction) domain.FraudReport {
 	for _, tx := range txns {
 		totalScore += tx.RiskScore
 
-		switch {
-		case tx.RiskScore > domain.ThresholdReview:
+		// Risk levels are derived from the thresholds that applied to each
+		// transaction's merchant, so classify by level rather than raw score.
+		switch tx.RiskLevel {
+		case domain.RiskHigh:
 			highRisk++
-			flaggedAmount += tx.Amount
-		case tx.RiskScore > domain.ThresholdApprove:
+			flaggedAmount += tx.ReportingAmount()
+		case domain.RiskMedium:
 			medRisk++
 		default:
 			lowRisk++
@@ -286,7 +1751,7 @@ func buildFraudReport(txns []*domain.Transaction) domain.FraudReport {
 		ipCounts[tx.IPAddress]++
 		emailCounts[tx.UserEmail]++
 		binCounts[tx.CardBIN]++
-		ipAmounts[tx.IPAddress] += tx.Amount
+		ipAmounts[tx.IPAddress] += tx.ReportingAmount()
 
 		if ipBINs[tx.IPAddress] == nil {
 			ipBINs[tx.IPAddress] = make(map[string]bool)
@@ -367,6 +1832,92 @@ func buildFraudReport(txns []*domain.Transaction) domain.FraudReport {
 	}
 }
 
+// GetShadowReport compares champion and challenger decisions for the
+// transactions scored in shadow during the last 24 hours.
+func (h *Handler) GetShadowReport(w http.ResponseWriter, r *http.Request) {
+	since := time.Now().UTC().Add(-24 * time.Hour)
+	report := buildShadowReport(h.store.GetAllTransactions(since))
+	report.ReportingCurrency = h.reportingCurrency()
+	ok(w, report)
+}
+
+// decisionRank orders recommendations from most to least permissive.
+var decisionRank = map[string]int{
+	domain.ActionApprove: 0,
+	domain.ActionReview:  1,
+	domain.ActionDecline: 2,
+}
+
+func buildShadowReport(txns []*domain.Transaction) domain.ShadowReport {
+	report := domain.ShadowReport{
+		GeneratedAt:        time.Now().UTC(),
+		Period:             "last_24_hours",
+		ChallengerVersions: []string{},
+		Transitions:        []domain.DecisionTransition{},
+	}
+
+	versions := make(map[string]bool)
+	transitions := make(map[[2]string]*domain.DecisionTransition)
+	var deltaSum int
+
+	for _, tx := range txns {
+		sh := tx.Shadow
+		if sh == nil {
+			continue
+		}
+		report.Compared++
+		deltaSum += sh.RiskScore - tx.RiskScore
+		if !versions[sh.RuleSetVersion] {
+			versions[sh.RuleSetVersion] = true
+			report.ChallengerVersions = append(report.ChallengerVersions, sh.RuleSetVersion)
+		}
+
+		from, to := tx.Recommendation, sh.Recommendation
+		switch {
+		case decisionRank[to] > decisionRank[from]:
+			report.Stricter++
+		case decisionRank[to] < decisionRank[from]:
+			report.Looser++
+		default:
+			report.Unchanged++
+		}
+		if to == domain.ActionDecline && from != domain.ActionDecline {
+			report.ExtraDeclined++
+			report.ExtraDeclinedAmount += tx.ReportingAmount()
+		}
+		if from == domain.ActionDecline && to != domain.ActionDecline {
+			report.NoLongerDeclined++
+			report.NoLongerDeclinedAmount += tx.ReportingAmount()
+		}
+
+		key := [2]string{from, to}
+		t, seen := transitions[key]
+		if !seen {
+			t = &domain.DecisionTransition{From: from, To: to}
+			transitions[key] = t
+		}
+		t.Count++
+		t.Amount += tx.ReportingAmount()
+	}
+
+	for _, t := range transitions {
+		report.Transitions = append(report.Transitions, *t)
+	}
+	sort.Slice(report.Transitions, func(i, j int) bool {
+		a, b := report.Transitions[i], report.Transitions[j]
+		if decisionRank[a.From] != decisionRank[b.From] {
+			return decisionRank[a.From] < decisionRank[b.From]
+		}
+		return decisionRank[a.To] < decisionRank[b.To]
+	})
+	sort.Strings(report.ChallengerVersions)
+
+	if report.Compared > 0 {
+		report.AvgScoreDelta = float64(deltaSum) / float64(report.Compared)
+	}
+	return report
+}
+
 // ─── Webhooks ─────────────────────────────────────────────────────────────────
 
 // RegisterWebhook adds a new webhook endpoint.
@@ -399,19 +1950,210 @@ func (h *Handler) RegisterWebhook(w http.ResponseWriter, r *http.Request) {
 		Active:    true,
 	}
 	h.store.SaveWebhook(wh)
+	h.audit(r, "webhook.register", "webhooks/"+wh.ID, nil, wh)
 	created(w, wh)
 }
 
 // DeleteWebhook deactivates and removes a webhook.
 func (h *Handler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
+	before, _ := h.store.GetWebhook(id)
 	if !h.store.DeleteWebhook(id) {
 		notFound(w, fmt.Sprintf("webhook '%s' not found", id))
 		return
 	}
+	h.audit(r, "webhook.delete", "webhooks/"+id, before, nil)
+	noContent(w)
+}
+
+// ─── FX rates ─────────────────────────────────────────────────────────────────
+
+// GetFXRates returns the reporting currency and every dated rate.
+func (h *Handler) GetFXRates(w http.ResponseWriter, r *http.Request) {
+	rates := h.engine.Rates()
+	if rates == nil {
+		conflict(w, "FX normalisation is not configured")
+		return
+	}
+	ok(w, rates.Snapshot())
+}
+
+// ReplaceFXRates swaps in a complete rate table (PUT) or adds/overwrites
+// individual dated rates (POST), and stores the result so every replica
+// converts with it. The reporting currency cannot change at runtime because
+// stored transactions are already normalised into it.
+//
+// Body: {"reporting_currency": "USD", "rates": [{"currency": "BRL", "date": "2026-10-01", "rate": 5.5}]}
+func (h *Handler) ReplaceFXRates(w http.ResponseWriter, r *http.Request) {
+	rates := h.engine.Rates()
+	if rates == nil {
+		conflict(w, "FX normalisation is not configured")
+		return
+	}
+
+	var req fx.RateFile
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "INVALID_JSON", "request body must be valid JSON")
+		return
+	}
+	if req.ReportingCurrency != "" && !strings.EqualFold(req.ReportingCurrency, rates.ReportingCurrency()) {
+		badRequest(w, "INVALID_REPORTING_CURRENCY",
+			fmt.Sprintf("reporting currency is fixed at %s", rates.ReportingCurrency()))
+		return
+	}
+
+	apply, action := (*fx.Table).Replace, "fx_rates.replace"
+	if r.Method == http.MethodPost {
+		apply, action = (*fx.Table).Upsert, "fx_rates.upsert"
+	}
+	before, after, err := h.engine.UpdateRates(func(t *fx.Table) error { return apply(t, req.Rates) })
+	if errors.Is(err, scoring.ErrNotPublished) {
+		slog.Error("fx rates not stored", "error", err)
+		internalError(w)
+		return
+	}
+	if err != nil {
+		badRequest(w, "INVALID_RATES", flattenError(err))
+		return
+	}
+	h.audit(r, action, "fx-rates", before, after)
+	ok(w, after)
+}
+
+// ─── Threshold configuration ──────────────────────────────────────────────────
+
+// thresholdsView is the response body of GET /config/thresholds.
+type thresholdsView struct {
+	Default   domain.Thresholds         `json:"default"`
+	Overrides []*domain.ThresholdConfig `json:"overrides"`
+}
+
+// GetThresholds lists the built-in default thresholds and every override.
+func (h *Handler) GetThresholds(w http.ResponseWriter, r *http.Request) {
+	overrides := h.store.ListThresholds()
+	sort.Slice(overrides, func(i, j int) bool {
+		if overrides[i].Scope != overrides[j].Scope {
+			return overrides[i].Scope < overrides[j].Scope
+		}
+		return overrides[i].Key < overrides[j].Key
+	})
+	ok(w, thresholdsView{Default: domain.DefaultThresholds, Overrides: overrides})
+}
+
+// PutThresholds creates or replaces the threshold override for one scope.
+//
+// Body: {"scope": "merchant|country|global", "key": "...", "approve": 30, "review": 70}
+func (h *Handler) PutThresholds(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Scope   string `json:"scope"`
+		Key     string `json:"key"`
+		Approve int    `json:"approve"`
+		Review  int    `json:"review"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "INVALID_JSON", "request body must be valid JSON")
+		return
+	}
+
+	key, err := validateThresholdScope(req.Scope, req.Key)
+	if err != nil {
+		badRequest(w, "INVALID_SCOPE", err.Error())
+		return
+	}
+	if req.Approve < 0 || req.Review > 100 || req.Approve >= req.Review {
+		badRequest(w, "INVALID_THRESHOLDS", "thresholds must satisfy 0 <= approve < review <= 100")
+		return
+	}
+
+	cfg := &domain.ThresholdConfig{
+		Scope:      req.Scope,
+		Key:        key,
+		Thresholds: domain.Thresholds{Approve: req.Approve, Review: req.Review},
+		UpdatedAt:  time.Now().UTC(),
+	}
+	change := h.store.SetThresholds(cfg, actorFrom(r))
+	h.audit(r, "thresholds.set", thresholdResource(cfg.Scope, cfg.Key), change.Before, change.After)
+	ok(w, cfg)
+}
+
+// DeleteThresholds removes an override so the next less specific scope applies.
+//
+// Query params: scope, key (key is omitted for the global scope)
+func (h *Handler) DeleteThresholds(w http.ResponseWriter, r *http.Request) {
+	scope := r.URL.Query().Get("scope")
+	key, err := validateThresholdScope(scope, r.URL.Query().Get("key"))
+	if err != nil {
+		badRequest(w, "INVALID_SCOPE", err.Error())
+		return
+	}
+	change, found := h.store.DeleteThresholds(scope, key, actorFrom(r))
+	if !found {
+		notFound(w, fmt.Sprintf("no threshold override for %s '%s'", scope, key))
+		return
+	}
+	h.audit(r, "thresholds.delete", thresholdResource(scope, key), change.Before, nil)
 	noContent(w)
 }
 
+// GetThresholdHistory returns every threshold change, newest first.
+func (h *Handler) GetThresholdHistory(w http.ResponseWriter, r *http.Request) {
+	changes := h.store.ListThresholdChanges()
+	for i, j := 0, len(changes)-1; i < j; i, j = i+1, j-1 {
+		changes[i], changes[j] = changes[j], changes[i]
+	}
+	ok(w, changes)
+}
+
+// thresholdResource names a threshold override in the audit log.
+func thresholdResource(scope, key string) string {
+	if key == "" {
+		return "thresholds/" + scope
+	}
+	return "thresholds/" + scope + "/" + key
+}
+
+// validateThresholdScope checks a scope/key pair and returns the normalised key.
+func validateThresholdScope(scope, key string) (string, error) {
+	switch scope {
+	case domain.ScopeGlobal:
+		if key != "" {
+			return "", fmt.Errorf("key must be empty for the global scope")
+		}
+		return "", nil
+	case domain.ScopeMerchant:
+		if key == "" {
+			return "", fmt.Errorf("key (merchant ID) is required for the merchant scope")
+		}
+		return key, nil
+	case domain.ScopeCountry:
+		if len(key) != 2 {
+			return "", fmt.Errorf("key must be an ISO-3166-1 alpha-2 country code for the country scope")
+		}
+		return strings.ToUpper(key), nil
+	default:
+		return "", fmt.Errorf("scope must be one of: merchant, country, global")
+	}
+}
+
+// actorFrom identifies who made a change: the API key that authenticated the
+// request. Headers are not trusted for this; see onBehalfOf.
+func actorFrom(r *http.Request) string {
+	if key, found := auth.FromContext(r.Context()); found {
+		return "key:" + key.Name
+	}
+	return anonymousActor
+}
+
+// anonymousActor is recorded when a request does not identify its caller.
+const anonymousActor = "anonymous"
+
+// onBehalfOf returns the X-Actor header: who the caller says it acted for,
+// such as the analyst behind a shared key. It is recorded next to the actor,
+// never in its place, since any key holder can set it.
+func onBehalfOf(r *http.Request) string {
+	return strings.TrimSpace(r.Header.Get("X-Actor"))
+}
+
 // ─── Admin ────────────────────────────────────────────────────────────────────
 
 // SeedData loads an array of TransactionRequests from the request body,
@@ -423,22 +2165,13 @@ func (h *Handler) SeedData(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var loaded, skipped int
+	var loaded, skipped, rejected int
 	for i := range requests {
-		req := &requests[i]
-		score, factors, explanation := h.engine.Score(req)
-		recommendation, riskLevel := scoring.Recommend(score)
-
-		tx := &domain.Transaction{
-			TransactionRequest: *req,
-			RiskScore:          score,
-			RiskLevel:          riskLevel,
-			Recommendation:     recommendation,
-			Factors:            factors,
-			Explanation:        explanation,
-			ProcessedAt:        time.Now().UTC(),
+		tx, err := h.engine.Assess(&requests[i])
+		if err != nil {
+			rejected++
+			continue
 		}
-
 		if err := h.store.SaveTransaction(tx); err != nil {
 			skipped++
 		} else {
@@ -446,11 +2179,282 @@ func (h *Handler) SeedData(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	ok(w, map[string]int{"loaded": loaded, "skipped_duplicates": skipped})
+	result := map[string]int{"loaded": loaded, "skipped_duplicates": skipped, "rejected": rejected}
+	h.audit(r, "admin.seed", "transactions", nil, result)
+	ok(w, result)
+}
+
+// GetRules returns the active scoring rule set with its version and hash.
+func (h *Handler) GetRules(w http.ResponseWriter, r *http.Request) {
+	ok(w, h.engine.ActiveRules())
+}
+
+// ReplaceRules validates the rule document in the request body, stores it and
+// swaps it in atomically; other replicas pick it up on their next refresh.
+// The uploaded set has no source file, so a later reload request fails until
+// the server is pointed back at a file.
+func (h *Handler) ReplaceRules(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		badRequest(w, "INVALID_BODY", "could not read request body")
+		return
+	}
+	rs, err := scoring.ParseRuleSet(body)
+	if err != nil {
+		badRequest(w, "INVALID_RULES", flattenError(err))
+		return
+	}
+	before := h.engine.ActiveRules()
+	if err := h.engine.PublishRuleSet(rs); err != nil {
+		slog.Error("rule set not stored", "error", err)
+		internalError(w)
+		return
+	}
+	after := h.engine.ActiveRules()
+	h.audit(r, "rules.replace", "rules", before, after)
+	ok(w, after)
+}
+
+// ReloadRules re-reads the rule file the active rule set came from.
+// SIGHUP triggers the same reload.
+func (h *Handler) ReloadRules(w http.ResponseWriter, r *http.Request) {
+	before := h.engine.ActiveRules()
+	if _, err := h.engine.ReloadRules(); err != nil {
+		switch {
+		case errors.Is(err, scoring.ErrNoRuleSource):
+			conflict(w, err.Error())
+		case errors.Is(err, scoring.ErrNotPublished):
+			slog.Error("rule set not stored", "error", err)
+			internalError(w)
+		default:
+			badRequest(w, "INVALID_RULES", flattenError(err))
+		}
+		return
+	}
+	after := h.engine.ActiveRules()
+	h.audit(r, "rules.reload", "rules", before, after)
+	ok(w, after)
+}
+
+// GetShadowRules returns the challenger rule set scored in shadow mode.
+func (h *Handler) GetShadowRules(w http.ResponseWriter, r *http.Request) {
+	info, on := h.engine.ChallengerRules()
+	if !on {
+		notFound(w, "shadow mode is off")
+		return
+	}
+	ok(w, info)
+}
+
+// ReplaceShadowRules validates the rule document in the request body, stores
+// it and starts scoring every new transaction with it in shadow.
+func (h *Handler) ReplaceShadowRules(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		badRequest(w, "INVALID_BODY", "could not read request body")
+		return
+	}
+	rs, err := scoring.ParseRuleSet(body)
+	if err != nil {
+		badRequest(w, "INVALID_RULES", flattenError(err))
+		return
+	}
+	before := h.challengerState()
+	if err := h.engine.PublishChallenger(rs); err != nil {
+		slog.Error("shadow rule set not stored", "error", err)
+		internalError(w)
+		return
+	}
+	info, _ := h.engine.ChallengerRules()
+	h.audit(r, "shadow_rules.replace", "rules/shadow", before, info)
+	ok(w, info)
+}
+
+// DeleteShadowRules turns shadow mode off. Shadow results already recorded on
+// transactions are kept.
+func (h *Handler) DeleteShadowRules(w http.ResponseWriter, r *http.Request) {
+	before := h.challengerState()
+	if err := h.engine.PublishChallenger(nil); err != nil {
+		slog.Error("shadow rule set not stored", "error", err)
+		internalError(w)
+		return
+	}
+	h.audit(r, "shadow_rules.delete", "rules/shadow", before, nil)
+	noContent(w)
+}
+
+// challengerState is the shadow rule set for the audit log, nil when off.
+func (h *Handler) challengerState() *scoring.RuleSetInfo {
+	if info, on := h.engine.ChallengerRules(); on {
+		return &info
+	}
+	return nil
+}
+
+// ─── API keys ─────────────────────────────────────────────────────────────────
+
+// createKeyRequest is the body of POST /api/v1/admin/keys.
+type createKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// issuedKey is returned when a key is created or rotated. The token is shown
+// only in this response; the server keeps just its hash.
+type issuedKey struct {
+	auth.Key
+	Token string `json:"token"`
+}
+
+// ListKeys returns every API key, revoked ones included. Tokens are never returned.
+func (h *Handler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	ok(w, h.keys.List())
+}
+
+// CreateKey issues a new API key with the requested scopes.
+func (h *Handler) CreateKey(w http.ResponseWriter, r *http.Request) {
+	var req createKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "INVALID_JSON", "request body must be valid JSON")
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		badRequest(w, "MISSING_NAME", "name is required")
+		return
+	}
+
+	key, token, err := h.keys.Create(strings.TrimSpace(req.Name), req.Scopes, actorFrom(r))
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidScope) {
+			badRequest(w, "INVALID_SCOPE", err.Error())
+			return
+		}
+		slog.Error("create API key", "error", err)
+		internalError(w)
+		return
+	}
+	h.audit(r, "api_key.create", "api_keys/"+key.ID, nil, key)
+	created(w, issuedKey{Key: key, Token: token})
+}
+
+// RotateKey replaces a key's secret. The previous token stops working at once.
+func (h *Handler) RotateKey(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	before, _ := h.keys.Get(id)
+	key, token, err := h.keys.Rotate(id)
+	if err != nil {
+		keyError(w, err, id)
+		return
+	}
+	h.audit(r, "api_key.rotate", "api_keys/"+id, before, key)
+	ok(w, issuedKey{Key: key, Token: token})
+}
+
+// RevokeKey permanently disables a key.
+func (h *Handler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	before, _ := h.keys.Get(id)
+	key, err := h.keys.Revoke(id)
+	if err != nil {
+		keyError(w, err, id)
+		return
+	}
+	h.audit(r, "api_key.revoke", "api_keys/"+id, before, key)
+	noContent(w)
+}
+
+func keyError(w http.ResponseWriter, err error, id string) {
+	switch {
+	case errors.Is(err, auth.ErrKeyNotFound):
+		notFound(w, fmt.Sprintf("API key '%s' not found", id))
+	case errors.Is(err, auth.ErrKeyRevoked):
+		conflict(w, fmt.Sprintf("API key '%s' is revoked", id))
+	default:
+		slog.Error("API key operation", "id", id, "error", err)
+		internalError(w)
+	}
+}
+
+// ─── Audit log ────────────────────────────────────────────────────────────────
+
+// audit records a mutation that has already taken effect. A failure to audit
+// is logged rather than returned: the change cannot be rolled back here.
+func (h *Handler) audit(r *http.Request, action, resource string, before, after any) {
+	_, err := h.auditLog.Record(actorFrom(r), onBehalfOf(r), action, resource, middleware.GetReqID(r.Context()), before, after)
+	if err != nil {
+		slog.Error("audit record failed", "action", action, "resource", resource, "error", err)
+	}
+}
+
+// GetAuditLog returns audit entries, newest first.
+//
+// Query params (all optional):
+//
+//	actor        — exact actor, e.g. "key:ops"
+//	on_behalf_of — exact X-Actor label
+//	action       — exact action, or a prefix such as "blocklist" for blocklist.*
+//	resource     — exact resource, e.g. "blocklist/<id>"
+//	since        — RFC 3339, inclusive
+//	until        — RFC 3339, exclusive
+//	limit        — max entries (default: 100, max: 1000)
+func (h *Handler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := audit.Filter{
+		Actor:    q.Get("actor"),
+		OnBehalf: q.Get("on_behalf_of"),
+		Action:   q.Get("action"),
+		Resource: q.Get("resource"),
+		Limit:    100,
+	}
+	for name, dst := range map[string]*time.Time{"since": &f.Since, "until": &f.Until} {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				badRequest(w, "INVALID_PARAM", name+" must be an RFC 3339 timestamp")
+				return
+			}
+			*dst = t
+		}
+	}
+	if l := q.Get("limit"); l != "" {
+		parsed, err := strconv.Atoi(l)
+		if err != nil || parsed < 1 || parsed > 1000 {
+			badRequest(w, "INVALID_PARAM", "limit must be an integer between 1 and 1000")
+			return
+		}
+		f.Limit = parsed
+	}
+	ok(w, h.auditLog.Query(f))
+}
+
+// auditVerification is the response body of GET /audit/verify.
+type auditVerification struct {
+	Valid    bool   `json:"valid"`
+	Entries  int    `json:"entries"`
+	BrokenAt int64  `json:"broken_at,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// VerifyAuditLog recomputes the hash chain and reports the first broken link.
+func (h *Handler) VerifyAuditLog(w http.ResponseWriter, r *http.Request) {
+	entries := h.auditLog.Entries()
+	result := auditVerification{Valid: true, Entries: len(entries)}
+	var chainErr *audit.ChainError
+	if err := audit.VerifyChain(entries); errors.As(err, &chainErr) {
+		result.Valid = false
+		result.BrokenAt = chainErr.Seq
+		result.Reason = chainErr.Reason
+	}
+	ok(w, result)
 }
 
 // ─── Validation ───────────────────────────────────────────────────────────────
 
+// flattenError renders a possibly joined error on a single line.
+func flattenError(err error) string {
+	return strings.ReplaceAll(err.Error(), "\n", "; ")
+}
+
 func validateTransactionRequest(req *domain.TransactionRequest) error {
 	if req.TransactionID == "" {
 		return fmt.Errorf("transaction_id is required")
@@ -6,10 +6,16 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"lumina/fraud-api/internal/api"
+	"lumina/fraud-api/internal/audit"
+	"lumina/fraud-api/internal/auth"
+	"lumina/fraud-api/internal/domain"
+	"lumina/fraud-api/internal/review"
 	"lumina/fraud-api/internal/scoring"
 	"lumina/fraud-api/internal/store"
 	"lumina/fraud-api/internal/webhook"
@@ -17,42 +23,71 @@ import (
 
 // ─── Test server setup ────────────────────────────────────────────────────────
 
+// testAdminKey authenticates every request made through the helpers below.
+const testAdminKey = "lfk_test_admin_key_0123456789"
+
 func newTestServer(t *testing.T) *httptest.Server {
 	t.Helper()
-	s := store.New()
-	e := scoring.New(s)
+	return newTestServerWithStore(t, store.New())
+}
+
+// newTestServerWithStore serves the API from s, e.g. a store wrapped to
+// inject a failure.
+func newTestServerWithStore(t *testing.T, s store.Store) *httptest.Server {
+	t.Helper()
+	e := scoring.New(s, nil, nil)
 	n := webhook.New(s)
-	h := api.NewHandler(s, e, n)
+	keys := auth.New(s)
+	if _, _, err := keys.Add("test-admin", testAdminKey, []string{auth.ScopeAdmin}, "test"); err != nil {
+		t.Fatal(err)
+	}
+	l, err := audit.New(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := api.NewHandler(s, e, n, review.New(s, 0, 0), l, keys)
 	return httptest.NewServer(api.NewRouter(h))
 }
 
-func post(t *testing.T, srv *httptest.Server, path string, body any) *http.Response {
+// send performs req with the test admin key unless it already carries credentials.
+func send(t *testing.T, req *http.Request) *http.Response {
 	t.Helper()
-	b, _ := json.Marshal(body)
-	resp, err := http.Post(srv.URL+path, "application/json", bytes.NewReader(b))
+	if req.Header.Get("Authorization") == "" && req.Header.Get("X-API-Key") == "" {
+		req.Header.Set("Authorization", "Bearer "+testAdminKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		t.Fatalf("POST %s: %v", path, err)
+		t.Fatalf("%s %s: %v", req.Method, req.URL.Path, err)
 	}
 	return resp
 }
 
+func post(t *testing.T, srv *httptest.Server, path string, body any) *http.Response {
+	t.Helper()
+	b, _ := json.Marshal(body)
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+path, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	return send(t, req)
+}
+
 func get(t *testing.T, srv *httptest.Server, path string) *http.Response {
 	t.Helper()
-	resp, err := http.Get(srv.URL + path)
-	if err != nil {
-		t.Fatalf("GET %s: %v", path, err)
-	}
-	return resp
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+	return send(t, req)
+}
+
+func put(t *testing.T, srv *httptest.Server, path string, body any) *http.Response {
+	t.Helper()
+	b, _ := json.Marshal(body)
+	req, _ := http.NewRequest(http.MethodPut, srv.URL+path, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	return send(t, req)
 }
 
 func del(t *testing.T, srv *httptest.Server, path string) *http.Response {
 	t.Helper()
 	req, _ := http.NewRequest(http.MethodDelete, srv.URL+path, nil)
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		t.Fatalf("DELETE %s: %v", path, err)
-	}
-	return resp
+	return send(t, req)
 }
 
 func decodeData(t *testing.T, resp *http.Response) map[string]any {
@@ -156,6 +191,131 @@ func TestSubmitTransaction_DuplicateID_Returns409(t *testing.T) {
 	}
 }
 
+// postIdempotent submits a transaction carrying an Idempotency-Key header.
+func postIdempotent(t *testing.T, srv *httptest.Server, key string, body any) *http.Response {
+	t.Helper()
+	b, _ := json.Marshal(body)
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/v1/transactions", bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", key)
+	return send(t, req)
+}
+
+func TestSubmitTransaction_IdempotencyKey_ReplaysOriginalResult(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	payload := validTxPayload("idem-tx")
+	first := postIdempotent(t, srv, "retry-1", payload)
+	if first.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", first.StatusCode)
+	}
+	original := decodeData(t, first)
+
+	// Same instant written with another offset is the same request.
+	payload["timestamp"] = "2026-02-25T11:00:00-03:00"
+	retry := postIdempotent(t, srv, "retry-1", payload)
+	if retry.StatusCode != http.StatusOK || retry.Header.Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("expected a 200 replay, got %d", retry.StatusCode)
+	}
+	replay := decodeData(t, retry)
+	if replay["processed_at"] != original["processed_at"] || replay["risk_score"] != original["risk_score"] {
+		t.Errorf("expected the stored result, got %v want %v", replay, original)
+	}
+
+	// Without the key, a resubmission is still a duplicate.
+	if resp := post(t, srv, "/api/v1/transactions", payload); resp.StatusCode != http.StatusConflict {
+		t.Errorf("expected 409 without a key, got %d", resp.StatusCode)
+	}
+}
+
+func TestSubmitTransaction_IdempotencyKey_DifferentPayload_Returns422(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	postIdempotent(t, srv, "retry-2", validTxPayload("idem-a"))
+
+	for name, payload := range map[string]map[string]any{
+		"new transaction": validTxPayload("idem-b"),
+		"changed amount":  func() map[string]any { p := validTxPayload("idem-a"); p["amount"] = 75.0; return p }(),
+	} {
+		resp := postIdempotent(t, srv, "retry-2", payload)
+		if resp.StatusCode != http.StatusUnprocessableEntity {
+			t.Errorf("%s: expected 422, got %d", name, resp.StatusCode)
+			continue
+		}
+		if code := decodeError(t, resp)["code"]; code != "IDEMPOTENCY_KEY_MISMATCH" {
+			t.Errorf("%s: expected IDEMPOTENCY_KEY_MISMATCH, got %v", name, code)
+		}
+	}
+	if resp := get(t, srv, "/api/v1/transactions/idem-b"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("a rejected replay must not be saved, got %d", resp.StatusCode)
+	}
+}
+
+func TestSubmitTransaction_IdempotencyKey_ConcurrentPayloads_OnlyOneSaved(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	const n = 16
+	statuses := make(chan int, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			b, _ := json.Marshal(validTxPayload(fmt.Sprintf("race-%d", i)))
+			req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/v1/transactions", bytes.NewReader(b))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+testAdminKey)
+			req.Header.Set("Idempotency-Key", "race-key")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+			statuses <- resp.StatusCode
+		}(i)
+	}
+	wg.Wait()
+	close(statuses)
+
+	counts := map[int]int{}
+	for code := range statuses {
+		counts[code]++
+	}
+	if counts[http.StatusCreated] != 1 || counts[http.StatusUnprocessableEntity] != n-1 {
+		t.Errorf("expected one 201 and %d 422s, got %v", n-1, counts)
+	}
+	var saved int
+	for i := 0; i < n; i++ {
+		if get(t, srv, fmt.Sprintf("/api/v1/transactions/race-%d", i)).StatusCode == http.StatusOK {
+			saved++
+		}
+	}
+	if saved != 1 {
+		t.Errorf("expected exactly one transaction saved under the key, got %d", saved)
+	}
+}
+
+func TestSubmitTransaction_IdempotencyKey_ReleasedWhenRequestFails(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	post(t, srv, "/api/v1/transactions", validTxPayload("rel-taken"))
+	clash := validTxPayload("rel-taken")
+	clash["amount"] = 75.0
+	if resp := postIdempotent(t, srv, "retry-rel", clash); resp.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409 for a taken transaction ID, got %d", resp.StatusCode)
+	}
+
+	// The failed request must not leave its key bound.
+	if resp := postIdempotent(t, srv, "retry-rel", validTxPayload("rel-new")); resp.StatusCode != http.StatusCreated {
+		t.Errorf("expected the key to be free again, got %d", resp.StatusCode)
+	}
+}
+
 func TestSubmitTransaction_MissingField_Returns400(t *testing.T) {
 	srv := newTestServer(t)
 	defer srv.Close()
@@ -177,11 +337,8 @@ func TestSubmitTransaction_InvalidJSON_Returns400(t *testing.T) {
 	srv := newTestServer(t)
 	defer srv.Close()
 
-	resp, err := http.Post(srv.URL+"/api/v1/transactions", "application/json",
-		bytes.NewBufferString("not-json"))
-	if err != nil {
-		t.Fatal(err)
-	}
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/v1/transactions", bytes.NewBufferString("not-json"))
+	resp := send(t, req)
 	if resp.StatusCode != http.StatusBadRequest {
 		t.Errorf("expected 400, got %d", resp.StatusCode)
 	}
@@ -230,181 +387,686 @@ func TestSubmitTransaction_ObviousFraud_ScoresHigh(t *testing.T) {
 	}
 }
 
-// ─── GET /api/v1/transactions/{id} ───────────────────────────────────────────
+// ─── POST /api/v1/transactions/batch ─────────────────────────────────────────
+
+// batchPayload returns n valid items for one account, timestamped an hour
+// apart and listed newest first.
+func batchPayload(prefix string, n int) []map[string]any {
+	items := make([]map[string]any, n)
+	base := time.Date(2026, 2, 25, 14, 0, 0, 0, time.UTC)
+	for i := range items {
+		p := validTxPayload(fmt.Sprintf("%s-%d", prefix, i))
+		p["timestamp"] = base.Add(-time.Duration(i) * time.Hour).Format(time.RFC3339)
+		items[i] = p
+	}
+	return items
+}
 
-func TestGetTransaction_Exists_Returns200(t *testing.T) {
+func factorsOf(item map[string]any) []string {
+	tx, _ := item["transaction"].(map[string]any)
+	factors, _ := tx["factors"].([]any)
+	var names []string
+	for _, f := range factors {
+		names = append(names, f.(map[string]any)["name"].(string))
+	}
+	return names
+}
+
+func TestSubmitBatch_BestEffort_ScoresInTimeOrderWithPerItemResults(t *testing.T) {
 	srv := newTestServer(t)
 	defer srv.Close()
 
-	post(t, srv, "/api/v1/transactions", validTxPayload("get-001"))
-	resp := get(t, srv, "/api/v1/transactions/get-001")
+	items := batchPayload("bat", 3)
+	invalid := validTxPayload("bat-invalid")
+	delete(invalid, "user_email")
+	items = append(items, invalid, validTxPayload("bat-0")) // duplicate of the first ID
 
+	resp := post(t, srv, "/api/v1/transactions/batch", map[string]any{"transactions": items})
 	if resp.StatusCode != http.StatusOK {
-		t.Errorf("expected 200, got %d", resp.StatusCode)
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
 	}
 	d := decodeData(t, resp)
-	if d["transaction_id"] != "get-001" {
-		t.Errorf("expected transaction_id=get-001, got %v", d["transaction_id"])
+	if d["scored"].(float64) != 3 || d["rejected"].(float64) != 2 {
+		t.Errorf("expected 3 scored and 2 rejected, got %v", d)
+	}
+	results := d["results"].([]any)
+	codes := make([]string, len(results))
+	for i, r := range results {
+		item := r.(map[string]any)
+		if int(item["index"].(float64)) != i {
+			t.Errorf("results[%d] has index %v; results must follow request order", i, item["index"])
+		}
+		if e, ok := item["error"].(map[string]any); ok {
+			codes[i] = e["code"].(string)
+		}
+	}
+	if codes[3] != "VALIDATION_ERROR" || codes[4] != "CONFLICT" {
+		t.Errorf("expected a validation error and a conflict, got %v", codes)
+	}
+
+	// Listed newest first but scored oldest first: only the newest item sees
+	// the other two in its velocity window.
+	newest, oldest := results[0].(map[string]any), results[2].(map[string]any)
+	if names := factorsOf(newest); !strings.Contains(strings.Join(names, ","), "email_velocity_24h") {
+		t.Errorf("expected the newest item to see its predecessors, got %v", names)
+	}
+	if names := factorsOf(oldest); strings.Contains(strings.Join(names, ","), "email_velocity_24h") {
+		t.Errorf("expected the oldest item to be scored without history, got %v", names)
 	}
 }
 
-func TestGetTransaction_NotFound_Returns404(t *testing.T) {
+func TestSubmitBatch_AllOrNothing_SavesNothingWhenAnItemFails(t *testing.T) {
 	srv := newTestServer(t)
 	defer srv.Close()
 
-	resp := get(t, srv, "/api/v1/transactions/ghost-tx")
-	if resp.StatusCode != http.StatusNotFound {
-		t.Errorf("expected 404, got %d", resp.StatusCode)
+	items := batchPayload("aon", 2)
+	items[1]["amount"] = 0
+
+	resp := post(t, srv, "/api/v1/transactions/batch", map[string]any{"mode": "all_or_nothing", "transactions": items})
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d", resp.StatusCode)
+	}
+	var env struct {
+		Data struct {
+			Rejected int `json:"rejected"`
+			Skipped  int `json:"skipped"`
+		} `json:"data"`
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Error.Code != "BATCH_REJECTED" || env.Data.Rejected != 1 || env.Data.Skipped != 1 {
+		t.Errorf("expected one rejected and one skipped item, got %+v", env)
+	}
+	if resp := get(t, srv, "/api/v1/transactions/aon-0"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected the valid item not to be saved, got %d", resp.StatusCode)
+	}
+
+	resp = post(t, srv, "/api/v1/transactions/batch", map[string]any{"mode": "all_or_nothing", "transactions": batchPayload("aon", 3)})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected a valid batch to be saved, got %d", resp.StatusCode)
+	}
+	// Saved in one write at the end, yet each item still sees the ones before it.
+	newest := decodeData(t, resp)["results"].([]any)[0].(map[string]any)
+	if names := factorsOf(newest); !strings.Contains(strings.Join(names, ","), "email_velocity_24h") {
+		t.Errorf("expected the newest item to see its predecessors, got %v", names)
 	}
 }
 
-// ─── GET /api/v1/entities/{type}/{value} ─────────────────────────────────────
+// racingStore saves one transaction of its own just before each batch save,
+// the way a concurrent request could between the precheck and the write.
+type racingStore struct {
+	store.Store
+	id string
+}
 
-func TestGetEntitySummary_ValidEmail_Returns200(t *testing.T) {
+func (s *racingStore) SaveTransactions(txs []*domain.Transaction) error {
+	for _, tx := range txs {
+		if tx.TransactionID == s.id {
+			racer := *tx
+			_ = s.Store.SaveTransaction(&racer)
+		}
+	}
+	return s.Store.SaveTransactions(txs)
+}
+
+func TestSubmitBatch_AllOrNothing_ConcurrentSaveFailsTheWholeBatch(t *testing.T) {
+	s := store.New()
+	srv := newTestServerWithStore(t, &racingStore{Store: s, id: "race-1"})
+	defer srv.Close()
+
+	resp := post(t, srv, "/api/v1/transactions/batch", map[string]any{"mode": "all_or_nothing", "transactions": batchPayload("race", 3)})
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", resp.StatusCode)
+	}
+	var env struct {
+		Data struct {
+			Results []struct {
+				Status string `json:"status"`
+			} `json:"results"`
+		} `json:"data"`
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Error.Code != "BATCH_REJECTED" {
+		t.Errorf("expected BATCH_REJECTED, got %q", env.Error.Code)
+	}
+	want := []string{"skipped", "rejected", "skipped"}
+	for i, r := range env.Data.Results {
+		if r.Status != want[i] {
+			t.Errorf("results[%d]: expected %s, got %s", i, want[i], r.Status)
+		}
+	}
+	for _, id := range []string{"race-0", "race-2"} {
+		if _, ok := s.GetTransaction(id); ok {
+			t.Errorf("%s must not be saved when the batch fails", id)
+		}
+	}
+}
+
+func TestSubmitBatch_StreamsNDJSON(t *testing.T) {
 	srv := newTestServer(t)
 	defer srv.Close()
 
-	post(t, srv, "/api/v1/transactions", validTxPayload("ent-001"))
-	resp := get(t, srv, "/api/v1/entities/email/test%40example.com")
+	b, _ := json.Marshal(map[string]any{"transactions": batchPayload("nd", 4)})
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/v1/transactions/batch", bytes.NewReader(b))
+	req.Header.Set("Accept", "application/x-ndjson")
+	resp := send(t, req)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("expected a 200 NDJSON stream, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
 
-	if resp.StatusCode != http.StatusOK {
-		t.Errorf("expected 200, got %d", resp.StatusCode)
+	var lines []map[string]any
+	dec := json.NewDecoder(resp.Body)
+	for dec.More() {
+		var line map[string]any
+		if err := dec.Decode(&line); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
 	}
-	d := decodeData(t, resp)
-	if d["total_count"].(float64) < 1 {
-		t.Error("expected at least 1 transaction in summary")
+	if len(lines) != 5 {
+		t.Fatalf("expected 4 results and a summary, got %d lines", len(lines))
+	}
+	// Streamed in scoring order: oldest, i.e. the last listed, first.
+	if lines[0]["index"].(float64) != 3 {
+		t.Errorf("expected the oldest item first, got index %v", lines[0]["index"])
+	}
+	summary, _ := lines[4]["summary"].(map[string]any)
+	if summary["scored"] != 4.0 {
+		t.Errorf("expected a summary with 4 scored, got %v", lines[4])
 	}
 }
 
-func TestGetEntitySummary_ValidIP_Returns200(t *testing.T) {
+func TestSubmitBatch_InvalidRequests_Return400(t *testing.T) {
 	srv := newTestServer(t)
 	defer srv.Close()
 
-	post(t, srv, "/api/v1/transactions", validTxPayload("ent-ip-001"))
-	resp := get(t, srv, "/api/v1/entities/ip/177.10.20.30")
+	for name, body := range map[string]any{
+		"unknown mode": map[string]any{"mode": "most", "transactions": batchPayload("x", 1)},
+		"empty":        map[string]any{"transactions": []any{}},
+		"not an array": []any{validTxPayload("x")},
+	} {
+		if resp := post(t, srv, "/api/v1/transactions/batch", body); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, resp.StatusCode)
+		}
+	}
+}
 
+// ─── POST /api/v1/score/simulate ─────────────────────────────────────────────
+
+func simulatedFactors(t *testing.T, resp *http.Response) []string {
+	t.Helper()
 	if resp.StatusCode != http.StatusOK {
-		t.Errorf("expected 200, got %d", resp.StatusCode)
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
 	}
+	return factorsOf(decodeData(t, resp))
 }
 
-func TestGetEntitySummary_InvalidType_Returns400(t *testing.T) {
+func TestSimulate_DoesNotPersist(t *testing.T) {
 	srv := newTestServer(t)
 	defer srv.Close()
 
-	resp := get(t, srv, "/api/v1/entities/foobar/somevalue")
-	if resp.StatusCode != http.StatusBadRequest {
-		t.Errorf("expected 400, got %d", resp.StatusCode)
+	names := simulatedFactors(t, post(t, srv, "/api/v1/score/simulate", map[string]any{"transaction": validTxPayload("sim-1")}))
+	if !strings.Contains(strings.Join(names, ","), "first_transaction") {
+		t.Errorf("expected the full factor breakdown, got %v", names)
+	}
+	if resp := get(t, srv, "/api/v1/transactions/sim-1"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected the simulated transaction not to be saved, got %d", resp.StatusCode)
+	}
+	if resp := post(t, srv, "/api/v1/transactions", validTxPayload("sim-1")); resp.StatusCode != http.StatusCreated {
+		t.Errorf("expected the ID to remain free, got %d", resp.StatusCode)
 	}
 }
 
-func TestGetEntitySummary_EmptyHistory_ReturnsZeroCount(t *testing.T) {
+func TestSimulate_HypotheticalRules(t *testing.T) {
 	srv := newTestServer(t)
 	defer srv.Close()
 
-	resp := get(t, srv, "/api/v1/entities/email/nobody%40nowhere.com")
+	body := map[string]any{
+		"transaction": validTxPayload("sim-rules"),
+		"rules":       map[string]any{"version": "what-if", "rules": map[string]any{"purchase_behaviour": map[string]any{"enabled": false}}},
+	}
+	resp := post(t, srv, "/api/v1/score/simulate", body)
 	if resp.StatusCode != http.StatusOK {
-		t.Errorf("expected 200, got %d", resp.StatusCode)
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
 	}
 	d := decodeData(t, resp)
-	if d["total_count"].(float64) != 0 {
-		t.Errorf("expected 0, got %v", d["total_count"])
+	if names := factorsOf(d); strings.Contains(strings.Join(names, ","), "first_transaction") {
+		t.Errorf("expected the disabled rule not to fire, got %v", names)
+	}
+	if d["rules_source"] != "request" || d["rules"].(map[string]any)["version"] != "what-if" {
+		t.Errorf("expected the requested rules to be reported, got %v", d["rules"])
+	}
+
+	// The active rules are untouched.
+	names := simulatedFactors(t, post(t, srv, "/api/v1/score/simulate", map[string]any{"transaction": validTxPayload("sim-rules")}))
+	if !strings.Contains(strings.Join(names, ","), "first_transaction") {
+		t.Errorf("expected the active rules to be unchanged, got %v", names)
+	}
+
+	body["rules"] = map[string]any{"rules": map[string]any{"no_such_rule": map[string]any{}}}
+	if resp := post(t, srv, "/api/v1/score/simulate", body); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected invalid rules to be rejected, got %d", resp.StatusCode)
 	}
 }
 
-func TestGetEntitySummary_InvalidDaysParam_Returns400(t *testing.T) {
+func TestSimulate_SyntheticHistoryReplacesLiveHistory(t *testing.T) {
 	srv := newTestServer(t)
 	defer srv.Close()
 
-	resp := get(t, srv, "/api/v1/entities/ip/1.2.3.4?days=999")
-	if resp.StatusCode != http.StatusBadRequest {
-		t.Errorf("expected 400 for days>90, got %d", resp.StatusCode)
+	// Live history that would make the transaction look like a repeat customer.
+	live := validTxPayload("live-1")
+	live["timestamp"] = "2026-02-20T14:00:00Z"
+	post(t, srv, "/api/v1/transactions", live)
+
+	history := batchPayload("hist", 3)[1:] // 13:00 and 12:00, same account
+	body := map[string]any{"transaction": validTxPayload("sim-hist"), "history": history}
+	d := decodeData(t, post(t, srv, "/api/v1/score/simulate", body))
+	names := strings.Join(factorsOf(d), ",")
+	if !strings.Contains(names, "email_velocity_24h") {
+		t.Errorf("expected the synthetic history to drive velocity, got %s", names)
+	}
+	if d["history_source"] != "request" {
+		t.Errorf("expected history_source=request, got %v", d["history_source"])
+	}
+	if resp := get(t, srv, "/api/v1/transactions/hist-1"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected synthetic history not to be saved, got %d", resp.StatusCode)
+	}
+
+	// An empty history is a clean slate: a first transaction again.
+	body["history"] = []any{}
+	if names := simulatedFactors(t, post(t, srv, "/api/v1/score/simulate", body)); !strings.Contains(strings.Join(names, ","), "first_transaction") {
+		t.Errorf("expected an empty history to hide live history, got %v", names)
+	}
+
+	bad := validTxPayload("")
+	delete(bad, "card_bin")
+	body["history"] = []any{bad}
+	if resp := post(t, srv, "/api/v1/score/simulate", body); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected invalid history to be rejected, got %d", resp.StatusCode)
 	}
 }
 
-// ─── Blocklist ────────────────────────────────────────────────────────────────
+// ─── GET /api/v1/transactions/{id} ───────────────────────────────────────────
 
-func TestBlocklist_AddAndList(t *testing.T) {
+func TestGetTransaction_Exists_Returns200(t *testing.T) {
 	srv := newTestServer(t)
 	defer srv.Close()
 
-	post(t, srv, "/api/v1/blocklist", map[string]any{
-		"type": "ip", "value": "9.8.7.6", "list_type": "block", "reason": "test",
-	})
+	post(t, srv, "/api/v1/transactions", validTxPayload("get-001"))
+	resp := get(t, srv, "/api/v1/transactions/get-001")
 
-	resp := get(t, srv, "/api/v1/blocklist")
 	if resp.StatusCode != http.StatusOK {
 		t.Errorf("expected 200, got %d", resp.StatusCode)
 	}
+	d := decodeData(t, resp)
+	if d["transaction_id"] != "get-001" {
+		t.Errorf("expected transaction_id=get-001, got %v", d["transaction_id"])
+	}
 }
 
-func TestBlocklist_Add_Returns201(t *testing.T) {
+func TestGetTransaction_NotFound_Returns404(t *testing.T) {
 	srv := newTestServer(t)
 	defer srv.Close()
 
-	resp := post(t, srv, "/api/v1/blocklist", map[string]any{
-		"type": "email", "value": "bad@evil.com", "list_type": "block", "reason": "test",
-	})
-	if resp.StatusCode != http.StatusCreated {
-		t.Errorf("expected 201, got %d", resp.StatusCode)
+	resp := get(t, srv, "/api/v1/transactions/ghost-tx")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", resp.StatusCode)
 	}
 }
 
-func TestBlocklist_Add_InvalidType_Returns400(t *testing.T) {
+// ─── GET /api/v1/transactions ────────────────────────────────────────────────
+
+func TestListTransactions_FiltersByCountry(t *testing.T) {
 	srv := newTestServer(t)
 	defer srv.Close()
 
-	resp := post(t, srv, "/api/v1/blocklist", map[string]any{
-		"type": "phone", "value": "1234", "list_type": "block",
-	})
-	if resp.StatusCode != http.StatusBadRequest {
-		t.Errorf("expected 400, got %d", resp.StatusCode)
+	post(t, srv, "/api/v1/transactions", validTxPayload("list-br"))
+	foreign := validTxPayload("list-us")
+	foreign["ip_country"] = "US"
+	post(t, srv, "/api/v1/transactions", foreign)
+
+	resp := get(t, srv, "/api/v1/transactions?ip_country=us,ar")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	txs := decodeData(t, resp)["transactions"].([]any)
+	if len(txs) != 1 || txs[0].(map[string]any)["transaction_id"] != "list-us" {
+		t.Errorf("expected only list-us, got %v", txs)
 	}
 }
 
-func TestBlocklist_Add_InvalidListType_Returns400(t *testing.T) {
+func TestListTransactions_PagesThroughNextCursor(t *testing.T) {
 	srv := newTestServer(t)
 	defer srv.Close()
 
-	resp := post(t, srv, "/api/v1/blocklist", map[string]any{
-		"type": "ip", "value": "1.2.3.4", "list_type": "whitelist",
-	})
-	if resp.StatusCode != http.StatusBadRequest {
-		t.Errorf("expected 400, got %d", resp.StatusCode)
+	for i := 0; i < 5; i++ {
+		p := validTxPayload(fmt.Sprintf("page-%d", i))
+		p["timestamp"] = fmt.Sprintf("2026-02-25T14:0%d:00Z", i)
+		post(t, srv, "/api/v1/transactions", p)
+	}
+
+	var ids []string
+	path := "/api/v1/transactions?limit=2"
+	for pages := 0; path != ""; pages++ {
+		if pages > 3 {
+			t.Fatal("pagination did not terminate")
+		}
+		resp := get(t, srv, path)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d", resp.StatusCode)
+		}
+		d := decodeData(t, resp)
+		for _, tx := range d["transactions"].([]any) {
+			ids = append(ids, tx.(map[string]any)["transaction_id"].(string))
+		}
+		path = ""
+		if next, ok := d["next_cursor"].(string); ok {
+			path = "/api/v1/transactions?limit=2&cursor=" + next
+		}
+	}
+
+	want := []string{"page-4", "page-3", "page-2", "page-1", "page-0"}
+	if fmt.Sprint(ids) != fmt.Sprint(want) {
+		t.Errorf("expected %v newest first, got %v", want, ids)
 	}
 }
 
-func TestBlocklist_Delete_Returns204(t *testing.T) {
+func TestListTransactions_InvalidParams_Return400(t *testing.T) {
 	srv := newTestServer(t)
 	defer srv.Close()
 
-	addResp := post(t, srv, "/api/v1/blocklist", map[string]any{
-		"type": "ip", "value": "11.22.33.44", "list_type": "block", "reason": "del test",
-	})
-	d := decodeData(t, addResp)
-	id := d["id"].(string)
-
-	resp := del(t, srv, "/api/v1/blocklist/"+id)
-	if resp.StatusCode != http.StatusNoContent {
-		t.Errorf("expected 204, got %d", resp.StatusCode)
+	for _, query := range []string{
+		"since=yesterday",
+		"risk_level=extreme",
+		"min_score=101",
+		"min_score=60&max_score=40",
+		"min_amount=-1",
+		"sort=email",
+		"order=sideways",
+		"limit=0",
+		"cursor=not-a-cursor",
+	} {
+		resp := get(t, srv, "/api/v1/transactions?"+query)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, resp.StatusCode)
+		}
+		resp.Body.Close()
 	}
 }
 
-func TestBlocklist_DeleteMissing_Returns404(t *testing.T) {
+func TestListTransactions_CursorFromAnotherSort_Returns400(t *testing.T) {
 	srv := newTestServer(t)
 	defer srv.Close()
 
-	resp := del(t, srv, "/api/v1/blocklist/nonexistent-id")
-	if resp.StatusCode != http.StatusNotFound {
-		t.Errorf("expected 404, got %d", resp.StatusCode)
+	for _, id := range []string{"sort-1", "sort-2"} {
+		post(t, srv, "/api/v1/transactions", validTxPayload(id))
+	}
+	d := decodeData(t, get(t, srv, "/api/v1/transactions?limit=1"))
+	next, ok := d["next_cursor"].(string)
+	if !ok {
+		t.Fatalf("expected a next_cursor, got %v", d)
+	}
+
+	resp := get(t, srv, "/api/v1/transactions?limit=1&sort=amount&cursor="+next)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", resp.StatusCode)
 	}
 }
 
-func TestBlocklist_BlockedEntity_ScoresMax(t *testing.T) {
+// ─── GET /api/v1/entities/{type}/{value} ─────────────────────────────────────
+
+func TestGetEntitySummary_ValidEmail_Returns200(t *testing.T) {
 	srv := newTestServer(t)
 	defer srv.Close()
 
-	post(t, srv, "/api/v1/blocklist", map[string]any{
+	post(t, srv, "/api/v1/transactions", validTxPayload("ent-001"))
+	resp := get(t, srv, "/api/v1/entities/email/test%40example.com")
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200, got %d", resp.StatusCode)
+	}
+	d := decodeData(t, resp)
+	if d["total_count"].(float64) < 1 {
+		t.Error("expected at least 1 transaction in summary")
+	}
+}
+
+func TestGetEntitySummary_ValidIP_Returns200(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	post(t, srv, "/api/v1/transactions", validTxPayload("ent-ip-001"))
+	resp := get(t, srv, "/api/v1/entities/ip/177.10.20.30")
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestGetEntitySummary_InvalidType_Returns400(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	resp := get(t, srv, "/api/v1/entities/foobar/somevalue")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestGetEntitySummary_EmptyHistory_ReturnsZeroCount(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	resp := get(t, srv, "/api/v1/entities/email/nobody%40nowhere.com")
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200, got %d", resp.StatusCode)
+	}
+	d := decodeData(t, resp)
+	if d["total_count"].(float64) != 0 {
+		t.Errorf("expected 0, got %v", d["total_count"])
+	}
+}
+
+func TestGetEntitySummary_InvalidDaysParam_Returns400(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	resp := get(t, srv, "/api/v1/entities/ip/1.2.3.4?days=999")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for days>90, got %d", resp.StatusCode)
+	}
+}
+
+func TestGetEntitySummary_AggregatesWindowAndPagesTransactions(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	now := time.Now().UTC()
+	emails := []string{"a@example.com", "a@example.com", "b@example.com"}
+	for i, email := range emails {
+		p := validTxPayload(fmt.Sprintf("ent-page-%d", i))
+		p["timestamp"] = now.Add(-time.Duration(i) * 24 * time.Hour).Format(time.RFC3339)
+		p["ip_address"] = "203.0.113.9"
+		p["user_email"] = email
+		post(t, srv, "/api/v1/transactions", p)
+	}
+
+	d := decodeData(t, get(t, srv, "/api/v1/entities/ip/203.0.113.9?limit=2"))
+	if d["total_count"] != 3.0 {
+		t.Errorf("expected total_count=3 over the whole window, got %v", d["total_count"])
+	}
+	if txs := d["transactions"].([]any); len(txs) != 2 || txs[0].(map[string]any)["transaction_id"] != "ent-page-0" {
+		t.Errorf("expected the 2 newest transactions, got %v", txs)
+	}
+	cp := d["counterparts"].(map[string]any)
+	top := cp["top"].([]any)
+	if cp["entity_type"] != "email" || cp["distinct"] != 2.0 || top[0].(map[string]any)["value"] != "a@example.com" {
+		t.Errorf("expected 2 distinct emails led by a@example.com, got %v", cp)
+	}
+	if activity := d["activity"].([]any); len(activity) != 8 {
+		t.Errorf("expected one activity bucket per day of a 7-day window, got %d", len(activity))
+	}
+	if d["first_seen"] == nil || d["last_seen"] == nil {
+		t.Errorf("expected first_seen and last_seen, got %v / %v", d["first_seen"], d["last_seen"])
+	}
+
+	next, ok := d["next_cursor"].(string)
+	if !ok {
+		t.Fatalf("expected a next_cursor, got %v", d)
+	}
+	d = decodeData(t, get(t, srv, "/api/v1/entities/ip/203.0.113.9?limit=2&cursor="+next))
+	if txs := d["transactions"].([]any); len(txs) != 1 || txs[0].(map[string]any)["transaction_id"] != "ent-page-2" {
+		t.Errorf("expected ent-page-2 on the last page, got %v", txs)
+	}
+	if _, ok := d["next_cursor"]; ok {
+		t.Error("expected no next_cursor on the last page")
+	}
+}
+
+// ─── GET /api/v1/entities/{type}/{value}/graph ───────────────────────────────
+
+func TestGetEntityGraph_LinksAccountsSharingADevice(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	for i, email := range []string{"ring-a@example.com", "ring-b@example.com"} {
+		p := validTxPayload(fmt.Sprintf("graph-%d", i))
+		p["user_email"] = email
+		p["ip_address"] = fmt.Sprintf("198.51.100.%d", i+1)
+		p["device_fingerprint"] = "ring-device"
+		post(t, srv, "/api/v1/transactions", p)
+	}
+
+	resp := get(t, srv, "/api/v1/entities/email/ring-a@example.com/graph?depth=2")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	d := decodeData(t, resp)
+	var found bool
+	for _, n := range d["nodes"].([]any) {
+		node := n.(map[string]any)
+		if node["type"] == "email" && node["value"] == "ring-b@example.com" {
+			found = node["distance"] == 2.0
+		}
+	}
+	if !found {
+		t.Errorf("expected ring-b@example.com two hops away, got %v", d["nodes"])
+	}
+	edge := d["edges"].([]any)[0].(map[string]any)
+	if edge["weight"] == nil || edge["first_seen"] == nil || edge["last_seen"] == nil {
+		t.Errorf("expected edges with weight and timestamps, got %v", edge)
+	}
+}
+
+func TestGetEntityGraph_InvalidParams_Return400(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	for _, path := range []string{
+		"/api/v1/entities/phone/123/graph",
+		"/api/v1/entities/ip/1.2.3.4/graph?depth=9",
+		"/api/v1/entities/ip/1.2.3.4/graph?max_nodes=0",
+	} {
+		resp := get(t, srv, path)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", path, resp.StatusCode)
+		}
+		resp.Body.Close()
+	}
+}
+
+// ─── Blocklist ────────────────────────────────────────────────────────────────
+
+func TestBlocklist_AddAndList(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	post(t, srv, "/api/v1/blocklist", map[string]any{
+		"type": "ip", "value": "9.8.7.6", "list_type": "block", "reason": "test",
+	})
+
+	resp := get(t, srv, "/api/v1/blocklist")
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestBlocklist_Add_Returns201(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	resp := post(t, srv, "/api/v1/blocklist", map[string]any{
+		"type": "email", "value": "bad@evil.com", "list_type": "block", "reason": "test",
+	})
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("expected 201, got %d", resp.StatusCode)
+	}
+}
+
+func TestBlocklist_Add_InvalidType_Returns400(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	resp := post(t, srv, "/api/v1/blocklist", map[string]any{
+		"type": "phone", "value": "1234", "list_type": "block",
+	})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestBlocklist_Add_InvalidListType_Returns400(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	resp := post(t, srv, "/api/v1/blocklist", map[string]any{
+		"type": "ip", "value": "1.2.3.4", "list_type": "whitelist",
+	})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestBlocklist_Delete_Returns204(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	addResp := post(t, srv, "/api/v1/blocklist", map[string]any{
+		"type": "ip", "value": "11.22.33.44", "list_type": "block", "reason": "del test",
+	})
+	d := decodeData(t, addResp)
+	id := d["id"].(string)
+
+	resp := del(t, srv, "/api/v1/blocklist/"+id)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("expected 204, got %d", resp.StatusCode)
+	}
+}
+
+func TestBlocklist_DeleteMissing_Returns404(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	resp := del(t, srv, "/api/v1/blocklist/nonexistent-id")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestBlocklist_BlockedEntity_ScoresMax(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	post(t, srv, "/api/v1/blocklist", map[string]any{
 		"type": "ip", "value": "1.1.1.1", "list_type": "block", "reason": "blocked",
 	})
 
@@ -602,3 +1264,614 @@ func TestAdminSeed_LoadsTransactions(t *testing.T) {
 		t.Error("seeded transaction should be retrievable")
 	}
 }
+
+// ─── Admin rules ──────────────────────────────────────────────────────────────
+
+func TestAdminRules_GetReturnsVersionAndHash(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	d := decodeData(t, get(t, srv, "/api/v1/admin/rules"))
+	if d["version"] != "builtin" {
+		t.Errorf("expected builtin version, got %v", d["version"])
+	}
+	if h, _ := d["hash"].(string); len(h) != 64 {
+		t.Errorf("expected a sha-256 hex hash, got %v", d["hash"])
+	}
+}
+
+func TestAdminRules_Replace_StampsNewVersionOnTransactions(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	resp := put(t, srv, "/api/v1/admin/rules", map[string]any{
+		"version": "analyst-7",
+		"rules":   map[string]any{"timing": map[string]any{"enabled": false}},
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	hash := decodeData(t, resp)["hash"]
+
+	d := decodeData(t, post(t, srv, "/api/v1/transactions", validTxPayload("tx-rules-001")))
+	if d["ruleset_version"] != "analyst-7" {
+		t.Errorf("expected ruleset_version analyst-7, got %v", d["ruleset_version"])
+	}
+	if d["ruleset_hash"] != hash {
+		t.Errorf("expected ruleset_hash %v, got %v", hash, d["ruleset_hash"])
+	}
+}
+
+func TestAdminRules_ReplaceInvalid_Returns400(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	resp := put(t, srv, "/api/v1/admin/rules", map[string]any{
+		"version": "bad",
+		"rules":   map[string]any{"ip_velocity": map[string]any{"min_count": 0}},
+	})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+	e := decodeError(t, resp)
+	if e["code"] != "INVALID_RULES" {
+		t.Errorf("expected INVALID_RULES, got %v", e["code"])
+	}
+	if msg, _ := e["message"].(string); !strings.Contains(msg, "rules.ip_velocity.min_count") {
+		t.Errorf("error should name the offending field, got %q", msg)
+	}
+}
+
+func TestAdminRules_ReloadWithoutFile_Returns409(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	resp := post(t, srv, "/api/v1/admin/rules/reload", nil)
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("expected 409, got %d", resp.StatusCode)
+	}
+}
+
+// ─── Review queue ─────────────────────────────────────────────────────────────
+
+func withActor(t *testing.T, srv *httptest.Server, method, path, actor string, body any) *http.Response {
+	t.Helper()
+	var b []byte
+	if body != nil {
+		b, _ = json.Marshal(body)
+	}
+	req, _ := http.NewRequest(method, srv.URL+path, bytes.NewReader(b))
+	req.Header.Set("X-Actor", actor)
+	return send(t, req)
+}
+
+// reviewerKey creates a reviews:write key named after an analyst and returns its token.
+func reviewerKey(t *testing.T, srv *httptest.Server, analyst string) string {
+	t.Helper()
+	resp := post(t, srv, "/api/v1/admin/keys", map[string]any{
+		"name": analyst, "scopes": []string{"reviews:write"},
+	})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create key for %s: expected 201, got %d", analyst, resp.StatusCode)
+	}
+	token, _ := decodeData(t, resp)["token"].(string)
+	return token
+}
+
+func TestReviews_ClaimDecideAndBlocklist(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	// Send every scored transaction to review.
+	put(t, srv, "/api/v1/config/thresholds", map[string]any{"scope": "global", "approve": 0, "review": 100})
+	d := decodeData(t, post(t, srv, "/api/v1/transactions", validTxPayload("tx-rv-001")))
+	if d["final_status"] != "pending_review" {
+		t.Fatalf("expected pending_review, got %v", d["final_status"])
+	}
+
+	var env struct {
+		Data []map[string]any `json:"data"`
+	}
+	if err := json.NewDecoder(get(t, srv, "/api/v1/reviews?sort=amount").Body).Decode(&env); err != nil {
+		t.Fatal(err)
+	}
+	if len(env.Data) != 1 || env.Data[0]["transaction_id"] != "tx-rv-001" {
+		t.Fatalf("expected tx-rv-001 in the queue, got %v", env.Data)
+	}
+
+	// One key per analyst: the key, not the X-Actor label, owns the claim.
+	ana, bruno := reviewerKey(t, srv, "ana"), reviewerKey(t, srv, "bruno")
+	if resp := withKey(t, srv, http.MethodPost, "/api/v1/reviews/tx-rv-001/claim", ana, nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("claim: expected 200, got %d", resp.StatusCode)
+	}
+	if resp := withKey(t, srv, http.MethodPost, "/api/v1/reviews/tx-rv-001/claim", bruno, nil); resp.StatusCode != http.StatusConflict {
+		t.Errorf("second claim: expected 409, got %d", resp.StatusCode)
+	}
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/v1/reviews/tx-rv-001/decision",
+		bytes.NewBufferString(`{"decision":"approve"}`))
+	req.Header.Set("X-API-Key", bruno)
+	req.Header.Set("X-Actor", "ana")
+	if resp := send(t, req); resp.StatusCode != http.StatusConflict {
+		t.Errorf("X-Actor must not let another key decide the claim: expected 409, got %d", resp.StatusCode)
+	}
+
+	resp := withKey(t, srv, http.MethodPost, "/api/v1/reviews/tx-rv-001/decision", ana, map[string]any{
+		"decision": "decline", "notes": "account takeover", "blocklist": []string{"email"},
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("decision: expected 200, got %d", resp.StatusCode)
+	}
+	tx := decodeData(t, resp)
+	if tx["final_status"] != "declined" {
+		t.Errorf("expected final_status declined, got %v", tx["final_status"])
+	}
+
+	blocked := decodeData(t, post(t, srv, "/api/v1/transactions", validTxPayload("tx-rv-002")))
+	if blocked["risk_score"] != 100.0 {
+		t.Errorf("the decided email should now be blocklisted, got score %v", blocked["risk_score"])
+	}
+
+	sla := decodeData(t, get(t, srv, "/api/v1/reviews/sla"))
+	if sla["target"] != "4h" {
+		t.Errorf("expected a 4h SLA target, got %v", sla["target"])
+	}
+}
+
+// ─── Outcomes ─────────────────────────────────────────────────────────────────
+
+func TestOutcome_Record_Returns201AndAttachesToTransaction(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+	post(t, srv, "/api/v1/transactions", validTxPayload("tx-oc-001"))
+
+	resp := post(t, srv, "/api/v1/transactions/tx-oc-001/outcome", map[string]any{
+		"type": "chargeback", "source": "acquirer", "reason_code": "10.4",
+	})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	o := decodeData(t, resp)
+	if o["transaction_id"] != "tx-oc-001" || o["id"] == "" || o["occurred_at"] == nil {
+		t.Errorf("unexpected outcome: %v", o)
+	}
+
+	tx := decodeData(t, get(t, srv, "/api/v1/transactions/tx-oc-001"))
+	outcomes, _ := tx["outcomes"].([]any)
+	if len(outcomes) != 1 || outcomes[0].(map[string]any)["type"] != "chargeback" {
+		t.Errorf("expected the chargeback on the transaction, got %v", tx["outcomes"])
+	}
+}
+
+func TestOutcome_Validation(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+	post(t, srv, "/api/v1/transactions", validTxPayload("tx-oc-002"))
+
+	cases := []struct {
+		name string
+		path string
+		body map[string]any
+		want int
+	}{
+		{"unknown type", "/api/v1/transactions/tx-oc-002/outcome", map[string]any{"type": "dispute", "source": "a"}, http.StatusBadRequest},
+		{"missing source", "/api/v1/transactions/tx-oc-002/outcome", map[string]any{"type": "refund"}, http.StatusBadRequest},
+		{"amount above transaction", "/api/v1/transactions/tx-oc-002/outcome", map[string]any{"type": "refund", "source": "a", "amount": 500}, http.StatusBadRequest},
+		{"future occurred_at", "/api/v1/transactions/tx-oc-002/outcome", map[string]any{"type": "refund", "source": "a", "occurred_at": time.Now().Add(time.Hour).Format(time.RFC3339)}, http.StatusBadRequest},
+		{"unknown transaction", "/api/v1/transactions/nope/outcome", map[string]any{"type": "refund", "source": "a"}, http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		if resp := post(t, srv, tc.path, tc.body); resp.StatusCode != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, resp.StatusCode)
+		}
+	}
+}
+
+func TestOutcome_FeedsEntitySummaryAndReport(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	now := time.Now().UTC()
+	for i := 0; i < 4; i++ {
+		p := validTxPayload(fmt.Sprintf("tx-oc-rate-%d", i))
+		p["timestamp"] = now.Add(-time.Duration(i) * time.Hour).Format(time.RFC3339)
+		p["user_email"] = "disputer@example.com"
+		p["card_bin"] = "400011"
+		post(t, srv, "/api/v1/transactions", p)
+	}
+	post(t, srv, "/api/v1/transactions/tx-oc-rate-0/outcome", map[string]any{"type": "chargeback", "source": "acquirer"})
+	// A second chargeback on the same transaction still counts it once.
+	post(t, srv, "/api/v1/transactions/tx-oc-rate-0/outcome", map[string]any{"type": "chargeback", "source": "acquirer"})
+	post(t, srv, "/api/v1/transactions/tx-oc-rate-1/outcome", map[string]any{"type": "false_positive", "source": "analyst"})
+
+	summary := decodeData(t, get(t, srv, "/api/v1/entities/email/disputer@example.com"))
+	oc, _ := summary["outcomes"].(map[string]any)
+	if oc["chargebacks"] != 1.0 || oc["false_positives"] != 1.0 || oc["chargeback_rate"] != 0.25 {
+		t.Errorf("expected 1 chargeback, 1 false positive and a 0.25 rate, got %v", oc)
+	}
+
+	report := decodeData(t, get(t, srv, "/api/v1/reports/fraud-patterns"))
+	outcomes, _ := report["outcomes"].(map[string]any)
+	byBIN, _ := outcomes["by_bin"].([]any)
+	if len(byBIN) != 1 {
+		t.Fatalf("expected BIN 400011 ranked, got %v", outcomes["by_bin"])
+	}
+	if b := byBIN[0].(map[string]any); b["entity_value"] != "400011" || b["chargeback_rate"] != 0.25 {
+		t.Errorf("unexpected BIN entry: %v", b)
+	}
+
+	var env struct {
+		Data []map[string]any `json:"data"`
+	}
+	if err := json.NewDecoder(get(t, srv, "/api/v1/outcomes?type=chargeback").Body).Decode(&env); err != nil {
+		t.Fatal(err)
+	}
+	if len(env.Data) != 2 {
+		t.Errorf("expected 2 chargeback records, got %d", len(env.Data))
+	}
+}
+
+// ─── Audit log ────────────────────────────────────────────────────────────────
+
+func TestAudit_RecordsMutationsWithActorAndState(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	resp := withActor(t, srv, http.MethodPost, "/api/v1/blocklist", "ana", map[string]any{
+		"type": "email", "value": "bad@x.com", "list_type": "block", "reason": "test",
+	})
+	id := decodeData(t, resp)["id"].(string)
+	withActor(t, srv, http.MethodDelete, "/api/v1/blocklist/"+id, "bruno", nil)
+	put(t, srv, "/api/v1/config/thresholds", map[string]any{"scope": "global", "approve": 20, "review": 60})
+
+	var env struct {
+		Data []map[string]any `json:"data"`
+	}
+	if err := json.NewDecoder(get(t, srv, "/api/v1/audit?action=blocklist").Body).Decode(&env); err != nil {
+		t.Fatal(err)
+	}
+	if len(env.Data) != 2 {
+		t.Fatalf("expected 2 blocklist entries, got %d", len(env.Data))
+	}
+	removed, added := env.Data[0], env.Data[1]
+	if removed["action"] != "blocklist.delete" || removed["on_behalf_of"] != "bruno" || removed["before"] == nil || removed["after"] != nil {
+		t.Errorf("unexpected delete entry: %v", removed)
+	}
+	if added["on_behalf_of"] != "ana" || added["resource"] != "blocklist/"+id || added["request_id"] == "" {
+		t.Errorf("unexpected add entry: %v", added)
+	}
+	// X-Actor is only a label: the actor is always the key that made the call.
+	for _, e := range env.Data {
+		if e["actor"] != "key:test-admin" {
+			t.Errorf("expected the entry attributed to the API key, got %v", e["actor"])
+		}
+	}
+
+	env.Data = nil
+	if err := json.NewDecoder(get(t, srv, "/api/v1/audit?on_behalf_of=ana").Body).Decode(&env); err != nil {
+		t.Fatal(err)
+	}
+	if len(env.Data) != 1 || env.Data[0]["action"] != "blocklist.add" {
+		t.Errorf("expected ana's add only, got %v", env.Data)
+	}
+
+	env.Data = nil
+	if err := json.NewDecoder(get(t, srv, "/api/v1/audit?resource=thresholds/global").Body).Decode(&env); err != nil {
+		t.Fatal(err)
+	}
+	if len(env.Data) != 1 || env.Data[0]["actor"] != "key:test-admin" {
+		t.Errorf("expected one threshold entry attributed to the API key, got %v", env.Data)
+	}
+
+	v := decodeData(t, get(t, srv, "/api/v1/audit/verify"))
+	if v["valid"] != true || v["entries"] != 3.0 {
+		t.Errorf("expected a valid chain of 3 entries, got %v", v)
+	}
+}
+
+func TestAudit_InvalidParams_Return400(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	for _, q := range []string{"since=yesterday", "limit=0", "limit=5000"} {
+		if resp := get(t, srv, "/api/v1/audit?"+q); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", q, resp.StatusCode)
+		}
+	}
+}
+
+// ─── Shadow mode ──────────────────────────────────────────────────────────────
+
+func TestShadowRules_GetWhenOff_Returns404(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	resp := get(t, srv, "/api/v1/admin/rules/shadow")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestShadowRules_ReportComparesChampionAndChallenger(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	resp := put(t, srv, "/api/v1/admin/rules/shadow", map[string]any{
+		"version": "strict-first-tx",
+		"rules": map[string]any{"purchase_behaviour": map[string]any{
+			"first_transaction": map[string]any{"delta": 100, "high_delta": 100},
+		}},
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	payload := validTxPayload("tx-shadow-001")
+	payload["timestamp"] = time.Now().UTC().Format(time.RFC3339)
+	d := decodeData(t, post(t, srv, "/api/v1/transactions", payload))
+	if d["recommendation"] != "approve" {
+		t.Fatalf("champion decision must be unchanged, got %v", d["recommendation"])
+	}
+	shadow, _ := d["shadow"].(map[string]any)
+	if shadow["recommendation"] != "decline" || shadow["ruleset_version"] != "strict-first-tx" {
+		t.Errorf("expected a declining shadow result from strict-first-tx, got %v", d["shadow"])
+	}
+
+	r := decodeData(t, get(t, srv, "/api/v1/reports/shadow"))
+	if r["compared"] != 1.0 || r["stricter"] != 1.0 || r["extra_declined"] != 1.0 {
+		t.Errorf("expected 1 compared, stricter and extra-declined transaction, got %v", r)
+	}
+	if r["extra_declined_amount"] != 50.0 {
+		t.Errorf("expected extra_declined_amount 50, got %v", r["extra_declined_amount"])
+	}
+	transitions, _ := r["transitions"].([]any)
+	if len(transitions) != 1 {
+		t.Fatalf("expected one transition, got %v", r["transitions"])
+	}
+	if tr := transitions[0].(map[string]any); tr["from"] != "approve" || tr["to"] != "decline" {
+		t.Errorf("expected approve → decline, got %v", tr)
+	}
+
+	if resp := del(t, srv, "/api/v1/admin/rules/shadow"); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("expected 204, got %d", resp.StatusCode)
+	}
+	d = decodeData(t, post(t, srv, "/api/v1/transactions", validTxPayload("tx-shadow-002")))
+	if _, has := d["shadow"]; has {
+		t.Error("no shadow result expected after shadow mode is turned off")
+	}
+}
+
+// ─── Threshold configuration ──────────────────────────────────────────────────
+
+func TestThresholds_PutAppliesToMerchantCountry(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	resp := put(t, srv, "/api/v1/config/thresholds", map[string]any{
+		"scope": "country", "key": "br", "approve": 1, "review": 3,
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	d := decodeData(t, post(t, srv, "/api/v1/transactions", validTxPayload("tx-th-001")))
+	if d["recommendation"] != "decline" {
+		t.Errorf("expected decline under BR thresholds, got %v (score %v)", d["recommendation"], d["risk_score"])
+	}
+	if d["threshold_scope"] != "country:BR" {
+		t.Errorf("expected threshold_scope country:BR, got %v", d["threshold_scope"])
+	}
+}
+
+func TestThresholds_InvalidOrdering_Returns400(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	resp := put(t, srv, "/api/v1/config/thresholds", map[string]any{
+		"scope": "global", "approve": 70, "review": 30,
+	})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestThresholds_HistoryRecordsActor(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	b, _ := json.Marshal(map[string]any{"scope": "merchant", "key": "m-1", "approve": 20, "review": 60})
+	req, _ := http.NewRequest(http.MethodPut, srv.URL+"/api/v1/config/thresholds", bytes.NewReader(b))
+	req.Header.Set("X-Actor", "analyst@lumina")
+	send(t, req)
+	del(t, srv, "/api/v1/config/thresholds?scope=merchant&key=m-1")
+
+	var env struct {
+		Data []map[string]any `json:"data"`
+	}
+	if err := json.NewDecoder(get(t, srv, "/api/v1/config/thresholds/history").Body).Decode(&env); err != nil {
+		t.Fatal(err)
+	}
+	if len(env.Data) != 2 {
+		t.Fatalf("expected 2 history entries, got %d", len(env.Data))
+	}
+	if env.Data[1]["actor"] != "key:test-admin" {
+		t.Errorf("expected the create attributed to the API key, not X-Actor, got %v", env.Data[1]["actor"])
+	}
+	if env.Data[0]["after"] != nil {
+		t.Errorf("latest entry is the delete and should have no after, got %v", env.Data[0]["after"])
+	}
+}
+
+// ─── API keys ─────────────────────────────────────────────────────────────────
+
+// withKey sends a request authenticated by token instead of the test admin key.
+func withKey(t *testing.T, srv *httptest.Server, method, path, token string, body any) *http.Response {
+	t.Helper()
+	var b []byte
+	if body != nil {
+		b, _ = json.Marshal(body)
+	}
+	req, _ := http.NewRequest(method, srv.URL+path, bytes.NewReader(b))
+	req.Header.Set("X-API-Key", token)
+	return send(t, req)
+}
+
+func TestAuth_MissingOrInvalidKey_Returns401(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/api/v1/admin/seed", "application/json", bytes.NewBufferString("[]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a key, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("WWW-Authenticate") == "" {
+		t.Error("expected a WWW-Authenticate header")
+	}
+	if e := decodeError(t, resp); e["code"] != "UNAUTHORIZED" {
+		t.Errorf("expected UNAUTHORIZED, got %v", e["code"])
+	}
+
+	resp = withKey(t, srv, http.MethodGet, "/api/v1/blocklist", "lfk_not_a_real_key_at_all", nil)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 for an unknown key, got %d", resp.StatusCode)
+	}
+
+	// Health checks stay open for load balancers.
+	resp, err = http.Get(srv.URL + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected /health to be open, got %d", resp.StatusCode)
+	}
+}
+
+func TestAuth_ScopesEnforcedPerRouteGroup(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	resp := post(t, srv, "/api/v1/admin/keys", map[string]any{
+		"name": "checkout", "scopes": []string{"transactions:write"},
+	})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	token, _ := decodeData(t, resp)["token"].(string)
+	if token == "" {
+		t.Fatal("expected the token in the create response")
+	}
+
+	if resp := withKey(t, srv, http.MethodPost, "/api/v1/transactions", token, validTxPayload("tx-auth-001")); resp.StatusCode != http.StatusCreated {
+		t.Errorf("transactions:write should submit transactions, got %d", resp.StatusCode)
+	}
+
+	for _, c := range []struct{ method, path string }{
+		{http.MethodGet, "/api/v1/transactions/tx-auth-001"},
+		{http.MethodGet, "/api/v1/blocklist"},
+		{http.MethodDelete, "/api/v1/blocklist/any"},
+		{http.MethodPost, "/api/v1/webhooks"},
+		{http.MethodPost, "/api/v1/admin/seed"},
+		{http.MethodGet, "/api/v1/admin/keys"},
+	} {
+		resp := withKey(t, srv, c.method, c.path, token, nil)
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("%s %s: expected 403, got %d", c.method, c.path, resp.StatusCode)
+			continue
+		}
+		if e := decodeError(t, resp); e["code"] != "FORBIDDEN" {
+			t.Errorf("%s %s: expected FORBIDDEN, got %v", c.method, c.path, e["code"])
+		}
+	}
+}
+
+func TestAuth_CreateRotateRevoke(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	d := decodeData(t, post(t, srv, "/api/v1/admin/keys", map[string]any{
+		"name": "lists-bot", "scopes": []string{"lists:admin"},
+	}))
+	id, _ := d["id"].(string)
+	first, _ := d["token"].(string)
+	if d["hash"] != nil {
+		t.Error("the key hash must never be returned")
+	}
+	if resp := withKey(t, srv, http.MethodGet, "/api/v1/blocklist", first, nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 with the new key, got %d", resp.StatusCode)
+	}
+
+	// Rotation issues a new secret and retires the old one at once.
+	d = decodeData(t, post(t, srv, "/api/v1/admin/keys/"+id+"/rotate", nil))
+	second, _ := d["token"].(string)
+	if second == "" || second == first || d["id"] != id {
+		t.Fatalf("unexpected rotate response: %v", d)
+	}
+	if resp := withKey(t, srv, http.MethodGet, "/api/v1/blocklist", first, nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 with the rotated-out key, got %d", resp.StatusCode)
+	}
+	if resp := withKey(t, srv, http.MethodGet, "/api/v1/blocklist", second, nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 with the rotated key, got %d", resp.StatusCode)
+	}
+
+	if resp := del(t, srv, "/api/v1/admin/keys/"+id); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204 on revoke, got %d", resp.StatusCode)
+	}
+	if resp := withKey(t, srv, http.MethodGet, "/api/v1/blocklist", second, nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 with a revoked key, got %d", resp.StatusCode)
+	}
+	if resp := del(t, srv, "/api/v1/admin/keys/"+id); resp.StatusCode != http.StatusConflict {
+		t.Errorf("expected 409 revoking twice, got %d", resp.StatusCode)
+	}
+	if resp := post(t, srv, "/api/v1/admin/keys/"+id+"/rotate", nil); resp.StatusCode != http.StatusConflict {
+		t.Errorf("expected 409 rotating a revoked key, got %d", resp.StatusCode)
+	}
+
+	// The key stays listed, marked revoked; no token is ever listed.
+	var env struct {
+		Data []map[string]any `json:"data"`
+	}
+	if err := json.NewDecoder(get(t, srv, "/api/v1/admin/keys").Body).Decode(&env); err != nil {
+		t.Fatal(err)
+	}
+	if len(env.Data) != 2 {
+		t.Fatalf("expected the admin key and the revoked key, got %d", len(env.Data))
+	}
+	for _, k := range env.Data {
+		if k["token"] != nil {
+			t.Errorf("listing must not expose tokens: %v", k)
+		}
+	}
+	if env.Data[1]["revoked_at"] == nil {
+		t.Errorf("expected revoked_at on the revoked key, got %v", env.Data[1])
+	}
+
+	// Key management is audited without secrets.
+	env.Data = nil
+	if err := json.NewDecoder(get(t, srv, "/api/v1/audit?action=api_key").Body).Decode(&env); err != nil {
+		t.Fatal(err)
+	}
+	if len(env.Data) != 3 {
+		t.Fatalf("expected create, rotate and revoke audit entries, got %d", len(env.Data))
+	}
+	for _, e := range env.Data {
+		b, _ := json.Marshal(e)
+		if bytes.Contains(b, []byte(first)) || bytes.Contains(b, []byte(second)) {
+			t.Errorf("audit entry leaks a token: %s", b)
+		}
+	}
+}
+
+func TestAuth_InvalidScope_Returns400(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	resp := post(t, srv, "/api/v1/admin/keys", map[string]any{"name": "x", "scopes": []string{"everything"}})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+	if e := decodeError(t, resp); e["code"] != "INVALID_SCOPE" {
+		t.Errorf("expected INVALID_SCOPE, got %v", e["code"])
+	}
+}
@@ -9,7 +9,8 @@ import (
 // ─── Response envelope ────────────────────────────────────────────────────────
 
 // envelope is the standard wrapper for all API responses.
-// Success responses set `error` to nil; error responses set `data` to nil.
+// Success responses set `error` to nil; error responses set `data` to nil,
+// except a rejected batch, which carries its per-item results in `data`.
 type envelope struct {
 	Data  any    `json:"data,omitempty"`
 	Error *apiError `json:"error,omitempty"`
@@ -53,6 +54,17 @@ func badRequest(w http.ResponseWriter, code, message string) {
 	writeJSON(w, http.StatusBadRequest, envelope{Error: &apiError{Code: code, Message: message}})
 }
 
+// unauthorized writes a 401 error response asking for a bearer token.
+func unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="lumina-fraud-api"`)
+	writeJSON(w, http.StatusUnauthorized, envelope{Error: &apiError{Code: "UNAUTHORIZED", Message: message}})
+}
+
+// forbidden writes a 403 error response.
+func forbidden(w http.ResponseWriter, message string) {
+	writeJSON(w, http.StatusForbidden, envelope{Error: &apiError{Code: "FORBIDDEN", Message: message}})
+}
+
 // notFound writes a 404 error response.
 func notFound(w http.ResponseWriter, message string) {
 	writeJSON(w, http.StatusNotFound, envelope{Error: &apiError{Code: "NOT_FOUND", Message: message}})
@@ -7,6 +7,8 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+
+	"lumina/fraud-api/internal/auth"
 )
 
 // NewRouter creates and returns a configured Chi router.
@@ -25,35 +27,112 @@ func NewRouter(h *Handler) http.Handler {
 	})
 
 	// ── API v1 ────────────────────────────────────────────────────────────────
+	// Every route requires an API key; each group then checks its scope.
 	r.Route("/api/v1", func(r chi.Router) {
+		r.Use(authenticate(h.keys))
 
 		// Transactions — core requirement 1 & 2
 		r.Route("/transactions", func(r chi.Router) {
-			r.Post("/", h.SubmitTransaction)
-			r.Get("/{id}", h.GetTransaction)
+			r.With(requireScope(auth.ScopeTransactionsRead)).Get("/", h.ListTransactions)
+			r.With(requireScope(auth.ScopeTransactionsWrite)).Post("/", h.SubmitTransaction)
+			r.With(requireScope(auth.ScopeTransactionsWrite)).Post("/batch", h.SubmitTransactionBatch)
+			r.With(requireScope(auth.ScopeTransactionsRead)).Get("/{id}", h.GetTransaction)
+			r.With(requireScope(auth.ScopeTransactionsWrite)).Post("/{id}/outcome", h.RecordOutcome)
+			r.With(requireScope(auth.ScopeTransactionsRead)).Get("/{id}/outcomes", h.ListTransactionOutcomes)
+		})
+
+		// Read-only views: outcomes, entity summaries, reports
+		r.Group(func(r chi.Router) {
+			r.Use(requireScope(auth.ScopeTransactionsRead))
+
+			// Post-decision outcomes (chargebacks, confirmed fraud, false positives)
+			r.Get("/outcomes", h.ListOutcomes)
+
+			// Entity activity summaries — core requirement 3
+			r.Get("/entities/{type}/{value}", h.GetEntitySummary)
+
+			// Entities linked by shared transactions, for ring investigations
+			r.Get("/entities/{type}/{value}/graph", h.GetEntityGraph)
+
+			// Fraud pattern report — stretch goal 3
+			r.Get("/reports/fraud-patterns", h.GetFraudReport)
+
+			// Champion vs challenger comparison for shadow-mode rules
+			r.Get("/reports/shadow", h.GetShadowReport)
+
+			// Dry-run scoring: nothing is saved and no webhook fires
+			r.Post("/score/simulate", h.SimulateScore)
 		})
 
-		// Entity activity summaries — core requirement 3
-		r.Get("/entities/{type}/{value}", h.GetEntitySummary)
+		// Manual review queue
+		r.Route("/reviews", func(r chi.Router) {
+			r.Use(requireScope(auth.ScopeReviewsWrite))
+			r.Get("/", h.ListReviews)
+			r.Get("/sla", h.GetReviewSLA)
+			r.Post("/{id}/claim", h.ClaimReview)
+			r.Delete("/{id}/claim", h.ReleaseReview)
+			r.Post("/{id}/decision", h.DecideReview)
+		})
 
 		// Blocklist / Allowlist management — stretch goal 1
 		r.Route("/blocklist", func(r chi.Router) {
+			r.Use(requireScope(auth.ScopeListsAdmin))
 			r.Get("/", h.ListBlocklist)
 			r.Post("/", h.AddBlocklistEntry)
 			r.Delete("/{id}", h.DeleteBlocklistEntry)
 		})
 
-		// Fraud pattern report — stretch goal 3
-		r.Get("/reports/fraud-patterns", h.GetFraudReport)
-
 		// Webhook registration — stretch goal 4
 		r.Route("/webhooks", func(r chi.Router) {
+			r.Use(requireScope(auth.ScopeWebhooksAdmin))
 			r.Post("/", h.RegisterWebhook)
 			r.Delete("/{id}", h.DeleteWebhook)
 		})
 
-		// Admin / demo utilities
-		r.Post("/admin/seed", h.SeedData)
+		// Configuration, audit and admin utilities
+		r.Group(func(r chi.Router) {
+			r.Use(requireScope(auth.ScopeAdmin))
+
+			// Recommendation thresholds, per merchant / country / global
+			r.Route("/config/thresholds", func(r chi.Router) {
+				r.Get("/", h.GetThresholds)
+				r.Put("/", h.PutThresholds)
+				r.Delete("/", h.DeleteThresholds)
+				r.Get("/history", h.GetThresholdHistory)
+			})
+
+			// Audit log of every mutating operation
+			r.Get("/audit", h.GetAuditLog)
+			r.Get("/audit/verify", h.VerifyAuditLog)
+
+			// Admin / demo utilities
+			r.Post("/admin/seed", h.SeedData)
+
+			// API key management
+			r.Route("/admin/keys", func(r chi.Router) {
+				r.Get("/", h.ListKeys)
+				r.Post("/", h.CreateKey)
+				r.Post("/{id}/rotate", h.RotateKey)
+				r.Delete("/{id}", h.RevokeKey)
+			})
+
+			// FX rates used to normalise amounts into the reporting currency
+			r.Route("/admin/fx-rates", func(r chi.Router) {
+				r.Get("/", h.GetFXRates)
+				r.Put("/", h.ReplaceFXRates)
+				r.Post("/", h.ReplaceFXRates)
+			})
+
+			// Scoring rule management (hot reload)
+			r.Route("/admin/rules", func(r chi.Router) {
+				r.Get("/", h.GetRules)
+				r.Put("/", h.ReplaceRules)
+				r.Post("/reload", h.ReloadRules)
+				r.Get("/shadow", h.GetShadowRules)
+				r.Put("/shadow", h.ReplaceShadowRules)
+				r.Delete("/shadow", h.DeleteShadowRules)
+			})
+		})
 	})
 
 	return r
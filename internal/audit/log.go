@@ -0,0 +1,243 @@
+// Package audit keeps an append-only, hash-chained record of every mutating
+// operation performed through the API: who did it, what changed, and the
+// request it arrived on.
+//
+// Each entry stores the SHA-256 of its predecessor and of its own content, so
+// altering, removing or reordering any past entry breaks every hash after it.
+// VerifyChain detects this, whether run on the live log or on an export.
+//
+// Entries are kept in the store, so the chain is as durable as the store and
+// every process sharing it extends the same chain.
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"lumina/fraud-api/internal/domain"
+	"lumina/fraud-api/internal/store"
+)
+
+// maxAppendAttempts bounds how often Record rebuilds an entry after other
+// writers extended the chain first.
+const maxAppendAttempts = 16
+
+// GenesisHash is the PrevHash of the first entry in a chain.
+var GenesisHash = strings.Repeat("0", 64)
+
+// Entry is one audited operation. Entries are never modified once recorded.
+// Seq is the 1-based position in the chain; Actor is the authenticated
+// caller and OnBehalf who the caller says it acted for, which is not
+// verified.
+type Entry = domain.AuditEntry
+
+// Filter selects entries in Query. Zero fields match everything.
+type Filter struct {
+	Actor    string
+	OnBehalf string
+	Action   string // exact action, or a prefix such as "blocklist" for "blocklist.*"
+	Resource string
+	Since    time.Time // inclusive
+	Until    time.Time // exclusive
+	Limit    int       // 0 means no limit
+}
+
+// ChainError reports the first entry at which a chain fails verification.
+type ChainError struct {
+	Seq    int64
+	Reason string
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("audit chain broken at seq %d: %s", e.Seq, e.Reason)
+}
+
+// Log is an append-only audit log kept in a store. It is safe for concurrent
+// use, including by several Logs sharing the store.
+type Log struct {
+	store store.AuditRepository
+
+	// A copy of the stored chain, caught up with the store before each use.
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// New returns a Log that continues the chain already in s, if any. Run
+// Verify to check the loaded chain.
+func New(s store.AuditRepository) (*Log, error) {
+	l := &Log{store: s}
+	if err := l.catchUp(); err != nil {
+		return nil, fmt.Errorf("load audit log: %w", err)
+	}
+	return l, nil
+}
+
+// Record appends an entry. actor is the authenticated caller; onBehalf is an
+// optional label the caller supplied, such as the analyst using a shared key,
+// kept beside actor and never in its place. before and after are marshalled
+// to JSON; pass nil for a state that does not exist (nothing before a create,
+// nothing after a delete).
+func (l *Log) Record(actor, onBehalf, action, resource, requestID string, before, after any) (Entry, error) {
+	b, err := marshalState(before)
+	if err != nil {
+		return Entry{}, fmt.Errorf("audit %s: before state: %w", action, err)
+	}
+	a, err := marshalState(after)
+	if err != nil {
+		return Entry{}, fmt.Errorf("audit %s: after state: %w", action, err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for attempt := 0; attempt < maxAppendAttempts; attempt++ {
+		if err := l.catchUp(); err != nil {
+			return Entry{}, fmt.Errorf("audit %s: %w", action, err)
+		}
+		e := Entry{
+			Seq:       int64(len(l.entries)) + 1,
+			Time:      time.Now().UTC(),
+			Actor:     actor,
+			OnBehalf:  onBehalf,
+			Action:    action,
+			Resource:  resource,
+			RequestID: requestID,
+			Before:    b,
+			After:     a,
+			PrevHash:  GenesisHash,
+		}
+		if n := len(l.entries); n > 0 {
+			e.PrevHash = l.entries[n-1].Hash
+		}
+		e.Hash = hashEntry(e)
+		err := l.store.AppendAuditEntry(e)
+		if errors.Is(err, store.ErrAuditConflict) {
+			continue // another writer appended first; link after its entry
+		}
+		if err != nil {
+			return Entry{}, fmt.Errorf("audit %s: %w", action, err)
+		}
+		l.entries = append(l.entries, e)
+		return e, nil
+	}
+	return Entry{}, fmt.Errorf("audit %s: %d conflicting appends", action, maxAppendAttempts)
+}
+
+// catchUp appends the entries other writers stored since the last one seen.
+// Must be called with l.mu held.
+func (l *Log) catchUp() error {
+	newer, err := l.store.AuditEntriesAfter(int64(len(l.entries)))
+	if err != nil {
+		return err
+	}
+	l.entries = append(l.entries, newer...)
+	return nil
+}
+
+// snapshot catches up and returns the chain. If the store cannot be read it
+// logs the error and returns the entries seen so far.
+func (l *Log) snapshot() []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.catchUp(); err != nil {
+		slog.Error("audit log read failed; serving the entries seen so far", "error", err)
+	}
+	return l.entries[:len(l.entries):len(l.entries)]
+}
+
+// Query returns matching entries, newest first.
+func (l *Log) Query(f Filter) []Entry {
+	entries := l.snapshot()
+	result := []Entry{}
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if !f.matches(&e) {
+			continue
+		}
+		result = append(result, e)
+		if f.Limit > 0 && len(result) == f.Limit {
+			break
+		}
+	}
+	return result
+}
+
+// Entries returns a copy of the whole chain in append order.
+func (l *Log) Entries() []Entry {
+	return append([]Entry(nil), l.snapshot()...)
+}
+
+// Verify checks the live chain. See VerifyChain.
+func (l *Log) Verify() error {
+	return VerifyChain(l.Entries())
+}
+
+// VerifyChain checks that entries, in append order, form an unbroken chain
+// from GenesisHash. It returns a *ChainError for the first entry whose
+// sequence number, link or content hash does not match.
+func VerifyChain(entries []Entry) error {
+	prev := GenesisHash
+	for i, e := range entries {
+		switch {
+		case e.Seq != int64(i)+1:
+			return &ChainError{Seq: e.Seq, Reason: fmt.Sprintf("expected seq %d", i+1)}
+		case e.PrevHash != prev:
+			return &ChainError{Seq: e.Seq, Reason: "prev_hash does not match the preceding entry"}
+		case e.Hash != hashEntry(e):
+			return &ChainError{Seq: e.Seq, Reason: "content does not match its hash"}
+		}
+		prev = e.Hash
+	}
+	return nil
+}
+
+func (f *Filter) matches(e *Entry) bool {
+	if f.Actor != "" && e.Actor != f.Actor {
+		return false
+	}
+	if f.OnBehalf != "" && e.OnBehalf != f.OnBehalf {
+		return false
+	}
+	if f.Action != "" && e.Action != f.Action && !strings.HasPrefix(e.Action, f.Action+".") {
+		return false
+	}
+	if f.Resource != "" && e.Resource != f.Resource {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !e.Time.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// hashEntry is the SHA-256 of the entry's JSON encoding with Hash cleared.
+// The encoding is deterministic: fixed field order and compact raw states.
+func hashEntry(e Entry) string {
+	e.Hash = ""
+	data, _ := json.Marshal(e) // cannot fail: every field is JSON-safe
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// marshalState encodes a before/after state. nil, including a typed nil
+// pointer, is recorded as no state at all.
+func marshalState(v any) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil || string(data) == "null" {
+		return nil, err
+	}
+	return data, nil
+}
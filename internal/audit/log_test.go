@@ -0,0 +1,207 @@
+package audit_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"lumina/fraud-api/internal/audit"
+	"lumina/fraud-api/internal/store"
+)
+
+func newLog(t *testing.T, s store.AuditRepository) *audit.Log {
+	t.Helper()
+	l, err := audit.New(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func record(t *testing.T, l *audit.Log, actor, action, resource string, before, after any) audit.Entry {
+	t.Helper()
+	e, err := l.Record(actor, "", action, resource, "req-1", before, after)
+	if err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	return e
+}
+
+// ─── Recording & chaining ─────────────────────────────────────────────────────
+
+func TestRecord_ChainsEntries(t *testing.T) {
+	l := newLog(t, store.New())
+	first := record(t, l, "ana", "blocklist.add", "blocklist/1", nil, map[string]string{"value": "x"})
+	second := record(t, l, "ana", "blocklist.delete", "blocklist/1", map[string]string{"value": "x"}, nil)
+
+	if first.Seq != 1 || second.Seq != 2 {
+		t.Errorf("expected seq 1 and 2, got %d and %d", first.Seq, second.Seq)
+	}
+	if first.PrevHash != audit.GenesisHash {
+		t.Errorf("first entry must link to the genesis hash, got %s", first.PrevHash)
+	}
+	if second.PrevHash != first.Hash {
+		t.Error("second entry must link to the first entry's hash")
+	}
+	if first.Before != nil || second.After != nil {
+		t.Error("absent states must be omitted")
+	}
+	if err := l.Verify(); err != nil {
+		t.Errorf("untouched chain must verify: %v", err)
+	}
+}
+
+func TestRecord_TypedNilStateIsOmitted(t *testing.T) {
+	l := newLog(t, store.New())
+	var none *struct{ A int }
+	if e := record(t, l, "ana", "x.set", "x", none, nil); e.Before != nil {
+		t.Errorf("a nil pointer should record no state, got %s", e.Before)
+	}
+}
+
+// ─── Persistence & sharing ────────────────────────────────────────────────────
+
+func TestNew_ContinuesStoredChain(t *testing.T) {
+	s := store.New()
+	first := record(t, newLog(t, s), "ana", "blocklist.add", "blocklist/1", nil, 1)
+
+	restarted := newLog(t, s)
+	if err := restarted.Verify(); err != nil {
+		t.Fatalf("stored chain must verify after a restart: %v", err)
+	}
+	second := record(t, restarted, "ana", "blocklist.delete", "blocklist/1", 1, nil)
+	if second.Seq != 2 || second.PrevHash != first.Hash {
+		t.Errorf("expected seq 2 linked to the stored entry, got seq %d", second.Seq)
+	}
+}
+
+func TestRecord_LogsSharingAStore_KeepOneChain(t *testing.T) {
+	s := store.New()
+	a, b := newLog(t, s), newLog(t, s)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		l := a
+		if i%2 == 1 {
+			l = b
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := l.Record("ana", "", "x.set", fmt.Sprintf("x/%d", i), "", nil, i); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for name, l := range map[string]*audit.Log{"a": a, "b": b} {
+		entries := l.Entries()
+		if len(entries) != 50 {
+			t.Errorf("log %s sees %d entries, want 50", name, len(entries))
+		}
+		if err := audit.VerifyChain(entries); err != nil {
+			t.Errorf("log %s: interleaved appends must form one valid chain: %v", name, err)
+		}
+	}
+}
+
+// ─── Tamper detection ─────────────────────────────────────────────────────────
+
+func TestVerifyChain_DetectsTampering(t *testing.T) {
+	l := newLog(t, store.New())
+	for i := 0; i < 4; i++ {
+		record(t, l, "ana", "thresholds.set", fmt.Sprintf("thresholds/merchant/m%d", i), nil, map[string]int{"review": 70})
+	}
+
+	cases := map[string]func([]audit.Entry) []audit.Entry{
+		"edited state": func(es []audit.Entry) []audit.Entry {
+			es[1].After = json.RawMessage(`{"review":99}`)
+			return es
+		},
+		"edited actor": func(es []audit.Entry) []audit.Entry {
+			es[2].Actor = "mallory"
+			return es
+		},
+		"removed entry": func(es []audit.Entry) []audit.Entry {
+			return append(es[:1], es[2:]...)
+		},
+		"reordered": func(es []audit.Entry) []audit.Entry {
+			es[1], es[2] = es[2], es[1]
+			return es
+		},
+	}
+	for name, tamper := range cases {
+		var chainErr *audit.ChainError
+		if err := audit.VerifyChain(tamper(l.Entries())); !errors.As(err, &chainErr) {
+			t.Errorf("%s: expected a ChainError, got %v", name, err)
+		}
+	}
+	if err := l.Verify(); err != nil {
+		t.Errorf("tampering with a copy must not affect the log: %v", err)
+	}
+}
+
+func TestVerifyChain_SurvivesJSONExport(t *testing.T) {
+	l := newLog(t, store.New())
+	record(t, l, "ana", "rules.replace", "rules", map[string]string{"version": "a"}, map[string]string{"version": "b"})
+	record(t, l, "bruno", "webhook.register", "webhooks/1", nil, map[string]any{"url": "https://x", "threshold": 80})
+
+	data, err := json.MarshalIndent(l.Entries(), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exported []audit.Entry
+	if err := json.Unmarshal(data, &exported); err != nil {
+		t.Fatal(err)
+	}
+	if err := audit.VerifyChain(exported); err != nil {
+		t.Errorf("an exported chain must still verify: %v", err)
+	}
+}
+
+// ─── Querying ─────────────────────────────────────────────────────────────────
+
+func TestQuery_Filters(t *testing.T) {
+	l := newLog(t, store.New())
+	record(t, l, "ana", "blocklist.add", "blocklist/1", nil, 1)
+	record(t, l, "bruno", "blocklist.delete", "blocklist/1", 1, nil)
+	record(t, l, "ana", "webhook.register", "webhooks/1", nil, 1)
+	record(t, l, "ana", "blocklistx.add", "other", nil, 1)
+
+	if got := l.Query(audit.Filter{Actor: "ana"}); len(got) != 3 || got[0].Seq != 4 {
+		t.Errorf("expected ana's 3 entries newest first, got %d", len(got))
+	}
+	if got := l.Query(audit.Filter{Action: "blocklist"}); len(got) != 2 {
+		t.Errorf("prefix blocklist should match blocklist.* only, got %d", len(got))
+	}
+	if got := l.Query(audit.Filter{Resource: "blocklist/1", Limit: 1}); len(got) != 1 || got[0].Action != "blocklist.delete" {
+		t.Errorf("expected the latest blocklist/1 entry, got %v", got)
+	}
+	future := time.Now().Add(time.Hour)
+	if got := l.Query(audit.Filter{Since: future}); len(got) != 0 {
+		t.Errorf("since in the future should match nothing, got %d", len(got))
+	}
+	if got := l.Query(audit.Filter{Until: future}); len(got) != 4 {
+		t.Errorf("until in the future should match everything, got %d", len(got))
+	}
+}
+
+// ─── Concurrency (race detector) ─────────────────────────────────────────────
+
+func TestRecord_Concurrent_ChainStaysValid(t *testing.T) {
+	l := newLog(t, store.New())
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _ = l.Record("ana", "", "x.set", fmt.Sprintf("x/%d", i), "", nil, i)
+		}(i)
+	}
+	wg.Wait()
+	if err := l.Verify(); err != nil {
+		t.Errorf("concurrent appends must keep the chain valid: %v", err)
+	}
+}
@@ -0,0 +1,270 @@
+// Package auth issues and checks API keys.
+//
+// A key is a random bearer token shown to its owner exactly once, at creation
+// or rotation. Only its SHA-256 is kept, in the store, so a leaked store does
+// not leak usable credentials. Each key carries scopes that gate which route groups it
+// may call; the admin scope implies every other scope.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lumina/fraud-api/internal/domain"
+	"lumina/fraud-api/internal/store"
+)
+
+// Scopes a key can carry.
+const (
+	ScopeTransactionsWrite = "transactions:write" // submit transactions, record outcomes
+	ScopeTransactionsRead  = "transactions:read"  // transactions, entities, reports
+	ScopeReviewsWrite      = "reviews:write"      // work the manual review queue
+	ScopeListsAdmin        = "lists:admin"        // blocklist / allowlist
+	ScopeWebhooksAdmin     = "webhooks:admin"     // webhook registration
+	ScopeAdmin             = "admin"              // everything, including key management
+)
+
+// AllScopes lists every valid scope.
+var AllScopes = []string{
+	ScopeTransactionsWrite,
+	ScopeTransactionsRead,
+	ScopeReviewsWrite,
+	ScopeListsAdmin,
+	ScopeWebhooksAdmin,
+	ScopeAdmin,
+}
+
+// tokenPrefix marks a string as one of our keys, which helps secret scanners.
+const tokenPrefix = "lfk_"
+
+// Errors returned by the Keyring.
+var (
+	ErrInvalidKey   = errors.New("invalid or revoked API key")
+	ErrKeyNotFound  = store.ErrAPIKeyNotFound
+	ErrKeyRevoked   = store.ErrAPIKeyRevoked
+	ErrTokenTaken   = store.ErrAPIKeyExists // a key, possibly revoked, has the token
+	ErrInvalidScope = errors.New("invalid scope")
+)
+
+// Key describes an API key. The secret itself is never stored.
+type Key struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"` // first characters of the token, to recognise it
+	Scopes    []string   `json:"scopes"`
+	CreatedBy string     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	RotatedAt *time.Time `json:"rotated_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Allows reports whether the key grants scope.
+func (k *Key) Allows(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Keyring issues and checks keys kept in a store, so every replica sharing
+// the store accepts the same keys and they survive a restart. It is safe for
+// concurrent use.
+type Keyring struct {
+	store store.APIKeyRepository
+}
+
+// New creates a Keyring over s.
+func New(s store.APIKeyRepository) *Keyring {
+	return &Keyring{store: s}
+}
+
+// Create issues a new key and returns it with its token. The token cannot be
+// recovered later.
+func (k *Keyring) Create(name string, scopes []string, createdBy string) (Key, string, error) {
+	token, err := newToken()
+	if err != nil {
+		return Key{}, "", err
+	}
+	return k.Add(name, token, scopes, createdBy)
+}
+
+// Add registers a key with a caller-supplied token, e.g. a bootstrap admin
+// key from the environment. Returns ErrTokenTaken if the token belongs to
+// another key, even a revoked one.
+func (k *Keyring) Add(name, token string, scopes []string, createdBy string) (Key, string, error) {
+	scopes, err := normaliseScopes(scopes)
+	if err != nil {
+		return Key{}, "", err
+	}
+	if strings.TrimSpace(name) == "" {
+		return Key{}, "", errors.New("name is required")
+	}
+	if len(token) < 16 {
+		return Key{}, "", errors.New("token must be at least 16 characters")
+	}
+
+	key := &domain.APIKey{
+		ID:        uuid.NewString(),
+		Name:      name,
+		Prefix:    displayPrefix(token),
+		TokenHash: hashToken(token),
+		Scopes:    scopes,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := k.store.CreateAPIKey(key); err != nil {
+		return Key{}, "", err
+	}
+	return keyFrom(key), token, nil
+}
+
+// Rotate replaces a key's secret, keeping its ID, name and scopes. The old
+// token stops working immediately.
+func (k *Keyring) Rotate(id string) (Key, string, error) {
+	token, err := newToken()
+	if err != nil {
+		return Key{}, "", err
+	}
+	key, exists := k.store.GetAPIKey(id)
+	if !exists {
+		return Key{}, "", ErrKeyNotFound
+	}
+	now := time.Now().UTC()
+	rotated := *key
+	rotated.TokenHash = hashToken(token)
+	rotated.Prefix = displayPrefix(token)
+	rotated.RotatedAt = &now
+	if err := k.store.UpdateAPIKey(&rotated); err != nil {
+		return Key{}, "", err
+	}
+	return keyFrom(&rotated), token, nil
+}
+
+// Revoke disables a key permanently. It stays listed for the record.
+func (k *Keyring) Revoke(id string) (Key, error) {
+	key, exists := k.store.GetAPIKey(id)
+	if !exists {
+		return Key{}, ErrKeyNotFound
+	}
+	now := time.Now().UTC()
+	revoked := *key
+	revoked.RevokedAt = &now
+	if err := k.store.UpdateAPIKey(&revoked); err != nil {
+		return Key{}, err
+	}
+	return keyFrom(&revoked), nil
+}
+
+// Get returns a key by ID.
+func (k *Keyring) Get(id string) (Key, bool) {
+	key, exists := k.store.GetAPIKey(id)
+	if !exists {
+		return Key{}, false
+	}
+	return keyFrom(key), true
+}
+
+// List returns every key, revoked ones included, oldest first.
+func (k *Keyring) List() []Key {
+	stored := k.store.ListAPIKeys()
+	result := make([]Key, 0, len(stored))
+	for _, key := range stored {
+		result = append(result, keyFrom(key))
+	}
+	return result
+}
+
+// Authenticate resolves a bearer token to its key.
+func (k *Keyring) Authenticate(token string) (Key, error) {
+	if token == "" {
+		return Key{}, ErrInvalidKey
+	}
+	key, exists := k.store.GetAPIKeyByHash(hashToken(token))
+	if !exists || key.RevokedAt != nil {
+		return Key{}, ErrInvalidKey
+	}
+	return keyFrom(key), nil
+}
+
+// ─── Request context ──────────────────────────────────────────────────────────
+
+type contextKey struct{}
+
+// WithKey returns a copy of ctx carrying the authenticated key.
+func WithKey(ctx context.Context, key Key) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+// FromContext returns the key that authenticated the request, if any.
+func FromContext(ctx context.Context) (Key, bool) {
+	key, ok := ctx.Value(contextKey{}).(Key)
+	return key, ok
+}
+
+// ─── Helpers ──────────────────────────────────────────────────────────────────
+
+// keyFrom is the public view of a stored key, without its token hash.
+func keyFrom(k *domain.APIKey) Key {
+	return Key{
+		ID:        k.ID,
+		Name:      k.Name,
+		Prefix:    k.Prefix,
+		Scopes:    append([]string(nil), k.Scopes...),
+		CreatedBy: k.CreatedBy,
+		CreatedAt: k.CreatedAt,
+		RotatedAt: k.RotatedAt,
+		RevokedAt: k.RevokedAt,
+	}
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate API key: %w", err)
+	}
+	return tokenPrefix + hex.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func displayPrefix(token string) string {
+	return token[:min(len(token), len(tokenPrefix)+6)]
+}
+
+// normaliseScopes validates, de-duplicates and sorts scopes.
+func normaliseScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidScope)
+	}
+	valid := make(map[string]bool, len(AllScopes))
+	for _, s := range AllScopes {
+		valid[s] = true
+	}
+	seen := make(map[string]bool, len(scopes))
+	var out []string
+	for _, s := range scopes {
+		if !valid[s] {
+			return nil, fmt.Errorf("%w %q: must be one of: %s", ErrInvalidScope, s, strings.Join(AllScopes, ", "))
+		}
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
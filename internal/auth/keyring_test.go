@@ -0,0 +1,118 @@
+package auth_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"lumina/fraud-api/internal/auth"
+	"lumina/fraud-api/internal/store"
+)
+
+func TestCreate_AuthenticatesByTokenOnly(t *testing.T) {
+	k := auth.New(store.New())
+	key, token, err := k.Create("checkout", []string{auth.ScopeTransactionsWrite, auth.ScopeTransactionsWrite}, "ops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(token, key.Prefix) || len(key.Scopes) != 1 {
+		t.Errorf("unexpected key: %+v", key)
+	}
+
+	got, err := k.Authenticate(token)
+	if err != nil || got.ID != key.ID {
+		t.Fatalf("Authenticate = %+v, %v", got, err)
+	}
+	if _, err := k.Authenticate(token + "x"); !errors.Is(err, auth.ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey for a wrong token, got %v", err)
+	}
+}
+
+func TestCreate_RejectsUnknownScopes(t *testing.T) {
+	k := auth.New(store.New())
+	if _, _, err := k.Create("x", []string{"root"}, "ops"); !errors.Is(err, auth.ErrInvalidScope) {
+		t.Errorf("expected ErrInvalidScope, got %v", err)
+	}
+	if _, _, err := k.Create("x", nil, "ops"); !errors.Is(err, auth.ErrInvalidScope) {
+		t.Errorf("expected ErrInvalidScope with no scopes, got %v", err)
+	}
+}
+
+func TestAllows_AdminImpliesEveryScope(t *testing.T) {
+	admin := auth.Key{Scopes: []string{auth.ScopeAdmin}}
+	reader := auth.Key{Scopes: []string{auth.ScopeTransactionsRead}}
+	for _, s := range auth.AllScopes {
+		if !admin.Allows(s) {
+			t.Errorf("admin should allow %s", s)
+		}
+	}
+	if reader.Allows(auth.ScopeTransactionsWrite) || !reader.Allows(auth.ScopeTransactionsRead) {
+		t.Error("reader scopes not enforced")
+	}
+}
+
+func TestRotateAndRevoke(t *testing.T) {
+	k := auth.New(store.New())
+	key, old, _ := k.Create("bot", []string{auth.ScopeListsAdmin}, "ops")
+
+	rotated, fresh, err := k.Rotate(key.ID)
+	if err != nil || rotated.RotatedAt == nil {
+		t.Fatalf("Rotate = %+v, %v", rotated, err)
+	}
+	if _, err := k.Authenticate(old); err == nil {
+		t.Error("old token still authenticates after rotation")
+	}
+	if _, err := k.Authenticate(fresh); err != nil {
+		t.Errorf("new token rejected: %v", err)
+	}
+
+	if _, err := k.Revoke(key.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.Authenticate(fresh); err == nil {
+		t.Error("revoked key still authenticates")
+	}
+	if _, _, err := k.Rotate(key.ID); !errors.Is(err, auth.ErrKeyRevoked) {
+		t.Errorf("expected ErrKeyRevoked, got %v", err)
+	}
+	if _, err := k.Revoke("missing"); !errors.Is(err, auth.ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound, got %v", err)
+	}
+	if list := k.List(); len(list) != 1 || list[0].RevokedAt == nil {
+		t.Errorf("expected the revoked key to stay listed, got %+v", list)
+	}
+}
+
+func TestAdd_RejectsDuplicateAndShortTokens(t *testing.T) {
+	k := auth.New(store.New())
+	if _, _, err := k.Add("a", "short", []string{auth.ScopeAdmin}, "env"); err == nil {
+		t.Error("expected a short token to be rejected")
+	}
+	if _, _, err := k.Add("a", "lfk_0123456789abcdef", []string{auth.ScopeAdmin}, "env"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := k.Add("b", "lfk_0123456789abcdef", []string{auth.ScopeAdmin}, "env"); err == nil {
+		t.Error("expected a duplicate token to be rejected")
+	}
+}
+
+func TestKeyrings_ShareKeysThroughTheStore(t *testing.T) {
+	s := store.New()
+	a, b := auth.New(s), auth.New(s)
+	key, token, err := a.Create("checkout", []string{auth.ScopeTransactionsWrite}, "ops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := b.Authenticate(token); err != nil || got.ID != key.ID {
+		t.Fatalf("expected a key issued by one replica to work on another, got %+v, %v", got, err)
+	}
+	if _, err := b.Revoke(key.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Authenticate(token); !errors.Is(err, auth.ErrInvalidKey) {
+		t.Errorf("expected a revocation on one replica to apply on another, got %v", err)
+	}
+	if _, _, err := a.Add("again", token, []string{auth.ScopeAdmin}, "env"); !errors.Is(err, auth.ErrTokenTaken) {
+		t.Errorf("expected a revoked token to stay unusable, got %v", err)
+	}
+}
@@ -0,0 +1,211 @@
+// Package backtest replays historical transactions through a fresh store and
+// scoring engine so a candidate rule set can be measured before it ships.
+//
+// Replay mirrors how the server loads seed data: transactions are sorted by
+// timestamp and assessed one at a time, each saved before the next is scored,
+// so velocity and history-based rules see exactly the context they would have
+// seen in real time.
+package backtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"lumina/fraud-api/internal/domain"
+	"lumina/fraud-api/internal/fx"
+	"lumina/fraud-api/internal/scoring"
+	"lumina/fraud-api/internal/store"
+)
+
+// BucketWidth is the width of each score histogram bucket.
+const BucketWidth = 10
+
+// Record is one transaction in a dump. It decodes both the seed format (a
+// plain TransactionRequest) and a store export (a scored Transaction, whose
+// scoring fields are ignored). The ground-truth label is IsFraud when set,
+// otherwise whatever the recorded outcomes imply.
+type Record struct {
+	domain.TransactionRequest
+	IsFraud  *bool            `json:"is_fraud,omitempty"`
+	Outcomes []domain.Outcome `json:"outcomes,omitempty"`
+}
+
+// label returns the record's ground truth, if it has one.
+func (r *Record) label() (fraud, known bool) {
+	if r.IsFraud != nil {
+		return *r.IsFraud, true
+	}
+	return domain.FraudLabel(r.Outcomes)
+}
+
+// Result summarises one replay.
+type Result struct {
+	RuleSetVersion string         `json:"ruleset_version"`
+	RuleSetHash    string         `json:"ruleset_hash"`
+	Transactions   int            `json:"transactions"` // scored and saved
+	Rejected       int            `json:"rejected"`     // unknown currency or duplicate ID
+	Decisions      map[string]int `json:"decisions"`    // approve / review / decline → count
+	RuleFires      []RuleFire     `json:"rule_fires"`
+	Histogram      []Bucket       `json:"histogram"`
+	Labelled       int            `json:"labelled"`          // transactions with is_fraud or a labelling outcome
+	Metrics        []Metric       `json:"metrics,omitempty"` // only when Labelled > 0
+}
+
+// RuleFire counts the transactions on which a risk factor fired at least once.
+type RuleFire struct {
+	Rule  string  `json:"rule"`
+	Count int     `json:"count"`
+	Rate  float64 `json:"rate"` // Count / Result.Transactions
+}
+
+// Bucket is one score histogram bin covering [From, To].
+type Bucket struct {
+	From  int `json:"from"`
+	To    int `json:"to"`
+	Count int `json:"count"`
+}
+
+// Metric is precision and recall against the labels when Flagged decisions
+// are treated as predicting fraud.
+type Metric struct {
+	Flagged        []string `json:"flagged"`
+	TruePositives  int      `json:"true_positives"`
+	FalsePositives int      `json:"false_positives"`
+	FalseNegatives int      `json:"false_negatives"`
+	Precision      float64  `json:"precision"`
+	Recall         float64  `json:"recall"`
+}
+
+// Load reads a JSON array of records from path.
+func Load(path string) ([]Record, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var records []Record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("transaction dump %s: parse error: %w", path, err)
+	}
+	return records, nil
+}
+
+// Run replays records chronologically against rules and summarises the
+// decisions. rates may be nil to score raw amounts. records is not modified.
+func Run(records []Record, rules *scoring.RuleSet, rates *fx.Table) Result {
+	sorted := make([]Record, len(records))
+	copy(sorted, records)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+
+	s := store.New()
+	e := scoring.New(s, rules, rates)
+	info := e.ActiveRules()
+
+	res := Result{
+		RuleSetVersion: info.Version,
+		RuleSetHash:    info.Hash,
+		Decisions: map[string]int{
+			domain.ActionApprove: 0,
+			domain.ActionReview:  0,
+			domain.ActionDecline: 0,
+		},
+		Histogram: make([]Bucket, 100/BucketWidth),
+	}
+	for i := range res.Histogram {
+		res.Histogram[i] = Bucket{From: i * BucketWidth, To: (i+1)*BucketWidth - 1}
+	}
+	// The top bucket also holds the maximum score of 100.
+	res.Histogram[len(res.Histogram)-1].To = 100
+
+	fires := make(map[string]int)
+	var scored []scoredRecord
+
+	for i := range sorted {
+		req := sorted[i].TransactionRequest
+		tx, err := e.Assess(&req)
+		if err != nil {
+			res.Rejected++
+			continue
+		}
+		if err := s.SaveTransaction(tx); err != nil {
+			res.Rejected++
+			continue
+		}
+		res.Transactions++
+		res.Decisions[tx.Recommendation]++
+		res.Histogram[min(tx.RiskScore/BucketWidth, len(res.Histogram)-1)].Count++
+
+		seen := make(map[string]bool, len(tx.Factors))
+		for _, f := range tx.Factors {
+			if !seen[f.Name] {
+				seen[f.Name] = true
+				fires[f.Name]++
+			}
+		}
+		if fraud, known := sorted[i].label(); known {
+			res.Labelled++
+			scored = append(scored, scoredRecord{decision: tx.Recommendation, fraud: fraud})
+		}
+	}
+
+	res.RuleFires = make([]RuleFire, 0, len(fires))
+	for name, n := range fires {
+		res.RuleFires = append(res.RuleFires, RuleFire{
+			Rule:  name,
+			Count: n,
+			Rate:  float64(n) / float64(res.Transactions),
+		})
+	}
+	sort.Slice(res.RuleFires, func(i, j int) bool {
+		if res.RuleFires[i].Count != res.RuleFires[j].Count {
+			return res.RuleFires[i].Count > res.RuleFires[j].Count
+		}
+		return res.RuleFires[i].Rule < res.RuleFires[j].Rule
+	})
+
+	if res.Labelled > 0 {
+		res.Metrics = []Metric{
+			measure(scored, domain.ActionDecline),
+			measure(scored, domain.ActionReview, domain.ActionDecline),
+		}
+	}
+	return res
+}
+
+type scoredRecord struct {
+	decision string
+	fraud    bool
+}
+
+// measure computes precision and recall treating any of the flagged decisions
+// as a fraud prediction.
+func measure(scored []scoredRecord, flagged ...string) Metric {
+	m := Metric{Flagged: flagged}
+	for _, r := range scored {
+		predicted := false
+		for _, f := range flagged {
+			if r.decision == f {
+				predicted = true
+				break
+			}
+		}
+		switch {
+		case predicted && r.fraud:
+			m.TruePositives++
+		case predicted && !r.fraud:
+			m.FalsePositives++
+		case !predicted && r.fraud:
+			m.FalseNegatives++
+		}
+	}
+	if p := m.TruePositives + m.FalsePositives; p > 0 {
+		m.Precision = float64(m.TruePositives) / float64(p)
+	}
+	if a := m.TruePositives + m.FalseNegatives; a > 0 {
+		m.Recall = float64(m.TruePositives) / float64(a)
+	}
+	return m
+}
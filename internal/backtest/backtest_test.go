@@ -0,0 +1,145 @@
+package backtest_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"lumina/fraud-api/internal/backtest"
+	"lumina/fraud-api/internal/domain"
+	"lumina/fraud-api/internal/scoring"
+)
+
+func record(id string, at time.Time, fraud *bool) backtest.Record {
+	return backtest.Record{
+		TransactionRequest: domain.TransactionRequest{
+			TransactionID:     id,
+			Timestamp:         at,
+			Amount:            50,
+			Currency:          "BRL",
+			UserEmail:         "bt@example.com",
+			IPAddress:         "177.1.1.1",
+			IPCountry:         "BR",
+			CardBIN:           "453211",
+			CardCountry:       "BR",
+			DeviceFingerprint: "dev-bt",
+			AccountCreatedAt:  at.Add(-365 * 24 * time.Hour),
+			MerchantCountry:   "BR",
+		},
+		IsFraud: fraud,
+	}
+}
+
+func TestRun_ReplaysChronologically(t *testing.T) {
+	yes, no := true, false
+	base := time.Date(2026, 2, 25, 14, 0, 0, 0, time.UTC)
+
+	// Declines a user's first transaction and nothing after it.
+	rs := scoring.DefaultRuleSet()
+	rs.Rules.PurchaseBehaviour.FirstTransaction.Delta = 100
+	rs.Rules.PurchaseBehaviour.FirstTransaction.HighDelta = 100
+
+	// Listed newest first: only a chronological replay treats bt-1 as the
+	// user's first transaction.
+	records := []backtest.Record{
+		record("bt-3", base.Add(2*time.Minute), &no),
+		record("bt-2", base.Add(time.Minute), &no),
+		record("bt-1", base, &yes),
+	}
+
+	res := backtest.Run(records, rs, nil)
+
+	if res.Transactions != 3 || res.Rejected != 0 {
+		t.Fatalf("expected 3 scored and 0 rejected, got %d/%d", res.Transactions, res.Rejected)
+	}
+	if res.Decisions["decline"] != 1 {
+		t.Fatalf("expected exactly one decline, got %v", res.Decisions)
+	}
+	if m := res.Metrics[0]; m.TruePositives != 1 || m.FalsePositives != 0 {
+		t.Errorf("the earliest transaction should be the one declined, got %+v", m)
+	}
+	if records[0].TransactionID != "bt-3" {
+		t.Error("Run must not reorder the caller's records")
+	}
+}
+
+func TestRun_DistributionsAddUp(t *testing.T) {
+	base := time.Date(2026, 2, 25, 14, 0, 0, 0, time.UTC)
+	var records []backtest.Record
+	for i := 0; i < 10; i++ {
+		r := record(fmt.Sprintf("bt-dist-%d", i), base.Add(time.Duration(i)*time.Minute), nil)
+		r.UserEmail = fmt.Sprintf("u%d@example.com", i)
+		records = append(records, r)
+	}
+	records = append(records, records[0]) // duplicate ID is rejected
+
+	res := backtest.Run(records, scoring.DefaultRuleSet(), nil)
+
+	if res.Rejected != 1 {
+		t.Errorf("expected the duplicate to be rejected, got %d", res.Rejected)
+	}
+	var decided, histogram int
+	for _, n := range res.Decisions {
+		decided += n
+	}
+	for _, b := range res.Histogram {
+		histogram += b.Count
+	}
+	if decided != res.Transactions || histogram != res.Transactions {
+		t.Errorf("decisions (%d) and histogram (%d) must both total %d", decided, histogram, res.Transactions)
+	}
+	if last := res.Histogram[len(res.Histogram)-1]; last.To != 100 {
+		t.Errorf("top bucket must include 100, got %d–%d", last.From, last.To)
+	}
+	if res.Metrics != nil {
+		t.Error("metrics need labels")
+	}
+}
+
+func TestRun_PrecisionRecall(t *testing.T) {
+	yes, no := true, false
+	base := time.Date(2026, 2, 25, 14, 0, 0, 0, time.UTC)
+
+	// A candidate that declines every first transaction and nothing else.
+	rs := scoring.DefaultRuleSet()
+	rs.Rules.PurchaseBehaviour.FirstTransaction.Delta = 100
+	rs.Rules.PurchaseBehaviour.FirstTransaction.HighDelta = 100
+	rs.Rules.EmailVelocity.Enabled = false
+
+	fraudFirst := record("pr-1", base, &yes) // declined, fraud → TP
+	legitFirst := record("pr-2", base, &no)  // declined, legit → FP
+	legitFirst.UserEmail = "other@example.com"
+	fraudRepeat := record("pr-3", base.Add(time.Minute), &yes) // approved, fraud → FN
+
+	res := backtest.Run([]backtest.Record{fraudFirst, legitFirst, fraudRepeat}, rs, nil)
+
+	if res.Labelled != 3 || len(res.Metrics) == 0 {
+		t.Fatalf("expected metrics over 3 labelled records, got %d / %v", res.Labelled, res.Metrics)
+	}
+	m := res.Metrics[0]
+	if m.TruePositives != 1 || m.FalsePositives != 1 || m.FalseNegatives != 1 {
+		t.Errorf("expected TP=1 FP=1 FN=1, got %+v", m)
+	}
+	if m.Precision != 0.5 || m.Recall != 0.5 {
+		t.Errorf("expected precision and recall 0.5, got %.2f / %.2f", m.Precision, m.Recall)
+	}
+}
+
+func TestRun_LabelsFromOutcomes(t *testing.T) {
+	base := time.Date(2026, 2, 25, 14, 0, 0, 0, time.UTC)
+	charged := record("lbl-1", base, nil)
+	charged.Outcomes = []domain.Outcome{{Type: domain.OutcomeChargeback}}
+	cleared := record("lbl-2", base.Add(time.Minute), nil)
+	cleared.Outcomes = []domain.Outcome{{Type: domain.OutcomeConfirmedFraud}, {Type: domain.OutcomeFalsePositive}}
+	refunded := record("lbl-3", base.Add(2*time.Minute), nil)
+	refunded.Outcomes = []domain.Outcome{{Type: domain.OutcomeRefund}}
+
+	res := backtest.Run([]backtest.Record{charged, cleared, refunded}, scoring.DefaultRuleSet(), nil)
+
+	if res.Labelled != 2 {
+		t.Fatalf("expected the chargeback and false positive to label 2 records, got %d", res.Labelled)
+	}
+	if m := res.Metrics[0]; m.TruePositives+m.FalseNegatives != 1 {
+		t.Errorf("expected exactly one fraud label, got %+v", m)
+	}
+}
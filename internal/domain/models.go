@@ -2,7 +2,10 @@
 // Keeping domain types in one place makes the fraud scoring rules easy to reason about.
 package domain
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // ─── Constants ───────────────────────────────────────────────────────────────
 
@@ -42,16 +45,82 @@ const (
 	ListAllow = "allow"
 )
 
+// Final status of a transaction: the recommendation for automatic decisions,
+// or the analyst's decision once a manual review closes.
+const (
+	StatusApproved      = "approved"
+	StatusDeclined      = "declined"
+	StatusPendingReview = "pending_review" // waiting in the review queue
+)
+
+// Outcome types recorded against a transaction after its decision.
+const (
+	OutcomeChargeback     = "chargeback"      // cardholder disputed the payment
+	OutcomeConfirmedFraud = "confirmed_fraud" // investigation confirmed fraud
+	OutcomeFalsePositive  = "false_positive"  // flagged, but the customer was legitimate
+	OutcomeRefund         = "refund"          // refunded by the merchant; not a fraud signal
+)
+
 // ─── Scoring thresholds ───────────────────────────────────────────────────────
 
-// Score thresholds for recommendation decisions.
-// Lumina can override these via API config in a future iteration.
+// Default score thresholds for recommendation decisions.
+// They can be overridden globally, per merchant country or per merchant ID
+// through /api/v1/config/thresholds.
 const (
 	ThresholdApprove = 30 // <= 30  → approve
 	ThresholdReview  = 70 // 31-70  → review
 	// > 70 → decline
 )
 
+// Threshold override scopes, from most to least specific.
+const (
+	ScopeMerchant = "merchant" // keyed by TransactionRequest.MerchantID
+	ScopeCountry  = "country"  // keyed by TransactionRequest.MerchantCountry
+	ScopeGlobal   = "global"   // replaces the built-in defaults
+	ScopeDefault  = "default"  // built-in constants; not an override
+)
+
+// Thresholds are the score cut-offs that turn a risk score into a recommendation.
+type Thresholds struct {
+	Approve int `json:"approve"` // score <= Approve → approve
+	Review  int `json:"review"`  // score <= Review  → review; above → decline
+}
+
+// DefaultThresholds are the built-in cut-offs used when no override applies.
+var DefaultThresholds = Thresholds{Approve: ThresholdApprove, Review: ThresholdReview}
+
+// ThresholdConfig is a threshold override for one scope.
+type ThresholdConfig struct {
+	Scope string `json:"scope"`         // merchant | country | global
+	Key   string `json:"key,omitempty"` // merchant ID or country code; empty for global
+	Thresholds
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ThresholdChange is the audit record written for every threshold change.
+type ThresholdChange struct {
+	ID        string      `json:"id"`
+	Scope     string      `json:"scope"`
+	Key       string      `json:"key,omitempty"`
+	Before    *Thresholds `json:"before"` // nil when the override was created
+	After     *Thresholds `json:"after"`  // nil when the override was removed
+	Actor     string      `json:"actor"`
+	ChangedAt time.Time   `json:"changed_at"`
+}
+
+// ─── Runtime settings ─────────────────────────────────────────────────────────
+
+// Setting is a configuration document changed at runtime through the admin
+// API, such as the active rule set. It is kept in the store so that every
+// replica applies it and it outlives a restart. The store treats Document as
+// opaque.
+type Setting struct {
+	Name      string          `json:"name"`
+	Document  json.RawMessage `json:"document"` // "null" once the setting is cleared
+	Revision  int64           `json:"revision"` // changes on every write
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
 // ─── Core domain types ────────────────────────────────────────────────────────
 
 // TransactionRequest is the payload submitted by Lumina's payment flow.
@@ -69,6 +138,7 @@ type TransactionRequest struct {
 	DeviceFingerprint string    `json:"device_fingerprint"`   // opaque client-side hash
 	AccountCreatedAt  time.Time `json:"account_created_at"`
 	MerchantCountry   string    `json:"merchant_country"`     // Lumina entity country
+	MerchantID        string    `json:"merchant_id,omitempty"` // optional; selects per-merchant thresholds
 }
 
 // RiskFactor is a single fraud signal that contributed to the score.
@@ -89,7 +159,166 @@ type Transaction struct {
 	Recommendation string       `json:"recommendation"`  // approve / review / decline
 	Factors        []RiskFactor `json:"factors"`
 	Explanation    string       `json:"explanation"` // single human-readable summary
-	ProcessedAt    time.Time    `json:"processed_at"`
+	RuleSetVersion string       `json:"ruleset_version,omitempty"` // rule file version that produced RiskScore
+	RuleSetHash    string       `json:"ruleset_hash,omitempty"`    // SHA-256 of that rule set's content
+	Thresholds     Thresholds   `json:"thresholds"`                // cut-offs that produced Recommendation
+	ThresholdScope string       `json:"threshold_scope"`           // which override supplied them, e.g. "country:BR"
+
+	// Amount converted into the reporting currency with the FX rate in force
+	// on Timestamp. Amount-based rules and reports use this value.
+	NormalizedAmount  float64 `json:"normalized_amount"`
+	ReportingCurrency string  `json:"reporting_currency,omitempty"` // empty when no FX table is configured
+	FXRate            float64 `json:"fx_rate,omitempty"`            // units of Currency per reporting unit
+
+	// Decision a challenger rule set would have made, when shadow mode is on.
+	// It never influences Recommendation or webhook delivery.
+	Shadow *ShadowResult `json:"shadow,omitempty"`
+
+	// Where the transaction stands now. Starts from Recommendation and is
+	// changed by a manual review decision; see Review.
+	FinalStatus string          `json:"final_status"`
+	Review      *ReviewDecision `json:"review,omitempty"`
+
+	// What happened after the decision, oldest first. Appended through
+	// POST /api/v1/transactions/{id}/outcome.
+	Outcomes []Outcome `json:"outcomes,omitempty"`
+
+	ProcessedAt time.Time `json:"processed_at"`
+}
+
+// ReviewDecision is an analyst's verdict on a transaction from the review queue.
+type ReviewDecision struct {
+	Decision          string    `json:"decision"` // approve | decline
+	Analyst           string    `json:"analyst"`
+	Notes             string    `json:"notes,omitempty"`
+	BlocklistEntryIDs []string  `json:"blocklist_entry_ids,omitempty"` // entries added alongside the decision
+	DecidedAt         time.Time `json:"decided_at"`
+}
+
+// ReviewClaim marks a queued transaction as being worked by one analyst.
+// Claims lapse at ExpiresAt so an abandoned case returns to the pool.
+type ReviewClaim struct {
+	TransactionID string    `json:"transaction_id"`
+	Analyst       string    `json:"analyst"`
+	ClaimedAt     time.Time `json:"claimed_at"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// ReviewCase is a transaction waiting in the manual review queue.
+type ReviewCase struct {
+	TransactionID string       `json:"transaction_id"`
+	RiskScore     int          `json:"risk_score"`
+	Amount        float64      `json:"amount"` // in ReportingCurrency
+	QueuedAt      time.Time    `json:"queued_at"`
+	AgeSeconds    int64        `json:"age_seconds"`
+	SLADueAt      time.Time    `json:"sla_due_at"`
+	Breached      bool         `json:"sla_breached"`
+	Claim         *ReviewClaim `json:"claim,omitempty"` // nil while unclaimed
+	Transaction   Transaction  `json:"transaction"`
+}
+
+// ReviewSLA summarises how long cases have been waiting in the queue.
+type ReviewSLA struct {
+	Target           string      `json:"target"` // e.g. "4h"
+	Pending          int         `json:"pending"`
+	Claimed          int         `json:"claimed"`
+	Breached         int         `json:"breached"`
+	OldestAgeSeconds int64       `json:"oldest_age_seconds"`
+	Ageing           []AgeBucket `json:"ageing"`
+}
+
+// AgeBucket counts queued cases whose age falls in [From, To).
+type AgeBucket struct {
+	Label string `json:"label"` // e.g. "1h-4h"
+	Count int    `json:"count"`
+}
+
+// Outcome records what actually happened to a transaction after it was
+// scored, tying the decision back to reality.
+type Outcome struct {
+	ID            string    `json:"id"`
+	TransactionID string    `json:"transaction_id"`
+	Type          string    `json:"type"`                  // chargeback | confirmed_fraud | false_positive | refund
+	Source        string    `json:"source"`                // who reported it, e.g. "acquirer", "analyst"
+	ReasonCode    string    `json:"reason_code,omitempty"` // e.g. a card network chargeback reason code
+	Amount        float64   `json:"amount,omitempty"`      // disputed or refunded amount in Currency; 0 means the full amount
+	Note          string    `json:"note,omitempty"`
+	OccurredAt    time.Time `json:"occurred_at"` // when it happened at the source
+	RecordedAt    time.Time `json:"recorded_at"` // when it was received by this service
+}
+
+// FraudLabel derives a ground-truth label from a transaction's outcomes. A
+// chargeback or confirmed fraud marks it fraudulent; a later false positive
+// overrides that. Refunds say nothing either way. known is false when no
+// outcome carries a label.
+func FraudLabel(outcomes []Outcome) (fraud, known bool) {
+	for _, o := range outcomes {
+		switch o.Type {
+		case OutcomeChargeback, OutcomeConfirmedFraud:
+			fraud, known = true, true
+		case OutcomeFalsePositive:
+			fraud, known = false, true
+		}
+	}
+	return fraud, known
+}
+
+// HasOutcome reports whether an outcome of the given type was recorded.
+func (t *Transaction) HasOutcome(outcomeType string) bool {
+	for _, o := range t.Outcomes {
+		if o.Type == outcomeType {
+			return true
+		}
+	}
+	return false
+}
+
+// ShadowResult is the outcome of scoring a transaction with the challenger
+// rule set. It is recorded for comparison only.
+type ShadowResult struct {
+	RuleSetVersion string       `json:"ruleset_version"`
+	RuleSetHash    string       `json:"ruleset_hash"`
+	RiskScore      int          `json:"risk_score"`
+	RiskLevel      string       `json:"risk_level"`
+	Recommendation string       `json:"recommendation"`
+	Factors        []RiskFactor `json:"factors"`
+}
+
+// ReportingAmount returns the amount in the reporting currency. Transactions
+// recorded without FX normalisation fall back to the raw amount.
+func (t *Transaction) ReportingAmount() float64 {
+	if t.ReportingCurrency == "" {
+		return t.Amount
+	}
+	return t.NormalizedAmount
+}
+
+// Entities returns the email, IP, device and card BIN the transaction was
+// made with, skipping any that are empty.
+func (t *TransactionRequest) Entities() []EntityNode {
+	var nodes []EntityNode
+	for _, n := range []EntityNode{
+		{EntityEmail, t.UserEmail},
+		{EntityIP, t.IPAddress},
+		{EntityDevice, t.DeviceFingerprint},
+		{EntityBIN, t.CardBIN},
+	} {
+		if n.Value != "" {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+// IdempotencyKey binds a client's Idempotency-Key to the transaction its
+// first submission created, so a retry is answered with the stored result.
+// Keys lapse at ExpiresAt and can then be reused.
+type IdempotencyKey struct {
+	Key           string    `json:"key"`
+	TransactionID string    `json:"transaction_id"`
+	Fingerprint   string    `json:"fingerprint"` // SHA-256 of the request payload
+	CreatedAt     time.Time `json:"created_at"`
+	ExpiresAt     time.Time `json:"expires_at"`
 }
 
 // ─── Blocklist / Allowlist ────────────────────────────────────────────────────
@@ -107,6 +336,47 @@ type BlocklistEntry struct {
 	ExpiresAt *time.Time `json:"expires_at,omitempty"` // nil = permanent
 }
 
+// ─── Entity graph ─────────────────────────────────────────────────────────────
+
+// EntityNode identifies one entity in the link graph.
+type EntityNode struct {
+	Type  string `json:"type"` // email | ip | bin | device
+	Value string `json:"value"`
+}
+
+// EntityLink joins an entity to another that appeared in the same
+// transactions, e.g. an email to the devices it was used from.
+type EntityLink struct {
+	Node      EntityNode `json:"node"`   // the linked entity
+	Weight    int        `json:"weight"` // transactions that carried both
+	FirstSeen time.Time  `json:"first_seen"`
+	LastSeen  time.Time  `json:"last_seen"`
+}
+
+// EntityGraph is the part of the link graph within Depth hops of Root.
+type EntityGraph struct {
+	Root      EntityNode  `json:"root"`
+	Depth     int         `json:"depth"`
+	Nodes     []GraphNode `json:"nodes"` // Root first, then by distance
+	Edges     []GraphEdge `json:"edges"`
+	Truncated bool        `json:"truncated"` // the node cap was reached; more nodes are linked
+}
+
+// GraphNode is an entity in an EntityGraph.
+type GraphNode struct {
+	EntityNode
+	Distance int `json:"distance"` // hops from Root
+}
+
+// GraphEdge is an undirected link between two nodes of an EntityGraph.
+type GraphEdge struct {
+	From      EntityNode `json:"from"`
+	To        EntityNode `json:"to"`
+	Weight    int        `json:"weight"` // transactions that carried both
+	FirstSeen time.Time  `json:"first_seen"`
+	LastSeen  time.Time  `json:"last_seen"`
+}
+
 // ─── Webhooks ─────────────────────────────────────────────────────────────────
 
 // WebhookConfig is a registered callback that Lumina uses to receive
@@ -126,19 +396,137 @@ type WebhookPayload struct {
 	Transaction Transaction `json:"transaction"`
 }
 
+// ─── API keys ─────────────────────────────────────────────────────────────────
+
+// APIKey is a stored API key. Only the SHA-256 of its token is kept, so a
+// leaked store does not leak usable credentials.
+type APIKey struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"` // first characters of the token, to recognise it
+	TokenHash string     `json:"token_hash"`
+	Scopes    []string   `json:"scopes"`
+	CreatedBy string     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	RotatedAt *time.Time `json:"rotated_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// ─── Audit log ────────────────────────────────────────────────────────────────
+
+// AuditEntry is one audited operation in the hash chain kept by package
+// audit, which documents its fields. Entries are never modified once stored.
+type AuditEntry struct {
+	Seq       int64           `json:"seq"` // 1-based position in the chain
+	Time      time.Time       `json:"time"`
+	Actor     string          `json:"actor"`                  // authenticated caller, e.g. "key:<name>"
+	OnBehalf  string          `json:"on_behalf_of,omitempty"` // who the caller says it acted for; not verified
+	Action    string          `json:"action"`                 // e.g. "blocklist.add"
+	Resource  string          `json:"resource"`               // e.g. "blocklist/3f2c…"
+	RequestID string          `json:"request_id,omitempty"`
+	Before    json.RawMessage `json:"before,omitempty"` // state prior to the change
+	After     json.RawMessage `json:"after,omitempty"`  // state after the change
+	PrevHash  string          `json:"prev_hash"`
+	Hash      string          `json:"hash"`
+}
+
 // ─── Reporting ────────────────────────────────────────────────────────────────
 
 // EntitySummary provides aggregated activity for a tracked entity
 // (email, IP, card BIN, or device fingerprint) over a time window.
 type EntitySummary struct {
-	EntityType    string        `json:"entity_type"`
-	EntityValue   string        `json:"entity_value"`
-	Period        string        `json:"period"`
-	TotalCount    int           `json:"total_count"`
-	HighRiskCount int           `json:"high_risk_count"`
-	AvgRiskScore  float64       `json:"avg_risk_score"`
-	TotalAmount   float64       `json:"total_amount"`
-	Transactions  []Transaction `json:"transactions"`
+	EntityType        string        `json:"entity_type"`
+	EntityValue       string        `json:"entity_value"`
+	Period            string        `json:"period"`
+	TotalCount        int           `json:"total_count"`
+	HighRiskCount     int           `json:"high_risk_count"`
+	AvgRiskScore      float64       `json:"avg_risk_score"`
+	TotalAmount       float64       `json:"total_amount"` // in ReportingCurrency
+	ReportingCurrency string        `json:"reporting_currency,omitempty"`
+	Outcomes          OutcomeCounts `json:"outcomes"`
+	FirstSeen         *time.Time    `json:"first_seen,omitempty"` // nil when the window is empty
+	LastSeen          *time.Time    `json:"last_seen,omitempty"`
+	Counterparts      Counterparts  `json:"counterparts"`
+	Activity          []DayActivity `json:"activity"`    // every UTC day of the window, oldest first
+	TopFactors        []FactorCount `json:"top_factors"` // most frequent first
+
+	// One page of the window's transactions, newest first. NextCursor is
+	// set when more follow.
+	Transactions []Transaction `json:"transactions"`
+	NextCursor   string        `json:"next_cursor,omitempty"`
+}
+
+// Counterparts are the distinct entities of another type seen with an
+// entity: emails per IP or BIN, devices per email, BINs per device.
+type Counterparts struct {
+	EntityType string        `json:"entity_type"`
+	Distinct   int           `json:"distinct"`
+	Top        []EntityCount `json:"top"` // most transactions first
+}
+
+// EntityCount is the number of transactions that carried one entity value.
+type EntityCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// DayActivity is an entity's activity on one UTC day.
+type DayActivity struct {
+	Date          string  `json:"date"` // YYYY-MM-DD
+	Count         int     `json:"count"`
+	HighRiskCount int     `json:"high_risk_count"`
+	Amount        float64 `json:"amount"` // in ReportingCurrency
+}
+
+// FactorCount is the number of transactions a risk factor fired on.
+type FactorCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// OutcomeCounts tallies transactions by recorded outcome. A transaction with
+// two chargebacks counts once.
+type OutcomeCounts struct {
+	Transactions   int     `json:"transactions"` // transactions considered
+	Chargebacks    int     `json:"chargebacks"`
+	ConfirmedFraud int     `json:"confirmed_fraud"`
+	FalsePositives int     `json:"false_positives"`
+	Refunds        int     `json:"refunds"`
+	ChargebackRate float64 `json:"chargeback_rate"` // Chargebacks / Transactions
+}
+
+// Add counts tx towards the tallies.
+func (c *OutcomeCounts) Add(tx *Transaction) {
+	c.Transactions++
+	if tx.HasOutcome(OutcomeChargeback) {
+		c.Chargebacks++
+	}
+	if tx.HasOutcome(OutcomeConfirmedFraud) {
+		c.ConfirmedFraud++
+	}
+	if tx.HasOutcome(OutcomeFalsePositive) {
+		c.FalsePositives++
+	}
+	if tx.HasOutcome(OutcomeRefund) {
+		c.Refunds++
+	}
+	c.ChargebackRate = float64(c.Chargebacks) / float64(c.Transactions)
+}
+
+// EntityOutcomes is the outcome tally for one entity value, e.g. a card BIN.
+type EntityOutcomes struct {
+	EntityType  string `json:"entity_type"`
+	EntityValue string `json:"entity_value"`
+	OutcomeCounts
+}
+
+// OutcomeReport summarises recorded outcomes over a look-back window long
+// enough for chargebacks to arrive, and ranks the entities attracting them.
+type OutcomeReport struct {
+	Period  string           `json:"period"`
+	Totals  OutcomeCounts    `json:"totals"`
+	ByBIN   []EntityOutcomes `json:"by_bin"`   // highest chargeback rate first
+	ByEmail []EntityOutcomes `json:"by_email"` // highest chargeback rate first
 }
 
 // FraudReport is the 24-hour pattern export for operations teams.
@@ -147,6 +535,7 @@ type FraudReport struct {
 	Period      string         `json:"period"`
 	Summary     ReportSummary  `json:"summary"`
 	Patterns    []FraudPattern `json:"patterns"`
+	Outcomes    OutcomeReport  `json:"outcomes"`
 }
 
 // ReportSummary holds headline metrics for a FraudReport.
@@ -156,7 +545,36 @@ type ReportSummary struct {
 	MediumRiskCount   int     `json:"medium_risk_count"`
 	LowRiskCount      int     `json:"low_risk_count"`
 	AvgRiskScore      float64 `json:"avg_risk_score"`
-	TotalFlaggedAmount float64 `json:"total_flagged_amount"`
+	TotalFlaggedAmount float64 `json:"total_flagged_amount"` // in ReportingCurrency
+	ReportingCurrency  string  `json:"reporting_currency,omitempty"`
+}
+
+// ShadowReport compares champion (live) and challenger (shadow) decisions over
+// the transactions that were scored by both.
+type ShadowReport struct {
+	GeneratedAt            time.Time            `json:"generated_at"`
+	Period                 string               `json:"period"`
+	ChallengerVersions     []string             `json:"challenger_versions"`
+	Compared               int                  `json:"compared"`  // transactions with a shadow result
+	Unchanged              int                  `json:"unchanged"` // same recommendation
+	Stricter               int                  `json:"stricter"`  // e.g. approve → review, review → decline
+	Looser                 int                  `json:"looser"`    // e.g. decline → review
+	AvgScoreDelta          float64              `json:"avg_score_delta"` // challenger minus champion
+	Transitions            []DecisionTransition `json:"transitions"`
+	ExtraDeclined          int                  `json:"extra_declined"` // declined only by the challenger
+	ExtraDeclinedAmount    float64              `json:"extra_declined_amount"`
+	NoLongerDeclined       int                  `json:"no_longer_declined"` // declined only by the champion
+	NoLongerDeclinedAmount float64              `json:"no_longer_declined_amount"`
+	ReportingCurrency      string               `json:"reporting_currency,omitempty"`
+}
+
+// DecisionTransition counts transactions whose recommendation moved from the
+// champion's decision (From) to the challenger's (To).
+type DecisionTransition struct {
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Count  int     `json:"count"`
+	Amount float64 `json:"amount"`
 }
 
 // FraudPattern describes a recurring suspicious behaviour detected in a window.
@@ -0,0 +1,212 @@
+// Package fx normalises transaction amounts into a single reporting currency
+// using a local table of dated exchange rates.
+//
+// Lumina settles in BRL, MXN, ARS and COP; comparing raw amounts across those
+// currencies is meaningless (500 ARS is a fraction of 500 BRL). Every amount
+// that is averaged, compared against a threshold, or summed in a report is
+// first converted with the rate in force on the transaction's date.
+//
+// Rates are loaded from a JSON file at startup and can be replaced or extended
+// at runtime through the admin API. The table is safe for concurrent use.
+package fx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DateLayout is the format of Rate.Date.
+const DateLayout = "2006-01-02"
+
+// ErrUnknownCurrency is returned when no rate exists for a currency.
+var ErrUnknownCurrency = errors.New("no exchange rate for currency")
+
+// Rate is the value of one reporting-currency unit in Currency from Date
+// onwards. With a USD reporting currency, {"currency": "BRL", "rate": 5.4}
+// means 1 USD = 5.4 BRL.
+type Rate struct {
+	Currency string  `json:"currency"`
+	Date     string  `json:"date"` // YYYY-MM-DD, UTC
+	Rate     float64 `json:"rate"`
+}
+
+// RateFile is the on-disk and admin-API representation of a rate table.
+type RateFile struct {
+	ReportingCurrency string `json:"reporting_currency"`
+	Rates             []Rate `json:"rates"`
+}
+
+// datedRate is a Rate with its date parsed.
+type datedRate struct {
+	from time.Time
+	rate float64
+}
+
+// Table holds dated exchange rates per currency.
+type Table struct {
+	mu        sync.RWMutex
+	reporting string
+	rates     map[string][]datedRate // sorted by from, ascending
+}
+
+// New creates an empty table that converts into the given reporting currency.
+func New(reportingCurrency string) *Table {
+	return &Table{
+		reporting: strings.ToUpper(reportingCurrency),
+		rates:     make(map[string][]datedRate),
+	}
+}
+
+// Load reads a rate file from disk.
+func Load(path string) (*Table, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f RateFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("fx rate file %s: parse error: %w", path, err)
+	}
+	if f.ReportingCurrency == "" {
+		return nil, fmt.Errorf("fx rate file %s: reporting_currency is required", path)
+	}
+	t := New(f.ReportingCurrency)
+	if err := t.Replace(f.Rates); err != nil {
+		return nil, fmt.Errorf("fx rate file %s: %w", path, err)
+	}
+	return t, nil
+}
+
+// ReportingCurrency returns the currency amounts are normalised into.
+func (t *Table) ReportingCurrency() string {
+	return t.reporting
+}
+
+// Empty reports whether the table holds no rates at all.
+func (t *Table) Empty() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.rates) == 0
+}
+
+// Replace validates rates and swaps them in for the whole table.
+// On error the table is left unchanged.
+func (t *Table) Replace(rates []Rate) error {
+	parsed, err := parseRates(rates)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rates = make(map[string][]datedRate, len(parsed))
+	t.merge(parsed)
+	return nil
+}
+
+// Upsert validates rates and adds them to the table, replacing any existing
+// rate for the same currency and date. On error the table is left unchanged.
+func (t *Table) Upsert(rates []Rate) error {
+	parsed, err := parseRates(rates)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.merge(parsed)
+	return nil
+}
+
+// merge folds parsed rates into the table, keeping each currency sorted.
+// Must be called with the write lock held.
+func (t *Table) merge(parsed map[string][]datedRate) {
+	for cur, incoming := range parsed {
+		byDate := make(map[time.Time]float64, len(t.rates[cur])+len(incoming))
+		for _, r := range t.rates[cur] {
+			byDate[r.from] = r.rate
+		}
+		for _, r := range incoming {
+			byDate[r.from] = r.rate
+		}
+		merged := make([]datedRate, 0, len(byDate))
+		for from, rate := range byDate {
+			merged = append(merged, datedRate{from: from, rate: rate})
+		}
+		sort.Slice(merged, func(i, j int) bool { return merged[i].from.Before(merged[j].from) })
+		t.rates[cur] = merged
+	}
+}
+
+// Snapshot returns the table's contents in RateFile form, sorted by currency
+// and date.
+func (t *Table) Snapshot() RateFile {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	f := RateFile{ReportingCurrency: t.reporting, Rates: []Rate{}}
+	currencies := make([]string, 0, len(t.rates))
+	for cur := range t.rates {
+		currencies = append(currencies, cur)
+	}
+	sort.Strings(currencies)
+	for _, cur := range currencies {
+		for _, r := range t.rates[cur] {
+			f.Rates = append(f.Rates, Rate{Currency: cur, Date: r.from.Format(DateLayout), Rate: r.rate})
+		}
+	}
+	return f
+}
+
+// Convert normalises amount from currency into the reporting currency using
+// the latest rate dated on or before at. Amounts dated before the first known
+// rate use that first rate. It returns the converted amount and the rate used.
+func (t *Table) Convert(amount float64, currency string, at time.Time) (float64, float64, error) {
+	cur := strings.ToUpper(currency)
+	if cur == t.reporting {
+		return amount, 1, nil
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	rates := t.rates[cur]
+	if len(rates) == 0 {
+		return 0, 0, fmt.Errorf("%w %q", ErrUnknownCurrency, currency)
+	}
+	// First rate that starts strictly after `at`; the one before it applies.
+	i := sort.Search(len(rates), func(i int) bool { return rates[i].from.After(at) })
+	if i > 0 {
+		i--
+	}
+	rate := rates[i].rate
+	return amount / rate, rate, nil
+}
+
+// parseRates validates a batch of rates and groups them by currency.
+func parseRates(rates []Rate) (map[string][]datedRate, error) {
+	var errs []error
+	parsed := make(map[string][]datedRate)
+	for i, r := range rates {
+		cur := strings.ToUpper(strings.TrimSpace(r.Currency))
+		if len(cur) != 3 {
+			errs = append(errs, fmt.Errorf("rates[%d].currency: must be an ISO-4217 code, got %q", i, r.Currency))
+		}
+		from, err := time.Parse(DateLayout, r.Date)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rates[%d].date: must be YYYY-MM-DD, got %q", i, r.Date))
+		}
+		if r.Rate <= 0 {
+			errs = append(errs, fmt.Errorf("rates[%d].rate: must be positive, got %v", i, r.Rate))
+		}
+		parsed[cur] = append(parsed[cur], datedRate{from: from, rate: r.Rate})
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,114 @@
+package fx_test
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"lumina/fraud-api/internal/fx"
+)
+
+func day(s string) time.Time {
+	t, _ := time.Parse(fx.DateLayout, s)
+	return t
+}
+
+func newTable(t *testing.T) *fx.Table {
+	t.Helper()
+	tbl := fx.New("USD")
+	err := tbl.Replace([]fx.Rate{
+		{Currency: "BRL", Date: "2026-01-01", Rate: 5.0},
+		{Currency: "BRL", Date: "2026-07-01", Rate: 6.0},
+		{Currency: "ARS", Date: "2026-01-01", Rate: 1000},
+	})
+	if err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	return tbl
+}
+
+func almost(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestConvert_UsesRateInForceOnDate(t *testing.T) {
+	tbl := newTable(t)
+
+	got, rate, _ := tbl.Convert(60, "BRL", day("2026-03-15"))
+	if !almost(got, 12) || rate != 5.0 {
+		t.Errorf("March: expected 12 USD at 5.0, got %v at %v", got, rate)
+	}
+	got, rate, _ = tbl.Convert(60, "brl", day("2026-07-01"))
+	if !almost(got, 10) || rate != 6.0 {
+		t.Errorf("July: expected 10 USD at 6.0, got %v at %v", got, rate)
+	}
+}
+
+func TestConvert_BeforeFirstRate_UsesEarliest(t *testing.T) {
+	tbl := newTable(t)
+	got, _, err := tbl.Convert(50, "BRL", day("2025-06-01"))
+	if err != nil || !almost(got, 10) {
+		t.Errorf("expected earliest rate to apply, got %v (%v)", got, err)
+	}
+}
+
+func TestConvert_ReportingCurrency_IsIdentity(t *testing.T) {
+	tbl := newTable(t)
+	got, rate, err := tbl.Convert(42, "usd", day("2026-03-01"))
+	if err != nil || got != 42 || rate != 1 {
+		t.Errorf("expected 42 at 1, got %v at %v (%v)", got, rate, err)
+	}
+}
+
+func TestConvert_UnknownCurrency_ReturnsError(t *testing.T) {
+	tbl := newTable(t)
+	_, _, err := tbl.Convert(10, "JPY", day("2026-03-01"))
+	if !errors.Is(err, fx.ErrUnknownCurrency) {
+		t.Errorf("expected ErrUnknownCurrency, got %v", err)
+	}
+}
+
+func TestUpsert_OverwritesSameDateAndKeepsOthers(t *testing.T) {
+	tbl := newTable(t)
+	if err := tbl.Upsert([]fx.Rate{{Currency: "BRL", Date: "2026-07-01", Rate: 4.0}}); err != nil {
+		t.Fatal(err)
+	}
+	got, _, _ := tbl.Convert(40, "BRL", day("2026-08-01"))
+	if !almost(got, 10) {
+		t.Errorf("expected upserted 4.0 rate, got %v", got)
+	}
+	if _, _, err := tbl.Convert(1000, "ARS", day("2026-08-01")); err != nil {
+		t.Errorf("upsert must keep other currencies: %v", err)
+	}
+}
+
+func TestReplace_InvalidRates_LeavesTableUnchanged(t *testing.T) {
+	tbl := newTable(t)
+	err := tbl.Replace([]fx.Rate{{Currency: "BRL", Date: "01/07/2026", Rate: -1}})
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, want := range []string{"rates[0].date", "rates[0].rate"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %s, got %v", want, err)
+		}
+	}
+	if _, _, err := tbl.Convert(1, "ARS", day("2026-03-01")); err != nil {
+		t.Error("failed replace must not clear existing rates")
+	}
+}
+
+func TestLoad_ShippedRateFile(t *testing.T) {
+	tbl, err := fx.Load("../../data/fx_rates.json")
+	if err != nil {
+		t.Fatalf("load data/fx_rates.json: %v", err)
+	}
+	if tbl.ReportingCurrency() != "USD" {
+		t.Errorf("expected USD, got %s", tbl.ReportingCurrency())
+	}
+	for _, cur := range []string{"BRL", "MXN", "ARS", "COP"} {
+		if _, _, err := tbl.Convert(100, cur, day("2026-10-01")); err != nil {
+			t.Errorf("shipped file must cover %s: %v", cur, err)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Package graph explores the entity link graph: the emails, IPs, devices and
+// card BINs joined by the transactions they appeared in together. Fraud rings
+// share devices, IPs and cards across many accounts, so the component around
+// one entity shows the rest of the ring.
+//
+// The store keeps the links up to date as transactions are saved and pruned
+// (see store.EntityLinks); this package only walks them.
+package graph
+
+import (
+	"sort"
+
+	"lumina/fraud-api/internal/domain"
+	"lumina/fraud-api/internal/store"
+)
+
+// Bounds on a walk. A hot entity such as a shared NAT IP can link thousands
+// of others, so the node count is capped as well as the depth.
+const (
+	DefaultDepth    = 2
+	MaxDepth        = 4
+	DefaultMaxNodes = 200
+	MaxNodes        = 1000
+)
+
+// Explore walks the link graph breadth-first from root, up to depth hops, and
+// returns the nodes reached with every link between them. At most maxNodes
+// nodes are returned (DefaultMaxNodes when maxNodes is not positive); when
+// more were linked, Truncated is set. Each node's links are followed
+// heaviest first, so a capped graph keeps the strongest ties.
+func Explore(links store.EntityLinks, root domain.EntityNode, depth, maxNodes int) domain.EntityGraph {
+	return walk(links, []domain.EntityNode{root}, depth, maxNodes)
+}
+
+// Cluster is Explore from several roots at once, all at distance 0: the
+// union of their neighbourhoods. The scoring engine walks the cluster of a
+// transaction that is not saved yet, whose entities are not yet linked to
+// each other. Root is the first of roots.
+func Cluster(links store.EntityLinks, roots []domain.EntityNode, depth, maxNodes int) domain.EntityGraph {
+	return walk(links, roots, depth, maxNodes)
+}
+
+func walk(links store.EntityLinks, roots []domain.EntityNode, depth, maxNodes int) domain.EntityGraph {
+	if maxNodes <= 0 {
+		maxNodes = DefaultMaxNodes
+	}
+	g := domain.EntityGraph{Depth: depth, Nodes: []domain.GraphNode{}, Edges: []domain.GraphEdge{}}
+	position := make(map[domain.EntityNode]int)
+	for _, root := range roots {
+		if _, dup := position[root]; !dup {
+			position[root] = len(g.Nodes)
+			g.Nodes = append(g.Nodes, domain.GraphNode{EntityNode: root})
+		}
+	}
+	if len(roots) > 0 {
+		g.Root = roots[0]
+	}
+
+	// Nodes are appended in distance order, so ranging by index is the BFS
+	// queue. Nodes at the full depth are still read, to find the edges
+	// between them, but add no new nodes.
+	for i := 0; i < len(g.Nodes); i++ {
+		n := g.Nodes[i]
+		for _, l := range heaviestFirst(links.GetEntityLinks(n.Type, n.Value)) {
+			pos, seen := position[l.Node]
+			if !seen {
+				if n.Distance >= depth {
+					continue
+				}
+				if len(g.Nodes) >= maxNodes {
+					g.Truncated = true
+					continue
+				}
+				pos = len(g.Nodes)
+				position[l.Node] = pos
+				g.Nodes = append(g.Nodes, domain.GraphNode{EntityNode: l.Node, Distance: n.Distance + 1})
+			}
+			// Every edge is seen from both ends; keep it from the first.
+			if pos > i {
+				g.Edges = append(g.Edges, domain.GraphEdge{
+					From:      n.EntityNode,
+					To:        l.Node,
+					Weight:    l.Weight,
+					FirstSeen: l.FirstSeen,
+					LastSeen:  l.LastSeen,
+				})
+			}
+		}
+	}
+	return g
+}
+
+// heaviestFirst orders links by weight, then by entity, so walks are
+// deterministic whatever order the store returns them in.
+func heaviestFirst(links []domain.EntityLink) []domain.EntityLink {
+	sort.Slice(links, func(i, j int) bool {
+		a, b := links[i], links[j]
+		if a.Weight != b.Weight {
+			return a.Weight > b.Weight
+		}
+		if a.Node.Type != b.Node.Type {
+			return a.Node.Type < b.Node.Type
+		}
+		return a.Node.Value < b.Node.Value
+	})
+	return links
+}
@@ -0,0 +1,72 @@
+package graph_test
+
+import (
+	"testing"
+	"time"
+
+	"lumina/fraud-api/internal/domain"
+	"lumina/fraud-api/internal/graph"
+	"lumina/fraud-api/internal/store"
+)
+
+func save(t *testing.T, s store.Store, id, email, ip, device, bin string) {
+	t.Helper()
+	err := s.SaveTransaction(&domain.Transaction{
+		TransactionRequest: domain.TransactionRequest{
+			TransactionID:     id,
+			Timestamp:         time.Now().UTC(),
+			UserEmail:         email,
+			IPAddress:         ip,
+			DeviceFingerprint: device,
+			CardBIN:           bin,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// ring saves two accounts sharing one device, and an unrelated third.
+func ring(t *testing.T) store.Store {
+	s := store.New()
+	save(t, s, "tx-1", "a@x.com", "1.1.1.1", "shared-dev", "411111")
+	save(t, s, "tx-2", "b@x.com", "2.2.2.2", "shared-dev", "522222")
+	save(t, s, "tx-3", "c@x.com", "3.3.3.3", "own-dev", "633333")
+	return s
+}
+
+func TestExplore_ReachesAccountsThroughSharedDevice(t *testing.T) {
+	g := graph.Explore(ring(t), domain.EntityNode{Type: domain.EntityEmail, Value: "a@x.com"}, 2, 0)
+
+	distance := make(map[domain.EntityNode]int)
+	for _, n := range g.Nodes {
+		distance[n.EntityNode] = n.Distance
+	}
+	if len(g.Nodes) != 7 || g.Truncated {
+		t.Fatalf("expected a's transaction and b's, 7 nodes, got %+v", g.Nodes)
+	}
+	if d, ok := distance[domain.EntityNode{Type: domain.EntityEmail, Value: "b@x.com"}]; !ok || d != 2 {
+		t.Errorf("expected b@x.com two hops away via the device, got %d (found: %v)", d, ok)
+	}
+	if _, ok := distance[domain.EntityNode{Type: domain.EntityEmail, Value: "c@x.com"}]; ok {
+		t.Error("an unrelated account must not be in the component")
+	}
+	// Each transaction links its 4 entities pairwise: 6 edges each.
+	if len(g.Edges) != 12 {
+		t.Errorf("expected 12 edges, each listed once, got %d", len(g.Edges))
+	}
+}
+
+func TestExplore_DepthOneKeepsEdgesBetweenNeighbours(t *testing.T) {
+	g := graph.Explore(ring(t), domain.EntityNode{Type: domain.EntityEmail, Value: "a@x.com"}, 1, 0)
+	if len(g.Nodes) != 4 || len(g.Edges) != 6 {
+		t.Errorf("expected a's own transaction, 4 nodes and 6 edges, got %d and %d", len(g.Nodes), len(g.Edges))
+	}
+}
+
+func TestExplore_CapsNodes(t *testing.T) {
+	g := graph.Explore(ring(t), domain.EntityNode{Type: domain.EntityDevice, Value: "shared-dev"}, 2, 3)
+	if len(g.Nodes) != 3 || !g.Truncated {
+		t.Errorf("expected 3 nodes and a truncated graph, got %d nodes, truncated=%v", len(g.Nodes), g.Truncated)
+	}
+}
@@ -0,0 +1,113 @@
+// Package retention bounds how much history the store keeps. A Compactor
+// periodically prunes every transaction older than the retention horizon,
+// with its index entries, and forgets card BINs last seen from an IP before
+// it, so memory use stays proportional to the horizon rather than to uptime.
+//
+// Backends that expire data themselves (Redis TTLs) do not implement
+// store.Pruner and need no compactor.
+package retention
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"lumina/fraud-api/internal/store"
+)
+
+// Defaults used when New is given a zero duration.
+const (
+	// DefaultHorizon matches the longest look-back of the entity summary.
+	DefaultHorizon  = 90 * 24 * time.Hour
+	DefaultInterval = time.Hour
+)
+
+// Compactor prunes a store on a timer.
+type Compactor struct {
+	store    store.Pruner
+	horizon  time.Duration
+	interval time.Duration
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// Run describes one compaction pass.
+type Run struct {
+	Cutoff   time.Time        `json:"cutoff"`
+	Pruned   store.PruneStats `json:"pruned"`
+	Duration time.Duration    `json:"duration"`
+}
+
+// New creates a Compactor. Zero durations select DefaultHorizon and
+// DefaultInterval.
+func New(s store.Pruner, horizon, interval time.Duration) *Compactor {
+	if horizon <= 0 {
+		horizon = DefaultHorizon
+	}
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Compactor{
+		store:    s,
+		horizon:  horizon,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Horizon returns how far back history is kept.
+func (c *Compactor) Horizon() time.Duration { return c.horizon }
+
+// Start runs a pass immediately and then every interval until Stop.
+func (c *Compactor) Start() {
+	go func() {
+		defer close(c.done)
+		t := time.NewTicker(c.interval)
+		defer t.Stop()
+		for {
+			c.runAndLog()
+			select {
+			case <-c.stop:
+				return
+			case <-t.C:
+			}
+		}
+	}()
+}
+
+// Stop ends the background loop and waits for a pass in progress. It must
+// only be called after Start.
+func (c *Compactor) Stop() {
+	c.stopOnce.Do(func() { close(c.stop) })
+	<-c.done
+}
+
+// RunOnce prunes everything older than the horizon now.
+func (c *Compactor) RunOnce() (Run, error) {
+	start := time.Now()
+	run := Run{Cutoff: start.Add(-c.horizon).UTC()}
+	stats, err := c.store.Prune(run.Cutoff)
+	run.Pruned = stats
+	run.Duration = time.Since(start)
+	return run, err
+}
+
+func (c *Compactor) runAndLog() {
+	run, err := c.RunOnce()
+	if err != nil {
+		slog.Error("retention pass failed", "cutoff", run.Cutoff, "error", err)
+		return
+	}
+	if run.Pruned != (store.PruneStats{}) {
+		slog.Info("retention pass pruned history",
+			"cutoff", run.Cutoff,
+			"transactions", run.Pruned.Transactions,
+			"cards", run.Pruned.Cards,
+			"idempotency_keys", run.Pruned.IdempotencyKeys,
+			"duration_ms", run.Duration.Milliseconds(),
+		)
+	}
+}
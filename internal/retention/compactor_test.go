@@ -0,0 +1,93 @@
+package retention_test
+
+import (
+	"testing"
+	"time"
+
+	"lumina/fraud-api/internal/domain"
+	"lumina/fraud-api/internal/retention"
+	"lumina/fraud-api/internal/store"
+)
+
+func save(t *testing.T, s store.Store, id, ip, bin string, age time.Duration) {
+	t.Helper()
+	err := s.SaveTransaction(&domain.Transaction{
+		TransactionRequest: domain.TransactionRequest{
+			TransactionID:     id,
+			Timestamp:         time.Now().UTC().Add(-age),
+			UserEmail:         id + "@x.com",
+			IPAddress:         ip,
+			DeviceFingerprint: "dev-" + id,
+			CardBIN:           bin,
+		},
+		FinalStatus: domain.StatusApproved,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunOnce_PrunesBeyondHorizon(t *testing.T) {
+	s := store.New()
+	save(t, s, "old", "1.1.1.1", "411111", 40*24*time.Hour)
+	save(t, s, "recent", "1.1.1.1", "522222", 24*time.Hour)
+
+	c := retention.New(s, 30*24*time.Hour, time.Hour)
+	run, err := c.RunOnce()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Pruned.Transactions != 1 || run.Pruned.Cards != 1 {
+		t.Errorf("expected the old transaction and its card pair pruned, got %+v", run.Pruned)
+	}
+	if _, ok := s.GetTransaction("old"); ok {
+		t.Error("expected old to be gone")
+	}
+	if n := s.GetUniqueCardsByIP("1.1.1.1"); n != 1 {
+		t.Errorf("expected the card count to decay to 1, got %d", n)
+	}
+}
+
+func TestStart_PrunesInBackgroundUntilStopped(t *testing.T) {
+	s := store.New()
+	c := retention.New(s, time.Hour, 10*time.Millisecond)
+	c.Start()
+	defer c.Stop()
+
+	save(t, s, "stale", "2.2.2.2", "411111", 2*time.Hour)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, ok := s.GetTransaction("stale"); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the background pass to prune the stale transaction")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// Under sustained load, the store holds at most one horizon of history.
+func TestSustainedLoad_StaysBounded(t *testing.T) {
+	s := store.New()
+	c := retention.New(s, 24*time.Hour, time.Hour)
+
+	// Simulate 10 days of traffic arriving an hour at a time.
+	for hour := 240; hour > 0; hour-- {
+		for i := 0; i < 5; i++ {
+			id := time.Duration(hour).String() + "-" + string(rune('a'+i))
+			save(t, s, id, "10.0.0."+string(rune('0'+i)), string(rune('0'+hour%10))+"11111", time.Duration(hour)*time.Hour)
+		}
+		if hour%6 == 0 {
+			if _, err := c.RunOnce(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if _, err := c.RunOnce(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(s.GetAllTransactions(time.Time{})); n > 24*5 {
+		t.Errorf("expected at most one day of transactions (120), got %d", n)
+	}
+}
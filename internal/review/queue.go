@@ -0,0 +1,275 @@
+// Package review implements the manual review queue: transactions the engine
+// recommends for review wait here until an analyst claims the case and
+// records an approve or decline decision.
+//
+// The queue holds no state of its own. A transaction is queued while its
+// final status is pending_review, and claims live in the store, so the queue
+// survives restarts of this component and sees seeded data immediately.
+package review
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lumina/fraud-api/internal/domain"
+	"lumina/fraud-api/internal/store"
+)
+
+// Defaults used when New is given a zero duration.
+const (
+	DefaultClaimTTL = 30 * time.Minute // how long a claim holds before lapsing
+	DefaultSLA      = 4 * time.Hour    // target time from queueing to decision
+)
+
+// Sort orders for Pending.
+const (
+	SortScore  = "score"  // highest risk score first
+	SortAmount = "amount" // largest reporting amount first
+	SortAge    = "age"    // longest waiting first
+)
+
+// Validation errors returned by Pending and Decide.
+var (
+	ErrInvalidSort      = errors.New("sort must be one of: score, amount, age")
+	ErrInvalidDecision  = errors.New("decision must be 'approve' or 'decline'")
+	ErrInvalidBlocklist = errors.New("invalid blocklist request")
+)
+
+// ageBuckets are the SLA ageing bands, as upper bounds.
+var ageBuckets = []struct {
+	label string
+	below time.Duration
+}{
+	{"<1h", time.Hour},
+	{"1h-4h", 4 * time.Hour},
+	{"4h-24h", 24 * time.Hour},
+	{">24h", math.MaxInt64},
+}
+
+// Queue exposes the review workflow on top of the store.
+type Queue struct {
+	store    store.Store
+	claimTTL time.Duration
+	sla      time.Duration
+}
+
+// New creates a Queue. Zero durations select DefaultClaimTTL and DefaultSLA.
+func New(s store.Store, claimTTL, sla time.Duration) *Queue {
+	if claimTTL <= 0 {
+		claimTTL = DefaultClaimTTL
+	}
+	if sla <= 0 {
+		sla = DefaultSLA
+	}
+	return &Queue{store: s, claimTTL: claimTTL, sla: sla}
+}
+
+// Decision is an analyst's verdict on a case. Blocklist names the entity
+// types of the transaction (email, ip, bin, device) to block alongside a
+// decline.
+type Decision struct {
+	Decision  string   `json:"decision"`
+	Notes     string   `json:"notes"`
+	Blocklist []string `json:"blocklist"`
+}
+
+// Pending lists queued cases in the requested order. An empty sortBy means
+// SortScore.
+func (q *Queue) Pending(sortBy string) ([]domain.ReviewCase, error) {
+	var less func(a, b *domain.ReviewCase) bool
+	switch sortBy {
+	case "", SortScore:
+		less = func(a, b *domain.ReviewCase) bool { return a.RiskScore > b.RiskScore }
+	case SortAmount:
+		less = func(a, b *domain.ReviewCase) bool { return a.Amount > b.Amount }
+	case SortAge:
+		less = func(a, b *domain.ReviewCase) bool { return a.QueuedAt.Before(b.QueuedAt) }
+	default:
+		return nil, ErrInvalidSort
+	}
+
+	cases := q.cases(time.Now().UTC())
+	sort.SliceStable(cases, func(i, j int) bool {
+		a, b := &cases[i], &cases[j]
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		// Ties go to the older case, then by ID for a stable listing.
+		if !a.QueuedAt.Equal(b.QueuedAt) {
+			return a.QueuedAt.Before(b.QueuedAt)
+		}
+		return a.TransactionID < b.TransactionID
+	})
+	return cases, nil
+}
+
+// Claim assigns a case to analyst for the claim TTL.
+func (q *Queue) Claim(txID, analyst string) (domain.ReviewClaim, error) {
+	return q.store.ClaimReview(txID, analyst, time.Now().UTC(), q.claimTTL)
+}
+
+// Release returns a case claimed by analyst to the pool.
+func (q *Queue) Release(txID, analyst string) error {
+	return q.store.ReleaseReview(txID, analyst, time.Now().UTC())
+}
+
+// Decide records analyst's decision on a case they have claimed, updating the
+// transaction's final status. On a decline, the entity types listed in
+// d.Blocklist are added to the blocklist with the notes as the reason.
+func (q *Queue) Decide(txID, analyst string, d Decision) (*domain.Transaction, error) {
+	if d.Decision != domain.ActionApprove && d.Decision != domain.ActionDecline {
+		return nil, ErrInvalidDecision
+	}
+	if len(d.Blocklist) > 0 && d.Decision != domain.ActionDecline {
+		return nil, fmt.Errorf("%w: entities can only be blocked on a decline", ErrInvalidBlocklist)
+	}
+
+	tx, exists := q.store.GetTransaction(txID)
+	if !exists {
+		return nil, store.ErrTransactionNotFound
+	}
+	now := time.Now().UTC()
+	entries, err := blocklistEntries(tx, d, analyst, now)
+	if err != nil {
+		return nil, err
+	}
+
+	decision := domain.ReviewDecision{
+		Decision:  d.Decision,
+		Analyst:   analyst,
+		Notes:     d.Notes,
+		DecidedAt: now,
+	}
+	for _, e := range entries {
+		decision.BlocklistEntryIDs = append(decision.BlocklistEntryIDs, e.ID)
+	}
+
+	updated, err := q.store.DecideReview(txID, decision)
+	if err != nil {
+		return nil, err
+	}
+	// Entries are saved only once the decision has stuck, so a rejected
+	// decision never leaves stray blocks behind.
+	for _, e := range entries {
+		q.store.SaveBlocklistEntry(e)
+	}
+	return updated, nil
+}
+
+// SLA summarises queue ageing against the SLA target.
+func (q *Queue) SLA() domain.ReviewSLA {
+	cases := q.cases(time.Now().UTC())
+
+	report := domain.ReviewSLA{
+		Target:  formatDuration(q.sla),
+		Pending: len(cases),
+		Ageing:  make([]domain.AgeBucket, len(ageBuckets)),
+	}
+	for i, b := range ageBuckets {
+		report.Ageing[i].Label = b.label
+	}
+	for _, c := range cases {
+		if c.Claim != nil {
+			report.Claimed++
+		}
+		if c.Breached {
+			report.Breached++
+		}
+		report.OldestAgeSeconds = max(report.OldestAgeSeconds, c.AgeSeconds)
+		age := time.Duration(c.AgeSeconds) * time.Second
+		for i, b := range ageBuckets {
+			if age < b.below {
+				report.Ageing[i].Count++
+				break
+			}
+		}
+	}
+	return report
+}
+
+// cases builds the queue view as of now.
+func (q *Queue) cases(now time.Time) []domain.ReviewCase {
+	pending := q.store.PendingReviews()
+	claims := q.store.ActiveReviewClaims(now)
+
+	cases := make([]domain.ReviewCase, 0, len(pending))
+	for _, tx := range pending {
+		age := now.Sub(tx.ProcessedAt)
+		c := domain.ReviewCase{
+			TransactionID: tx.TransactionID,
+			RiskScore:     tx.RiskScore,
+			Amount:        tx.ReportingAmount(),
+			QueuedAt:      tx.ProcessedAt,
+			AgeSeconds:    int64(age / time.Second),
+			SLADueAt:      tx.ProcessedAt.Add(q.sla),
+			Breached:      age > q.sla,
+			Transaction:   *tx,
+		}
+		if claim, held := claims[tx.TransactionID]; held {
+			c.Claim = &claim
+		}
+		cases = append(cases, c)
+	}
+	return cases
+}
+
+// formatDuration renders d without zero trailing units: "4h", not "4h0m0s".
+func formatDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
+
+// blocklistEntries builds block entries for the requested entity types of tx.
+func blocklistEntries(tx *domain.Transaction, d Decision, analyst string, now time.Time) ([]*domain.BlocklistEntry, error) {
+	reason := fmt.Sprintf("declined in review of %s by %s", tx.TransactionID, analyst)
+	if d.Notes != "" {
+		reason += ": " + d.Notes
+	}
+
+	seen := make(map[string]bool, len(d.Blocklist))
+	var entries []*domain.BlocklistEntry
+	for _, entityType := range d.Blocklist {
+		if seen[entityType] {
+			continue
+		}
+		seen[entityType] = true
+
+		var value string
+		switch entityType {
+		case domain.EntityEmail:
+			value = tx.UserEmail
+		case domain.EntityIP:
+			value = tx.IPAddress
+		case domain.EntityBIN:
+			value = tx.CardBIN
+		case domain.EntityDevice:
+			value = tx.DeviceFingerprint
+		default:
+			return nil, fmt.Errorf("%w: entity type %q must be one of: email, ip, bin, device", ErrInvalidBlocklist, entityType)
+		}
+		entries = append(entries, &domain.BlocklistEntry{
+			ID:        uuid.NewString(),
+			Type:      entityType,
+			Value:     value,
+			ListType:  domain.ListBlock,
+			Reason:    reason,
+			CreatedAt: now,
+		})
+	}
+	return entries, nil
+}
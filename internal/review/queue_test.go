@@ -0,0 +1,188 @@
+package review_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"lumina/fraud-api/internal/domain"
+	"lumina/fraud-api/internal/review"
+	"lumina/fraud-api/internal/store"
+)
+
+// ─── Helpers ──────────────────────────────────────────────────────────────────
+
+func queued(s store.Store, id string, score int, amount float64, age time.Duration) {
+	_ = s.SaveTransaction(&domain.Transaction{
+		TransactionRequest: domain.TransactionRequest{
+			TransactionID:     id,
+			Timestamp:         time.Now().UTC(),
+			Amount:            amount,
+			Currency:          domain.BRL,
+			UserEmail:         id + "@example.com",
+			IPAddress:         "10.0.0.1",
+			CardBIN:           "411111",
+			DeviceFingerprint: "dev-" + id,
+		},
+		RiskScore:      score,
+		Recommendation: domain.ActionReview,
+		FinalStatus:    domain.StatusPendingReview,
+		ProcessedAt:    time.Now().UTC().Add(-age),
+	})
+}
+
+func ids(cases []domain.ReviewCase) []string {
+	out := make([]string, len(cases))
+	for i, c := range cases {
+		out[i] = c.TransactionID
+	}
+	return out
+}
+
+// ─── Listing ──────────────────────────────────────────────────────────────────
+
+func TestPending_SortOrders(t *testing.T) {
+	s := store.New()
+	queued(s, "a", 40, 300, time.Minute)
+	queued(s, "b", 65, 100, time.Hour)
+	queued(s, "c", 50, 200, 2*time.Hour)
+	_ = s.SaveTransaction(&domain.Transaction{
+		TransactionRequest: domain.TransactionRequest{TransactionID: "auto"},
+		FinalStatus:        domain.StatusApproved,
+	})
+	q := review.New(s, 0, 0)
+
+	for sortBy, want := range map[string]string{
+		review.SortScore:  "b c a",
+		review.SortAmount: "a c b",
+		review.SortAge:    "c b a",
+	} {
+		cases, err := q.Pending(sortBy)
+		if err != nil {
+			t.Fatalf("%s: %v", sortBy, err)
+		}
+		if got := ids(cases); len(got) != 3 || got[0]+" "+got[1]+" "+got[2] != want {
+			t.Errorf("sort=%s: expected %s, got %v", sortBy, want, got)
+		}
+	}
+
+	if _, err := q.Pending("risk"); !errors.Is(err, review.ErrInvalidSort) {
+		t.Errorf("expected ErrInvalidSort, got %v", err)
+	}
+}
+
+// ─── Claims ───────────────────────────────────────────────────────────────────
+
+func TestClaim_ExclusiveUntilReleasedOrLapsed(t *testing.T) {
+	s := store.New()
+	queued(s, "cl-1", 50, 100, 0)
+	q := review.New(s, 20*time.Millisecond, 0)
+
+	if _, err := q.Claim("cl-1", "ana"); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if _, err := q.Claim("cl-1", "bruno"); !errors.Is(err, store.ErrReviewClaimed) {
+		t.Errorf("expected ErrReviewClaimed for a second analyst, got %v", err)
+	}
+	if err := q.Release("cl-1", "bruno"); !errors.Is(err, store.ErrReviewClaimed) {
+		t.Errorf("only the claimant may release, got %v", err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, err := q.Claim("cl-1", "bruno"); err != nil {
+		t.Errorf("a lapsed claim should be taken over, got %v", err)
+	}
+	cases, _ := q.Pending("")
+	if cases[0].Claim == nil || cases[0].Claim.Analyst != "bruno" {
+		t.Errorf("expected the case to show bruno's claim, got %+v", cases[0].Claim)
+	}
+}
+
+// ─── Decisions ────────────────────────────────────────────────────────────────
+
+func TestDecide_RequiresClaim(t *testing.T) {
+	s := store.New()
+	queued(s, "d-1", 50, 100, 0)
+	q := review.New(s, 0, 0)
+
+	if _, err := q.Decide("d-1", "ana", review.Decision{Decision: "approve"}); !errors.Is(err, store.ErrReviewNotClaimed) {
+		t.Errorf("expected ErrReviewNotClaimed, got %v", err)
+	}
+	if _, err := q.Decide("d-1", "ana", review.Decision{Decision: "maybe"}); !errors.Is(err, review.ErrInvalidDecision) {
+		t.Errorf("expected ErrInvalidDecision, got %v", err)
+	}
+}
+
+func TestDecide_DeclineSetsFinalStatusAndBlocks(t *testing.T) {
+	s := store.New()
+	queued(s, "d-2", 60, 100, 0)
+	q := review.New(s, 0, 0)
+	_, _ = q.Claim("d-2", "ana")
+
+	tx, err := q.Decide("d-2", "ana", review.Decision{
+		Decision:  "decline",
+		Notes:     "stolen card",
+		Blocklist: []string{domain.EntityEmail, domain.EntityDevice},
+	})
+	if err != nil {
+		t.Fatalf("decide: %v", err)
+	}
+	if tx.FinalStatus != domain.StatusDeclined || tx.Review == nil || tx.Review.Analyst != "ana" {
+		t.Errorf("expected a declined transaction decided by ana, got %s / %+v", tx.FinalStatus, tx.Review)
+	}
+	if len(tx.Review.BlocklistEntryIDs) != 2 {
+		t.Errorf("expected 2 blocklist entry IDs, got %v", tx.Review.BlocklistEntryIDs)
+	}
+	if _, blocked := s.CheckBlocklist(domain.EntityDevice, "dev-d-2"); !blocked {
+		t.Error("device should be blocklisted")
+	}
+	if cases, _ := q.Pending(""); len(cases) != 0 {
+		t.Errorf("decided case must leave the queue, got %v", ids(cases))
+	}
+	if _, err := q.Claim("d-2", "ana"); !errors.Is(err, store.ErrNotInReview) {
+		t.Errorf("expected ErrNotInReview after the decision, got %v", err)
+	}
+}
+
+func TestDecide_RejectedDecisionBlocksNothing(t *testing.T) {
+	s := store.New()
+	queued(s, "d-3", 60, 100, 0)
+	q := review.New(s, 0, 0)
+
+	if _, err := q.Decide("d-3", "ana", review.Decision{Decision: "decline", Blocklist: []string{"email"}}); err == nil {
+		t.Fatal("expected an error without a claim")
+	}
+	if len(s.ListBlocklistEntries()) != 0 {
+		t.Error("a rejected decision must not add blocklist entries")
+	}
+
+	_, _ = q.Claim("d-3", "ana")
+	if _, err := q.Decide("d-3", "ana", review.Decision{Decision: "approve", Blocklist: []string{"email"}}); !errors.Is(err, review.ErrInvalidBlocklist) {
+		t.Errorf("blocking on an approve should be rejected, got %v", err)
+	}
+}
+
+// ─── SLA ──────────────────────────────────────────────────────────────────────
+
+func TestSLA_AgeingAndBreaches(t *testing.T) {
+	s := store.New()
+	queued(s, "s-1", 50, 100, 10*time.Minute)
+	queued(s, "s-2", 50, 100, 2*time.Hour)
+	queued(s, "s-3", 50, 100, 6*time.Hour)
+	queued(s, "s-4", 50, 100, 30*time.Hour)
+	q := review.New(s, 0, 0)
+	_, _ = q.Claim("s-1", "ana")
+
+	sla := q.SLA()
+	if sla.Target != "4h" || sla.Pending != 4 || sla.Claimed != 1 || sla.Breached != 2 {
+		t.Errorf("unexpected SLA summary: %+v", sla)
+	}
+	for _, b := range sla.Ageing {
+		if b.Count != 1 {
+			t.Errorf("expected one case in %s, got %d", b.Label, b.Count)
+		}
+	}
+	if sla.OldestAgeSeconds < int64((30 * time.Hour).Seconds()) {
+		t.Errorf("expected oldest age of at least 30h, got %ds", sla.OldestAgeSeconds)
+	}
+}
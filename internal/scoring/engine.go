@@ -17,25 +17,183 @@
 //   4. Purchase behaviour — anomalous amounts vs the user's historical average
 //   5. Card / BIN patterns — known high-risk or prepaid BIN prefixes
 //   6. Timing — off-hours transactions (fraud bots prefer 02:00–06:00 UTC)
+//   7. Fraud rings — linked entities that are blocklisted, confirmed fraud,
+//      or span an unusual number of accounts
+//   8. Impossible travel — IP country changes faster than a flight could cover
+//
+// Configuration:
+//   Every weight, window and threshold comes from a RuleSet (see rules.go),
+//   loaded from a versioned JSON or YAML rule file so analysts can retune the
+//   engine without a release. DefaultRuleSet reproduces the original
+//   hard-coded values.
+//   The active rule set can be swapped at runtime (SetRuleSet / ReloadRules);
+//   each Score call works against a single snapshot, so a reload never mixes
+//   two rule sets inside one decision.
 package scoring
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"lumina/fraud-api/internal/domain"
+	"lumina/fraud-api/internal/fx"
+	"lumina/fraud-api/internal/graph"
 	"lumina/fraud-api/internal/store"
 )
 
+// ErrNoRuleSource is returned by ReloadRules when the active rule set was not
+// loaded from a file (e.g. it was uploaded through the admin API).
+var ErrNoRuleSource = errors.New("active rule set has no source file to reload from")
+
+// Store is what the engine reads while scoring: entity history, velocity
+// counts, the link graph, block entries and thresholds. It is narrower than
+// store.Store so a view scored against, like a simulation's synthetic
+// history, has to answer each of these reads itself and none falls through
+// to the live store unnoticed.
+type Store interface {
+	store.EntityHistory
+	store.VelocityCounters
+	store.EntityLinks
+	CheckBlocklist(entityType, value string) (*domain.BlocklistEntry, bool)
+	FirstBlocked(nodes []domain.EntityNode) (i int, entry *domain.BlocklistEntry, ok bool)
+	ResolveThresholds(merchantID, merchantCountry string) (domain.Thresholds, string)
+}
+
 // Engine is the stateless fraud risk scoring engine.
 type Engine struct {
-	store *store.Store
+	store  Store
+	rates  *fx.Table
+	active atomic.Pointer[activeRules]
+
+	// challenger is an optional candidate rule set scored in shadow next to
+	// the active (champion) one; nil when shadow mode is off. See shadow.go.
+	challenger atomic.Pointer[activeRules]
+
+	// settings is where rule sets and rates are published, nil if the store
+	// does not keep settings. settingsMu serialises publishing and
+	// refreshing; revisions holds the revision of each document last
+	// applied. See settings.go.
+	settings   store.SettingsRepository
+	settingsMu sync.Mutex
+	revisions  map[string]int64
+}
+
+// activeRules is an immutable snapshot of the rule set in use, together with
+// the identifiers stamped onto every transaction it scores.
+type activeRules struct {
+	set      *RuleSet
+	hash     string
+	loadedAt time.Time
+}
+
+// RuleSetInfo describes the rule set the engine is currently scoring with.
+type RuleSetInfo struct {
+	Version  string    `json:"version"`
+	Hash     string    `json:"hash"`
+	Source   string    `json:"source,omitempty"`
+	LoadedAt time.Time `json:"loaded_at"`
+	Rules    Rules     `json:"rules"`
+}
+
+// New creates a scoring engine backed by the given store and driven by the
+// given rule set. A nil rule set selects DefaultRuleSet. Amounts are
+// normalised with rates; a nil or empty table scores raw amounts. Rule sets
+// and rates are published to s if it also keeps settings, as every
+// store.Store does.
+func New(s Store, rules *RuleSet, rates *fx.Table) *Engine {
+	if rules == nil {
+		rules = DefaultRuleSet()
+	}
+	e := &Engine{store: s, rates: rates, revisions: make(map[string]int64)}
+	e.settings, _ = s.(store.SettingsRepository)
+	e.SetRuleSet(rules)
+	return e
+}
+
+// WithStore returns an engine that scores with e's current rule sets,
+// challenger included, and FX rates, but reads history from s, e.g. a view
+// that also holds a batch not saved yet. The returned engine only scores; it
+// cannot publish settings.
+func (e *Engine) WithStore(s Store) *Engine {
+	c := &Engine{store: s, rates: e.rates, revisions: make(map[string]int64)}
+	c.active.Store(e.active.Load())
+	c.challenger.Store(e.challenger.Load())
+	return c
+}
+
+// Rates returns the FX table used to normalise amounts (may be nil).
+func (e *Engine) Rates() *fx.Table {
+	return e.rates
+}
+
+// RuleSet returns the rule configuration the engine currently scores with.
+// Callers must treat it as read-only.
+func (e *Engine) RuleSet() *RuleSet {
+	return e.active.Load().set
 }
 
-// New creates a scoring engine backed by the given store.
-func New(s *store.Store) *Engine {
-	return &Engine{store: s}
+// ActiveRules describes the current rule set, including its content hash.
+func (e *Engine) ActiveRules() RuleSetInfo {
+	return e.active.Load().info()
+}
+
+// SetRuleSet atomically replaces the active rule set. Score calls already in
+// flight finish with the rule set they started with. The caller must have
+// validated rs and must not modify it afterwards.
+func (e *Engine) SetRuleSet(rs *RuleSet) {
+	e.active.Store(newActiveRules(rs))
+}
+
+func newActiveRules(rs *RuleSet) *activeRules {
+	return &activeRules{set: rs, hash: hashRuleSet(rs), loadedAt: time.Now().UTC()}
+}
+
+func (a *activeRules) info() RuleSetInfo {
+	return RuleSetInfo{
+		Version:  a.set.Version,
+		Hash:     a.hash,
+		Source:   a.set.Source,
+		LoadedAt: a.loadedAt,
+		Rules:    a.set.Rules,
+	}
+}
+
+// ReloadRules re-reads the file the active rule set was loaded from and
+// publishes it (see PublishRuleSet). If the file is invalid or cannot be
+// stored, the current rule set stays active.
+func (e *Engine) ReloadRules() (*RuleSet, error) {
+	path := e.RuleSet().Source
+	if path == "" {
+		return nil, ErrNoRuleSource
+	}
+	rs, err := LoadRuleSet(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.PublishRuleSet(rs); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// hashRuleSet returns the hex SHA-256 of the rule set's canonical JSON form.
+// Two files that differ only in whitespace or key order hash identically.
+func hashRuleSet(rs *RuleSet) string {
+	b, err := json.Marshal(rs)
+	if err != nil {
+		// RuleSet contains only plain values; marshalling cannot fail.
+		panic(fmt.Sprintf("scoring: marshal rule set: %v", err))
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
 }
 
 // ─── Public API ───────────────────────────────────────────────────────────────
@@ -46,7 +204,78 @@ func New(s *store.Store) *Engine {
 //
 // The method does NOT save the transaction to the store; that is the caller's
 // responsibility.
+//
+// Amounts in a currency the FX table does not know are scored unconverted;
+// use Assess to have them rejected instead.
 func (e *Engine) Score(req *domain.TransactionRequest) (score int, factors []domain.RiskFactor, explanation string) {
+	amount, _, _, err := e.normalize(req)
+	if err != nil {
+		amount = req.Amount
+	}
+	return e.score(req, e.active.Load().set, amount)
+}
+
+// Assess scores a request and returns the enriched Transaction ready to be
+// saved, stamped with the version and hash of the rule set that scored it and
+// with the amount normalised into the reporting currency.
+// Like Score, it does not write to the store. It fails with
+// fx.ErrUnknownCurrency if the amount cannot be normalised.
+func (e *Engine) Assess(req *domain.TransactionRequest) (*domain.Transaction, error) {
+	amount, rate, reporting, err := e.normalize(req)
+	if err != nil {
+		return nil, err
+	}
+
+	a := e.active.Load()
+	score, factors, explanation := e.score(req, a.set, amount)
+	thresholds, scope := e.store.ResolveThresholds(req.MerchantID, req.MerchantCountry)
+	recommendation, riskLevel := Recommend(score, thresholds)
+
+	return &domain.Transaction{
+		TransactionRequest: *req,
+		RiskScore:          score,
+		RiskLevel:          riskLevel,
+		Recommendation:     recommendation,
+		Factors:            factors,
+		Explanation:        explanation,
+		RuleSetVersion:     a.set.Version,
+		RuleSetHash:        a.hash,
+		Thresholds:         thresholds,
+		ThresholdScope:     scope,
+		NormalizedAmount:   amount,
+		ReportingCurrency:  reporting,
+		FXRate:             rate,
+		Shadow:             e.shadow(req, amount, thresholds),
+		FinalStatus:        InitialStatus(recommendation),
+		ProcessedAt:        time.Now().UTC(),
+	}, nil
+}
+
+// CheckCurrency returns the error Assess would fail with because req's amount
+// cannot be normalised, or nil. It lets a caller validate a whole batch before
+// scoring any of it.
+func (e *Engine) CheckCurrency(req *domain.TransactionRequest) error {
+	_, _, _, err := e.normalize(req)
+	return err
+}
+
+// normalize converts the request amount into the reporting currency.
+// Without a configured FX table the raw amount is returned unchanged and
+// the reporting currency is empty.
+func (e *Engine) normalize(req *domain.TransactionRequest) (amount, rate float64, reporting string, err error) {
+	if e.rates == nil || e.rates.Empty() {
+		return req.Amount, 0, "", nil
+	}
+	amount, rate, err = e.rates.Convert(req.Amount, req.Currency, req.Timestamp)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	return amount, rate, e.rates.ReportingCurrency(), nil
+}
+
+// score runs the full pipeline against one rule set snapshot. amount is the
+// request amount in the reporting currency.
+func (e *Engine) score(req *domain.TransactionRequest, rs *RuleSet, amount float64) (score int, factors []domain.RiskFactor, explanation string) {
 	// Blocklist/allowlist takes absolute priority.
 	if entry, hit := e.checkLists(req); hit {
 		switch entry.ListType {
@@ -68,7 +297,8 @@ func (e *Engine) Score(req *domain.TransactionRequest) (score int, factors []dom
 	}
 
 	// Fetch all historical context needed by the rules in one pass.
-	ctx := e.buildContext(req)
+	ctx := e.buildContext(req, rs)
+	ctx.amount = amount
 
 	// Run every rule and aggregate factors.
 	rules := []func(*ruleContext) []domain.RiskFactor{
@@ -76,7 +306,9 @@ func (e *Engine) Score(req *domain.TransactionRequest) (score int, factors []dom
 		ruleVelocityIP,
 		ruleVelocityDevice,
 		ruleVelocityCardCycling,
+		ruleFraudRing,
 		ruleGeography,
+		ruleImpossibleTravel,
 		ruleAccountAge,
 		rulePurchaseBehaviour,
 		ruleCardBIN,
@@ -102,44 +334,124 @@ func (e *Engine) Score(req *domain.TransactionRequest) (score int, factors []dom
 	return total, factors, buildExplanation(total, factors)
 }
 
-// Recommend returns the recommendation and risk level for a given score.
-func Recommend(score int) (recommendation, riskLevel string) {
+// Recommend returns the recommendation and risk level for a given score under
+// the given thresholds. Engine.Assess resolves the thresholds that apply to a
+// transaction's merchant; pass domain.DefaultThresholds for the built-in cut-offs.
+func Recommend(score int, t domain.Thresholds) (recommendation, riskLevel string) {
 	switch {
-	case score <= domain.ThresholdApprove:
+	case score <= t.Approve:
 		return domain.ActionApprove, domain.RiskLow
-	case score <= domain.ThresholdReview:
+	case score <= t.Review:
 		return domain.ActionReview, domain.RiskMedium
 	default:
 		return domain.ActionDecline, domain.RiskHigh
 	}
 }
 
+// InitialStatus is a transaction's final status at the moment it is scored:
+// automatic decisions are final, reviews wait in the queue.
+func InitialStatus(recommendation string) string {
+	switch recommendation {
+	case domain.ActionApprove:
+		return domain.StatusApproved
+	case domain.ActionDecline:
+		return domain.StatusDeclined
+	default:
+		return domain.StatusPendingReview
+	}
+}
+
 // ─── Rule context ─────────────────────────────────────────────────────────────
 
 // ruleContext bundles the transaction request with pre-fetched historical data,
 // so each rule doesn't need to query the store independently.
+// The field names describe the default windows; the actual windows come from
+// the rule set in cfg. Windows the rules only count are read from the store's
+// velocity counters rather than loaded.
 type ruleContext struct {
-	req *domain.TransactionRequest
+	req    *domain.TransactionRequest
+	cfg    *Rules
+	amount float64 // req.Amount in the reporting currency
+
+	emailLast24h    []*domain.Transaction // same email, email_velocity.long window
+	baseline        []*domain.Transaction // same email, purchase_behaviour.baseline_window
+	emailLast10m    int                   // same email, email_velocity.short window (tight velocity)
+	ipLast1h        int                   // same IP, ip_velocity window
+	deviceLast30m   int                   // same device, device_velocity window
+	binLast1h       int                   // same card BIN, card_cycling.bin_multi_user window
+	binEmailsLast1h int                   // distinct emails among binLast1h; counted only when binLast1h can fire
+	uniqueCardsByIP int                   // distinct BINs ever seen from this IP
+	ring            *ringContext          // linked cluster; nil when fraud_ring is disabled
+}
 
-	emailLast24h  []*domain.Transaction // same email, last 24 h
-	emailLast10m  []*domain.Transaction // same email, last 10 min (tight velocity)
-	ipLast1h      []*domain.Transaction // same IP, last 1 h
-	deviceLast30m []*domain.Transaction // same device, last 30 min
-	binLast1h     []*domain.Transaction // same card BIN, last 1 h
-	uniqueCardsByIP int                 // distinct BINs ever seen from this IP
+// ringContext summarises the cluster of entities linked to the transaction's
+// own, for the fraud-ring rule.
+type ringContext struct {
+	accounts    int                    // distinct emails in the cluster, the transaction's own included
+	truncated   bool                   // the walk stopped at max_nodes, so accounts is a lower bound
+	blocklisted *domain.GraphNode      // nearest blocklisted entity, if any
+	blockEntry  *domain.BlocklistEntry // its blocklist entry
+	fraud       *domain.GraphNode      // nearest email with a transaction labelled fraud, if any
+	fraudTxID   string                 // that transaction
 }
 
-func (e *Engine) buildContext(req *domain.TransactionRequest) *ruleContext {
+func (e *Engine) buildContext(req *domain.TransactionRequest, rs *RuleSet) *ruleContext {
 	t := req.Timestamp
-	return &ruleContext{
+	r := &rs.Rules
+	ctx := &ruleContext{
 		req:             req,
-		emailLast24h:    e.store.GetTransactionsByEmail(req.UserEmail, t.Add(-24*time.Hour)),
-		emailLast10m:    e.store.GetTransactionsByEmail(req.UserEmail, t.Add(-10*time.Minute)),
-		ipLast1h:        e.store.GetTransactionsByIP(req.IPAddress, t.Add(-1*time.Hour)),
-		deviceLast30m:   e.store.GetTransactionsByDevice(req.DeviceFingerprint, t.Add(-30*time.Minute)),
-		binLast1h:       e.store.GetTransactionsByBIN(req.CardBIN, t.Add(-1*time.Hour)),
+		cfg:             r,
+		emailLast24h:    e.store.GetTransactionsByEmail(req.UserEmail, t.Add(-r.EmailVelocity.Long.Window.D())),
+		emailLast10m:    e.store.CountTransactions(domain.EntityEmail, req.UserEmail, t.Add(-r.EmailVelocity.Short.Window.D())),
+		ipLast1h:        e.store.CountTransactions(domain.EntityIP, req.IPAddress, t.Add(-r.IPVelocity.Window.D())),
+		deviceLast30m:   e.store.CountTransactions(domain.EntityDevice, req.DeviceFingerprint, t.Add(-r.DeviceVelocity.Window.D())),
+		binLast1h:       e.store.CountTransactions(domain.EntityBIN, req.CardBIN, t.Add(-r.CardCycling.BINMultiUser.Window.D())),
 		uniqueCardsByIP: e.store.GetUniqueCardsByIP(req.IPAddress),
 	}
+	if ctx.binLast1h >= r.CardCycling.BINMultiUser.MinCount {
+		ctx.binEmailsLast1h = e.store.CountDistinctEmails(domain.EntityBIN, req.CardBIN, t.Add(-r.CardCycling.BINMultiUser.Window.D()))
+	}
+	// The purchase baseline usually shares the long email window; avoid a
+	// second lookup when it does.
+	if r.PurchaseBehaviour.BaselineWindow == r.EmailVelocity.Long.Window {
+		ctx.baseline = ctx.emailLast24h
+	} else {
+		ctx.baseline = e.store.GetTransactionsByEmail(req.UserEmail, t.Add(-r.PurchaseBehaviour.BaselineWindow.D()))
+	}
+	if r.FraudRing.Enabled {
+		ctx.ring = e.buildRing(req, r.FraudRing)
+	}
+	return ctx
+}
+
+// buildRing walks the cluster around the transaction's entities. Nodes come
+// back nearest first, so the first blocklisted entity and the first account
+// with a fraud label found are the closest ones. Entries on the allowlist
+// say nothing about the rest of the cluster and are ignored. The blocklist
+// and the accounts' fraud labels are each checked in one store query for the
+// whole cluster, rather than per node.
+func (e *Engine) buildRing(req *domain.TransactionRequest, cfg FraudRingRule) *ringContext {
+	g := graph.Cluster(e.store, req.Entities(), cfg.Depth, cfg.MaxNodes)
+	ring := &ringContext{truncated: g.Truncated}
+	nodes := make([]domain.EntityNode, len(g.Nodes))
+	var accounts []*domain.GraphNode
+	var emails []string
+	for i := range g.Nodes {
+		n := &g.Nodes[i]
+		nodes[i] = n.EntityNode
+		if n.Type == domain.EntityEmail {
+			accounts = append(accounts, n)
+			emails = append(emails, n.Value)
+		}
+	}
+	ring.accounts = len(accounts)
+	if i, entry, ok := e.store.FirstBlocked(nodes); ok {
+		ring.blocklisted, ring.blockEntry = &g.Nodes[i], entry
+	}
+	if i, txID, ok := e.store.FirstFraudLabelled(emails); ok {
+		ring.fraud, ring.fraudTxID = accounts[i], txID
+	}
+	return ring
 }
 
 // ─── Blocklist check ──────────────────────────────────────────────────────────
@@ -162,26 +474,28 @@ func (e *Engine) checkLists(req *domain.TransactionRequest) (*domain.BlocklistEn
 // ─── Rule 1: Email velocity ───────────────────────────────────────────────────
 
 func ruleVelocityEmail(ctx *ruleContext) []domain.RiskFactor {
+	cfg := ctx.cfg.EmailVelocity
+	if !cfg.Enabled {
+		return nil
+	}
 	var factors []domain.RiskFactor
 
 	// 24-hour window: multiple purchases from the same account signal
 	// either account takeover or a compromised account being drained.
-	if n := len(ctx.emailLast24h); n >= 2 {
-		delta := clamp(5*n, 0, 25)
+	if n := len(ctx.emailLast24h); n >= cfg.Long.MinCount {
 		factors = append(factors, domain.RiskFactor{
 			Name:        "email_velocity_24h",
-			Description: fmt.Sprintf("Email used in %d transactions in the last 24 hours", n),
-			ScoreDelta:  delta,
+			Description: fmt.Sprintf("Email used in %d transactions in the last %s", n, describeWindow(cfg.Long.Window)),
+			ScoreDelta:  cfg.Long.delta(n),
 		})
 	}
 
 	// 10-minute tight window: strong signal for automated bot activity.
-	if n := len(ctx.emailLast10m); n >= 2 {
-		delta := clamp(10*n, 0, 30)
+	if n := ctx.emailLast10m; n >= cfg.Short.MinCount {
 		factors = append(factors, domain.RiskFactor{
 			Name:        "email_velocity_10min",
-			Description: fmt.Sprintf("Email used in %d transactions in the last 10 minutes", n),
-			ScoreDelta:  delta,
+			Description: fmt.Sprintf("Email used in %d transactions in the last %s", n, describeWindow(cfg.Short.Window)),
+			ScoreDelta:  cfg.Short.delta(n),
 		})
 	}
 
@@ -191,15 +505,18 @@ func ruleVelocityEmail(ctx *ruleContext) []domain.RiskFactor {
 // ─── Rule 2: IP velocity ──────────────────────────────────────────────────────
 
 func ruleVelocityIP(ctx *ruleContext) []domain.RiskFactor {
+	cfg := ctx.cfg.IPVelocity
+	if !cfg.Enabled {
+		return nil
+	}
 	var factors []domain.RiskFactor
 
 	// 3+ transactions from the same IP in an hour is above normal gaming behaviour.
-	if n := len(ctx.ipLast1h); n >= 3 {
-		delta := clamp(8*n, 0, 30)
+	if n := ctx.ipLast1h; n >= cfg.MinCount {
 		factors = append(factors, domain.RiskFactor{
 			Name:        "ip_velocity_1h",
-			Description: fmt.Sprintf("IP address used in %d transactions in the last hour", n),
-			ScoreDelta:  delta,
+			Description: fmt.Sprintf("IP address used in %d transactions in the last %s", n, describeWindow(cfg.Window)),
+			ScoreDelta:  cfg.delta(n),
 		})
 	}
 
@@ -209,15 +526,18 @@ func ruleVelocityIP(ctx *ruleContext) []domain.RiskFactor {
 // ─── Rule 3: Device velocity ──────────────────────────────────────────────────
 
 func ruleVelocityDevice(ctx *ruleContext) []domain.RiskFactor {
+	cfg := ctx.cfg.DeviceVelocity
+	if !cfg.Enabled {
+		return nil
+	}
 	var factors []domain.RiskFactor
 
 	// Same device making 2+ purchases in 30 min is highly suspicious.
-	if n := len(ctx.deviceLast30m); n >= 2 {
-		delta := clamp(12*n, 0, 30)
+	if n := ctx.deviceLast30m; n >= cfg.MinCount {
 		factors = append(factors, domain.RiskFactor{
 			Name:        "device_velocity_30min",
-			Description: fmt.Sprintf("Device fingerprint used in %d transactions in the last 30 minutes", n),
-			ScoreDelta:  delta,
+			Description: fmt.Sprintf("Device fingerprint used in %d transactions in the last %s", n, describeWindow(cfg.Window)),
+			ScoreDelta:  cfg.delta(n),
 		})
 	}
 
@@ -227,31 +547,31 @@ func ruleVelocityDevice(ctx *ruleContext) []domain.RiskFactor {
 // ─── Rule 4: Card cycling on same IP ─────────────────────────────────────────
 
 func ruleVelocityCardCycling(ctx *ruleContext) []domain.RiskFactor {
+	cfg := ctx.cfg.CardCycling
+	if !cfg.Enabled {
+		return nil
+	}
 	var factors []domain.RiskFactor
 
 	// Fraudsters testing stolen cards often cycle through multiple cards
 	// from the same IP or device. 3+ different BINs from one IP is a red flag.
-	if ctx.uniqueCardsByIP >= 3 {
-		delta := clamp(8*ctx.uniqueCardsByIP, 0, 30)
+	if ctx.uniqueCardsByIP >= cfg.CardsPerIP.MinCount {
 		factors = append(factors, domain.RiskFactor{
 			Name:        "card_cycling_ip",
 			Description: fmt.Sprintf("IP address has used %d different card BINs (possible card cycling)", ctx.uniqueCardsByIP),
-			ScoreDelta:  delta,
+			ScoreDelta:  cfg.CardsPerIP.delta(ctx.uniqueCardsByIP),
 		})
 	}
 
 	// Also flag if the same BIN is appearing across multiple different users
 	// in the last hour — a sign that a stolen card batch is being exploited.
-	if n := len(ctx.binLast1h); n >= 5 {
-		emails := make(map[string]bool)
-		for _, tx := range ctx.binLast1h {
-			emails[tx.UserEmail] = true
-		}
-		if len(emails) > 1 {
+	multi := cfg.BINMultiUser
+	if ctx.binLast1h >= multi.MinCount {
+		if n := ctx.binEmailsLast1h; n >= multi.MinAccounts {
 			factors = append(factors, domain.RiskFactor{
 				Name:        "bin_velocity_multi_user",
-				Description: fmt.Sprintf("Card BIN used by %d different accounts in the last hour", len(emails)),
-				ScoreDelta:  clamp(8*len(emails), 0, 25),
+				Description: fmt.Sprintf("Card BIN used by %d different accounts in the last %s", n, describeWindow(multi.Window)),
+				ScoreDelta:  multi.delta(n),
 			})
 		}
 	}
@@ -262,6 +582,10 @@ func ruleVelocityCardCycling(ctx *ruleContext) []domain.RiskFactor {
 // ─── Rule 5: Geography ────────────────────────────────────────────────────────
 
 func ruleGeography(ctx *ruleContext) []domain.RiskFactor {
+	cfg := ctx.cfg.Geography
+	if !cfg.Enabled {
+		return nil
+	}
 	var factors []domain.RiskFactor
 
 	ip := strings.ToUpper(ctx.req.IPCountry)
@@ -273,7 +597,7 @@ func ruleGeography(ctx *ruleContext) []domain.RiskFactor {
 		factors = append(factors, domain.RiskFactor{
 			Name:        "geo_ip_card_mismatch",
 			Description: fmt.Sprintf("IP country (%s) doesn't match card issuing country (%s)", ip, card),
-			ScoreDelta:  25,
+			ScoreDelta:  cfg.IPCardMismatch,
 		})
 	}
 
@@ -283,7 +607,7 @@ func ruleGeography(ctx *ruleContext) []domain.RiskFactor {
 		factors = append(factors, domain.RiskFactor{
 			Name:        "geo_ip_merchant_mismatch",
 			Description: fmt.Sprintf("IP country (%s) doesn't match merchant country (%s)", ip, merchant),
-			ScoreDelta:  10,
+			ScoreDelta:  cfg.IPMerchantMismatch,
 		})
 	}
 
@@ -292,7 +616,7 @@ func ruleGeography(ctx *ruleContext) []domain.RiskFactor {
 		factors = append(factors, domain.RiskFactor{
 			Name:        "geo_high_risk_country",
 			Description: fmt.Sprintf("Transaction originated from high-risk country (%s)", ip),
-			ScoreDelta:  15,
+			ScoreDelta:  cfg.HighRiskCountry,
 		})
 	}
 
@@ -301,7 +625,7 @@ func ruleGeography(ctx *ruleContext) []domain.RiskFactor {
 		factors = append(factors, domain.RiskFactor{
 			Name:        "geo_three_way_mismatch",
 			Description: "IP country, card country, and merchant country are all different",
-			ScoreDelta:  10,
+			ScoreDelta:  cfg.ThreeWayMismatch,
 		})
 	}
 
@@ -311,28 +635,32 @@ func ruleGeography(ctx *ruleContext) []domain.RiskFactor {
 // ─── Rule 6: Account age ──────────────────────────────────────────────────────
 
 func ruleAccountAge(ctx *ruleContext) []domain.RiskFactor {
+	cfg := ctx.cfg.AccountAge
+	if !cfg.Enabled {
+		return nil
+	}
 	var factors []domain.RiskFactor
 
 	age := ctx.req.Timestamp.Sub(ctx.req.AccountCreatedAt)
 
 	switch {
-	case age < time.Hour:
+	case age < cfg.Critical.YoungerThan.D():
 		factors = append(factors, domain.RiskFactor{
 			Name:        "account_age_critical",
 			Description: fmt.Sprintf("Account created %.0f minutes ago (very new)", age.Minutes()),
-			ScoreDelta:  25,
+			ScoreDelta:  cfg.Critical.Delta,
 		})
-	case age < 24*time.Hour:
+	case age < cfg.New.YoungerThan.D():
 		factors = append(factors, domain.RiskFactor{
 			Name:        "account_age_new",
 			Description: fmt.Sprintf("Account created %.0f hours ago", age.Hours()),
-			ScoreDelta:  15,
+			ScoreDelta:  cfg.New.Delta,
 		})
-	case age < 7*24*time.Hour:
+	case age < cfg.Week.YoungerThan.D():
 		factors = append(factors, domain.RiskFactor{
 			Name:        "account_age_week",
 			Description: fmt.Sprintf("Account created %.0f days ago", age.Hours()/24),
-			ScoreDelta:  5,
+			ScoreDelta:  cfg.Week.Delta,
 		})
 	}
 
@@ -342,18 +670,22 @@ func ruleAccountAge(ctx *ruleContext) []domain.RiskFactor {
 // ─── Rule 7: Purchase behaviour ──────────────────────────────────────────────
 
 func rulePurchaseBehaviour(ctx *ruleContext) []domain.RiskFactor {
+	cfg := ctx.cfg.PurchaseBehaviour
+	if !cfg.Enabled {
+		return nil
+	}
 	var factors []domain.RiskFactor
 
-	history := ctx.emailLast24h // use 24-h window as the baseline
+	history := ctx.baseline
 
 	if len(history) == 0 {
 		// No prior history — first transaction carries baseline risk.
-		delta := 5
+		delta := cfg.FirstTransaction.Delta
 		desc := "First transaction recorded for this email address"
-		if ctx.req.Amount > 50 {
+		if ctx.amount > cfg.FirstTransaction.HighAmount {
 			// First purchase that is unusually large warrants more suspicion.
-			delta = 15
-			desc = fmt.Sprintf("First transaction with a high amount ($%.2f)", ctx.req.Amount)
+			delta = cfg.FirstTransaction.HighDelta
+			desc = fmt.Sprintf("First transaction with a high amount ($%.2f)", ctx.amount)
 		}
 		factors = append(factors, domain.RiskFactor{
 			Name:        "first_transaction",
@@ -363,33 +695,35 @@ func rulePurchaseBehaviour(ctx *ruleContext) []domain.RiskFactor {
 		return factors
 	}
 
-	// Calculate the user's average transaction amount from history.
+	// Calculate the user's average transaction amount from history, in the
+	// reporting currency so a user paying in several currencies compares fairly.
 	var total float64
 	for _, tx := range history {
-		total += tx.Amount
+		total += tx.ReportingAmount()
 	}
 	avg := total / float64(len(history))
 
 	if avg > 0 {
-		ratio := ctx.req.Amount / avg
+		ratio := ctx.amount / avg
+		window := cfg.BaselineWindow
 		switch {
-		case ratio >= 10:
+		case ratio >= cfg.Extreme.Ratio:
 			factors = append(factors, domain.RiskFactor{
 				Name:        "amount_anomaly_extreme",
-				Description: fmt.Sprintf("Amount is %.1fx the user's 24h average ($%.2f avg)", ratio, avg),
-				ScoreDelta:  30,
+				Description: fmt.Sprintf("Amount is %.1fx the user's %s average ($%.2f avg)", ratio, window, avg),
+				ScoreDelta:  cfg.Extreme.Delta,
 			})
-		case ratio >= 5:
+		case ratio >= cfg.High.Ratio:
 			factors = append(factors, domain.RiskFactor{
 				Name:        "amount_anomaly_high",
-				Description: fmt.Sprintf("Amount is %.1fx the user's 24h average ($%.2f avg)", ratio, avg),
-				ScoreDelta:  20,
+				Description: fmt.Sprintf("Amount is %.1fx the user's %s average ($%.2f avg)", ratio, window, avg),
+				ScoreDelta:  cfg.High.Delta,
 			})
-		case ratio >= 3:
+		case ratio >= cfg.Medium.Ratio:
 			factors = append(factors, domain.RiskFactor{
 				Name:        "amount_anomaly_medium",
-				Description: fmt.Sprintf("Amount is %.1fx the user's 24h average ($%.2f avg)", ratio, avg),
-				ScoreDelta:  10,
+				Description: fmt.Sprintf("Amount is %.1fx the user's %s average ($%.2f avg)", ratio, window, avg),
+				ScoreDelta:  cfg.Medium.Delta,
 			})
 		}
 	}
@@ -400,6 +734,10 @@ func rulePurchaseBehaviour(ctx *ruleContext) []domain.RiskFactor {
 // ─── Rule 8: Card BIN patterns ───────────────────────────────────────────────
 
 func ruleCardBIN(ctx *ruleContext) []domain.RiskFactor {
+	cfg := ctx.cfg.CardBIN
+	if !cfg.Enabled {
+		return nil
+	}
 	var factors []domain.RiskFactor
 	bin := ctx.req.CardBIN
 
@@ -407,13 +745,13 @@ func ruleCardBIN(ctx *ruleContext) []domain.RiskFactor {
 		factors = append(factors, domain.RiskFactor{
 			Name:        "bin_high_risk",
 			Description: fmt.Sprintf("Card BIN %s is flagged for high fraud association", bin),
-			ScoreDelta:  30,
+			ScoreDelta:  cfg.HighRisk,
 		})
 	} else if isPrepaidBIN(bin) {
 		factors = append(factors, domain.RiskFactor{
 			Name:        "bin_prepaid",
 			Description: fmt.Sprintf("Card BIN %s is a prepaid card (commonly used in chargebacks)", bin),
-			ScoreDelta:  15,
+			ScoreDelta:  cfg.Prepaid,
 		})
 	}
 
@@ -423,22 +761,160 @@ func ruleCardBIN(ctx *ruleContext) []domain.RiskFactor {
 // ─── Rule 9: Timing ───────────────────────────────────────────────────────────
 
 func ruleTiming(ctx *ruleContext) []domain.RiskFactor {
+	cfg := ctx.cfg.Timing
+	if !cfg.Enabled {
+		return nil
+	}
 	var factors []domain.RiskFactor
 
 	// Fraud bots tend to operate in the 02:00–06:00 UTC window when fraud
 	// operations teams are offline and approvals are unmonitored.
 	hour := ctx.req.Timestamp.UTC().Hour()
-	if hour >= 2 && hour < 6 {
+	if hour >= cfg.StartHour && hour < cfg.EndHour {
 		factors = append(factors, domain.RiskFactor{
 			Name:        "off_hours",
-			Description: fmt.Sprintf("Transaction at %02d:00 UTC (off-hours 02:00–06:00)", hour),
-			ScoreDelta:  10,
+			Description: fmt.Sprintf("Transaction at %02d:00 UTC (off-hours %02d:00–%02d:00)", hour, cfg.StartHour, cfg.EndHour),
+			ScoreDelta:  cfg.Delta,
 		})
 	}
 
 	return factors
 }
 
+// ─── Rule 10: Fraud rings ─────────────────────────────────────────────────────
+
+func ruleFraudRing(ctx *ruleContext) []domain.RiskFactor {
+	cfg := ctx.cfg.FraudRing
+	ring := ctx.ring
+	if !cfg.Enabled || ring == nil {
+		return nil
+	}
+	var factors []domain.RiskFactor
+
+	// Rings reuse devices, IPs and cards across accounts, so an entity that
+	// was never seen before can still be one hop from a known bad actor.
+	// The descriptions name that actor so a reviewer can pivot to it.
+	if n := ring.blocklisted; n != nil {
+		factors = append(factors, domain.RiskFactor{
+			Name:        "fraud_ring_blocklisted",
+			Description: fmt.Sprintf("Linked %s to blocklisted %s '%s': %s", describeHops(n.Distance), n.Type, n.Value, ring.blockEntry.Reason),
+			ScoreDelta:  cfg.Blocklisted,
+		})
+	}
+
+	if n := ring.fraud; n != nil {
+		desc := fmt.Sprintf("Linked %s to account '%s', whose transaction %s is labelled fraud", describeHops(n.Distance), n.Value, ring.fraudTxID)
+		if n.Distance == 0 {
+			desc = fmt.Sprintf("Account '%s' has an earlier transaction %s labelled fraud", n.Value, ring.fraudTxID)
+		}
+		factors = append(factors, domain.RiskFactor{
+			Name:        "fraud_ring_confirmed_fraud",
+			Description: desc,
+			ScoreDelta:  cfg.ConfirmedFraud,
+		})
+	}
+
+	// Many accounts behind the same few devices, IPs and cards look like a
+	// farm of synthetic identities rather than a shared household.
+	if n := ring.accounts; n >= cfg.Accounts.MinCount {
+		count := fmt.Sprint(n)
+		if ring.truncated {
+			count = "at least " + count
+		}
+		factors = append(factors, domain.RiskFactor{
+			Name:        "fraud_ring_accounts",
+			Description: fmt.Sprintf("Linked cluster spans %s accounts sharing devices, IPs or cards", count),
+			ScoreDelta:  cfg.Accounts.delta(n),
+		})
+	}
+
+	return factors
+}
+
+// describeHops renders a graph distance for factor descriptions.
+func describeHops(d int) string {
+	switch d {
+	case 0:
+		return "through this transaction"
+	case 1:
+		return "directly"
+	default:
+		return fmt.Sprintf("%d hops away", d)
+	}
+}
+
+// ─── Rule 11: Impossible travel ───────────────────────────────────────────────
+
+func ruleImpossibleTravel(ctx *ruleContext) []domain.RiskFactor {
+	cfg := ctx.cfg.ImpossibleTravel
+	if !cfg.Enabled {
+		return nil
+	}
+	var factors []domain.RiskFactor
+
+	// The same account transacting from two countries further apart than a
+	// flight could cover in between means one of the sessions is not the
+	// account holder: a proxy, a shared credential, or a takeover. Report
+	// the least plausible of the user's recent transactions.
+	cur := strings.ToUpper(ctx.req.IPCountry)
+	var (
+		worst      *domain.Transaction
+		worstKm    float64
+		worstSpeed float64
+	)
+	for _, prev := range ctx.emailLast24h {
+		from := strings.ToUpper(prev.IPCountry)
+		if from == cur {
+			continue
+		}
+		km, ok := countryDistanceKm(from, cur)
+		if !ok || km < cfg.MinDistanceKm {
+			continue
+		}
+		gap := ctx.req.Timestamp.Sub(prev.Timestamp)
+		if gap < 0 {
+			gap = -gap
+		}
+		speed := math.Inf(1)
+		if gap > 0 {
+			speed = km / gap.Hours()
+		}
+		if speed > cfg.MaxSpeedKmh && speed > worstSpeed {
+			worst, worstKm, worstSpeed = prev, km, speed
+		}
+	}
+
+	if worst != nil {
+		gap := ctx.req.Timestamp.Sub(worst.Timestamp)
+		if gap < 0 {
+			gap = -gap
+		}
+		factors = append(factors, domain.RiskFactor{
+			Name: "impossible_travel",
+			Description: fmt.Sprintf("IP country changed from %s to %s in %s; the countries are %.0f km apart (transaction %s)",
+				strings.ToUpper(worst.IPCountry), cur, describeGap(gap), worstKm, worst.TransactionID),
+			ScoreDelta: cfg.Delta,
+		})
+	}
+
+	return factors
+}
+
+// describeGap renders the time between two transactions for factor
+// descriptions, to the minute.
+func describeGap(d time.Duration) string {
+	d = d.Round(time.Minute)
+	switch {
+	case d == 0:
+		return "under a minute"
+	case d == time.Minute:
+		return "1 minute"
+	case d == time.Hour:
+		return "1 hour"
+	}
+	return describeWindow(Duration(d))
+}
+
 // ─── Helpers ──────────────────────────────────────────────────────────────────
 
 // buildExplanation formats a score and its factors into a single readable string
@@ -1,20 +1,23 @@
 package scoring_test
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	"lumina/fraud-api/internal/domain"
+	"lumina/fraud-api/internal/fx"
 	"lumina/fraud-api/internal/scoring"
 	"lumina/fraud-api/internal/store"
 )
 
 // ─── Helpers ──────────────────────────────────────────────────────────────────
 
-func newEngine() (*scoring.Engine, *store.Store) {
+func newEngine() (*scoring.Engine, store.Store) {
 	s := store.New()
-	return scoring.New(s), s
+	return scoring.New(s, nil, nil), s
 }
 
 // baseReq returns a clean, low-risk transaction request as a starting point.
@@ -36,9 +39,9 @@ func baseReq(id string) *domain.TransactionRequest {
 }
 
 // save persists a request as a fully scored transaction so it becomes history.
-func save(s *store.Store, e *scoring.Engine, req *domain.TransactionRequest) {
+func save(s store.Store, e *scoring.Engine, req *domain.TransactionRequest) {
 	score, factors, explanation := e.Score(req)
-	rec, level := scoring.Recommend(score)
+	rec, level := scoring.Recommend(score, domain.DefaultThresholds)
 	_ = s.SaveTransaction(&domain.Transaction{
 		TransactionRequest: *req,
 		RiskScore:          score,
@@ -50,6 +53,16 @@ func save(s *store.Store, e *scoring.Engine, req *domain.TransactionRequest) {
 	})
 }
 
+// assess runs Engine.Assess and fails the test if the request is rejected.
+func assess(t *testing.T, e *scoring.Engine, req *domain.TransactionRequest) *domain.Transaction {
+	t.Helper()
+	tx, err := e.Assess(req)
+	if err != nil {
+		t.Fatalf("assess %s: %v", req.TransactionID, err)
+	}
+	return tx
+}
+
 // factorNames extracts rule names from a factor slice for easy assertion.
 func factorNames(factors []domain.RiskFactor) []string {
 	names := make([]string, len(factors))
@@ -68,6 +81,15 @@ func hasFactorName(factors []domain.RiskFactor, name string) bool {
 	return false
 }
 
+func findFactor(factors []domain.RiskFactor, name string) (domain.RiskFactor, bool) {
+	for _, f := range factors {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return domain.RiskFactor{}, false
+}
+
 // ─── Score clamping ───────────────────────────────────────────────────────────
 
 func TestScore_ClampedTo100(t *testing.T) {
@@ -343,6 +365,35 @@ func TestScore_CardCycling_TriggersAt3UniqueBINs(t *testing.T) {
 	}
 }
 
+func TestScore_BINMultiUser_CountsDistinctAccounts(t *testing.T) {
+	e, s := newEngine()
+	base := time.Date(2026, 2, 25, 14, 0, 0, 0, time.UTC)
+
+	// Five uses of the BIN in the hour, but by a single account: no signal.
+	for i := 0; i < 5; i++ {
+		r := baseReq(fmt.Sprintf("bin-same-%d", i))
+		r.IPAddress = fmt.Sprintf("10.0.0.%d", i)
+		r.Timestamp = base.Add(-time.Duration(i+1) * 5 * time.Minute)
+		save(s, e, r)
+	}
+	req := baseReq("bin-probe-1")
+	req.UserEmail = "other@user.com"
+	req.Timestamp = base
+	if _, factors, _ := e.Score(req); hasFactorName(factors, "bin_velocity_multi_user") {
+		t.Errorf("expected no bin_velocity_multi_user for one account, got %v", factorNames(factors))
+	}
+
+	// A second account using the BIN in the same hour trips it.
+	r := baseReq("bin-other-1")
+	r.UserEmail = "second@user.com"
+	r.IPAddress = "10.0.1.1"
+	r.Timestamp = base.Add(-time.Minute)
+	save(s, e, r)
+	if _, factors, _ := e.Score(req); !hasFactorName(factors, "bin_velocity_multi_user") {
+		t.Errorf("expected bin_velocity_multi_user, got %v", factorNames(factors))
+	}
+}
+
 // ─── Geography ────────────────────────────────────────────────────────────────
 
 func TestScore_IPCardMismatch_Adds25(t *testing.T) {
@@ -583,6 +634,46 @@ func TestScore_AmountAnomaly_10x_Adds30(t *testing.T) {
 	}
 }
 
+func TestAssess_NormalisesAmountIntoReportingCurrency(t *testing.T) {
+	rates := fx.New("USD")
+	_ = rates.Replace([]fx.Rate{
+		{Currency: domain.BRL, Date: "2026-01-01", Rate: 5},
+		{Currency: domain.ARS, Date: "2026-01-01", Rate: 1000},
+	})
+	e := scoring.New(store.New(), nil, rates)
+
+	// 500 BRL is $100 — a high first purchase. 500 ARS is $0.50 — not.
+	brl := baseReq("fx-brl-001")
+	brl.Amount = 500
+	ars := baseReq("fx-ars-001")
+	ars.Amount = 500
+	ars.Currency = domain.ARS
+	ars.UserEmail = "ars@example.com"
+
+	brlTx, arsTx := assess(t, e, brl), assess(t, e, ars)
+	if brlTx.NormalizedAmount != 100 || brlTx.ReportingCurrency != "USD" || brlTx.FXRate != 5 {
+		t.Errorf("expected 100 USD at rate 5, got %v %s at %v", brlTx.NormalizedAmount, brlTx.ReportingCurrency, brlTx.FXRate)
+	}
+	if !strings.Contains(brlTx.Explanation, "high amount ($100.00)") {
+		t.Errorf("expected a high first-purchase factor for 500 BRL, got %q", brlTx.Explanation)
+	}
+	if strings.Contains(arsTx.Explanation, "high amount") {
+		t.Errorf("500 ARS must not count as a high first purchase, got %q", arsTx.Explanation)
+	}
+}
+
+func TestAssess_UnknownCurrency_Rejected(t *testing.T) {
+	rates := fx.New("USD")
+	_ = rates.Replace([]fx.Rate{{Currency: domain.BRL, Date: "2026-01-01", Rate: 5}})
+	e := scoring.New(store.New(), nil, rates)
+
+	req := baseReq("fx-unknown-001")
+	req.Currency = "JPY"
+	if _, err := e.Assess(req); !errors.Is(err, fx.ErrUnknownCurrency) {
+		t.Errorf("expected ErrUnknownCurrency, got %v", err)
+	}
+}
+
 // ─── Card BIN patterns ────────────────────────────────────────────────────────
 
 func TestScore_HighRiskBIN_Adds30(t *testing.T) {
@@ -665,50 +756,265 @@ func TestScore_BusinessHours_NoTimingPenalty(t *testing.T) {
 	}
 }
 
+// ─── Impossible travel ────────────────────────────────────────────────────────
+
+func TestScore_ImpossibleTravel_FlagsCountryHopWithinMinutes(t *testing.T) {
+	e, s := newEngine()
+	prev := baseReq("travel-1")
+	prev.IPCountry = "MX"
+	prev.Timestamp = prev.Timestamp.Add(-30 * time.Minute)
+	save(s, e, prev)
+
+	_, factors, _ := e.Score(baseReq("travel-2"))
+
+	f, ok := findFactor(factors, "impossible_travel")
+	if !ok {
+		t.Fatalf("expected impossible_travel, got %v", factorNames(factors))
+	}
+	for _, want := range []string{"from MX to BR", "30 minutes", "travel-1"} {
+		if !strings.Contains(f.Description, want) {
+			t.Errorf("expected %q in %q", want, f.Description)
+		}
+	}
+	if f.ScoreDelta != 25 {
+		t.Errorf("expected +25, got %d", f.ScoreDelta)
+	}
+}
+
+func TestScore_ImpossibleTravel_PlausibleGapNotFlagged(t *testing.T) {
+	e, s := newEngine()
+	// Buenos Aires to São Paulo is a short flight; five hours is plenty.
+	prev := baseReq("travel-1")
+	prev.IPCountry = "AR"
+	prev.Timestamp = prev.Timestamp.Add(-5 * time.Hour)
+	save(s, e, prev)
+
+	if _, factors, _ := e.Score(baseReq("travel-2")); hasFactorName(factors, "impossible_travel") {
+		t.Errorf("expected no impossible_travel after 5 hours, got %v", factorNames(factors))
+	}
+}
+
+func TestScore_ImpossibleTravel_UnknownCountryNotFlagged(t *testing.T) {
+	e, s := newEngine()
+	prev := baseReq("travel-1")
+	prev.IPCountry = "XX"
+	prev.Timestamp = prev.Timestamp.Add(-time.Minute)
+	save(s, e, prev)
+
+	if _, factors, _ := e.Score(baseReq("travel-2")); hasFactorName(factors, "impossible_travel") {
+		t.Errorf("a country without a centroid cannot be judged, got %v", factorNames(factors))
+	}
+}
+
+// ─── Fraud rings ──────────────────────────────────────────────────────────────
+
+func TestScore_FraudRing_ConfirmedFraudOnSharedDevice(t *testing.T) {
+	e, s := newEngine()
+
+	// A confirmed fraudster used the device before; the new account has no
+	// history of its own.
+	old := baseReq("ring-old")
+	old.UserEmail = "fraudster@ring.com"
+	old.Timestamp = old.Timestamp.Add(-72 * time.Hour)
+	save(s, e, old)
+	if _, err := s.AddOutcome(domain.Outcome{ID: "o-1", TransactionID: "ring-old", Type: domain.OutcomeConfirmedFraud}); err != nil {
+		t.Fatal(err)
+	}
+
+	req := baseReq("ring-new")
+	req.UserEmail = "fresh@ring.com"
+	req.IPAddress = "200.1.1.1"
+	req.CardBIN = "999999"
+	_, factors, _ := e.Score(req)
+
+	f, ok := findFactor(factors, "fraud_ring_confirmed_fraud")
+	if !ok {
+		t.Fatalf("expected fraud_ring_confirmed_fraud, got %v", factorNames(factors))
+	}
+	if f.ScoreDelta != 25 || !strings.Contains(f.Description, "fraudster@ring.com") || !strings.Contains(f.Description, "ring-old") {
+		t.Errorf("expected +25 naming the linked account and its transaction, got %+v", f)
+	}
+	if !strings.Contains(f.Description, "directly") {
+		t.Errorf("expected the account to be linked directly through the device, got %q", f.Description)
+	}
+}
+
+func TestScore_FraudRing_FalsePositiveIsNotFraud(t *testing.T) {
+	e, s := newEngine()
+	old := baseReq("ring-fp")
+	old.UserEmail = "cleared@ring.com"
+	old.Timestamp = old.Timestamp.Add(-72 * time.Hour)
+	save(s, e, old)
+	_, _ = s.AddOutcome(domain.Outcome{ID: "o-1", TransactionID: "ring-fp", Type: domain.OutcomeChargeback})
+	_, _ = s.AddOutcome(domain.Outcome{ID: "o-2", TransactionID: "ring-fp", Type: domain.OutcomeFalsePositive})
+
+	req := baseReq("ring-new")
+	req.UserEmail = "fresh@ring.com"
+	if _, factors, _ := e.Score(req); hasFactorName(factors, "fraud_ring_confirmed_fraud") {
+		t.Errorf("a chargeback later marked false positive must not count, got %v", factorNames(factors))
+	}
+}
+
+func TestScore_FraudRing_LinkedBlocklistedEntity(t *testing.T) {
+	e, s := newEngine()
+	old := baseReq("ring-old")
+	old.UserEmail = "mule@ring.com"
+	old.IPAddress = "66.6.6.6"
+	old.Timestamp = old.Timestamp.Add(-72 * time.Hour)
+	save(s, e, old)
+	s.SaveBlocklistEntry(&domain.BlocklistEntry{
+		ID:       "bl-1",
+		Type:     domain.EntityIP,
+		Value:    "66.6.6.6",
+		ListType: domain.ListBlock,
+		Reason:   "proxy farm",
+	})
+
+	// Shares only the device with the blocklisted IP's transaction.
+	req := baseReq("ring-new")
+	req.UserEmail = "fresh@ring.com"
+	req.CardBIN = "999999"
+	score, factors, _ := e.Score(req)
+
+	f, ok := findFactor(factors, "fraud_ring_blocklisted")
+	if !ok {
+		t.Fatalf("expected fraud_ring_blocklisted, got %v", factorNames(factors))
+	}
+	if !strings.Contains(f.Description, "66.6.6.6") || !strings.Contains(f.Description, "proxy farm") {
+		t.Errorf("expected the description to name the blocklisted IP and reason, got %q", f.Description)
+	}
+	if score == 100 {
+		t.Error("a linked blocklisted entity adds risk; it must not short-circuit like a direct match")
+	}
+}
+
+func TestScore_FraudRing_ManyAccountsBehindOneDevice(t *testing.T) {
+	e, s := newEngine()
+	for i := 0; i < 5; i++ {
+		r := baseReq(fmt.Sprintf("farm-%d", i))
+		r.UserEmail = fmt.Sprintf("farm%d@ring.com", i)
+		r.Timestamp = r.Timestamp.Add(-time.Duration(i+48) * time.Hour)
+		save(s, e, r)
+	}
+
+	req := baseReq("farm-new")
+	req.UserEmail = "farm-new@ring.com"
+	_, factors, _ := e.Score(req)
+
+	f, ok := findFactor(factors, "fraud_ring_accounts")
+	if !ok {
+		t.Fatalf("expected fraud_ring_accounts, got %v", factorNames(factors))
+	}
+	// Five earlier accounts plus the submitting one: 6 × 3, capped at 20.
+	if f.ScoreDelta != 18 || !strings.Contains(f.Description, "6 accounts") {
+		t.Errorf("expected +18 for 6 accounts, got %+v", f)
+	}
+}
+
+func TestScore_FraudRing_Disabled(t *testing.T) {
+	s := store.New()
+	rules := scoring.DefaultRuleSet()
+	rules.Rules.FraudRing.Enabled = false
+	e := scoring.New(s, rules, nil)
+	for i := 0; i < 6; i++ {
+		r := baseReq(fmt.Sprintf("farm-%d", i))
+		r.UserEmail = fmt.Sprintf("farm%d@ring.com", i)
+		r.Timestamp = r.Timestamp.Add(-time.Duration(i+48) * time.Hour)
+		save(s, e, r)
+	}
+
+	if _, factors, _ := e.Score(baseReq("farm-new")); hasFactorName(factors, "fraud_ring_accounts") {
+		t.Errorf("expected no ring factors when disabled, got %v", factorNames(factors))
+	}
+}
+
 // ─── Recommendation ───────────────────────────────────────────────────────────
 
 func TestRecommend_LowScore_Approve(t *testing.T) {
-	rec, level := scoring.Recommend(20)
+	rec, level := scoring.Recommend(20, domain.DefaultThresholds)
 	if rec != domain.ActionApprove || level != domain.RiskLow {
 		t.Errorf("score 20 should approve/low, got %s/%s", rec, level)
 	}
 }
 
 func TestRecommend_BoundaryApprove_30(t *testing.T) {
-	rec, level := scoring.Recommend(30)
+	rec, level := scoring.Recommend(30, domain.DefaultThresholds)
 	if rec != domain.ActionApprove || level != domain.RiskLow {
 		t.Errorf("score 30 should approve/low, got %s/%s", rec, level)
 	}
 }
 
 func TestRecommend_MediumScore_Review(t *testing.T) {
-	rec, level := scoring.Recommend(50)
+	rec, level := scoring.Recommend(50, domain.DefaultThresholds)
 	if rec != domain.ActionReview || level != domain.RiskMedium {
 		t.Errorf("score 50 should review/medium, got %s/%s", rec, level)
 	}
 }
 
 func TestRecommend_BoundaryReview_31(t *testing.T) {
-	rec, level := scoring.Recommend(31)
+	rec, level := scoring.Recommend(31, domain.DefaultThresholds)
 	if rec != domain.ActionReview || level != domain.RiskMedium {
 		t.Errorf("score 31 should review/medium, got %s/%s", rec, level)
 	}
 }
 
 func TestRecommend_BoundaryDecline_71(t *testing.T) {
-	rec, level := scoring.Recommend(71)
+	rec, level := scoring.Recommend(71, domain.DefaultThresholds)
 	if rec != domain.ActionDecline || level != domain.RiskHigh {
 		t.Errorf("score 71 should decline/high, got %s/%s", rec, level)
 	}
 }
 
 func TestRecommend_HighScore_Decline(t *testing.T) {
-	rec, level := scoring.Recommend(100)
+	rec, level := scoring.Recommend(100, domain.DefaultThresholds)
 	if rec != domain.ActionDecline || level != domain.RiskHigh {
 		t.Errorf("score 100 should decline/high, got %s/%s", rec, level)
 	}
 }
 
+func TestRecommend_CustomThresholds(t *testing.T) {
+	th := domain.Thresholds{Approve: 10, Review: 40}
+	if rec, _ := scoring.Recommend(20, th); rec != domain.ActionReview {
+		t.Errorf("expected review at 20 with approve=10, got %s", rec)
+	}
+	if rec, level := scoring.Recommend(41, th); rec != domain.ActionDecline || level != domain.RiskHigh {
+		t.Errorf("expected decline/high at 41 with review=40, got %s/%s", rec, level)
+	}
+}
+
+func TestAssess_UsesMerchantThresholdsAndRecordsThem(t *testing.T) {
+	e, s := newEngine()
+	s.SetThresholds(&domain.ThresholdConfig{
+		Scope:      domain.ScopeMerchant,
+		Key:        "strict-merchant",
+		Thresholds: domain.Thresholds{Approve: 2, Review: 4},
+	}, "test")
+
+	req := baseReq("th-merchant-001")
+	req.MerchantID = "strict-merchant"
+	tx := assess(t, e, req) // first_transaction alone scores 5
+
+	if tx.Recommendation != domain.ActionDecline {
+		t.Errorf("expected decline under strict thresholds (score %d), got %s", tx.RiskScore, tx.Recommendation)
+	}
+	if tx.Thresholds.Review != 4 || tx.ThresholdScope != "merchant:strict-merchant" {
+		t.Errorf("expected merchant thresholds recorded, got %+v from %q", tx.Thresholds, tx.ThresholdScope)
+	}
+}
+
+func TestInitialStatus_QueuesOnlyReviews(t *testing.T) {
+	cases := map[string]string{
+		domain.ActionApprove: domain.StatusApproved,
+		domain.ActionReview:  domain.StatusPendingReview,
+		domain.ActionDecline: domain.StatusDeclined,
+	}
+	for rec, want := range cases {
+		if got := scoring.InitialStatus(rec); got != want {
+			t.Errorf("InitialStatus(%s) = %s, want %s", rec, got, want)
+		}
+	}
+}
+
 // ─── Explanation string ───────────────────────────────────────────────────────
 
 func TestScore_ExplanationIncludesScore(t *testing.T) {
@@ -766,3 +1072,36 @@ func TestScore_LegitTransaction_LowScore(t *testing.T) {
 		t.Errorf("clean first transaction should score <= 30, got %d", score)
 	}
 }
+
+// ─── Benchmarks ───────────────────────────────────────────────────────────────
+
+// BenchmarkAssess_HotEntity scores a transaction whose email, IP, device and
+// BIN already carry `history` older transactions. Every velocity window holds
+// the same few recent events at each size, so ns/op should stay flat as the
+// history grows.
+func BenchmarkAssess_HotEntity(b *testing.B) {
+	for _, history := range []int{100, 1_000, 10_000, 100_000} {
+		b.Run(fmt.Sprintf("history=%d", history), func(b *testing.B) {
+			e, s := newEngine()
+			req := baseReq("bench")
+			for i := 0; i < history; i++ {
+				old := baseReq(fmt.Sprintf("old-%d", i))
+				old.Timestamp = req.Timestamp.Add(-48*time.Hour - time.Duration(history-i)*time.Minute)
+				_ = s.SaveTransaction(&domain.Transaction{TransactionRequest: *old})
+			}
+			for i := 1; i <= 3; i++ {
+				recent := baseReq(fmt.Sprintf("recent-%d", i))
+				recent.Timestamp = req.Timestamp.Add(-time.Duration(i) * 5 * time.Minute)
+				_ = s.SaveTransaction(&domain.Transaction{TransactionRequest: *recent})
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := e.Assess(req); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
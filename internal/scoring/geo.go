@@ -0,0 +1,121 @@
+package scoring
+
+import "math"
+
+// ─── Country centroids ────────────────────────────────────────────────────────
+
+// latLon is a point on the globe in decimal degrees.
+type latLon struct {
+	lat, lon float64
+}
+
+// countryCentroids holds the geographic centre of each country the
+// impossible-travel rule can judge, keyed by ISO 3166-1 alpha-2 code. It
+// covers Lumina's markets and the usual origins of cross-border traffic;
+// transactions from any other country are never flagged.
+//
+// A centroid stands in for the whole country, so distances between large or
+// neighbouring countries are rough. The rule's min_distance_km keeps close
+// pairs from firing on that error alone.
+var countryCentroids = map[string]latLon{
+	// Latin America and the Caribbean
+	"AR": {-38.42, -63.62},
+	"BO": {-16.29, -63.59},
+	"BR": {-14.24, -51.93},
+	"CL": {-35.68, -71.54},
+	"CO": {4.57, -74.30},
+	"CR": {9.75, -83.75},
+	"CU": {21.52, -77.78},
+	"DO": {18.74, -70.16},
+	"EC": {-1.83, -78.18},
+	"GT": {15.78, -90.23},
+	"HN": {15.20, -86.24},
+	"JM": {18.11, -77.30},
+	"MX": {23.63, -102.55},
+	"NI": {12.87, -85.21},
+	"PA": {8.54, -80.78},
+	"PE": {-9.19, -75.02},
+	"PR": {18.22, -66.59},
+	"PY": {-23.44, -58.44},
+	"SV": {13.79, -88.90},
+	"UY": {-32.52, -55.77},
+	"VE": {6.42, -66.59},
+
+	// North America
+	"CA": {56.13, -106.35},
+	"US": {37.09, -95.71},
+
+	// Europe
+	"AT": {47.52, 14.55},
+	"BE": {50.50, 4.47},
+	"CH": {46.82, 8.23},
+	"DE": {51.17, 10.45},
+	"DK": {56.26, 9.50},
+	"ES": {40.46, -3.75},
+	"FI": {61.92, 25.75},
+	"FR": {46.23, 2.21},
+	"GB": {55.38, -3.44},
+	"GR": {39.07, 21.82},
+	"IE": {53.41, -8.24},
+	"IT": {41.87, 12.57},
+	"NL": {52.13, 5.29},
+	"NO": {60.47, 8.47},
+	"PL": {51.92, 19.15},
+	"PT": {39.40, -8.22},
+	"RO": {45.94, 24.97},
+	"RU": {61.52, 105.32},
+	"SE": {60.13, 18.64},
+	"TR": {38.96, 35.24},
+	"UA": {48.38, 31.17},
+
+	// Middle East and Africa
+	"AE": {23.42, 53.85},
+	"EG": {26.82, 30.80},
+	"GH": {7.95, -1.02},
+	"IL": {31.05, 34.85},
+	"KE": {-0.02, 37.91},
+	"MA": {31.79, -7.09},
+	"NG": {9.08, 8.68},
+	"SA": {23.89, 45.08},
+	"TZ": {-6.37, 34.89},
+	"ZA": {-30.56, 22.94},
+
+	// Asia and Oceania
+	"AU": {-25.27, 133.78},
+	"CN": {35.86, 104.20},
+	"ID": {-0.79, 113.92},
+	"IN": {20.59, 78.96},
+	"JP": {36.20, 138.25},
+	"KP": {40.34, 127.51},
+	"KR": {35.91, 127.77},
+	"MY": {4.21, 101.98},
+	"NZ": {-40.90, 174.89},
+	"PH": {12.88, 121.77},
+	"PK": {30.38, 69.35},
+	"SG": {1.35, 103.82},
+	"TH": {15.87, 100.99},
+	"VN": {14.06, 108.28},
+}
+
+// countryDistanceKm returns the great-circle distance between the centroids
+// of two countries. ok is false when either country is not in the table.
+func countryDistanceKm(a, b string) (km float64, ok bool) {
+	p, okA := countryCentroids[a]
+	q, okB := countryCentroids[b]
+	if !okA || !okB {
+		return 0, false
+	}
+	return haversineKm(p, q), true
+}
+
+// haversineKm is the great-circle distance between two points on a sphere
+// with the Earth's mean radius.
+func haversineKm(p, q latLon) float64 {
+	const earthRadiusKm = 6371.0
+	rad := math.Pi / 180
+	dLat := (q.lat - p.lat) * rad
+	dLon := (q.lon - p.lon) * rad
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(p.lat*rad)*math.Cos(q.lat*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
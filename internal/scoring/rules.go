@@ -0,0 +1,510 @@
+package scoring
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"lumina/fraud-api/internal/graph"
+)
+
+// ─── Rule configuration ───────────────────────────────────────────────────────
+
+// RuleSet is the declarative configuration for the scoring rules.
+// Every weight, window and threshold the engine uses lives here so that fraud
+// analysts can retune the engine by shipping a new rule file instead of a new
+// binary.
+//
+// Rule files are JSON. They are decoded on top of DefaultRuleSet, so a file
+// only needs to mention the values it changes — but it must always carry a
+// version so every decision can be traced back to the file that produced it.
+type RuleSet struct {
+	Version string `json:"version"`
+	Rules   Rules  `json:"rules"`
+
+	// Source is the file the rule set was loaded from, if any. ReloadRules
+	// re-reads it. It is not part of the rule set's content or hash.
+	Source string `json:"-"`
+}
+
+// Rules holds the configuration for each of the eleven scoring rules.
+type Rules struct {
+	EmailVelocity     EmailVelocityRule     `json:"email_velocity"`
+	IPVelocity        CounterRule           `json:"ip_velocity"`
+	DeviceVelocity    CounterRule           `json:"device_velocity"`
+	CardCycling       CardCyclingRule       `json:"card_cycling"`
+	Geography         GeographyRule         `json:"geography"`
+	AccountAge        AccountAgeRule        `json:"account_age"`
+	PurchaseBehaviour PurchaseBehaviourRule `json:"purchase_behaviour"`
+	CardBIN           CardBINRule           `json:"card_bin"`
+	Timing            TimingRule            `json:"timing"`
+	FraudRing         FraudRingRule         `json:"fraud_ring"`
+	ImpossibleTravel  ImpossibleTravelRule  `json:"impossible_travel"`
+}
+
+// Counter describes a count-triggered signal. It fires once the observed count
+// reaches MinCount and contributes PerUnit points per observation, capped at Cap.
+// Window is ignored by signals that are not time-windowed.
+type Counter struct {
+	Window   Duration `json:"window,omitempty"`
+	MinCount int      `json:"min_count"`
+	PerUnit  int      `json:"per_unit"`
+	Cap      int      `json:"cap"`
+}
+
+// delta returns the score contribution for n observations.
+func (c Counter) delta(n int) int {
+	return clamp(c.PerUnit*n, 0, c.Cap)
+}
+
+// CounterRule is a rule made of a single windowed Counter (IP and device velocity).
+type CounterRule struct {
+	Enabled bool `json:"enabled"`
+	Counter
+}
+
+// EmailVelocityRule configures rule 1: a long baseline window and a short
+// burst window over the same email address.
+type EmailVelocityRule struct {
+	Enabled bool    `json:"enabled"`
+	Long    Counter `json:"long"`
+	Short   Counter `json:"short"`
+}
+
+// CardCyclingRule configures rule 4: distinct BINs per IP (all-time) and the
+// same BIN being used by several accounts inside a window.
+type CardCyclingRule struct {
+	Enabled      bool             `json:"enabled"`
+	CardsPerIP   Counter          `json:"cards_per_ip"`
+	BINMultiUser BINMultiUserRule `json:"bin_multi_user"`
+}
+
+// BINMultiUserRule fires when a BIN appears in at least MinCount transactions
+// inside Window and those transactions span at least MinAccounts emails.
+// The delta is PerUnit per distinct account, capped at Cap.
+type BINMultiUserRule struct {
+	Counter
+	MinAccounts int `json:"min_accounts"`
+}
+
+// GeographyRule configures rule 5. Each field is the flat delta of one signal.
+type GeographyRule struct {
+	Enabled            bool `json:"enabled"`
+	IPCardMismatch     int  `json:"ip_card_mismatch"`
+	IPMerchantMismatch int  `json:"ip_merchant_mismatch"`
+	HighRiskCountry    int  `json:"high_risk_country"`
+	ThreeWayMismatch   int  `json:"three_way_mismatch"`
+}
+
+// AgeTier adds Delta when the account is younger than YoungerThan.
+type AgeTier struct {
+	YoungerThan Duration `json:"younger_than"`
+	Delta       int      `json:"delta"`
+}
+
+// AccountAgeRule configures rule 6. Tiers are evaluated from youngest to
+// oldest and only the first match applies.
+type AccountAgeRule struct {
+	Enabled  bool    `json:"enabled"`
+	Critical AgeTier `json:"critical"`
+	New      AgeTier `json:"new"`
+	Week     AgeTier `json:"week"`
+}
+
+// FirstTransactionRule scores a user's first transaction inside the baseline
+// window. Amounts above HighAmount (in the reporting currency) get HighDelta
+// instead of Delta.
+type FirstTransactionRule struct {
+	Delta      int     `json:"delta"`
+	HighAmount float64 `json:"high_amount"`
+	HighDelta  int     `json:"high_delta"`
+}
+
+// RatioTier adds Delta when the amount is at least Ratio times the baseline average.
+type RatioTier struct {
+	Ratio float64 `json:"ratio"`
+	Delta int     `json:"delta"`
+}
+
+// PurchaseBehaviourRule configures rule 7. Anomaly tiers are evaluated from
+// the most to the least extreme and only the first match applies.
+type PurchaseBehaviourRule struct {
+	Enabled          bool                 `json:"enabled"`
+	BaselineWindow   Duration             `json:"baseline_window"`
+	FirstTransaction FirstTransactionRule `json:"first_transaction"`
+	Extreme          RatioTier            `json:"extreme"`
+	High             RatioTier            `json:"high"`
+	Medium           RatioTier            `json:"medium"`
+}
+
+// CardBINRule configures rule 8.
+type CardBINRule struct {
+	Enabled  bool `json:"enabled"`
+	HighRisk int  `json:"high_risk"`
+	Prepaid  int  `json:"prepaid"`
+}
+
+// TimingRule configures rule 9: transactions with a UTC hour in
+// [StartHour, EndHour) add Delta.
+type TimingRule struct {
+	Enabled   bool `json:"enabled"`
+	StartHour int  `json:"start_hour"`
+	EndHour   int  `json:"end_hour"`
+	Delta     int  `json:"delta"`
+}
+
+// FraudRingRule configures rule 10: the cluster of entities linked to the
+// transaction's own by earlier transactions, walked up to Depth hops and
+// MaxNodes entities. Blocklisted and ConfirmedFraud are flat deltas for a
+// cluster holding a blocklisted entity or an account with a transaction
+// labelled fraud; Accounts counts the distinct emails in it (not windowed).
+type FraudRingRule struct {
+	Enabled        bool    `json:"enabled"`
+	Depth          int     `json:"depth"`
+	MaxNodes       int     `json:"max_nodes"`
+	Blocklisted    int     `json:"blocklisted"`
+	ConfirmedFraud int     `json:"confirmed_fraud"`
+	Accounts       Counter `json:"accounts"`
+}
+
+// ImpossibleTravelRule configures rule 11: the user's transactions in the
+// email_velocity.long window are compared with the current one, and a change
+// of IP country adds Delta when covering the distance between the two
+// countries' centroids would take more than MaxSpeedKmh. Countries closer
+// than MinDistanceKm are never flagged.
+type ImpossibleTravelRule struct {
+	Enabled       bool    `json:"enabled"`
+	MaxSpeedKmh   float64 `json:"max_speed_kmh"`
+	MinDistanceKm float64 `json:"min_distance_km"`
+	Delta         int     `json:"delta"`
+}
+
+// DefaultRuleSet returns the built-in rule configuration. It reproduces the
+// weights the engine shipped with before rules became configurable.
+func DefaultRuleSet() *RuleSet {
+	return &RuleSet{
+		Version: "builtin",
+		Rules: Rules{
+			EmailVelocity: EmailVelocityRule{
+				Enabled: true,
+				Long:    Counter{Window: Duration(24 * time.Hour), MinCount: 2, PerUnit: 5, Cap: 25},
+				Short:   Counter{Window: Duration(10 * time.Minute), MinCount: 2, PerUnit: 10, Cap: 30},
+			},
+			IPVelocity: CounterRule{
+				Enabled: true,
+				Counter: Counter{Window: Duration(time.Hour), MinCount: 3, PerUnit: 8, Cap: 30},
+			},
+			DeviceVelocity: CounterRule{
+				Enabled: true,
+				Counter: Counter{Window: Duration(30 * time.Minute), MinCount: 2, PerUnit: 12, Cap: 30},
+			},
+			CardCycling: CardCyclingRule{
+				Enabled:    true,
+				CardsPerIP: Counter{MinCount: 3, PerUnit: 8, Cap: 30},
+				BINMultiUser: BINMultiUserRule{
+					Counter:     Counter{Window: Duration(time.Hour), MinCount: 5, PerUnit: 8, Cap: 25},
+					MinAccounts: 2,
+				},
+			},
+			Geography: GeographyRule{
+				Enabled:            true,
+				IPCardMismatch:     25,
+				IPMerchantMismatch: 10,
+				HighRiskCountry:    15,
+				ThreeWayMismatch:   10,
+			},
+			AccountAge: AccountAgeRule{
+				Enabled:  true,
+				Critical: AgeTier{YoungerThan: Duration(time.Hour), Delta: 25},
+				New:      AgeTier{YoungerThan: Duration(24 * time.Hour), Delta: 15},
+				Week:     AgeTier{YoungerThan: Duration(7 * 24 * time.Hour), Delta: 5},
+			},
+			PurchaseBehaviour: PurchaseBehaviourRule{
+				Enabled:          true,
+				BaselineWindow:   Duration(24 * time.Hour),
+				FirstTransaction: FirstTransactionRule{Delta: 5, HighAmount: 50, HighDelta: 15},
+				Extreme:          RatioTier{Ratio: 10, Delta: 30},
+				High:             RatioTier{Ratio: 5, Delta: 20},
+				Medium:           RatioTier{Ratio: 3, Delta: 10},
+			},
+			CardBIN: CardBINRule{
+				Enabled:  true,
+				HighRisk: 30,
+				Prepaid:  15,
+			},
+			Timing: TimingRule{
+				Enabled:   true,
+				StartHour: 2,
+				EndHour:   6,
+				Delta:     10,
+			},
+			FraudRing: FraudRingRule{
+				Enabled:        true,
+				Depth:          2,
+				MaxNodes:       50,
+				Blocklisted:    25,
+				ConfirmedFraud: 25,
+				Accounts:       Counter{MinCount: 5, PerUnit: 3, Cap: 20},
+			},
+			ImpossibleTravel: ImpossibleTravelRule{
+				Enabled:       true,
+				MaxSpeedKmh:   900,
+				MinDistanceKm: 500,
+				Delta:         25,
+			},
+		},
+	}
+}
+
+// ─── Loading ──────────────────────────────────────────────────────────────────
+
+// LoadRuleSet reads and validates a rule file: YAML if its name ends in
+// .yaml or .yml (see yaml.go), JSON otherwise.
+func LoadRuleSet(path string) (*RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+		if data, err = yamlToJSON(data); err != nil {
+			return nil, fmt.Errorf("rule file %s: parse error: %w", path, err)
+		}
+	}
+	rs, err := ParseRuleSet(data)
+	if err != nil {
+		return nil, fmt.Errorf("rule file %s: %w", path, err)
+	}
+	rs.Source = path
+	return rs, nil
+}
+
+// ParseRuleSet decodes a JSON rule document on top of DefaultRuleSet and
+// validates the result. Unknown fields are rejected so that a typo in a rule
+// name can never silently fall back to the default weight.
+func ParseRuleSet(data []byte) (*RuleSet, error) {
+	rs := DefaultRuleSet()
+	rs.Version = ""
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(rs); err != nil {
+		return nil, fmt.Errorf("parse error: %w", err)
+	}
+	if err := rs.Validate(); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// ─── Validation ───────────────────────────────────────────────────────────────
+
+// FieldError describes a single invalid value in a rule set, identified by its
+// JSON path (e.g. "rules.email_velocity.short.window").
+type FieldError struct {
+	Field   string
+	Problem string
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Problem
+}
+
+// Validate checks the rule set for values the engine cannot use. Every
+// problem found is reported, not just the first; the returned error unwraps
+// to one *FieldError per problem.
+func (rs *RuleSet) Validate() error {
+	v := &validator{}
+	r := &rs.Rules
+
+	if strings.TrimSpace(rs.Version) == "" {
+		v.add("version", "is required")
+	}
+
+	v.counter("rules.email_velocity.long", r.EmailVelocity.Long, true)
+	v.counter("rules.email_velocity.short", r.EmailVelocity.Short, true)
+	v.counter("rules.ip_velocity", r.IPVelocity.Counter, true)
+	v.counter("rules.device_velocity", r.DeviceVelocity.Counter, true)
+	v.counter("rules.card_cycling.cards_per_ip", r.CardCycling.CardsPerIP, false)
+	v.counter("rules.card_cycling.bin_multi_user", r.CardCycling.BINMultiUser.Counter, true)
+	if r.CardCycling.BINMultiUser.MinAccounts < 1 {
+		v.add("rules.card_cycling.bin_multi_user.min_accounts", "must be at least 1")
+	}
+
+	g := r.Geography
+	v.delta("rules.geography.ip_card_mismatch", g.IPCardMismatch)
+	v.delta("rules.geography.ip_merchant_mismatch", g.IPMerchantMismatch)
+	v.delta("rules.geography.high_risk_country", g.HighRiskCountry)
+	v.delta("rules.geography.three_way_mismatch", g.ThreeWayMismatch)
+
+	a := r.AccountAge
+	v.ageTier("rules.account_age.critical", a.Critical)
+	v.ageTier("rules.account_age.new", a.New)
+	v.ageTier("rules.account_age.week", a.Week)
+	if a.New.YoungerThan <= a.Critical.YoungerThan {
+		v.add("rules.account_age.new.younger_than", "must be longer than critical.younger_than")
+	}
+	if a.Week.YoungerThan <= a.New.YoungerThan {
+		v.add("rules.account_age.week.younger_than", "must be longer than new.younger_than")
+	}
+
+	p := r.PurchaseBehaviour
+	v.window("rules.purchase_behaviour.baseline_window", p.BaselineWindow)
+	v.delta("rules.purchase_behaviour.first_transaction.delta", p.FirstTransaction.Delta)
+	v.delta("rules.purchase_behaviour.first_transaction.high_delta", p.FirstTransaction.HighDelta)
+	if p.FirstTransaction.HighAmount < 0 {
+		v.add("rules.purchase_behaviour.first_transaction.high_amount", "must not be negative")
+	}
+	v.ratioTier("rules.purchase_behaviour.extreme", p.Extreme)
+	v.ratioTier("rules.purchase_behaviour.high", p.High)
+	v.ratioTier("rules.purchase_behaviour.medium", p.Medium)
+	if p.High.Ratio >= p.Extreme.Ratio {
+		v.add("rules.purchase_behaviour.high.ratio", "must be lower than extreme.ratio")
+	}
+	if p.Medium.Ratio >= p.High.Ratio {
+		v.add("rules.purchase_behaviour.medium.ratio", "must be lower than high.ratio")
+	}
+
+	v.delta("rules.card_bin.high_risk", r.CardBIN.HighRisk)
+	v.delta("rules.card_bin.prepaid", r.CardBIN.Prepaid)
+
+	t := r.Timing
+	if t.StartHour < 0 || t.StartHour > 23 {
+		v.add("rules.timing.start_hour", "must be between 0 and 23")
+	}
+	if t.EndHour < 1 || t.EndHour > 24 {
+		v.add("rules.timing.end_hour", "must be between 1 and 24")
+	}
+	if t.EndHour <= t.StartHour {
+		v.add("rules.timing.end_hour", "must be after start_hour")
+	}
+	v.delta("rules.timing.delta", t.Delta)
+
+	f := r.FraudRing
+	if f.Depth < 1 || f.Depth > graph.MaxDepth {
+		v.add("rules.fraud_ring.depth", fmt.Sprintf("must be between 1 and %d", graph.MaxDepth))
+	}
+	if f.MaxNodes < 1 || f.MaxNodes > graph.MaxNodes {
+		v.add("rules.fraud_ring.max_nodes", fmt.Sprintf("must be between 1 and %d", graph.MaxNodes))
+	}
+	v.delta("rules.fraud_ring.blocklisted", f.Blocklisted)
+	v.delta("rules.fraud_ring.confirmed_fraud", f.ConfirmedFraud)
+	v.counter("rules.fraud_ring.accounts", f.Accounts, false)
+
+	it := r.ImpossibleTravel
+	if it.MaxSpeedKmh <= 0 {
+		v.add("rules.impossible_travel.max_speed_kmh", "must be positive")
+	}
+	if it.MinDistanceKm < 0 {
+		v.add("rules.impossible_travel.min_distance_km", "must not be negative")
+	}
+	v.delta("rules.impossible_travel.delta", it.Delta)
+
+	return errors.Join(v.errs...)
+}
+
+// validator accumulates FieldErrors so Validate can report them all at once.
+type validator struct {
+	errs []error
+}
+
+func (v *validator) add(field, problem string) {
+	v.errs = append(v.errs, &FieldError{Field: field, Problem: problem})
+}
+
+func (v *validator) delta(field string, d int) {
+	if d < 0 || d > 100 {
+		v.add(field, fmt.Sprintf("must be between 0 and 100, got %d", d))
+	}
+}
+
+func (v *validator) window(field string, d Duration) {
+	if d <= 0 {
+		v.add(field, "must be a positive duration")
+	}
+}
+
+func (v *validator) counter(field string, c Counter, windowed bool) {
+	if windowed {
+		v.window(field+".window", c.Window)
+	} else if c.Window != 0 {
+		v.add(field+".window", "is not supported by this signal")
+	}
+	if c.MinCount < 1 {
+		v.add(field+".min_count", "must be at least 1")
+	}
+	v.delta(field+".per_unit", c.PerUnit)
+	v.delta(field+".cap", c.Cap)
+}
+
+func (v *validator) ageTier(field string, t AgeTier) {
+	v.window(field+".younger_than", t.YoungerThan)
+	v.delta(field+".delta", t.Delta)
+}
+
+func (v *validator) ratioTier(field string, t RatioTier) {
+	if t.Ratio <= 1 {
+		v.add(field+".ratio", "must be greater than 1")
+	}
+	v.delta(field+".delta", t.Delta)
+}
+
+// ─── Duration ─────────────────────────────────────────────────────────────────
+
+// Duration is a time.Duration that is written in rule files as a Go duration
+// string such as "10m" or "24h".
+type Duration time.Duration
+
+// D returns the value as a time.Duration.
+func (d Duration) D() time.Duration { return time.Duration(d) }
+
+// String formats the duration compactly ("24h", "10m", "1h30m").
+func (d Duration) String() string {
+	s := time.Duration(d).String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"10m\" or \"24h\", got %s", b)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", s)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// describeWindow renders a window for factor descriptions, e.g. "hour",
+// "30 minutes", "24 hours".
+func describeWindow(d Duration) string {
+	td := d.D()
+	switch {
+	case td == time.Hour:
+		return "hour"
+	case td%time.Hour == 0:
+		return fmt.Sprintf("%d hours", int(td/time.Hour))
+	case td%time.Minute == 0:
+		return fmt.Sprintf("%d minutes", int(td/time.Minute))
+	default:
+		return d.String()
+	}
+}
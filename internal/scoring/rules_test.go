@@ -0,0 +1,385 @@
+package scoring_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"lumina/fraud-api/internal/scoring"
+	"lumina/fraud-api/internal/store"
+)
+
+// ─── Defaults ─────────────────────────────────────────────────────────────────
+
+func TestDefaultRuleSet_IsValid(t *testing.T) {
+	if err := scoring.DefaultRuleSet().Validate(); err != nil {
+		t.Errorf("default rule set must validate, got: %v", err)
+	}
+}
+
+func TestShippedRuleFile_MatchesDefaults(t *testing.T) {
+	rs, err := scoring.LoadRuleSet("../../data/rules.json")
+	if err != nil {
+		t.Fatalf("load data/rules.json: %v", err)
+	}
+	if !reflect.DeepEqual(rs.Rules, scoring.DefaultRuleSet().Rules) {
+		t.Error("data/rules.json has drifted from DefaultRuleSet")
+	}
+}
+
+func TestLoadRuleSet_YAMLFile_MatchesJSON(t *testing.T) {
+	data, err := os.ReadFile("../../data/rules.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	var yaml strings.Builder
+	yaml.WriteString("--- # data/rules.json as YAML\n")
+	writeYAML(&yaml, doc, "")
+	path := filepath.Join(t.TempDir(), "rules.yml")
+	if err := os.WriteFile(path, []byte(yaml.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rs, err := scoring.LoadRuleSet(path)
+	if err != nil {
+		t.Fatalf("load %s: %v\n%s", path, err, yaml.String())
+	}
+	if rs.Version != doc["version"] || !reflect.DeepEqual(rs.Rules, scoring.DefaultRuleSet().Rules) {
+		t.Errorf("expected the YAML file to load like data/rules.json, got version %q", rs.Version)
+	}
+}
+
+// writeYAML writes a decoded JSON object as a block mapping, keys sorted.
+func writeYAML(b *strings.Builder, m map[string]any, indent string) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if nested, ok := m[k].(map[string]any); ok {
+			fmt.Fprintf(b, "%s%s:\n", indent, k)
+			writeYAML(b, nested, indent+"  ")
+			continue
+		}
+		fmt.Fprintf(b, "%s%s: %v  # comment\n", indent, k, m[k])
+	}
+}
+
+func TestLoadRuleSet_YAMLFile_RejectsWithLine(t *testing.T) {
+	for name, tc := range map[string]struct{ yaml, want string }{
+		"sequence":      {"version: v\nrules:\n  timing:\n    - enabled\n", "line 4: sequences are not supported"},
+		"flow mapping":  {"version: v\nrules: {timing: {}}\n", "line 2:"},
+		"tab indent":    {"version: v\nrules:\n\ttiming:\n", "line 3: tabs"},
+		"duplicate key": {"version: v\nversion: w\n", `line 2: duplicate key "version"`},
+		"unknown field": {"version: v\nrules:\n  ip_velocty:\n    enabled: true\n", "ip_velocty"},
+		"bad duration":  {"version: v\nrules:\n  ip_velocity:\n    window: 1 hour\n", `invalid duration "1 hour"`},
+	} {
+		path := filepath.Join(t.TempDir(), "rules.yaml")
+		if err := os.WriteFile(path, []byte(tc.yaml), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := scoring.LoadRuleSet(path)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", name, tc.want, err)
+		}
+	}
+}
+
+func TestLoadRuleSet_YAMLScalars(t *testing.T) {
+	yaml := `# quoted and typed scalars
+version: '2026.10'   # quoted, or it would be a number
+rules:
+  timing:
+    enabled: false
+    start_hour: 1
+    end_hour: "5"
+`
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := scoring.LoadRuleSet(path)
+	if err == nil || !strings.Contains(err.Error(), "end_hour") {
+		t.Fatalf("expected a quoted number to stay a string and be rejected, got %v", err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Replace(yaml, `"5"`, "5", 1)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rs, err := scoring.LoadRuleSet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.Version != "2026.10" || rs.Rules.Timing.Enabled || rs.Rules.Timing.StartHour != 1 || rs.Rules.Timing.EndHour != 5 {
+		t.Errorf("unexpected rule set: version %q, timing %+v", rs.Version, rs.Rules.Timing)
+	}
+}
+
+// ─── Parsing & validation ─────────────────────────────────────────────────────
+
+func TestParseRuleSet_PartialFileKeepsDefaults(t *testing.T) {
+	rs, err := scoring.ParseRuleSet([]byte(`{
+		"version": "test-1",
+		"rules": {"ip_velocity": {"min_count": 5}}
+	}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rs.Rules.IPVelocity.MinCount != 5 {
+		t.Errorf("expected min_count 5, got %d", rs.Rules.IPVelocity.MinCount)
+	}
+	if rs.Rules.IPVelocity.Window.D() != time.Hour {
+		t.Errorf("expected default 1h window to survive, got %s", rs.Rules.IPVelocity.Window)
+	}
+}
+
+func TestParseRuleSet_MissingVersion_Rejected(t *testing.T) {
+	_, err := scoring.ParseRuleSet([]byte(`{"rules": {}}`))
+	if err == nil || !strings.Contains(err.Error(), "version: is required") {
+		t.Errorf("expected version error, got %v", err)
+	}
+}
+
+func TestParseRuleSet_UnknownField_Rejected(t *testing.T) {
+	_, err := scoring.ParseRuleSet([]byte(`{"version": "v", "rules": {"ip_velocty": {}}}`))
+	if err == nil || !strings.Contains(err.Error(), "ip_velocty") {
+		t.Errorf("expected unknown field error naming ip_velocty, got %v", err)
+	}
+}
+
+func TestParseRuleSet_BadDuration_Rejected(t *testing.T) {
+	_, err := scoring.ParseRuleSet([]byte(`{"version": "v", "rules": {"ip_velocity": {"window": "1 hour"}}}`))
+	if err == nil || !strings.Contains(err.Error(), `invalid duration "1 hour"`) {
+		t.Errorf("expected invalid duration error, got %v", err)
+	}
+}
+
+func TestParseRuleSet_ReportsEveryProblemWithPath(t *testing.T) {
+	_, err := scoring.ParseRuleSet([]byte(`{
+		"version": "v",
+		"rules": {
+			"email_velocity": {"short": {"window": "0s", "cap": 150}},
+			"timing": {"start_hour": 6, "end_hour": 2}
+		}
+	}`))
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+
+	want := map[string]bool{
+		"rules.email_velocity.short.window": false,
+		"rules.email_velocity.short.cap":    false,
+		"rules.timing.end_hour":             false,
+	}
+	var joined interface{ Unwrap() []error }
+	if !errors.As(err, &joined) {
+		t.Fatalf("expected a joined error, got %T", err)
+	}
+	for _, e := range joined.Unwrap() {
+		var fe *scoring.FieldError
+		if errors.As(e, &fe) {
+			if _, ok := want[fe.Field]; ok {
+				want[fe.Field] = true
+			}
+		}
+	}
+	for field, seen := range want {
+		if !seen {
+			t.Errorf("expected a FieldError for %s in: %v", field, err)
+		}
+	}
+}
+
+func TestParseRuleSet_UnorderedTiers_Rejected(t *testing.T) {
+	_, err := scoring.ParseRuleSet([]byte(`{
+		"version": "v",
+		"rules": {"account_age": {"new": {"younger_than": "30m", "delta": 15}}}
+	}`))
+	if err == nil || !strings.Contains(err.Error(), "rules.account_age.new.younger_than") {
+		t.Errorf("expected tier ordering error, got %v", err)
+	}
+}
+
+func TestParseRuleSet_FraudRingBounds_Rejected(t *testing.T) {
+	_, err := scoring.ParseRuleSet([]byte(`{
+		"version": "v",
+		"rules": {"fraud_ring": {"depth": 9, "accounts": {"window": "1h", "min_count": 5, "per_unit": 3, "cap": 20}}}
+	}`))
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, field := range []string{"rules.fraud_ring.depth", "rules.fraud_ring.accounts.window"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("expected an error for %s, got %v", field, err)
+		}
+	}
+}
+
+func TestParseRuleSet_ImpossibleTravelSpeed_Rejected(t *testing.T) {
+	_, err := scoring.ParseRuleSet([]byte(`{
+		"version": "v",
+		"rules": {"impossible_travel": {"max_speed_kmh": 0}}
+	}`))
+	if err == nil || !strings.Contains(err.Error(), "rules.impossible_travel.max_speed_kmh") {
+		t.Errorf("expected a max_speed_kmh error, got %v", err)
+	}
+}
+
+// ─── Engine behaviour under custom rules ──────────────────────────────────────
+
+func TestScore_DisabledRule_DoesNotFire(t *testing.T) {
+	rs := scoring.DefaultRuleSet()
+	rs.Rules.Timing.Enabled = false
+	e := scoring.New(store.New(), rs, nil)
+
+	req := baseReq("rules-off-001")
+	req.Timestamp = time.Date(2026, 2, 25, 3, 0, 0, 0, time.UTC)
+	_, factors, _ := e.Score(req)
+
+	if hasFactorName(factors, "off_hours") {
+		t.Error("off_hours must not fire when the timing rule is disabled")
+	}
+}
+
+func TestScore_CustomWeightsAndWindows_Apply(t *testing.T) {
+	rs := scoring.DefaultRuleSet()
+	rs.Rules.DeviceVelocity.Window = scoring.Duration(2 * time.Hour)
+	rs.Rules.DeviceVelocity.PerUnit = 3
+	s := store.New()
+	e := scoring.New(s, rs, nil)
+	base := time.Date(2026, 2, 25, 14, 0, 0, 0, time.UTC)
+
+	// Both prior transactions fall outside the default 30-minute window.
+	for i := 1; i <= 2; i++ {
+		r := baseReq(fmt.Sprintf("rules-dev-hist-%d", i))
+		r.UserEmail = fmt.Sprintf("other%d@example.com", i)
+		r.Timestamp = base.Add(-time.Duration(i) * 45 * time.Minute)
+		save(s, e, r)
+	}
+
+	req := baseReq("rules-dev-001")
+	req.Timestamp = base
+	_, factors, _ := e.Score(req)
+
+	for _, f := range factors {
+		if f.Name == "device_velocity_30min" {
+			if f.ScoreDelta != 6 {
+				t.Errorf("expected 2 × 3 = 6 points, got %d", f.ScoreDelta)
+			}
+			if !strings.Contains(f.Description, "last 2 hours") {
+				t.Errorf("description should name the configured window: %q", f.Description)
+			}
+			return
+		}
+	}
+	t.Errorf("expected device_velocity_30min with a 2h window, got %v", factorNames(factors))
+}
+
+// ─── Hot reload & version stamping ────────────────────────────────────────────
+
+func TestAssess_StampsRuleSetVersionAndHash(t *testing.T) {
+	e, _ := newEngine()
+	tx := assess(t, e, baseReq("stamp-001"))
+
+	info := e.ActiveRules()
+	if tx.RuleSetVersion != "builtin" || tx.RuleSetVersion != info.Version {
+		t.Errorf("expected version %q, got %q", info.Version, tx.RuleSetVersion)
+	}
+	if tx.RuleSetHash == "" || tx.RuleSetHash != info.Hash {
+		t.Errorf("expected hash %q, got %q", info.Hash, tx.RuleSetHash)
+	}
+}
+
+func TestSetRuleSet_SwapsRulesAndHash(t *testing.T) {
+	e, _ := newEngine()
+	before := e.ActiveRules().Hash
+
+	rs := scoring.DefaultRuleSet()
+	rs.Version = "no-timing"
+	rs.Rules.Timing.Enabled = false
+	e.SetRuleSet(rs)
+
+	req := baseReq("swap-001")
+	req.Timestamp = time.Date(2026, 2, 25, 3, 0, 0, 0, time.UTC)
+	tx := assess(t, e, req)
+
+	if hasFactorName(tx.Factors, "off_hours") {
+		t.Error("off_hours fired after the timing rule was disabled by a swap")
+	}
+	if tx.RuleSetVersion != "no-timing" {
+		t.Errorf("expected version no-timing, got %q", tx.RuleSetVersion)
+	}
+	if tx.RuleSetHash == before {
+		t.Error("hash must change when rule content changes")
+	}
+}
+
+func TestReloadRules_InvalidFileKeepsCurrentRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(`{"version": "v1"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rs, err := scoring.LoadRuleSet(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	e := scoring.New(store.New(), rs, nil)
+
+	if err := os.WriteFile(path, []byte(`{"version": "v2", "rules": {"timing": {"delta": -1}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.ReloadRules(); err == nil {
+		t.Fatal("expected reload of an invalid file to fail")
+	}
+	if v := e.ActiveRules().Version; v != "v1" {
+		t.Errorf("expected v1 to stay active, got %q", v)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"version": "v3"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.ReloadRules(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if v := e.ActiveRules().Version; v != "v3" {
+		t.Errorf("expected v3 after reload, got %q", v)
+	}
+}
+
+func TestReloadRules_NoSource_ReturnsErrNoRuleSource(t *testing.T) {
+	e, _ := newEngine()
+	if _, err := e.ReloadRules(); !errors.Is(err, scoring.ErrNoRuleSource) {
+		t.Errorf("expected ErrNoRuleSource, got %v", err)
+	}
+}
+
+func TestScore_ConcurrentSwaps_NoRace(t *testing.T) {
+	e, _ := newEngine()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			rs := scoring.DefaultRuleSet()
+			rs.Version = fmt.Sprintf("v%d", i)
+			e.SetRuleSet(rs)
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			_, _ = e.Assess(baseReq(fmt.Sprintf("race-%d", i)))
+		}(i)
+	}
+	wg.Wait()
+}
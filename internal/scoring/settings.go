@@ -0,0 +1,218 @@
+package scoring
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"lumina/fraud-api/internal/domain"
+	"lumina/fraud-api/internal/fx"
+	"lumina/fraud-api/internal/store"
+)
+
+// ─── Stored settings ──────────────────────────────────────────────────────────
+//
+// A rule set changed at runtime, uploaded through the admin API or reloaded
+// from its file, is published to the store as a setting, the way threshold
+// overrides are stored; so is the FX rate table. Every replica then scores
+// with them, and a restart does not fall back to the files the process was
+// started with. Refresh applies documents other replicas published.
+
+// ErrNotPublished wraps the store error that kept a rule set or rate table
+// from being published.
+var ErrNotPublished = errors.New("setting could not be stored")
+
+// errNoSettings is wrapped in ErrNotPublished by an engine whose store does
+// not keep settings.
+var errNoSettings = errors.New("store does not keep settings")
+
+// Setting names of the stored documents.
+const (
+	SettingRules       = "rules"
+	SettingShadowRules = "rules.shadow"
+	SettingFXRates     = "fx_rates"
+)
+
+// maxPublishAttempts bounds how often a publish is retried when another
+// writer stores the same setting in between.
+const maxPublishAttempts = 5
+
+// PublishRuleSet stores rs as the active rule set and swaps it in. If the
+// store write fails nothing changes. The caller must have validated rs and
+// must not modify it afterwards.
+func (e *Engine) PublishRuleSet(rs *RuleSet) error {
+	e.settingsMu.Lock()
+	defer e.settingsMu.Unlock()
+	if err := e.publish(SettingRules, rs); err != nil {
+		return err
+	}
+	e.SetRuleSet(rs)
+	return nil
+}
+
+// PublishChallenger stores rs as the shadow rule set and starts scoring
+// with it; a nil rs turns shadow mode off everywhere. If the store write
+// fails nothing changes.
+func (e *Engine) PublishChallenger(rs *RuleSet) error {
+	e.settingsMu.Lock()
+	defer e.settingsMu.Unlock()
+	if err := e.publish(SettingShadowRules, rs); err != nil {
+		return err
+	}
+	e.SetChallenger(rs)
+	return nil
+}
+
+// publish stores rs, or "null" for a nil rs, under name. Must be called with
+// settingsMu held.
+func (e *Engine) publish(name string, rs *RuleSet) error {
+	doc, err := json.Marshal(rs)
+	if err != nil {
+		return err
+	}
+	return e.put(name, func(json.RawMessage) ([]byte, error) { return doc, nil })
+}
+
+// put stores the document build makes from the one stored under name (nil
+// when there is none). If another writer stores name in between, build runs
+// again on the newer document. Errors from build are returned as they are.
+// Must be called with settingsMu held.
+func (e *Engine) put(name string, build func(stored json.RawMessage) ([]byte, error)) error {
+	if e.settings == nil {
+		return fmt.Errorf("%w: %s: %w", ErrNotPublished, name, errNoSettings)
+	}
+	for attempt := 0; attempt < maxPublishAttempts; attempt++ {
+		prev, _ := e.settings.GetSetting(name)
+		doc, err := build(prev.Document)
+		if err != nil {
+			return err
+		}
+		st, err := e.settings.PutSetting(domain.Setting{
+			Name: name, Document: doc, Revision: prev.Revision, UpdatedAt: time.Now().UTC(),
+		})
+		if errors.Is(err, store.ErrSettingConflict) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrNotPublished, name, err)
+		}
+		e.revisions[name] = st.Revision
+		return nil
+	}
+	return fmt.Errorf("%w: %s: %d conflicting writes", ErrNotPublished, name, maxPublishAttempts)
+}
+
+// UpdateRates applies change to a copy of the stored rate table, or of the
+// engine's own table if none is stored yet or the stored one is unusable,
+// stores the result and swaps it into the engine's table. It returns the table before and after the
+// change. An error from change is returned as it is and nothing changes;
+// a store error wraps ErrNotPublished. The engine must have a rate table.
+func (e *Engine) UpdateRates(change func(t *fx.Table) error) (before, after fx.RateFile, err error) {
+	e.settingsMu.Lock()
+	defer e.settingsMu.Unlock()
+	err = e.put(SettingFXRates, func(stored json.RawMessage) ([]byte, error) {
+		before = e.rates.Snapshot()
+		next := fx.New(e.rates.ReportingCurrency())
+		// A stored table this engine cannot use is replaced whole.
+		if f, err := e.parseRates(stored); err == nil && next.Replace(f.Rates) == nil {
+			before = f
+		} else if err := next.Replace(before.Rates); err != nil {
+			return nil, err
+		}
+		if err := change(next); err != nil {
+			return nil, err
+		}
+		after = next.Snapshot()
+		return json.Marshal(after)
+	})
+	if err != nil {
+		return before, after, err
+	}
+	return before, after, e.rates.Replace(after.Rates)
+}
+
+// parseRates decodes a stored rate table, which must convert into the
+// engine's reporting currency.
+func (e *Engine) parseRates(doc json.RawMessage) (fx.RateFile, error) {
+	var f fx.RateFile
+	if err := json.Unmarshal(doc, &f); err != nil {
+		return f, err
+	}
+	if !strings.EqualFold(f.ReportingCurrency, e.rates.ReportingCurrency()) {
+		return f, fmt.Errorf("converts into %s, not %s", f.ReportingCurrency, e.rates.ReportingCurrency())
+	}
+	return f, nil
+}
+
+// Refresh applies the documents stored since it last ran, whoever published
+// them. Until a document is stored, the engine keeps the rule sets and rates
+// it was started with. A stored rule set identical to the one in use is not
+// swapped in, so a file-loaded set keeps its Source for reloads. A stored
+// document that fails validation is reported once and skipped; the engine
+// keeps scoring with the rules and rates it has.
+func (e *Engine) Refresh() error {
+	e.settingsMu.Lock()
+	defer e.settingsMu.Unlock()
+	if e.settings == nil {
+		return nil
+	}
+	var errs []error
+	for _, name := range []string{SettingRules, SettingShadowRules, SettingFXRates} {
+		if name == SettingFXRates && e.rates == nil {
+			continue
+		}
+		st, ok := e.settings.GetSetting(name)
+		if !ok || st.Revision == e.revisions[name] {
+			continue
+		}
+		e.revisions[name] = st.Revision
+		var err error
+		if name == SettingFXRates {
+			err = e.refreshRates(st.Document)
+		} else {
+			err = e.refreshRules(name, st.Document)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("stored %s revision %d: %w", name, st.Revision, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// refreshRules swaps in a stored rule document unless it matches the set
+// already in use.
+func (e *Engine) refreshRules(name string, doc json.RawMessage) error {
+	var rs *RuleSet
+	if string(doc) != "null" {
+		parsed, err := ParseRuleSet(doc)
+		if err != nil {
+			return err
+		}
+		rs = parsed
+	}
+	current := &e.active
+	if name == SettingShadowRules {
+		current = &e.challenger
+	}
+	if cur := current.Load(); rs != nil && cur != nil && cur.hash == hashRuleSet(rs) {
+		return nil
+	}
+	switch {
+	case name == SettingShadowRules:
+		e.SetChallenger(rs)
+	case rs != nil:
+		e.SetRuleSet(rs)
+	}
+	return nil
+}
+
+// refreshRates swaps a stored rate table into the engine's table.
+func (e *Engine) refreshRates(doc json.RawMessage) error {
+	f, err := e.parseRates(doc)
+	if err != nil {
+		return err
+	}
+	return e.rates.Replace(f.Rates)
+}
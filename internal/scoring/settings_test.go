@@ -0,0 +1,138 @@
+package scoring_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"lumina/fraud-api/internal/domain"
+	"lumina/fraud-api/internal/fx"
+	"lumina/fraud-api/internal/scoring"
+	"lumina/fraud-api/internal/store"
+)
+
+func TestPublishRuleSet_ReachesEveryEngineOnTheStore(t *testing.T) {
+	s := store.New()
+	a, b := scoring.New(s, nil, nil), scoring.New(s, nil, nil)
+
+	rs := scoring.DefaultRuleSet()
+	rs.Version = "published-1"
+	if err := a.PublishRuleSet(rs); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.PublishChallenger(strictChallenger("shadow-1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.ActiveRules(); got.Version != "published-1" || got.Hash != a.ActiveRules().Hash {
+		t.Errorf("expected the published rule set, got %s (%s)", got.Version, got.Hash)
+	}
+	if got, on := b.ChallengerRules(); !on || got.Version != "shadow-1" {
+		t.Errorf("expected the published challenger, got %v %s", on, got.Version)
+	}
+
+	// A restart starts from the store, not from the rules it was given.
+	restarted := scoring.New(s, nil, nil)
+	if err := restarted.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if got := restarted.ActiveRules(); got.Version != "published-1" {
+		t.Errorf("expected a restarted engine to apply the stored rule set, got %s", got.Version)
+	}
+
+	if err := a.PublishChallenger(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if _, on := b.ChallengerRules(); on {
+		t.Error("expected turning shadow mode off to reach every engine")
+	}
+}
+
+func TestRefresh_InvalidStoredRules_KeepsCurrentSet(t *testing.T) {
+	s := store.New()
+	e := scoring.New(s, nil, nil)
+	before := e.ActiveRules()
+
+	if _, err := s.PutSetting(domain.Setting{
+		Name: scoring.SettingRules, Document: json.RawMessage(`{"rules":{}}`), UpdatedAt: time.Now(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Refresh(); err == nil {
+		t.Error("expected an error for a stored rule set without a version")
+	}
+	if got := e.ActiveRules(); got.Hash != before.Hash {
+		t.Errorf("an invalid stored rule set must not replace the active one, got %s", got.Version)
+	}
+	if err := e.Refresh(); err != nil {
+		t.Errorf("an invalid revision should be reported once, got %v", err)
+	}
+}
+
+func TestUpdateRates_ReachesEveryEngineOnTheStore(t *testing.T) {
+	s := store.New()
+	tableA, tableB := fx.New("USD"), fx.New("USD")
+	a, b := scoring.New(s, nil, tableA), scoring.New(s, nil, tableB)
+
+	brl := []fx.Rate{{Currency: "BRL", Date: "2026-10-01", Rate: 5.5}}
+	if _, _, err := a.UpdateRates(func(t *fx.Table) error { return t.Replace(brl) }); err != nil {
+		t.Fatal(err)
+	}
+	// b has not refreshed yet; its upsert must build on the stored table,
+	// not on its own empty one.
+	mxn := []fx.Rate{{Currency: "MXN", Date: "2026-10-01", Rate: 18}}
+	before, after, err := b.UpdateRates(func(t *fx.Table) error { return t.Upsert(mxn) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(before.Rates) != 1 || len(after.Rates) != 2 {
+		t.Fatalf("expected the upsert to extend the stored table, got %v -> %v", before.Rates, after.Rates)
+	}
+	if err := a.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	for name, table := range map[string]*fx.Table{"a": tableA, "b": tableB} {
+		if _, _, err := table.Convert(100, "MXN", time.Now()); err != nil {
+			t.Errorf("engine %s: %v", name, err)
+		}
+		if _, _, err := table.Convert(100, "BRL", time.Now()); err != nil {
+			t.Errorf("engine %s: %v", name, err)
+		}
+	}
+
+	// Invalid rates are rejected before anything is stored.
+	bad := []fx.Rate{{Currency: "ARS", Date: "yesterday", Rate: 900}}
+	if _, _, err := a.UpdateRates(func(t *fx.Table) error { return t.Upsert(bad) }); err == nil || errors.Is(err, scoring.ErrNotPublished) {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+	if st, _ := s.GetSetting(scoring.SettingFXRates); strings.Contains(string(st.Document), "ARS") {
+		t.Error("invalid rates must not be stored")
+	}
+}
+
+// readOnlyStore offers the engine's reads and nothing else.
+type readOnlyStore struct{ scoring.Store }
+
+func TestPublishRuleSet_StoreWithoutSettings_NotPublished(t *testing.T) {
+	e := scoring.New(readOnlyStore{store.New()}, nil, nil)
+	before := e.ActiveRules()
+
+	rs := scoring.DefaultRuleSet()
+	rs.Version = "unpublished"
+	if err := e.PublishRuleSet(rs); !errors.Is(err, scoring.ErrNotPublished) {
+		t.Fatalf("expected ErrNotPublished, got %v", err)
+	}
+	if got := e.ActiveRules(); got.Hash != before.Hash {
+		t.Errorf("a rule set that was not published must not be applied, got %s", got.Version)
+	}
+	if err := e.Refresh(); err != nil {
+		t.Errorf("expected nothing to refresh, got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package scoring
+
+import (
+	"lumina/fraud-api/internal/domain"
+)
+
+// ─── Shadow mode (champion / challenger) ──────────────────────────────────────
+//
+// A challenger rule set runs next to the active (champion) one on every
+// assessed transaction. Its score, level, recommendation and factors are
+// recorded on Transaction.Shadow so analysts can measure a rule change on live
+// traffic before promoting it. The challenger never affects the champion's
+// decision or webhook delivery.
+
+// SetChallenger starts scoring every transaction in shadow with rs as well as
+// the active rule set. A nil rs turns shadow mode off. The caller must have
+// validated rs and must not modify it afterwards.
+func (e *Engine) SetChallenger(rs *RuleSet) {
+	if rs == nil {
+		e.challenger.Store(nil)
+		return
+	}
+	e.challenger.Store(newActiveRules(rs))
+}
+
+// ChallengerRules describes the shadow rule set, if shadow mode is on.
+func (e *Engine) ChallengerRules() (RuleSetInfo, bool) {
+	c := e.challenger.Load()
+	if c == nil {
+		return RuleSetInfo{}, false
+	}
+	return c.info(), true
+}
+
+// ReloadChallenger re-reads the file the challenger was loaded from and
+// publishes it (see PublishChallenger).
+// It returns ErrNoRuleSource if shadow mode is off or the challenger was
+// uploaded rather than loaded from a file.
+func (e *Engine) ReloadChallenger() (*RuleSet, error) {
+	c := e.challenger.Load()
+	if c == nil || c.set.Source == "" {
+		return nil, ErrNoRuleSource
+	}
+	rs, err := LoadRuleSet(c.set.Source)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.PublishChallenger(rs); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// shadow scores req with the challenger, if any, under the same thresholds the
+// champion used so that any difference is attributable to the rules alone.
+func (e *Engine) shadow(req *domain.TransactionRequest, amount float64, thresholds domain.Thresholds) *domain.ShadowResult {
+	c := e.challenger.Load()
+	if c == nil {
+		return nil
+	}
+	score, factors, _ := e.score(req, c.set, amount)
+	recommendation, riskLevel := Recommend(score, thresholds)
+	return &domain.ShadowResult{
+		RuleSetVersion: c.set.Version,
+		RuleSetHash:    c.hash,
+		RiskScore:      score,
+		RiskLevel:      riskLevel,
+		Recommendation: recommendation,
+		Factors:        factors,
+	}
+}
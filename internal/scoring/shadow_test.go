@@ -0,0 +1,96 @@
+package scoring_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"lumina/fraud-api/internal/scoring"
+)
+
+// strictChallenger declines every first transaction outright.
+func strictChallenger(version string) *scoring.RuleSet {
+	rs := scoring.DefaultRuleSet()
+	rs.Version = version
+	rs.Rules.PurchaseBehaviour.FirstTransaction.Delta = 100
+	rs.Rules.PurchaseBehaviour.FirstTransaction.HighDelta = 100
+	return rs
+}
+
+func TestAssess_NoChallenger_NoShadowResult(t *testing.T) {
+	e, _ := newEngine()
+	if tx := assess(t, e, baseReq("shadow-off-001")); tx.Shadow != nil {
+		t.Errorf("expected no shadow result with shadow mode off, got %+v", tx.Shadow)
+	}
+}
+
+func TestAssess_Challenger_RecordsShadowWithoutChangingDecision(t *testing.T) {
+	e, _ := newEngine()
+	champion := assess(t, e, baseReq("shadow-base-001"))
+
+	e.SetChallenger(strictChallenger("challenger-1"))
+	tx := assess(t, e, baseReq("shadow-on-001"))
+
+	if tx.RiskScore != champion.RiskScore || tx.Recommendation != champion.Recommendation {
+		t.Errorf("challenger must not change the live decision: got %d/%s, want %d/%s",
+			tx.RiskScore, tx.Recommendation, champion.RiskScore, champion.Recommendation)
+	}
+	if tx.Shadow == nil {
+		t.Fatal("expected a shadow result")
+	}
+	if tx.Shadow.RuleSetVersion != "challenger-1" {
+		t.Errorf("expected shadow version challenger-1, got %q", tx.Shadow.RuleSetVersion)
+	}
+	if tx.Shadow.RuleSetHash == "" || tx.Shadow.RuleSetHash == tx.RuleSetHash {
+		t.Errorf("shadow hash should identify the challenger, got %q", tx.Shadow.RuleSetHash)
+	}
+	if tx.Shadow.RiskScore != 100 || tx.Shadow.Recommendation != "decline" {
+		t.Errorf("expected challenger to decline at 100, got %d/%s", tx.Shadow.RiskScore, tx.Shadow.Recommendation)
+	}
+}
+
+func TestSetChallenger_Nil_TurnsShadowModeOff(t *testing.T) {
+	e, _ := newEngine()
+	e.SetChallenger(strictChallenger("c"))
+	if _, on := e.ChallengerRules(); !on {
+		t.Fatal("expected shadow mode on")
+	}
+	e.SetChallenger(nil)
+	if _, on := e.ChallengerRules(); on {
+		t.Error("expected shadow mode off")
+	}
+	if tx := assess(t, e, baseReq("shadow-cleared-001")); tx.Shadow != nil {
+		t.Error("no shadow result expected after clearing the challenger")
+	}
+}
+
+func TestReloadChallenger_ReadsSourceFile(t *testing.T) {
+	e, _ := newEngine()
+	if _, err := e.ReloadChallenger(); !errors.Is(err, scoring.ErrNoRuleSource) {
+		t.Errorf("expected ErrNoRuleSource with shadow mode off, got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "shadow.json")
+	if err := os.WriteFile(path, []byte(`{"version": "s1"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rs, err := scoring.LoadRuleSet(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	e.SetChallenger(rs)
+
+	if err := os.WriteFile(path, []byte(`{"version": "s2"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.ReloadChallenger(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if info, _ := e.ChallengerRules(); info.Version != "s2" {
+		t.Errorf("expected s2 after reload, got %q", info.Version)
+	}
+	if v := e.ActiveRules().Version; v != "builtin" {
+		t.Errorf("reloading the challenger must not touch the champion, got %q", v)
+	}
+}
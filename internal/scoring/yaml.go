@@ -0,0 +1,260 @@
+package scoring
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ─── YAML rule files ──────────────────────────────────────────────────────────
+//
+// A rule document is nested mappings of scalars, so rule files written in
+// YAML are read with a converter for exactly that subset rather than a full
+// YAML library: block mappings indented with spaces, plain and quoted
+// scalars, comments, and a leading "---". Anything else, such as sequences,
+// flow collections, anchors, tags, block scalars or tabs in indentation, is
+// rejected with its line number instead of being guessed at. The result is
+// JSON, decoded and validated by ParseRuleSet like any other rule document.
+
+// yamlLine is one content line of a YAML document.
+type yamlLine struct {
+	num    int    // 1-based
+	indent int    // leading spaces
+	text   string // the line without its indentation
+}
+
+// yamlToJSON converts a YAML rule document to JSON.
+func yamlToJSON(data []byte) ([]byte, error) {
+	lines, err := yamlLines(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, errors.New("empty document")
+	}
+	var out bytes.Buffer
+	i, err := yamlMapping(lines, 0, lines[0].indent, &out)
+	if err != nil {
+		return nil, err
+	}
+	if i < len(lines) {
+		return nil, fmt.Errorf("line %d: indented less than the first line", lines[i].num)
+	}
+	return out.Bytes(), nil
+}
+
+// yamlLines splits a document into content lines, dropping blank lines,
+// comment lines and the document markers.
+func yamlLines(data []byte) ([]yamlLine, error) {
+	var lines []yamlLine
+	for n, raw := range strings.Split(strings.TrimPrefix(string(data), "\ufeff"), "\n") {
+		num := n + 1
+		raw = strings.TrimRight(raw, " \t\r")
+		text := strings.TrimLeft(raw, " ")
+		switch {
+		case text == "" || text[0] == '#':
+			continue
+		case text[0] == '\t':
+			return nil, fmt.Errorf("line %d: tabs cannot indent YAML", num)
+		case text == "---" || strings.HasPrefix(text, "--- #"):
+			if len(lines) > 0 {
+				return nil, fmt.Errorf("line %d: only one document is allowed", num)
+			}
+			continue
+		case text == "...":
+			return lines, nil
+		case text[0] == '%':
+			return nil, fmt.Errorf("line %d: directives are not supported", num)
+		}
+		lines = append(lines, yamlLine{num: num, indent: len(raw) - len(text), text: text})
+	}
+	return lines, nil
+}
+
+// yamlMapping writes the block mapping starting at lines[i], whose keys are
+// indented by indent, as a JSON object. It returns the index of the first
+// line after the mapping.
+func yamlMapping(lines []yamlLine, i, indent int, out *bytes.Buffer) (int, error) {
+	seen := make(map[string]bool)
+	out.WriteByte('{')
+	for i < len(lines) && lines[i].indent >= indent {
+		l := lines[i]
+		if l.indent > indent {
+			return i, fmt.Errorf("line %d: unexpected indentation", l.num)
+		}
+		key, value, err := yamlEntry(l)
+		if err != nil {
+			return i, err
+		}
+		if seen[key] {
+			return i, fmt.Errorf("line %d: duplicate key %q", l.num, key)
+		}
+		if len(seen) > 0 {
+			out.WriteByte(',')
+		}
+		seen[key] = true
+		k, _ := json.Marshal(key)
+		out.Write(k)
+		out.WriteByte(':')
+
+		i++
+		switch {
+		case value != "":
+			scalar, err := yamlScalar(value)
+			if err != nil {
+				return i, fmt.Errorf("line %d: %w", l.num, err)
+			}
+			out.Write(scalar)
+		case i < len(lines) && lines[i].indent > indent:
+			if i, err = yamlMapping(lines, i, lines[i].indent, out); err != nil {
+				return i, err
+			}
+		default:
+			out.WriteString("null")
+		}
+	}
+	out.WriteByte('}')
+	return i, nil
+}
+
+// yamlEntry splits a mapping line into its key and its value, with any
+// trailing comment removed. The value is empty when a nested mapping, or
+// nothing, follows.
+func yamlEntry(l yamlLine) (key, value string, err error) {
+	text, quotedKey := l.text, false
+	switch text[0] {
+	case '-':
+		if len(text) == 1 || text[1] == ' ' {
+			return "", "", fmt.Errorf("line %d: sequences are not supported", l.num)
+		}
+	case '"', '\'':
+		quoted, rest, err := yamlQuoted(text)
+		if err != nil {
+			return "", "", fmt.Errorf("line %d: %w", l.num, err)
+		}
+		if !strings.HasPrefix(rest, ":") {
+			return "", "", fmt.Errorf("line %d: expected ':' after the key", l.num)
+		}
+		key, text, quotedKey = quoted, rest[1:], true
+	case '?', '[', '{', '&', '*', '!', '|', '>', '@', '`':
+		return "", "", fmt.Errorf("line %d: %q is not supported in rule files", l.num, text[0])
+	}
+	if !quotedKey {
+		colon := strings.Index(text, ": ")
+		if colon < 0 && strings.HasSuffix(text, ":") {
+			colon = len(text) - 1
+		}
+		if colon <= 0 {
+			return "", "", fmt.Errorf("line %d: expected 'key: value'", l.num)
+		}
+		key, text = text[:colon], text[colon+1:]
+	}
+	if text != "" && text[0] != ' ' {
+		return "", "", fmt.Errorf("line %d: expected a space after ':'", l.num)
+	}
+
+	value = strings.TrimLeft(text, " ")
+	switch {
+	case value == "" || value[0] == '#':
+		return key, "", nil
+	case value[0] == '"' || value[0] == '\'':
+		_, rest, err := yamlQuoted(value)
+		if err != nil {
+			return "", "", fmt.Errorf("line %d: %w", l.num, err)
+		}
+		if rest = strings.TrimLeft(rest, " "); rest != "" && rest[0] != '#' {
+			return "", "", fmt.Errorf("line %d: unexpected text after the quoted value", l.num)
+		}
+		return key, strings.TrimSuffix(value, rest), nil
+	}
+	if hash := strings.Index(value, " #"); hash >= 0 {
+		value = strings.TrimRight(value[:hash], " ")
+	}
+	if strings.Contains(value, ": ") {
+		return "", "", fmt.Errorf("line %d: nested mappings must start on their own line", l.num)
+	}
+	return key, value, nil
+}
+
+// yamlQuoted decodes the quoted scalar text starts with, and returns the
+// text after its closing quote.
+func yamlQuoted(text string) (value, rest string, err error) {
+	if text[0] == '\'' {
+		var b strings.Builder
+		for i := 1; i < len(text); i++ {
+			if text[i] != '\'' {
+				b.WriteByte(text[i])
+				continue
+			}
+			if i+1 < len(text) && text[i+1] == '\'' {
+				b.WriteByte('\'')
+				i++
+				continue
+			}
+			return b.String(), text[i+1:], nil
+		}
+		return "", "", errors.New("unterminated quoted string")
+	}
+	for i := 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '"':
+			// Escapes rule files need (\", \\, \n, \t, \uXXXX) read the
+			// same in JSON.
+			if err := json.Unmarshal([]byte(text[:i+1]), &value); err != nil {
+				return "", "", fmt.Errorf("unsupported escape in %s", text[:i+1])
+			}
+			return value, text[i+1:], nil
+		}
+	}
+	return "", "", errors.New("unterminated quoted string")
+}
+
+var (
+	yamlInt   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	yamlFloat = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+)
+
+// yamlScalar converts a scalar to its JSON form, typed as YAML's core schema
+// types it: booleans, null, integers and floats; everything else, like a
+// "10m" window, is a string.
+func yamlScalar(value string) ([]byte, error) {
+	switch value {
+	case "true", "True", "TRUE":
+		return []byte("true"), nil
+	case "false", "False", "FALSE":
+		return []byte("false"), nil
+	case "null", "Null", "NULL", "~":
+		return []byte("null"), nil
+	}
+	switch {
+	case value[0] == '"' || value[0] == '\'':
+		s, _, err := yamlQuoted(value)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(s)
+	case yamlInt.MatchString(value):
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("integer %s out of range", value)
+		}
+		return []byte(strconv.FormatInt(n, 10)), nil
+	case yamlFloat.MatchString(value):
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("number %s out of range", value)
+		}
+		return []byte(strconv.FormatFloat(f, 'g', -1, 64)), nil
+	case value == "-" || strings.HasPrefix(value, "- "):
+		return nil, errors.New("sequences are not supported")
+	case strings.ContainsAny(value[:1], "[{&*!|>@`%"):
+		return nil, fmt.Errorf("%q is not supported in rule files", value[0])
+	}
+	return json.Marshal(value)
+}
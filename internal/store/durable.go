@@ -0,0 +1,901 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"lumina/fraud-api/internal/domain"
+)
+
+// Defaults for DurableOptions zero values.
+const (
+	DefaultSyncInterval     = time.Second
+	DefaultSnapshotEvery    = 10000
+	DefaultSnapshotInterval = 10 * time.Minute
+)
+
+// snapshotFile holds the latest full copy of the store.
+const snapshotFile = "snapshot.json"
+
+// WAL operations. Records carry the effect of a mutation — the stored
+// transaction, the generated threshold change ID — never its inputs, so
+// replay reproduces the state exactly instead of re-deriving it.
+const (
+	opSaveTransaction       = "transaction.save"
+	opSaveTransactions      = "transaction.save_batch"
+	opReplaceTransaction    = "transaction.replace" // outcomes
+	opClaimReview           = "review.claim"
+	opReleaseReview         = "review.release"
+	opDecideReview          = "review.decide"
+	opSaveBlocklist         = "blocklist.save"
+	opDeleteBlocklist       = "blocklist.delete"
+	opSaveWebhook           = "webhook.save"
+	opDeleteWebhook         = "webhook.delete"
+	opSetThresholds         = "thresholds.set"
+	opDeleteThresholds      = "thresholds.delete"
+	opPutSetting            = "setting.put"
+	opCreateAPIKey          = "api_key.create"
+	opUpdateAPIKey          = "api_key.update"
+	opAppendAudit           = "audit.append"
+	opPrune                 = "retention.prune"
+	opSaveIdempotencyKey    = "idempotency.save"
+	opReleaseIdempotencyKey = "idempotency.release"
+)
+
+// DurableOptions configures a Durable store. Zero values pick the defaults.
+type DurableOptions struct {
+	Dir              string        // data directory; created if missing
+	Sync             SyncPolicy    // default: SyncInterval
+	SyncInterval     time.Duration // default: DefaultSyncInterval
+	SnapshotEvery    int           // records between snapshots; negative disables
+	SnapshotInterval time.Duration // time between snapshots; negative disables
+}
+
+// Durable is a Memory store made crash-safe by a write-ahead log and
+// periodic snapshots in a data directory.
+//
+// Every mutation is applied in memory and then appended to the WAL, both
+// under one lock so the log order is the apply order. Reads are served by
+// the embedded Memory untouched. On open, the latest snapshot is loaded and
+// the WAL after it replayed, restoring the primary map, every secondary index
+// and cardsByIP exactly. A snapshot starts a new WAL segment and deletes the
+// segments it covers, which keeps the log from growing without bound.
+//
+// A failed WAL write is returned where the method can return an error and
+// logged otherwise. The in-memory change stands, but will not survive a
+// restart. Only one process may use a data directory at a time.
+type Durable struct {
+	*Memory
+
+	opts DurableOptions
+
+	mu            sync.Mutex // serialises mutations and WAL appends
+	wal           *os.File
+	seq           uint64 // last sequence number written
+	sinceSnapshot int
+	closed        bool
+
+	snapMu sync.Mutex // one snapshot at a time
+
+	kick chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Durable satisfies the full Store interface.
+var _ Store = (*Durable)(nil)
+
+// OpenDurable recovers the store in opts.Dir, or creates an empty one, and
+// starts its background sync and snapshot loop.
+func OpenDurable(opts DurableOptions) (*Durable, error) {
+	if opts.Dir == "" {
+		return nil, errors.New("data directory is required")
+	}
+	if opts.Sync == "" {
+		opts.Sync = SyncInterval
+	}
+	if _, err := ParseSyncPolicy(string(opts.Sync)); err != nil {
+		return nil, err
+	}
+	if opts.SyncInterval <= 0 {
+		opts.SyncInterval = DefaultSyncInterval
+	}
+	if opts.SnapshotEvery == 0 {
+		opts.SnapshotEvery = DefaultSnapshotEvery
+	}
+	if opts.SnapshotInterval == 0 {
+		opts.SnapshotInterval = DefaultSnapshotInterval
+	}
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	d := &Durable{
+		Memory: New(),
+		opts:   opts,
+		kick:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if err := d.recover(); err != nil {
+		return nil, err
+	}
+	if err := d.startSegment(); err != nil {
+		return nil, err
+	}
+	go d.loop()
+	return d, nil
+}
+
+// Close stops the background loop and syncs and closes the WAL. It does not
+// snapshot: the next open replays the log.
+func (d *Durable) Close() error {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil
+	}
+	d.closed = true
+	d.mu.Unlock()
+
+	close(d.stop)
+	<-d.done
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return errors.Join(d.wal.Sync(), d.wal.Close())
+}
+
+// ─── Mutations ────────────────────────────────────────────────────────────────
+
+// SaveTransaction persists and logs a transaction.
+func (d *Durable) SaveTransaction(tx *domain.Transaction) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.Memory.SaveTransaction(tx); err != nil {
+		return err
+	}
+	return d.append(opSaveTransaction, tx)
+}
+
+// SaveTransactions persists a batch and logs it as one record, so recovery
+// replays all of it or none of it.
+func (d *Durable) SaveTransactions(txs []*domain.Transaction) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.Memory.SaveTransactions(txs); err != nil {
+		return err
+	}
+	return d.append(opSaveTransactions, txs)
+}
+
+// AddOutcome appends and logs an outcome.
+func (d *Durable) AddOutcome(o domain.Outcome) (*domain.Transaction, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	tx, err := d.Memory.AddOutcome(o)
+	if err != nil {
+		return nil, err
+	}
+	return tx, d.append(opReplaceTransaction, tx)
+}
+
+// ClaimReview claims a case and logs the claim.
+func (d *Durable) ClaimReview(txID, analyst string, now time.Time, ttl time.Duration) (domain.ReviewClaim, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	claim, err := d.Memory.ClaimReview(txID, analyst, now, ttl)
+	if err != nil {
+		return claim, err
+	}
+	return claim, d.append(opClaimReview, claim)
+}
+
+// ReleaseReview releases a claim and logs it.
+func (d *Durable) ReleaseReview(txID, analyst string, now time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.Memory.ReleaseReview(txID, analyst, now); err != nil {
+		return err
+	}
+	return d.append(opReleaseReview, txID)
+}
+
+// DecideReview records and logs an analyst decision.
+func (d *Durable) DecideReview(txID string, dec domain.ReviewDecision) (*domain.Transaction, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	tx, err := d.Memory.DecideReview(txID, dec)
+	if err != nil {
+		return nil, err
+	}
+	return tx, d.append(opDecideReview, tx)
+}
+
+// SaveBlocklistEntry upserts and logs a list entry.
+func (d *Durable) SaveBlocklistEntry(entry *domain.BlocklistEntry) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Memory.SaveBlocklistEntry(entry)
+	d.appendOrLog(opSaveBlocklist, entry)
+}
+
+// DeleteBlocklistEntry removes and logs a list entry.
+func (d *Durable) DeleteBlocklistEntry(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.Memory.DeleteBlocklistEntry(id) {
+		return false
+	}
+	d.appendOrLog(opDeleteBlocklist, id)
+	return true
+}
+
+// SaveWebhook upserts and logs a webhook.
+func (d *Durable) SaveWebhook(wh *domain.WebhookConfig) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Memory.SaveWebhook(wh)
+	d.appendOrLog(opSaveWebhook, wh)
+}
+
+// DeleteWebhook removes and logs a webhook.
+func (d *Durable) DeleteWebhook(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.Memory.DeleteWebhook(id) {
+		return false
+	}
+	d.appendOrLog(opDeleteWebhook, id)
+	return true
+}
+
+// CreateAPIKey stores and logs a new API key.
+func (d *Durable) CreateAPIKey(k *domain.APIKey) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.Memory.CreateAPIKey(k); err != nil {
+		return err
+	}
+	return d.append(opCreateAPIKey, k)
+}
+
+// UpdateAPIKey replaces and logs an API key.
+func (d *Durable) UpdateAPIKey(k *domain.APIKey) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.Memory.UpdateAPIKey(k); err != nil {
+		return err
+	}
+	return d.append(opUpdateAPIKey, k)
+}
+
+// AppendAuditEntry stores and logs an audit entry.
+func (d *Durable) AppendAuditEntry(e domain.AuditEntry) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.Memory.AppendAuditEntry(e); err != nil {
+		return err
+	}
+	return d.append(opAppendAudit, e)
+}
+
+// Prune applies retention in memory and logs the cutoff; replaying it prunes
+// the same records.
+func (d *Durable) Prune(cutoff time.Time) (PruneStats, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	stats, err := d.Memory.Prune(cutoff)
+	if err != nil || stats == (PruneStats{}) {
+		return stats, err
+	}
+	return stats, d.append(opPrune, cutoff)
+}
+
+// SaveIdempotencyKey stores and logs an idempotency key.
+func (d *Durable) SaveIdempotencyKey(k domain.IdempotencyKey) (domain.IdempotencyKey, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	saved, err := d.Memory.SaveIdempotencyKey(k)
+	if err != nil {
+		return saved, err
+	}
+	return saved, d.append(opSaveIdempotencyKey, saved)
+}
+
+// ReleaseIdempotencyKey releases and logs an idempotency key.
+func (d *Durable) ReleaseIdempotencyKey(k domain.IdempotencyKey) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.Memory.ReleaseIdempotencyKey(k); err != nil {
+		return err
+	}
+	return d.append(opReleaseIdempotencyKey, k)
+}
+
+// thresholdRecord is the payload of opSetThresholds and opDeleteThresholds.
+type thresholdRecord struct {
+	Config *domain.ThresholdConfig `json:"config,omitempty"` // nil on delete
+	Change domain.ThresholdChange  `json:"change"`
+}
+
+// SetThresholds upserts an override and logs it with its change record.
+func (d *Durable) SetThresholds(cfg *domain.ThresholdConfig, actor string) domain.ThresholdChange {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	change := d.Memory.SetThresholds(cfg, actor)
+	d.appendOrLog(opSetThresholds, thresholdRecord{Config: cfg, Change: change})
+	return change
+}
+
+// DeleteThresholds removes an override and logs it with its change record.
+func (d *Durable) DeleteThresholds(scope, key, actor string) (domain.ThresholdChange, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	change, found := d.Memory.DeleteThresholds(scope, key, actor)
+	if found {
+		d.appendOrLog(opDeleteThresholds, thresholdRecord{Change: change})
+	}
+	return change, found
+}
+
+// PutSetting stores and logs a setting with its new revision.
+func (d *Durable) PutSetting(s domain.Setting) (domain.Setting, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	stored, err := d.Memory.PutSetting(s)
+	if err != nil {
+		return stored, err
+	}
+	return stored, d.append(opPutSetting, stored)
+}
+
+// ─── WAL writes ───────────────────────────────────────────────────────────────
+
+// append logs one mutation. Must be called with d.mu held.
+func (d *Durable) append(op string, v any) error {
+	if d.closed {
+		return errors.New("store is closed")
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("wal %s: %w", op, err)
+	}
+	line, err := encodeRecord(walRecord{Seq: d.seq + 1, Op: op, Data: data})
+	if err != nil {
+		return fmt.Errorf("wal %s: %w", op, err)
+	}
+	if _, err := d.wal.Write(line); err != nil {
+		return fmt.Errorf("wal %s: %w", op, err)
+	}
+	if d.opts.Sync == SyncAlways {
+		if err := d.wal.Sync(); err != nil {
+			return fmt.Errorf("wal %s: %w", op, err)
+		}
+	}
+	d.seq++
+	d.sinceSnapshot++
+	if d.opts.SnapshotEvery > 0 && d.sinceSnapshot >= d.opts.SnapshotEvery {
+		select {
+		case d.kick <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// appendOrLog is append for mutations whose signature has no error return.
+func (d *Durable) appendOrLog(op string, v any) {
+	if err := d.append(op, v); err != nil {
+		slog.Error("wal append failed; change will not survive a restart", "op", op, "error", err)
+	}
+}
+
+// startSegment opens a fresh segment for records after d.seq.
+// Must be called with d.mu held, or before the store is shared.
+func (d *Durable) startSegment() error {
+	f, err := os.OpenFile(filepath.Join(d.opts.Dir, segmentName(d.seq+1)), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := syncDir(d.opts.Dir); err != nil {
+		f.Close()
+		return err
+	}
+	if d.wal != nil {
+		if err := errors.Join(d.wal.Sync(), d.wal.Close()); err != nil {
+			slog.Error("closing wal segment", "error", err)
+		}
+	}
+	d.wal = f
+	return nil
+}
+
+// loop runs interval fsyncs and scheduled snapshots until Close.
+func (d *Durable) loop() {
+	defer close(d.done)
+
+	var syncTick, snapTick <-chan time.Time
+	if d.opts.Sync == SyncInterval {
+		t := time.NewTicker(d.opts.SyncInterval)
+		defer t.Stop()
+		syncTick = t.C
+	}
+	if d.opts.SnapshotInterval > 0 {
+		t := time.NewTicker(d.opts.SnapshotInterval)
+		defer t.Stop()
+		snapTick = t.C
+	}
+
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-syncTick:
+			d.mu.Lock()
+			err := d.wal.Sync()
+			d.mu.Unlock()
+			if err != nil {
+				slog.Error("wal sync failed", "error", err)
+			}
+		case <-snapTick:
+			d.snapshotIfDirty()
+		case <-d.kick:
+			d.snapshotIfDirty()
+		}
+	}
+}
+
+func (d *Durable) snapshotIfDirty() {
+	d.mu.Lock()
+	dirty := d.sinceSnapshot > 0 && !d.closed
+	d.mu.Unlock()
+	if !dirty {
+		return
+	}
+	if err := d.Snapshot(); err != nil {
+		slog.Error("snapshot failed", "dir", d.opts.Dir, "error", err)
+	}
+}
+
+// ─── Snapshots ────────────────────────────────────────────────────────────────
+
+// memoryState is the on-disk form of a Memory store. Entity indexes are saved
+// as ID lists in time order; a restore re-dates them from the transactions
+// rather than re-indexing, so ties keep their original order.
+type memoryState struct {
+	Seq              uint64                             `json:"seq"` // last WAL record included
+	Transactions     map[string]*domain.Transaction     `json:"transactions"`
+	Blocklist        map[string]*domain.BlocklistEntry  `json:"blocklist"`
+	Webhooks         map[string]*domain.WebhookConfig   `json:"webhooks"`
+	TxByEmail        map[string][]string                `json:"tx_by_email"`
+	TxByIP           map[string][]string                `json:"tx_by_ip"`
+	TxByDevice       map[string][]string                `json:"tx_by_device"`
+	TxByBIN          map[string][]string                `json:"tx_by_bin"`
+	CardsSeenAt      map[string]map[string]time.Time    `json:"cards_seen_at"`
+	Thresholds       map[string]*domain.ThresholdConfig `json:"thresholds"`
+	ThresholdChanges []domain.ThresholdChange           `json:"threshold_changes"`
+	Settings         map[string]domain.Setting          `json:"settings"`
+	ReviewClaims     map[string]domain.ReviewClaim      `json:"review_claims"`
+	IdempotencyKeys  map[string]domain.IdempotencyKey   `json:"idempotency_keys"`
+	APIKeys          map[string]*domain.APIKey          `json:"api_keys"`
+	AuditLog         []domain.AuditEntry                `json:"audit_log"`
+}
+
+// Snapshot writes the full state to disk and deletes the WAL segments it
+// covers. Writes pause only while the state is copied, not while it is saved.
+func (d *Durable) Snapshot() error {
+	d.snapMu.Lock()
+	defer d.snapMu.Unlock()
+
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return errors.New("store is closed")
+	}
+	state := d.Memory.exportState()
+	state.Seq = d.seq
+	if err := d.startSegment(); err != nil {
+		d.mu.Unlock()
+		return err
+	}
+	d.sinceSnapshot = 0
+	d.mu.Unlock()
+
+	if err := writeSnapshot(d.opts.Dir, state); err != nil {
+		return err
+	}
+
+	segs, err := listSegments(d.opts.Dir)
+	if err != nil {
+		return err
+	}
+	for _, seg := range segs {
+		if seg.firstSeq <= state.Seq {
+			if err := os.Remove(seg.path); err != nil {
+				return err
+			}
+		}
+	}
+	slog.Info("store snapshot written", "dir", d.opts.Dir, "seq", state.Seq)
+	return nil
+}
+
+func writeSnapshot(dir string, state memoryState) error {
+	tmp, err := os.CreateTemp(dir, snapshotFile+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op after the rename
+
+	enc := json.NewEncoder(tmp)
+	if err := errors.Join(enc.Encode(state), tmp.Sync(), tmp.Close()); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, snapshotFile)); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// exportState copies the store's maps so they can be encoded without a lock.
+// Stored values are never mutated in place, so sharing them is safe.
+func (s *Memory) exportState() memoryState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	state := memoryState{
+		Transactions:     make(map[string]*domain.Transaction, len(s.transactions)),
+		Blocklist:        make(map[string]*domain.BlocklistEntry, len(s.blocklist)),
+		Webhooks:         make(map[string]*domain.WebhookConfig, len(s.webhooks)),
+		TxByEmail:        copyIndex(s.txByEmail),
+		TxByIP:           copyIndex(s.txByIP),
+		TxByDevice:       copyIndex(s.txByDevice),
+		TxByBIN:          copyIndex(s.txByBIN),
+		CardsSeenAt:      make(map[string]map[string]time.Time, len(s.cardsByIP)),
+		Thresholds:       make(map[string]*domain.ThresholdConfig, len(s.thresholds)),
+		ThresholdChanges: append([]domain.ThresholdChange(nil), s.thresholdChanges...),
+		Settings:         make(map[string]domain.Setting, len(s.settings)),
+		ReviewClaims:     make(map[string]domain.ReviewClaim, len(s.reviewClaims)),
+		IdempotencyKeys:  make(map[string]domain.IdempotencyKey, len(s.idempotencyKeys)),
+		APIKeys:          make(map[string]*domain.APIKey, len(s.apiKeys)),
+		AuditLog:         append([]domain.AuditEntry(nil), s.auditLog...),
+	}
+	for k, v := range s.transactions {
+		state.Transactions[k] = v
+	}
+	for k, v := range s.blocklist {
+		state.Blocklist[k] = v
+	}
+	for k, v := range s.webhooks {
+		state.Webhooks[k] = v
+	}
+	for ip, bins := range s.cardsByIP {
+		c := make(map[string]time.Time, len(bins))
+		for b, seen := range bins {
+			c[b] = seen
+		}
+		state.CardsSeenAt[ip] = c
+	}
+	for k, v := range s.thresholds {
+		state.Thresholds[k] = v
+	}
+	for k, v := range s.settings {
+		state.Settings[k] = v
+	}
+	for k, v := range s.reviewClaims {
+		state.ReviewClaims[k] = v
+	}
+	for k, v := range s.idempotencyKeys {
+		state.IdempotencyKeys[k] = v
+	}
+	for k, v := range s.apiKeys {
+		state.APIKeys[k] = v
+	}
+	return state
+}
+
+func copyIndex(idx map[string]timeIndex) map[string][]string {
+	c := make(map[string][]string, len(idx))
+	for k, entries := range idx {
+		c[k] = entries.ids()
+	}
+	return c
+}
+
+// restoreIndex rebuilds an entity index from snapshot ID lists, which are
+// already in time order. IDs with no stored transaction are dropped. Must be
+// called with the write lock held, after transactions are loaded.
+func (s *Memory) restoreIndex(saved map[string][]string) map[string]timeIndex {
+	idx := make(map[string]timeIndex, len(saved))
+	for k, ids := range saved {
+		entries := make(timeIndex, 0, len(ids))
+		for _, id := range ids {
+			if tx, ok := s.transactions[id]; ok {
+				entries = append(entries, indexEntry{at: tx.Timestamp, id: id})
+			}
+		}
+		if len(entries) > 0 {
+			idx[k] = entries
+		}
+	}
+	return idx
+}
+
+// timeIndexOf rebuilds the all-transactions time index, which snapshots do
+// not store. Ties are ordered by ID. Must be called with the write lock held,
+// after transactions are loaded.
+func (s *Memory) timeIndexOf() timeIndex {
+	idx := make(timeIndex, 0, len(s.transactions))
+	for id, tx := range s.transactions {
+		idx = append(idx, indexEntry{at: tx.Timestamp, id: id})
+	}
+	sort.Slice(idx, func(i, j int) bool {
+		if !idx[i].at.Equal(idx[j].at) {
+			return idx[i].at.Before(idx[j].at)
+		}
+		return idx[i].id < idx[j].id
+	})
+	return idx
+}
+
+// importState replaces the store's contents with a snapshot.
+func (s *Memory) importState(state memoryState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fresh := New()
+	s.transactions = orEmpty(state.Transactions, fresh.transactions)
+	s.blocklist = orEmpty(state.Blocklist, fresh.blocklist)
+	s.webhooks = orEmpty(state.Webhooks, fresh.webhooks)
+	s.txByTime = s.timeIndexOf()
+	s.txByEmail = s.restoreIndex(state.TxByEmail)
+	s.txByIP = s.restoreIndex(state.TxByIP)
+	s.txByDevice = s.restoreIndex(state.TxByDevice)
+	s.txByBIN = s.restoreIndex(state.TxByBIN)
+	s.relink()
+	s.emailsByEntity = fresh.emailsByEntity
+	s.fraudByEmail = fresh.fraudByEmail
+	for _, tx := range s.transactions {
+		s.countEmail(tx)
+		s.flagFraud(tx)
+	}
+	s.cardsByIP = orEmpty(state.CardsSeenAt, fresh.cardsByIP)
+	s.thresholds = orEmpty(state.Thresholds, fresh.thresholds)
+	s.thresholdChanges = state.ThresholdChanges
+	s.settings = orEmpty(state.Settings, fresh.settings)
+	s.reviewClaims = orEmpty(state.ReviewClaims, fresh.reviewClaims)
+	s.idempotencyKeys = orEmpty(state.IdempotencyKeys, fresh.idempotencyKeys)
+	s.apiKeys, s.apiKeysByHash = fresh.apiKeys, fresh.apiKeysByHash
+	for _, k := range state.APIKeys {
+		s.putAPIKey(k)
+	}
+	s.auditLog = state.AuditLog
+}
+
+func orEmpty[M ~map[K]V, K comparable, V any](m, empty M) M {
+	if m == nil {
+		return empty
+	}
+	return m
+}
+
+// ─── Recovery ─────────────────────────────────────────────────────────────────
+
+// recover loads the snapshot, if any, and replays the WAL after it.
+func (d *Durable) recover() error {
+	data, err := os.ReadFile(filepath.Join(d.opts.Dir, snapshotFile))
+	switch {
+	case err == nil:
+		var state memoryState
+		if err := json.Unmarshal(data, &state); err != nil {
+			return fmt.Errorf("read snapshot: %w", err)
+		}
+		d.Memory.importState(state)
+		d.seq = state.Seq
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+	snapshotSeq := d.seq
+
+	segs, err := listSegments(d.opts.Dir)
+	if err != nil {
+		return err
+	}
+	var replayed int
+	for i, seg := range segs {
+		torn, err := readSegment(seg.path, i == len(segs)-1, func(rec walRecord) error {
+			if rec.Seq <= d.seq {
+				return nil // already in the snapshot
+			}
+			if rec.Seq != d.seq+1 {
+				return fmt.Errorf("%w: expected seq %d, found %d", errCorruptWAL, d.seq+1, rec.Seq)
+			}
+			if err := d.Memory.replay(rec); err != nil {
+				return fmt.Errorf("replay seq %d (%s): %w", rec.Seq, rec.Op, err)
+			}
+			d.seq = rec.Seq
+			replayed++
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("recover %s: %w", d.opts.Dir, err)
+		}
+		if torn {
+			slog.Warn("discarded a torn record at the end of the wal", "segment", filepath.Base(seg.path))
+		}
+	}
+	d.sinceSnapshot = replayed
+	if snapshotSeq > 0 || replayed > 0 {
+		slog.Info("store recovered", "dir", d.opts.Dir, "snapshot_seq", snapshotSeq, "replayed", replayed)
+	}
+	return nil
+}
+
+// replay applies one WAL record to the store.
+func (s *Memory) replay(rec walRecord) error {
+	switch rec.Op {
+	case opSaveTransaction:
+		var tx domain.Transaction
+		if err := json.Unmarshal(rec.Data, &tx); err != nil {
+			return err
+		}
+		return s.SaveTransaction(&tx)
+
+	case opSaveTransactions:
+		var txs []*domain.Transaction
+		if err := json.Unmarshal(rec.Data, &txs); err != nil {
+			return err
+		}
+		return s.SaveTransactions(txs)
+
+	case opReplaceTransaction, opDecideReview:
+		var tx domain.Transaction
+		if err := json.Unmarshal(rec.Data, &tx); err != nil {
+			return err
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, exists := s.transactions[tx.TransactionID]; !exists {
+			return ErrTransactionNotFound
+		}
+		s.transactions[tx.TransactionID] = &tx
+		s.flagFraud(&tx)
+		if rec.Op == opDecideReview {
+			delete(s.reviewClaims, tx.TransactionID)
+		}
+		return nil
+
+	case opClaimReview:
+		var c domain.ReviewClaim
+		if err := json.Unmarshal(rec.Data, &c); err != nil {
+			return err
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.reviewClaims[c.TransactionID] = c
+		return nil
+
+	case opReleaseReview:
+		var id string
+		if err := json.Unmarshal(rec.Data, &id); err != nil {
+			return err
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.reviewClaims, id)
+		return nil
+
+	case opSaveBlocklist:
+		var e domain.BlocklistEntry
+		if err := json.Unmarshal(rec.Data, &e); err != nil {
+			return err
+		}
+		s.SaveBlocklistEntry(&e)
+		return nil
+
+	case opDeleteBlocklist:
+		var id string
+		if err := json.Unmarshal(rec.Data, &id); err != nil {
+			return err
+		}
+		s.DeleteBlocklistEntry(id)
+		return nil
+
+	case opSaveWebhook:
+		var wh domain.WebhookConfig
+		if err := json.Unmarshal(rec.Data, &wh); err != nil {
+			return err
+		}
+		s.SaveWebhook(&wh)
+		return nil
+
+	case opDeleteWebhook:
+		var id string
+		if err := json.Unmarshal(rec.Data, &id); err != nil {
+			return err
+		}
+		s.DeleteWebhook(id)
+		return nil
+
+	case opCreateAPIKey:
+		var k domain.APIKey
+		if err := json.Unmarshal(rec.Data, &k); err != nil {
+			return err
+		}
+		return s.CreateAPIKey(&k)
+
+	case opUpdateAPIKey:
+		var k domain.APIKey
+		if err := json.Unmarshal(rec.Data, &k); err != nil {
+			return err
+		}
+		return s.UpdateAPIKey(&k)
+
+	case opAppendAudit:
+		var e domain.AuditEntry
+		if err := json.Unmarshal(rec.Data, &e); err != nil {
+			return err
+		}
+		return s.AppendAuditEntry(e)
+
+	case opPrune:
+		var cutoff time.Time
+		if err := json.Unmarshal(rec.Data, &cutoff); err != nil {
+			return err
+		}
+		_, err := s.Prune(cutoff)
+		return err
+
+	case opSaveIdempotencyKey:
+		var k domain.IdempotencyKey
+		if err := json.Unmarshal(rec.Data, &k); err != nil {
+			return err
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.idempotencyKeys[k.Key] = k
+		return nil
+
+	case opReleaseIdempotencyKey:
+		var k domain.IdempotencyKey
+		if err := json.Unmarshal(rec.Data, &k); err != nil {
+			return err
+		}
+		return s.ReleaseIdempotencyKey(k)
+
+	case opSetThresholds, opDeleteThresholds:
+		var r thresholdRecord
+		if err := json.Unmarshal(rec.Data, &r); err != nil {
+			return err
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		k := thresholdKey(r.Change.Scope, r.Change.Key)
+		if r.Config != nil {
+			s.thresholds[k] = r.Config
+		} else {
+			delete(s.thresholds, k)
+		}
+		s.thresholdChanges = append(s.thresholdChanges, r.Change)
+		return nil
+
+	case opPutSetting:
+		var st domain.Setting
+		if err := json.Unmarshal(rec.Data, &st); err != nil {
+			return err
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.settings[st.Name] = st
+		return nil
+	}
+	return fmt.Errorf("unknown op %q", rec.Op)
+}
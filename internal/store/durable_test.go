@@ -0,0 +1,324 @@
+package store_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"lumina/fraud-api/internal/domain"
+	"lumina/fraud-api/internal/store"
+	"lumina/fraud-api/internal/store/storetest"
+)
+
+func openDurable(t *testing.T, dir string, sync store.SyncPolicy) *store.Durable {
+	t.Helper()
+	d, err := store.OpenDurable(store.DurableOptions{Dir: dir, Sync: sync, SnapshotEvery: -1, SnapshotInterval: -1})
+	if err != nil {
+		t.Fatalf("open %s: %v", dir, err)
+	}
+	return d
+}
+
+func TestDurable_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		d := openDurable(t, t.TempDir(), store.SyncNever)
+		t.Cleanup(func() { _ = d.Close() })
+		return d
+	})
+}
+
+func durableTx(id, email, ip, bin string, ts time.Time) *domain.Transaction {
+	return &domain.Transaction{
+		TransactionRequest: domain.TransactionRequest{
+			TransactionID:     id,
+			Timestamp:         ts,
+			Amount:            75,
+			Currency:          domain.BRL,
+			UserEmail:         email,
+			IPAddress:         ip,
+			DeviceFingerprint: "dev-" + email,
+			CardBIN:           bin,
+		},
+		RiskScore:   55,
+		FinalStatus: domain.StatusPendingReview,
+	}
+}
+
+// populate exercises every kind of mutation the WAL records.
+func populate(t *testing.T, s store.Store, now time.Time) {
+	t.Helper()
+	for i, bin := range []string{"411111", "522222", "411111", "633333"} {
+		id := "dur-" + string(rune('a'+i))
+		if err := s.SaveTransaction(durableTx(id, "u@x.com", "7.7.7.7", bin, now.Add(time.Duration(i)*time.Minute))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.SaveTransactions([]*domain.Transaction{
+		durableTx("dur-batch-1", "v@x.com", "7.7.7.7", "411111", now),
+		durableTx("dur-batch-2", "v@x.com", "7.7.7.7", "522222", now.Add(time.Minute)),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AddOutcome(domain.Outcome{ID: "o-1", TransactionID: "dur-a", Type: domain.OutcomeChargeback, OccurredAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = s.ClaimReview("dur-b", "ana", now, time.Hour)
+	_, _ = s.ClaimReview("dur-c", "ana", now, time.Hour)
+	if _, err := s.DecideReview("dur-c", domain.ReviewDecision{Decision: domain.ActionDecline, Analyst: "ana", DecidedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = s.ClaimReview("dur-d", "bruno", now, time.Hour)
+	_ = s.ReleaseReview("dur-d", "bruno", now)
+
+	s.SaveBlocklistEntry(&domain.BlocklistEntry{ID: "bl-1", Type: domain.EntityIP, Value: "7.7.7.7", ListType: domain.ListBlock, CreatedAt: now})
+	s.SaveBlocklistEntry(&domain.BlocklistEntry{ID: "bl-2", Type: domain.EntityEmail, Value: "x@x.com", ListType: domain.ListBlock, CreatedAt: now})
+	s.DeleteBlocklistEntry("bl-2")
+	s.SaveWebhook(&domain.WebhookConfig{ID: "wh-1", URL: "http://a", Threshold: 80, Active: true, CreatedAt: now})
+	s.SaveWebhook(&domain.WebhookConfig{ID: "wh-2", URL: "http://b", Threshold: 90, Active: true, CreatedAt: now})
+	s.DeleteWebhook("wh-2")
+	s.SetThresholds(&domain.ThresholdConfig{Scope: domain.ScopeCountry, Key: "BR", Thresholds: domain.Thresholds{Approve: 25, Review: 65}, UpdatedAt: now}, "ops")
+	s.SetThresholds(&domain.ThresholdConfig{Scope: domain.ScopeGlobal, Thresholds: domain.Thresholds{Approve: 20, Review: 60}, UpdatedAt: now}, "ops")
+	s.DeleteThresholds(domain.ScopeGlobal, "", "ops")
+	if _, err := s.PutSetting(domain.Setting{Name: "rules", Document: json.RawMessage(`{"version":"v2"}`), UpdatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.SaveIdempotencyKey(domain.IdempotencyKey{Key: "ik-1", TransactionID: "dur-a", Fingerprint: "f", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []*domain.APIKey{
+		{ID: "key-1", Name: "ops", TokenHash: "h-1", Scopes: []string{"admin"}, CreatedAt: now},
+		{ID: "key-2", Name: "ci", TokenHash: "h-2", Scopes: []string{"admin"}, CreatedAt: now},
+	} {
+		if err := s.CreateAPIKey(k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	revokedAt := now
+	if err := s.UpdateAPIKey(&domain.APIKey{ID: "key-1", Name: "ops", TokenHash: "h-1b", Scopes: []string{"admin"}, CreatedAt: now, RotatedAt: &revokedAt}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdateAPIKey(&domain.APIKey{ID: "key-2", Name: "ci", TokenHash: "h-2", Scopes: []string{"admin"}, CreatedAt: now, RevokedAt: &revokedAt}); err != nil {
+		t.Fatal(err)
+	}
+	for seq := int64(1); seq <= 2; seq++ {
+		e := domain.AuditEntry{Seq: seq, Time: now, Actor: "key:ops", Action: "blocklist.add", After: json.RawMessage(`{"value":"7.7.7.7"}`)}
+		if err := s.AppendAuditEntry(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// observe captures everything a caller can read back, as JSON so that time
+// values compare by instant rather than by monotonic clock reading.
+func observe(t *testing.T, s store.Store, now time.Time) string {
+	t.Helper()
+	since := now.Add(-time.Hour)
+	th, scope := s.ResolveThresholds("m", "BR")
+	tx, _ := s.GetTransaction("dur-a")
+	key, _ := s.GetIdempotencyKey("ik-1", now)
+	page, err := s.SearchTransactions(store.TransactionQuery{Desc: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyByHash, _ := s.GetAPIKeyByHash("h-1b")
+	auditLog, err := s.AuditEntriesAfter(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setting, _ := s.GetSetting("rules")
+	links := s.GetEntityLinks(domain.EntityIP, "7.7.7.7")
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].Node.Type+links[i].Node.Value < links[j].Node.Type+links[j].Node.Value
+	})
+	view := map[string]any{
+		"by_email":      s.GetTransactionsByEmail("u@x.com", since),
+		"by_ip":         s.GetTransactionsByIP("7.7.7.7", since),
+		"by_device":     s.GetTransactionsByDevice("dev-u@x.com", since),
+		"by_bin":        s.GetTransactionsByBIN("411111", since),
+		"cards_by_ip":   s.GetUniqueCardsByIP("7.7.7.7"),
+		"tx":            tx,
+		"outcomes":      s.ListOutcomes("", since),
+		"pending":       len(s.PendingReviews()),
+		"claims":        s.ActiveReviewClaims(now),
+		"blocklist":     s.ListBlocklistEntries(),
+		"webhooks":      s.ListActiveWebhooks(),
+		"thresholds":    th,
+		"scope":         scope,
+		"threshold_log": s.ListThresholdChanges(),
+		"setting":       setting,
+		"idempotency":   key,
+		"search":        page.Transactions,
+		"links":         links,
+		"api_keys":      s.ListAPIKeys(),
+		"key_by_hash":   keyByHash,
+		"audit_log":     auditLog,
+	}
+	b, err := json.Marshal(view)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestDurable_RecoversFromWAL(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	d := openDurable(t, dir, store.SyncAlways)
+	populate(t, d, now)
+	want := observe(t, d, now)
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := openDurable(t, dir, store.SyncAlways)
+	defer r.Close()
+	if got := observe(t, r, now); got != want {
+		t.Errorf("recovered state differs\nwant %s\ngot  %s", want, got)
+	}
+	if err := r.SaveTransaction(durableTx("dur-a", "u@x.com", "7.7.7.7", "411111", now)); err != store.ErrDuplicateTransaction {
+		t.Errorf("expected recovered IDs to be enforced, got %v", err)
+	}
+}
+
+func TestDurable_SnapshotCompactsAndRecovers(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	d := openDurable(t, dir, store.SyncInterval)
+	populate(t, d, now)
+	if err := d.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+	segs, _ := filepath.Glob(filepath.Join(dir, "wal-*.log"))
+	if len(segs) != 1 {
+		t.Fatalf("expected only the fresh segment after compaction, got %v", segs)
+	}
+
+	// Writes after the snapshot land in the new segment and are replayed on top.
+	if err := d.SaveTransaction(durableTx("dur-e", "u@x.com", "7.7.7.7", "744444", now.Add(5*time.Minute))); err != nil {
+		t.Fatal(err)
+	}
+	want := observe(t, d, now)
+	_ = d.Close()
+
+	r := openDurable(t, dir, store.SyncInterval)
+	defer r.Close()
+	if got := observe(t, r, now); got != want {
+		t.Errorf("recovered state differs\nwant %s\ngot  %s", want, got)
+	}
+	if n := r.GetUniqueCardsByIP("7.7.7.7"); n != 4 {
+		t.Errorf("expected cardsByIP to hold 4 BINs, got %d", n)
+	}
+}
+
+func TestDurable_SnapshotEveryTriggersCompaction(t *testing.T) {
+	dir := t.TempDir()
+	d, err := store.OpenDurable(store.DurableOptions{Dir: dir, SnapshotEvery: 3, SnapshotInterval: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	now := time.Now().UTC()
+	for i := 0; i < 3; i++ {
+		_ = d.SaveTransaction(durableTx("auto-"+string(rune('a'+i)), "a@x.com", "1.1.1.1", "411111", now))
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "snapshot.json")); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected a snapshot after 3 records")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDurable_TornTailIsDiscarded(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().UTC()
+
+	d := openDurable(t, dir, store.SyncAlways)
+	_ = d.SaveTransaction(durableTx("torn-1", "a@x.com", "1.1.1.1", "411111", now))
+	_ = d.Close()
+
+	// Simulate a crash part-way through writing the next record.
+	segs, _ := filepath.Glob(filepath.Join(dir, "wal-*.log"))
+	f, err := os.OpenFile(segs[len(segs)-1], os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(`1234abcd {"seq":2,"op":"transaction.sa`)
+	_ = f.Close()
+
+	r := openDurable(t, dir, store.SyncAlways)
+	defer r.Close()
+	if _, ok := r.GetTransaction("torn-1"); !ok {
+		t.Error("expected the complete record to be recovered")
+	}
+	if err := r.SaveTransaction(durableTx("torn-2", "a@x.com", "1.1.1.1", "411111", now)); err != nil {
+		t.Errorf("expected writes to continue after recovery, got %v", err)
+	}
+}
+
+func TestDurable_CorruptRecordFailsOpen(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().UTC()
+
+	d := openDurable(t, dir, store.SyncAlways)
+	_ = d.SaveTransaction(durableTx("c-1", "a@x.com", "1.1.1.1", "411111", now))
+	_ = d.SaveTransaction(durableTx("c-2", "a@x.com", "1.1.1.1", "411111", now))
+	_ = d.Close()
+
+	segs, _ := filepath.Glob(filepath.Join(dir, "wal-*.log"))
+	data, _ := os.ReadFile(segs[0])
+	_ = os.WriteFile(segs[0], []byte(strings.Replace(string(data), "c-1", "c-9", 1)), 0o644)
+
+	if _, err := store.OpenDurable(store.DurableOptions{Dir: dir}); err == nil {
+		t.Error("expected a checksum failure before the tail to refuse to open")
+	}
+}
+
+func TestParseSyncPolicy(t *testing.T) {
+	for in, want := range map[string]store.SyncPolicy{"always": store.SyncAlways, " Interval ": store.SyncInterval, "never": store.SyncNever} {
+		got, err := store.ParseSyncPolicy(in)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseSyncPolicy(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := store.ParseSyncPolicy("sometimes"); err == nil {
+		t.Error("expected an error for an unknown policy")
+	}
+}
+
+func TestDurable_PruneIsReplayed(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	d := openDurable(t, dir, store.SyncAlways)
+	populate(t, d, now)
+	old := durableTx("dur-old", "u@x.com", "7.7.7.7", "899999", now.Add(-100*24*time.Hour))
+	old.FinalStatus = domain.StatusApproved
+	_ = d.SaveTransaction(old)
+	if stats, err := d.Prune(now.Add(-90 * 24 * time.Hour)); err != nil || stats.Transactions != 1 {
+		t.Fatalf("expected dur-old to be pruned, got %+v, %v", stats, err)
+	}
+	want := observe(t, d, now)
+	_ = d.Close()
+
+	r := openDurable(t, dir, store.SyncAlways)
+	defer r.Close()
+	if got := observe(t, r, now); got != want {
+		t.Errorf("recovered state differs\nwant %s\ngot  %s", want, got)
+	}
+	if _, ok := r.GetTransaction("dur-old"); ok {
+		t.Error("expected the prune to be replayed")
+	}
+}
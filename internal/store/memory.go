@@ -1,54 +1,107 @@
-// Package store provides thread-safe, in-memory storage for the fraud API.
-//
-// Design rationale: For a 90-day rolling fraud-detection window, an in-memory
-// store is sufficient for demo and small-scale production loads. The secondary
-// indexes (byEmail, byIP, byDevice, byBIN) give O(1) entity lookups while the
-// time-range filtering is a linear scan over a typically small slice.
-// A production deployment would swap this for Redis or TimescaleDB.
 package store
 
 import (
-	"errors"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
+
 	"lumina/fraud-api/internal/domain"
 )
 
-// ErrDuplicateTransaction is returned when a transaction ID is submitted twice.
-var ErrDuplicateTransaction = errors.New("transaction already exists")
-
-// Store is a thread-safe in-memory data store.
-type Store struct {
+// Memory is a thread-safe in-memory Store.
+//
+// Design rationale: For a 90-day rolling fraud-detection window, an in-memory
+// store is sufficient for demo and small-scale production loads. The secondary
+// indexes (byEmail, byIP, byDevice, byBIN) give O(1) entity lookups and are
+// kept sorted by time, so a window query costs O(log n) plus the transactions
+// it returns, however long the entity's history.
+// A production deployment would swap this for Redis or TimescaleDB.
+type Memory struct {
 	mu sync.RWMutex
 
 	transactions map[string]*domain.Transaction
 	blocklist    map[string]*domain.BlocklistEntry
 	webhooks     map[string]*domain.WebhookConfig
 
-	// Secondary indexes: entity value → slice of transaction IDs.
+	// Every transaction ID sorted by time, for time-range scans and search.
+	txByTime timeIndex
+
+	// Secondary indexes: entity value → transaction IDs sorted by time.
 	// Maintained on every write so reads stay fast.
-	txByEmail  map[string][]string
-	txByIP     map[string][]string
-	txByDevice map[string][]string
-	txByBIN    map[string][]string
+	txByEmail  map[string]timeIndex
+	txByIP     map[string]timeIndex
+	txByDevice map[string]timeIndex
+	txByBIN    map[string]timeIndex
 
-	// Tracks the set of distinct card BINs seen per IP address.
+	// Tracks the distinct card BINs seen per IP address, with the latest
+	// transaction time for each so retention can forget stale pairs.
 	// Used to detect the "card cycling on one IP" fraud pattern.
-	cardsByIP map[string]map[string]bool
+	cardsByIP map[string]map[string]time.Time
+
+	// Entity link graph: each entity → the entities it shared a transaction
+	// with, stored in both directions. Derived from the transactions: saving
+	// and pruning one links and unlinks its entities, and snapshot restores
+	// rebuild it.
+	links map[domain.EntityNode]map[domain.EntityNode]domain.EntityLink
+
+	// The emails seen with each IP, device and card BIN, by when each was
+	// last seen with it, so CountDistinctEmails is a binary search instead
+	// of a walk over the window. Derived from the transactions like links.
+	emailsByEntity map[domain.EntityNode]*recencyIndex
+
+	// IDs of the transactions labelled fraud by their outcomes, per email,
+	// so the fraud-ring rule can check a whole cluster for fraud history
+	// without loading it. Kept in step with outcomes by flagFraud.
+	fraudByEmail map[string]map[string]bool
+
+	// Recommendation threshold overrides keyed by thresholdKey(scope, key),
+	// plus the append-only history of every change made to them.
+	thresholds       map[string]*domain.ThresholdConfig
+	thresholdChanges []domain.ThresholdChange
+
+	// Runtime settings by name.
+	settings map[string]domain.Setting
+
+	// Analyst claims on queued review cases, keyed by transaction ID.
+	reviewClaims map[string]domain.ReviewClaim
+
+	// Client idempotency keys, including lapsed ones until the next Prune.
+	idempotencyKeys map[string]domain.IdempotencyKey
+
+	// API keys by ID, and their IDs by current token hash.
+	apiKeys       map[string]*domain.APIKey
+	apiKeysByHash map[string]string
+
+	// The audit hash chain in append order; entry i has Seq i+1.
+	auditLog []domain.AuditEntry
 }
 
-// New creates an empty, ready-to-use Store.
-func New() *Store {
-	return &Store{
-		transactions: make(map[string]*domain.Transaction),
-		blocklist:    make(map[string]*domain.BlocklistEntry),
-		webhooks:     make(map[string]*domain.WebhookConfig),
-		txByEmail:    make(map[string][]string),
-		txByIP:       make(map[string][]string),
-		txByDevice:   make(map[string][]string),
-		txByBIN:      make(map[string][]string),
-		cardsByIP:    make(map[string]map[string]bool),
+// Memory satisfies the full Store interface.
+var _ Store = (*Memory)(nil)
+
+// New creates an empty, ready-to-use in-memory Store.
+func New() *Memory {
+	return &Memory{
+		transactions:    make(map[string]*domain.Transaction),
+		blocklist:       make(map[string]*domain.BlocklistEntry),
+		webhooks:        make(map[string]*domain.WebhookConfig),
+		txByEmail:       make(map[string]timeIndex),
+		txByIP:          make(map[string]timeIndex),
+		txByDevice:      make(map[string]timeIndex),
+		txByBIN:         make(map[string]timeIndex),
+		cardsByIP:       make(map[string]map[string]time.Time),
+		links:           make(map[domain.EntityNode]map[domain.EntityNode]domain.EntityLink),
+		emailsByEntity:  make(map[domain.EntityNode]*recencyIndex),
+		fraudByEmail:    make(map[string]map[string]bool),
+		thresholds:      make(map[string]*domain.ThresholdConfig),
+		settings:        make(map[string]domain.Setting),
+		reviewClaims:    make(map[string]domain.ReviewClaim),
+		idempotencyKeys: make(map[string]domain.IdempotencyKey),
+		apiKeys:         make(map[string]*domain.APIKey),
+		apiKeysByHash:   make(map[string]string),
 	}
 }
 
@@ -56,30 +109,66 @@ func New() *Store {
 
 // SaveTransaction persists a transaction and updates all secondary indexes.
 // Returns ErrDuplicateTransaction if the ID already exists.
-func (s *Store) SaveTransaction(tx *domain.Transaction) error {
+func (s *Memory) SaveTransaction(tx *domain.Transaction) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	if _, exists := s.transactions[tx.TransactionID]; exists {
 		return ErrDuplicateTransaction
 	}
+	s.saveTransaction(tx)
+	return nil
+}
+
+// SaveTransactions saves a batch under one lock, after checking that none of
+// its IDs is taken, so readers see all of it or none of it.
+func (s *Memory) SaveTransactions(txs []*domain.Transaction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[string]bool, len(txs))
+	for _, tx := range txs {
+		if _, exists := s.transactions[tx.TransactionID]; exists || seen[tx.TransactionID] {
+			return ErrDuplicateTransaction
+		}
+		seen[tx.TransactionID] = true
+	}
+	for _, tx := range txs {
+		s.saveTransaction(tx)
+	}
+	return nil
+}
 
+// saveTransaction stores and indexes tx. Must be called with the write lock
+// held, after checking the ID is free.
+func (s *Memory) saveTransaction(tx *domain.Transaction) {
 	s.transactions[tx.TransactionID] = tx
-	s.txByEmail[tx.UserEmail] = append(s.txByEmail[tx.UserEmail], tx.TransactionID)
-	s.txByIP[tx.IPAddress] = append(s.txByIP[tx.IPAddress], tx.TransactionID)
-	s.txByDevice[tx.DeviceFingerprint] = append(s.txByDevice[tx.DeviceFingerprint], tx.TransactionID)
-	s.txByBIN[tx.CardBIN] = append(s.txByBIN[tx.CardBIN], tx.TransactionID)
+	s.indexTransaction(tx)
+	s.linkTransaction(tx)
+	s.countEmail(tx)
+	s.flagFraud(tx)
 
 	if s.cardsByIP[tx.IPAddress] == nil {
-		s.cardsByIP[tx.IPAddress] = make(map[string]bool)
+		s.cardsByIP[tx.IPAddress] = make(map[string]time.Time)
 	}
-	s.cardsByIP[tx.IPAddress][tx.CardBIN] = true
+	if seen, ok := s.cardsByIP[tx.IPAddress][tx.CardBIN]; !ok || tx.Timestamp.After(seen) {
+		s.cardsByIP[tx.IPAddress][tx.CardBIN] = tx.Timestamp
+	}
+}
 
-	return nil
+// indexTransaction adds tx to the time index and the four entity indexes at
+// its timestamp. Must be called with the write lock held.
+func (s *Memory) indexTransaction(tx *domain.Transaction) {
+	id, at := tx.TransactionID, tx.Timestamp
+	s.txByTime = s.txByTime.insert(id, at)
+	s.txByEmail[tx.UserEmail] = s.txByEmail[tx.UserEmail].insert(id, at)
+	s.txByIP[tx.IPAddress] = s.txByIP[tx.IPAddress].insert(id, at)
+	s.txByDevice[tx.DeviceFingerprint] = s.txByDevice[tx.DeviceFingerprint].insert(id, at)
+	s.txByBIN[tx.CardBIN] = s.txByBIN[tx.CardBIN].insert(id, at)
 }
 
 // GetTransaction retrieves a single transaction by ID.
-func (s *Store) GetTransaction(id string) (*domain.Transaction, bool) {
+func (s *Memory) GetTransaction(id string) (*domain.Transaction, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	tx, ok := s.transactions[id]
@@ -87,8 +176,8 @@ func (s *Store) GetTransaction(id string) (*domain.Transaction, bool) {
 }
 
 // GetTransactionsByEmail returns all transactions from the given email that
-// occurred at or after `since`. Results are in arbitrary order.
-func (s *Store) GetTransactionsByEmail(email string, since time.Time) []*domain.Transaction {
+// occurred at or after `since`, oldest first.
+func (s *Memory) GetTransactionsByEmail(email string, since time.Time) []*domain.Transaction {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	return s.filterByTime(s.txByEmail[email], since)
@@ -96,7 +185,7 @@ func (s *Store) GetTransactionsByEmail(email string, since time.Time) []*domain.
 
 // GetTransactionsByIP returns all transactions originating from the given IP
 // at or after `since`.
-func (s *Store) GetTransactionsByIP(ip string, since time.Time) []*domain.Transaction {
+func (s *Memory) GetTransactionsByIP(ip string, since time.Time) []*domain.Transaction {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	return s.filterByTime(s.txByIP[ip], since)
@@ -104,7 +193,7 @@ func (s *Store) GetTransactionsByIP(ip string, since time.Time) []*domain.Transa
 
 // GetTransactionsByDevice returns all transactions from a device fingerprint
 // at or after `since`.
-func (s *Store) GetTransactionsByDevice(device string, since time.Time) []*domain.Transaction {
+func (s *Memory) GetTransactionsByDevice(device string, since time.Time) []*domain.Transaction {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	return s.filterByTime(s.txByDevice[device], since)
@@ -112,60 +201,763 @@ func (s *Store) GetTransactionsByDevice(device string, since time.Time) []*domai
 
 // GetTransactionsByBIN returns all transactions using a particular card BIN
 // at or after `since`.
-func (s *Store) GetTransactionsByBIN(bin string, since time.Time) []*domain.Transaction {
+func (s *Memory) GetTransactionsByBIN(bin string, since time.Time) []*domain.Transaction {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	return s.filterByTime(s.txByBIN[bin], since)
 }
 
 // GetUniqueCardsByIP returns how many distinct card BINs have been used from
-// the given IP address (all-time, not time-windowed — card cycling is a
-// persistent signal even across days).
-func (s *Store) GetUniqueCardsByIP(ip string) int {
+// the given IP address. It is not time-windowed like the other lookups (card
+// cycling is a persistent signal even across days), but pairs last seen
+// before the retention horizon are forgotten by Prune.
+func (s *Memory) GetUniqueCardsByIP(ip string) int {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	return len(s.cardsByIP[ip])
 }
 
-// GetAllTransactions returns every transaction stored at or after `since`.
-func (s *Store) GetAllTransactions(since time.Time) []*domain.Transaction {
+// GetAllTransactions returns every transaction stored at or after `since`,
+// oldest first.
+func (s *Memory) GetAllTransactions(since time.Time) []*domain.Transaction {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.filterByTime(s.txByTime, since)
+}
+
+// SearchTransactions walks the time index over the query's window in the
+// query's time direction. A time-sorted page stops as soon as it is full, so
+// its cost follows the page rather than the store; sorting by score or
+// amount reads the whole window.
+func (s *Memory) SearchTransactions(q TransactionQuery) (TransactionPage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := s.txByTime.between(q.window())
+	b := newPageBuilder(&q, time.Time.Equal)
+	for i := range entries {
+		e := entries[i]
+		if q.Desc {
+			e = entries[len(entries)-1-i]
+		}
+		tx, ok := s.transactions[e.id]
+		if ok && b.offer(tx) {
+			break
+		}
+	}
+	return b.page(), nil
+}
+
+// CountTransactions counts the entity's transactions at or after since. The
+// index is sorted by time, so this is a binary search with no allocation.
+func (s *Memory) CountTransactions(entityType, value string, since time.Time) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.entityIndex(entityType)[value].since(since))
+}
+
+// CountDistinctEmails counts the distinct emails among the entity's
+// transactions at or after since: the emails last seen with it since then.
+// Like CountTransactions, this is a binary search with no allocation.
+func (s *Memory) CountDistinctEmails(entityType, value string, since time.Time) int {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	if entityType == domain.EntityEmail {
+		return min(len(s.txByEmail[value].since(since)), 1)
+	}
+	emails, ok := s.emailsByEntity[domain.EntityNode{Type: entityType, Value: value}]
+	if !ok {
+		return 0
+	}
+	return emails.countSince(since)
+}
+
+// countEmail records tx's email as seen with its IP, device and BIN. Must be
+// called with the write lock held.
+func (s *Memory) countEmail(tx *domain.Transaction) {
+	if tx.UserEmail == "" {
+		return
+	}
+	for _, n := range tx.Entities() {
+		if n.Type != domain.EntityEmail {
+			s.seeEmail(n, tx.UserEmail, tx.Timestamp)
+		}
+	}
+}
+
+func (s *Memory) seeEmail(n domain.EntityNode, email string, at time.Time) {
+	emails := s.emailsByEntity[n]
+	if emails == nil {
+		emails = newRecencyIndex()
+		s.emailsByEntity[n] = emails
+	}
+	emails.see(email, at)
+}
+
+// recountEmails rebuilds the distinct-email index of each entity pruned
+// transactions touched from its links to emails, which unlinkTransactions
+// has re-dated. Must be called with the write lock held, after unlinking.
+func (s *Memory) recountEmails(pruned []*domain.Transaction) {
+	done := make(map[domain.EntityNode]bool)
+	for _, tx := range pruned {
+		for _, n := range tx.Entities() {
+			if n.Type == domain.EntityEmail || done[n] {
+				continue
+			}
+			done[n] = true
+			delete(s.emailsByEntity, n)
+			for to, l := range s.links[n] {
+				if to.Type == domain.EntityEmail {
+					s.seeEmail(n, to.Value, l.LastSeen)
+				}
+			}
+		}
+	}
+}
+
+// entityIndex returns the secondary index for an entity type, or nil.
+// Must be called with at least a read-lock held.
+func (s *Memory) entityIndex(entityType string) map[string]timeIndex {
+	switch entityType {
+	case domain.EntityEmail:
+		return s.txByEmail
+	case domain.EntityIP:
+		return s.txByIP
+	case domain.EntityDevice:
+		return s.txByDevice
+	case domain.EntityBIN:
+		return s.txByBIN
+	}
+	return nil
+}
+
+// filterByTime resolves the index entries at or after `since` to Transaction
+// pointers. The start is found by binary search, so only the window is read.
+// Must be called with at least a read-lock held.
+func (s *Memory) filterByTime(idx timeIndex, since time.Time) []*domain.Transaction {
 	var result []*domain.Transaction
-	for _, tx := range s.transactions {
-		if !tx.Timestamp.Before(since) {
+	for _, e := range idx.since(since) {
+		if tx, ok := s.transactions[e.id]; ok {
 			result = append(result, tx)
 		}
 	}
 	return result
 }
 
-// filterByTime resolves a slice of IDs to Transaction pointers,
-// keeping only those at or after `since`.
-// Must be called with at least a read-lock held.
-func (s *Store) filterByTime(ids []string, since time.Time) []*domain.Transaction {
+// ─── Entity links ─────────────────────────────────────────────────────────────
+
+// GetEntityLinks returns the entities that shared a transaction with the
+// given one.
+func (s *Memory) GetEntityLinks(entityType, value string) []domain.EntityLink {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	linked := s.links[domain.EntityNode{Type: entityType, Value: value}]
+	result := make([]domain.EntityLink, 0, len(linked))
+	for _, l := range linked {
+		result = append(result, l)
+	}
+	return result
+}
+
+// linkTransaction links every pair of tx's entities, in both directions.
+// Must be called with the write lock held.
+func (s *Memory) linkTransaction(tx *domain.Transaction) {
+	nodes := tx.Entities()
+	for i, a := range nodes {
+		for _, b := range nodes[i+1:] {
+			s.link(a, b, tx.Timestamp)
+			s.link(b, a, tx.Timestamp)
+		}
+	}
+}
+
+func (s *Memory) link(from, to domain.EntityNode, at time.Time) {
+	linked := s.links[from]
+	if linked == nil {
+		linked = make(map[domain.EntityNode]domain.EntityLink)
+		s.links[from] = linked
+	}
+	l, ok := linked[to]
+	if !ok {
+		l = domain.EntityLink{Node: to, FirstSeen: at, LastSeen: at}
+	}
+	l.Weight++
+	if at.Before(l.FirstSeen) {
+		l.FirstSeen = at
+	}
+	if at.After(l.LastSeen) {
+		l.LastSeen = at
+	}
+	linked[to] = l
+}
+
+// relink rebuilds the link graph from the stored transactions. Must be
+// called with the write lock held.
+func (s *Memory) relink() {
+	s.links = make(map[domain.EntityNode]map[domain.EntityNode]domain.EntityLink)
+	for _, tx := range s.transactions {
+		s.linkTransaction(tx)
+	}
+}
+
+// unlinkTransactions removes pruned transactions from the link graph, the
+// reverse of linkTransaction. A surviving link first or last seen at a
+// pruned transaction's time is re-dated from its entity's remaining history.
+// Must be called with the write lock held, after the entity indexes are
+// pruned.
+func (s *Memory) unlinkTransactions(pruned []*domain.Transaction) {
+	redate := make(map[domain.EntityNode]map[domain.EntityNode]bool)
+	for _, tx := range pruned {
+		for _, from := range tx.Entities() {
+			for _, to := range tx.Entities() {
+				if from == to || !s.unlink(from, to, tx.Timestamp) {
+					continue
+				}
+				if redate[from] == nil {
+					redate[from] = make(map[domain.EntityNode]bool)
+				}
+				redate[from][to] = true
+			}
+		}
+	}
+	for from, to := range redate {
+		s.redateLinks(from, to)
+	}
+}
+
+// unlink takes one transaction at `at` off the link from → to, deleting the
+// link when none remain. It reports whether the link survives with a first
+// or last sighting that may have been the removed transaction.
+func (s *Memory) unlink(from, to domain.EntityNode, at time.Time) bool {
+	linked := s.links[from]
+	l, ok := linked[to]
+	if !ok {
+		return false
+	}
+	l.Weight--
+	if l.Weight <= 0 {
+		delete(linked, to)
+		if len(linked) == 0 {
+			delete(s.links, from)
+		}
+		return false
+	}
+	linked[to] = l
+	return at.Equal(l.FirstSeen) || at.Equal(l.LastSeen)
+}
+
+// redateLinks recomputes the first and last sighting of from's links to the
+// given entities with one pass over from's time-sorted index. Links removed
+// since they were marked are skipped. Must be called with the write lock
+// held.
+func (s *Memory) redateLinks(from domain.EntityNode, to map[domain.EntityNode]bool) {
+	seen := make(map[domain.EntityNode][2]time.Time, len(to))
+	for _, e := range s.entityIndex(from.Type)[from.Value] {
+		tx, ok := s.transactions[e.id]
+		if !ok {
+			continue
+		}
+		for _, n := range tx.Entities() {
+			if !to[n] {
+				continue
+			}
+			at, found := seen[n]
+			if !found {
+				at[0] = tx.Timestamp
+			}
+			at[1] = tx.Timestamp
+			seen[n] = at
+		}
+	}
+	linked := s.links[from]
+	for n, at := range seen {
+		if l, ok := linked[n]; ok {
+			l.FirstSeen, l.LastSeen = at[0], at[1]
+			linked[n] = l
+		}
+	}
+}
+
+// ─── Retention ────────────────────────────────────────────────────────────────
+
+// Memory satisfies Pruner.
+var _ Pruner = (*Memory)(nil)
+
+// Prune deletes transactions that occurred before cutoff and removes them from
+// every secondary index and the link graph, then forgets IP/BIN pairs last
+// seen before cutoff.
+// Transactions awaiting review are kept. Only the indexes and links of the
+// pruned transactions' entities are touched, so the write lock is held for
+// time proportional to what is pruned rather than to the whole history.
+func (s *Memory) Prune(cutoff time.Time) (PruneStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var stats PruneStats
+	var pruned []*domain.Transaction
+	prunedIDs := make(map[string]bool)
+	for _, e := range s.txByTime.between(time.Time{}, cutoff) {
+		tx, ok := s.transactions[e.id]
+		if ok && tx.FinalStatus != domain.StatusPendingReview {
+			delete(s.transactions, e.id)
+			delete(s.reviewClaims, e.id)
+			pruned = append(pruned, tx)
+			prunedIDs[e.id] = true
+		}
+	}
+	stats.Transactions = len(pruned)
+	if len(pruned) > 0 {
+		s.txByTime = s.liveEntriesBefore(s.txByTime, cutoff)
+		for _, tx := range pruned {
+			s.pruneIndex(s.txByEmail, tx.UserEmail, cutoff)
+			s.pruneIndex(s.txByIP, tx.IPAddress, cutoff)
+			s.pruneIndex(s.txByDevice, tx.DeviceFingerprint, cutoff)
+			s.pruneIndex(s.txByBIN, tx.CardBIN, cutoff)
+		}
+		s.unlinkTransactions(pruned)
+		s.recountEmails(pruned)
+		for _, tx := range pruned {
+			s.unflagFraud(tx)
+		}
+	}
+	for ip, bins := range s.cardsByIP {
+		for bin, seen := range bins {
+			if seen.Before(cutoff) {
+				delete(bins, bin)
+				stats.Cards++
+			}
+		}
+		if len(bins) == 0 {
+			delete(s.cardsByIP, ip)
+		}
+	}
+	// Keys are matched to the transactions pruned here, not to those missing:
+	// a key reserved by a request still scoring has no transaction yet.
+	for key, k := range s.idempotencyKeys {
+		if prunedIDs[k.TransactionID] || k.ExpiresAt.Before(cutoff) {
+			delete(s.idempotencyKeys, key)
+			stats.IdempotencyKeys++
+		}
+	}
+	return stats, nil
+}
+
+// pruneIndex drops IDs no longer in the primary map from one entity's index,
+// and the entity if none are left. Must be called with the write lock held.
+func (s *Memory) pruneIndex(idx map[string]timeIndex, key string, cutoff time.Time) {
+	entries, ok := idx[key]
+	if !ok {
+		return
+	}
+	if kept := s.liveEntriesBefore(entries, cutoff); len(kept) == 0 {
+		delete(idx, key)
+	} else {
+		idx[key] = kept
+	}
+}
+
+// liveEntriesBefore drops the entries before cutoff whose transaction is no
+// longer stored; pruning never removes later ones, so those are kept without
+// a lookup. Must be called with the write lock held.
+func (s *Memory) liveEntriesBefore(entries timeIndex, cutoff time.Time) timeIndex {
+	old := entries.between(time.Time{}, cutoff)
+	kept := s.liveEntries(old)
+	if len(kept) == len(old) {
+		return entries
+	}
+	return append(kept, entries[len(old):]...)
+}
+
+// liveEntries returns the entries whose transaction is still stored. Must be
+// called with the write lock held.
+func (s *Memory) liveEntries(entries timeIndex) timeIndex {
+	var kept timeIndex
+	for _, e := range entries {
+		if _, ok := s.transactions[e.id]; ok {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+// ─── Outcomes ─────────────────────────────────────────────────────────────────
+
+// AddOutcome appends an outcome to its transaction and returns the updated
+// transaction. Transactions are never mutated in place: readers may still hold
+// the previous pointer, so the record is copied and swapped under the lock.
+// Returns ErrTransactionNotFound if o.TransactionID is unknown.
+func (s *Memory) AddOutcome(o domain.Outcome) (*domain.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, exists := s.transactions[o.TransactionID]
+	if !exists {
+		return nil, ErrTransactionNotFound
+	}
+	updated := *tx
+	updated.Outcomes = make([]domain.Outcome, len(tx.Outcomes), len(tx.Outcomes)+1)
+	copy(updated.Outcomes, tx.Outcomes)
+	updated.Outcomes = append(updated.Outcomes, o)
+	s.transactions[o.TransactionID] = &updated
+	s.flagFraud(&updated)
+	return &updated, nil
+}
+
+// flagFraud records in fraudByEmail whether tx's outcomes currently label it
+// fraud. Must be called with the write lock held.
+func (s *Memory) flagFraud(tx *domain.Transaction) {
+	if fraud, known := domain.FraudLabel(tx.Outcomes); !fraud || !known {
+		s.unflagFraud(tx)
+		return
+	}
+	ids := s.fraudByEmail[tx.UserEmail]
+	if ids == nil {
+		ids = make(map[string]bool)
+		s.fraudByEmail[tx.UserEmail] = ids
+	}
+	ids[tx.TransactionID] = true
+}
+
+// unflagFraud removes tx from fraudByEmail. Must be called with the write
+// lock held.
+func (s *Memory) unflagFraud(tx *domain.Transaction) {
+	ids := s.fraudByEmail[tx.UserEmail]
+	delete(ids, tx.TransactionID)
+	if len(ids) == 0 {
+		delete(s.fraudByEmail, tx.UserEmail)
+	}
+}
+
+// FirstFraudLabelled returns the index of the first of emails with a
+// fraud-labelled transaction, and that email's earliest one.
+func (s *Memory) FirstFraudLabelled(emails []string) (int, string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for i, email := range emails {
+		var first *domain.Transaction
+		for id := range s.fraudByEmail[email] {
+			tx := s.transactions[id]
+			if first == nil || tx.Timestamp.Before(first.Timestamp) ||
+				(tx.Timestamp.Equal(first.Timestamp) && id < first.TransactionID) {
+				first = tx
+			}
+		}
+		if first != nil {
+			return i, first.TransactionID, true
+		}
+	}
+	return 0, "", false
+}
+
+// ListOutcomes returns outcomes that occurred at or after `since`, newest
+// first. An empty outcomeType matches every type.
+func (s *Memory) ListOutcomes(outcomeType string, since time.Time) []domain.Outcome {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []domain.Outcome{}
+	for _, tx := range s.transactions {
+		for _, o := range tx.Outcomes {
+			if (outcomeType == "" || o.Type == outcomeType) && !o.OccurredAt.Before(since) {
+				result = append(result, o)
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].OccurredAt.After(result[j].OccurredAt)
+	})
+	return result
+}
+
+// ─── Review queue ─────────────────────────────────────────────────────────────
+
+// PendingReviews returns every transaction whose final status is still
+// pending_review. Results are in arbitrary order.
+func (s *Memory) PendingReviews() []*domain.Transaction {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	var result []*domain.Transaction
-	for _, id := range ids {
-		tx, ok := s.transactions[id]
-		if ok && !tx.Timestamp.Before(since) {
+	for _, tx := range s.transactions {
+		if tx.FinalStatus == domain.StatusPendingReview {
 			result = append(result, tx)
 		}
 	}
 	return result
 }
 
+// ActiveReviewClaims returns the claims that have not lapsed at `now`,
+// keyed by transaction ID.
+func (s *Memory) ActiveReviewClaims(now time.Time) map[string]domain.ReviewClaim {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[string]domain.ReviewClaim, len(s.reviewClaims))
+	for id, c := range s.reviewClaims {
+		if now.Before(c.ExpiresAt) {
+			result[id] = c
+		}
+	}
+	return result
+}
+
+// ClaimReview assigns a queued case to analyst until now+ttl. Re-claiming
+// one's own case extends it; a lapsed claim by someone else is taken over.
+func (s *Memory) ClaimReview(txID, analyst string, now time.Time, ttl time.Duration) (domain.ReviewClaim, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkPendingReview(txID); err != nil {
+		return domain.ReviewClaim{}, err
+	}
+	if c, held := s.reviewClaims[txID]; held && c.Analyst != analyst && now.Before(c.ExpiresAt) {
+		return domain.ReviewClaim{}, ErrReviewClaimed
+	}
+	claim := domain.ReviewClaim{TransactionID: txID, Analyst: analyst, ClaimedAt: now, ExpiresAt: now.Add(ttl)}
+	s.reviewClaims[txID] = claim
+	return claim, nil
+}
+
+// ReleaseReview returns a claimed case to the pool. Only the analyst holding
+// an active claim may release it.
+func (s *Memory) ReleaseReview(txID, analyst string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkClaim(txID, analyst, now); err != nil {
+		return err
+	}
+	delete(s.reviewClaims, txID)
+	return nil
+}
+
+// DecideReview closes a queued case with the analyst's decision, setting the
+// transaction's final status. The analyst must hold an active claim at
+// d.DecidedAt. Like AddOutcome, the transaction is copied rather than mutated.
+func (s *Memory) DecideReview(txID string, d domain.ReviewDecision) (*domain.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkClaim(txID, d.Analyst, d.DecidedAt); err != nil {
+		return nil, err
+	}
+	updated := *s.transactions[txID]
+	updated.FinalStatus = domain.StatusApproved
+	if d.Decision == domain.ActionDecline {
+		updated.FinalStatus = domain.StatusDeclined
+	}
+	updated.Review = &d
+	s.transactions[txID] = &updated
+	delete(s.reviewClaims, txID)
+	return &updated, nil
+}
+
+// checkPendingReview must be called with at least a read-lock held.
+func (s *Memory) checkPendingReview(txID string) error {
+	tx, exists := s.transactions[txID]
+	if !exists {
+		return ErrTransactionNotFound
+	}
+	if tx.FinalStatus != domain.StatusPendingReview {
+		return ErrNotInReview
+	}
+	return nil
+}
+
+// checkClaim verifies that analyst holds an active claim on a queued case.
+// Must be called with at least a read-lock held.
+func (s *Memory) checkClaim(txID, analyst string, now time.Time) error {
+	if err := s.checkPendingReview(txID); err != nil {
+		return err
+	}
+	c, held := s.reviewClaims[txID]
+	if !held || !now.Before(c.ExpiresAt) {
+		return ErrReviewNotClaimed
+	}
+	if c.Analyst != analyst {
+		return ErrReviewClaimed
+	}
+	return nil
+}
+
+// ─── Idempotency keys ─────────────────────────────────────────────────────────
+
+// SaveIdempotencyKey stores k unless its key is still bound at k.CreatedAt,
+// in which case the existing record is returned with ErrIdempotencyKeyExists.
+func (s *Memory) SaveIdempotencyKey(k domain.IdempotencyKey) (domain.IdempotencyKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if held, ok := s.idempotencyKeys[k.Key]; ok && k.CreatedAt.Before(held.ExpiresAt) {
+		return held, ErrIdempotencyKeyExists
+	}
+	s.idempotencyKeys[k.Key] = k
+	return k, nil
+}
+
+// ReleaseIdempotencyKey deletes k's record if it is still the one stored.
+func (s *Memory) ReleaseIdempotencyKey(k domain.IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if held, ok := s.idempotencyKeys[k.Key]; ok && sameIdempotencyKey(held, k) {
+		delete(s.idempotencyKeys, k.Key)
+	}
+	return nil
+}
+
+// sameIdempotencyKey reports whether two records are the same binding of a
+// key, rather than a later one made after the first lapsed.
+func sameIdempotencyKey(a, b domain.IdempotencyKey) bool {
+	return a.Key == b.Key && a.TransactionID == b.TransactionID &&
+		a.Fingerprint == b.Fingerprint && a.CreatedAt.Equal(b.CreatedAt)
+}
+
+// GetIdempotencyKey returns the record for key unless it has expired at now.
+func (s *Memory) GetIdempotencyKey(key string, now time.Time) (domain.IdempotencyKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	k, ok := s.idempotencyKeys[key]
+	if !ok || !now.Before(k.ExpiresAt) {
+		return domain.IdempotencyKey{}, false
+	}
+	return k, true
+}
+
+// ─── API keys ─────────────────────────────────────────────────────────────────
+
+// CreateAPIKey stores a new key unless a key has its token hash.
+func (s *Memory) CreateAPIKey(k *domain.APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, taken := s.apiKeysByHash[k.TokenHash]; taken {
+		return ErrAPIKeyExists
+	}
+	s.putAPIKey(k)
+	return nil
+}
+
+// UpdateAPIKey replaces a stored key that has not been revoked.
+func (s *Memory) UpdateAPIKey(k *domain.APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev, exists := s.apiKeys[k.ID]
+	if !exists {
+		return ErrAPIKeyNotFound
+	}
+	if prev.RevokedAt != nil {
+		return ErrAPIKeyRevoked
+	}
+	if id, taken := s.apiKeysByHash[k.TokenHash]; taken && id != k.ID {
+		return ErrAPIKeyExists
+	}
+	if prev.TokenHash != k.TokenHash {
+		delete(s.apiKeysByHash, prev.TokenHash)
+	}
+	s.putAPIKey(k)
+	return nil
+}
+
+// putAPIKey stores k and indexes its token hash. Must be called with the
+// write lock held.
+func (s *Memory) putAPIKey(k *domain.APIKey) {
+	s.apiKeys[k.ID] = k
+	s.apiKeysByHash[k.TokenHash] = k.ID
+}
+
+// GetAPIKey returns a key by ID.
+func (s *Memory) GetAPIKey(id string) (*domain.APIKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	k, ok := s.apiKeys[id]
+	return k, ok
+}
+
+// GetAPIKeyByHash returns the key whose current token hashes to hash.
+func (s *Memory) GetAPIKeyByHash(hash string) (*domain.APIKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	id, ok := s.apiKeysByHash[hash]
+	if !ok {
+		return nil, false
+	}
+	return s.apiKeys[id], true
+}
+
+// ListAPIKeys returns every key, revoked ones included, oldest first.
+func (s *Memory) ListAPIKeys() []*domain.APIKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*domain.APIKey, 0, len(s.apiKeys))
+	for _, k := range s.apiKeys {
+		result = append(result, k)
+	}
+	sortAPIKeys(result)
+	return result
+}
+
+// sortAPIKeys orders keys oldest first, ties by ID.
+func sortAPIKeys(keys []*domain.APIKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+}
+
+// ─── Audit log ────────────────────────────────────────────────────────────────
+
+// AppendAuditEntry stores e if it directly follows the last stored entry.
+func (s *Memory) AppendAuditEntry(e domain.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e.Seq != int64(len(s.auditLog))+1 {
+		return ErrAuditConflict
+	}
+	s.auditLog = append(s.auditLog, e)
+	return nil
+}
+
+// AuditEntriesAfter returns the entries after seq, in append order.
+func (s *Memory) AuditEntriesAfter(seq int64) ([]domain.AuditEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if seq < 0 {
+		seq = 0
+	}
+	if seq >= int64(len(s.auditLog)) {
+		return nil, nil
+	}
+	return append([]domain.AuditEntry(nil), s.auditLog[seq:]...), nil
+}
+
 // ─── Blocklist / Allowlist ────────────────────────────────────────────────────
 
 // SaveBlocklistEntry upserts a blocklist or allowlist rule.
-func (s *Store) SaveBlocklistEntry(entry *domain.BlocklistEntry) {
+func (s *Memory) SaveBlocklistEntry(entry *domain.BlocklistEntry) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.blocklist[entry.ID] = entry
 }
 
+// GetBlocklistEntry retrieves a single entry by ID, expired or not.
+func (s *Memory) GetBlocklistEntry(id string) (*domain.BlocklistEntry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entry, ok := s.blocklist[id]
+	return entry, ok
+}
+
 // DeleteBlocklistEntry removes an entry by ID. Returns false if not found.
-func (s *Store) DeleteBlocklistEntry(id string) bool {
+func (s *Memory) DeleteBlocklistEntry(id string) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	_, exists := s.blocklist[id]
@@ -177,7 +969,7 @@ func (s *Store) DeleteBlocklistEntry(id string) bool {
 
 // CheckBlocklist looks up whether an entity is on the block or allow list.
 // Expired entries are silently skipped.
-func (s *Store) CheckBlocklist(entityType, value string) (*domain.BlocklistEntry, bool) {
+func (s *Memory) CheckBlocklist(entityType, value string) (*domain.BlocklistEntry, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -194,8 +986,30 @@ func (s *Store) CheckBlocklist(entityType, value string) (*domain.BlocklistEntry
 	return nil, false
 }
 
+// FirstBlocked returns the index of the first of nodes with an active block
+// entry, and the entry, in one pass over the list.
+func (s *Memory) FirstBlocked(nodes []domain.EntityNode) (int, *domain.BlocklistEntry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	blocked := make(map[domain.EntityNode]*domain.BlocklistEntry)
+	for _, entry := range s.blocklist {
+		if entry.ListType != domain.ListBlock || (entry.ExpiresAt != nil && entry.ExpiresAt.Before(now)) {
+			continue
+		}
+		blocked[domain.EntityNode{Type: entry.Type, Value: entry.Value}] = entry
+	}
+	for i, n := range nodes {
+		if entry, ok := blocked[n]; ok {
+			return i, entry, true
+		}
+	}
+	return 0, nil, false
+}
+
 // ListBlocklistEntries returns all non-expired entries.
-func (s *Store) ListBlocklistEntries() []*domain.BlocklistEntry {
+func (s *Memory) ListBlocklistEntries() []*domain.BlocklistEntry {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -212,14 +1026,22 @@ func (s *Store) ListBlocklistEntries() []*domain.BlocklistEntry {
 // ─── Webhooks ─────────────────────────────────────────────────────────────────
 
 // SaveWebhook persists a webhook configuration.
-func (s *Store) SaveWebhook(wh *domain.WebhookConfig) {
+func (s *Memory) SaveWebhook(wh *domain.WebhookConfig) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.webhooks[wh.ID] = wh
 }
 
+// GetWebhook retrieves a single webhook by ID.
+func (s *Memory) GetWebhook(id string) (*domain.WebhookConfig, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	wh, ok := s.webhooks[id]
+	return wh, ok
+}
+
 // DeleteWebhook removes a webhook by ID. Returns false if not found.
-func (s *Store) DeleteWebhook(id string) bool {
+func (s *Memory) DeleteWebhook(id string) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	_, exists := s.webhooks[id]
@@ -230,7 +1052,7 @@ func (s *Store) DeleteWebhook(id string) bool {
 }
 
 // ListActiveWebhooks returns all webhooks that are currently active.
-func (s *Store) ListActiveWebhooks() []*domain.WebhookConfig {
+func (s *Memory) ListActiveWebhooks() []*domain.WebhookConfig {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -242,3 +1064,132 @@ func (s *Store) ListActiveWebhooks() []*domain.WebhookConfig {
 	}
 	return result
 }
+
+// ─── Thresholds ───────────────────────────────────────────────────────────────
+
+func thresholdKey(scope, key string) string {
+	return scope + ":" + key
+}
+
+// SetThresholds upserts a threshold override and records the change.
+// The returned change carries the previous value (nil if none) and the new one.
+func (s *Memory) SetThresholds(cfg *domain.ThresholdConfig, actor string) domain.ThresholdChange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := thresholdKey(cfg.Scope, cfg.Key)
+	var before *domain.Thresholds
+	if prev, ok := s.thresholds[k]; ok {
+		t := prev.Thresholds
+		before = &t
+	}
+	s.thresholds[k] = cfg
+
+	after := cfg.Thresholds
+	return s.recordThresholdChange(cfg.Scope, cfg.Key, before, &after, actor, cfg.UpdatedAt)
+}
+
+// DeleteThresholds removes an override and records the change.
+// Returns false if no override exists for the scope and key.
+func (s *Memory) DeleteThresholds(scope, key, actor string) (domain.ThresholdChange, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := thresholdKey(scope, key)
+	prev, ok := s.thresholds[k]
+	if !ok {
+		return domain.ThresholdChange{}, false
+	}
+	delete(s.thresholds, k)
+
+	before := prev.Thresholds
+	return s.recordThresholdChange(scope, key, &before, nil, actor, time.Now().UTC()), true
+}
+
+// recordThresholdChange appends to the change history.
+// Must be called with the write lock held.
+func (s *Memory) recordThresholdChange(scope, key string, before, after *domain.Thresholds, actor string, at time.Time) domain.ThresholdChange {
+	ch := domain.ThresholdChange{
+		ID:        uuid.NewString(),
+		Scope:     scope,
+		Key:       key,
+		Before:    before,
+		After:     after,
+		Actor:     actor,
+		ChangedAt: at,
+	}
+	s.thresholdChanges = append(s.thresholdChanges, ch)
+	return ch
+}
+
+// ListThresholds returns every threshold override.
+func (s *Memory) ListThresholds() []*domain.ThresholdConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*domain.ThresholdConfig, 0, len(s.thresholds))
+	for _, cfg := range s.thresholds {
+		result = append(result, cfg)
+	}
+	return result
+}
+
+// ListThresholdChanges returns the threshold change history, oldest first.
+func (s *Memory) ListThresholdChanges() []domain.ThresholdChange {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]domain.ThresholdChange, len(s.thresholdChanges))
+	copy(result, s.thresholdChanges)
+	return result
+}
+
+// ResolveThresholds returns the thresholds that apply to a merchant, checking
+// the merchant ID, then the merchant country, then the global override, and
+// finally falling back to domain.DefaultThresholds. The second return value
+// names the scope that matched, e.g. "merchant:acme", "country:BR" or "default".
+func (s *Memory) ResolveThresholds(merchantID, merchantCountry string) (domain.Thresholds, string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	candidates := []struct{ scope, key string }{
+		{domain.ScopeMerchant, merchantID},
+		{domain.ScopeCountry, strings.ToUpper(merchantCountry)},
+		{domain.ScopeGlobal, ""},
+	}
+	for _, c := range candidates {
+		if c.scope != domain.ScopeGlobal && c.key == "" {
+			continue
+		}
+		if cfg, ok := s.thresholds[thresholdKey(c.scope, c.key)]; ok {
+			if c.key == "" {
+				return cfg.Thresholds, c.scope
+			}
+			return cfg.Thresholds, thresholdKey(c.scope, c.key)
+		}
+	}
+	return domain.DefaultThresholds, domain.ScopeDefault
+}
+
+// ─── Settings ─────────────────────────────────────────────────────────────────
+
+// PutSetting stores a setting, one revision past the one it replaces, if
+// st.Revision is still the stored one.
+func (s *Memory) PutSetting(st domain.Setting) (domain.Setting, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.settings[st.Name].Revision != st.Revision {
+		return st, ErrSettingConflict
+	}
+	st.Revision++
+	s.settings[st.Name] = st
+	return st, nil
+}
+
+// GetSetting returns the setting stored under name.
+func (s *Memory) GetSetting(name string) (domain.Setting, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	st, ok := s.settings[name]
+	return st, ok
+}
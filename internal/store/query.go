@@ -0,0 +1,210 @@
+package store
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"lumina/fraud-api/internal/domain"
+)
+
+// Sort keys for TransactionQuery. Ties on the key are ordered by transaction
+// ID, so every transaction has one position and cursors never skip or repeat.
+const (
+	SortByTimestamp = "timestamp"
+	SortByScore     = "risk_score"
+	SortByAmount    = "amount"
+)
+
+// DefaultSearchLimit is the page size used when TransactionQuery.Limit is not
+// positive.
+const DefaultSearchLimit = 50
+
+// TransactionQuery selects and orders transactions for SearchTransactions.
+// Zero fields match everything; a list matches any of its values.
+type TransactionQuery struct {
+	Since time.Time // inclusive
+	Until time.Time // exclusive
+
+	RiskLevels        []string
+	Recommendations   []string
+	Currencies        []string
+	IPCountries       []string
+	CardCountries     []string
+	MerchantCountries []string
+	Factor            string // name of a risk factor that fired
+
+	MinScore, MaxScore *int // inclusive
+	// Inclusive bounds in the reporting currency; see
+	// domain.Transaction.ReportingAmount.
+	MinAmount, MaxAmount *float64
+
+	Sort  string // a SortBy constant; empty sorts by timestamp
+	Desc  bool
+	After *Cursor // resume after this position; nil starts from the first match
+	Limit int
+}
+
+// Cursor is the position of a transaction in a query's order: its sort keys
+// and ID. A page resumes strictly after it.
+type Cursor struct {
+	Timestamp time.Time `json:"timestamp"`
+	Score     int       `json:"risk_score"`
+	Amount    float64   `json:"amount"`
+	ID        string    `json:"id"`
+}
+
+// CursorAt returns the cursor positioned at tx.
+func CursorAt(tx *domain.Transaction) Cursor {
+	return Cursor{Timestamp: tx.Timestamp, Score: tx.RiskScore, Amount: tx.ReportingAmount(), ID: tx.TransactionID}
+}
+
+// TransactionPage is one page of search results.
+type TransactionPage struct {
+	Transactions []*domain.Transaction
+	More         bool // further matches follow the last transaction
+}
+
+func (q *TransactionQuery) sortKey() string {
+	if q.Sort == "" {
+		return SortByTimestamp
+	}
+	return q.Sort
+}
+
+func (q *TransactionQuery) limit() int {
+	if q.Limit <= 0 {
+		return DefaultSearchLimit
+	}
+	return q.Limit
+}
+
+// window returns the time range to scan. When sorting by time, a cursor
+// narrows it to the side still to be read.
+func (q *TransactionQuery) window() (from, to time.Time) {
+	from, to = q.Since, q.Until
+	if q.After == nil || q.sortKey() != SortByTimestamp {
+		return from, to
+	}
+	at := q.After.Timestamp
+	if q.Desc {
+		if end := at.Add(time.Nanosecond); to.IsZero() || end.Before(to) {
+			to = end
+		}
+	} else if at.After(from) {
+		from = at
+	}
+	return from, to
+}
+
+// compare orders two positions by the query's sort key, then by ID, in the
+// query's direction.
+func (q *TransactionQuery) compare(a, b Cursor) int {
+	var c int
+	switch q.sortKey() {
+	case SortByScore:
+		c = a.Score - b.Score
+	case SortByAmount:
+		switch {
+		case a.Amount < b.Amount:
+			c = -1
+		case a.Amount > b.Amount:
+			c = 1
+		}
+	default:
+		c = a.Timestamp.Compare(b.Timestamp)
+	}
+	if c == 0 {
+		c = strings.Compare(a.ID, b.ID)
+	}
+	if q.Desc {
+		return -c
+	}
+	return c
+}
+
+// matches reports whether tx passes every filter and lies after the cursor.
+func (q *TransactionQuery) matches(tx *domain.Transaction) bool {
+	switch {
+	case !q.Since.IsZero() && tx.Timestamp.Before(q.Since),
+		!q.Until.IsZero() && !tx.Timestamp.Before(q.Until),
+		!anyOf(q.RiskLevels, tx.RiskLevel),
+		!anyOf(q.Recommendations, tx.Recommendation),
+		!anyOf(q.Currencies, tx.Currency),
+		!anyOf(q.IPCountries, tx.IPCountry),
+		!anyOf(q.CardCountries, tx.CardCountry),
+		!anyOf(q.MerchantCountries, tx.MerchantCountry),
+		q.MinScore != nil && tx.RiskScore < *q.MinScore,
+		q.MaxScore != nil && tx.RiskScore > *q.MaxScore,
+		q.MinAmount != nil && tx.ReportingAmount() < *q.MinAmount,
+		q.MaxAmount != nil && tx.ReportingAmount() > *q.MaxAmount,
+		q.Factor != "" && !firedFactor(tx, q.Factor),
+		q.After != nil && q.compare(CursorAt(tx), *q.After) <= 0:
+		return false
+	}
+	return true
+}
+
+func anyOf(values []string, v string) bool {
+	for _, want := range values {
+		if v == want {
+			return true
+		}
+	}
+	return len(values) == 0
+}
+
+func firedFactor(tx *domain.Transaction, name string) bool {
+	for _, f := range tx.Factors {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// pageBuilder assembles a page for backends that scan transactions in time
+// order rather than asking an engine to sort. Offered transactions must
+// arrive in the query's time direction, grouped by sameTime: the timestamp
+// itself, or a coarser unit if the backend only orders to that precision.
+//
+// A time-sorted query stops the scan once the page is full and the scan has
+// left the tie group of the page's last match, since nothing later can sort
+// before it. Other sorts must see the whole window.
+type pageBuilder struct {
+	q        *TransactionQuery
+	sameTime func(a, b time.Time) bool
+	matched  []*domain.Transaction
+	edge     *time.Time // time of the first match beyond the limit
+}
+
+func newPageBuilder(q *TransactionQuery, sameTime func(a, b time.Time) bool) *pageBuilder {
+	return &pageBuilder{q: q, sameTime: sameTime}
+}
+
+// offer considers tx and reports whether the scan can stop.
+func (b *pageBuilder) offer(tx *domain.Transaction) bool {
+	if b.edge != nil && !b.sameTime(tx.Timestamp, *b.edge) {
+		return true
+	}
+	if !b.q.matches(tx) {
+		return false
+	}
+	b.matched = append(b.matched, tx)
+	if b.edge == nil && b.q.sortKey() == SortByTimestamp && len(b.matched) > b.q.limit() {
+		at := tx.Timestamp
+		b.edge = &at
+	}
+	return false
+}
+
+// page orders the matches and cuts them to the limit.
+func (b *pageBuilder) page() TransactionPage {
+	sort.Slice(b.matched, func(i, j int) bool {
+		return b.q.compare(CursorAt(b.matched[i]), CursorAt(b.matched[j])) < 0
+	})
+	if n := b.q.limit(); len(b.matched) > n {
+		return TransactionPage{Transactions: b.matched[:n], More: true}
+	}
+	return TransactionPage{Transactions: b.matched}
+}
@@ -0,0 +1,1566 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"lumina/fraud-api/internal/domain"
+)
+
+// Defaults for RedisOptions zero values.
+const (
+	DefaultRedisPrefix = "lumina:"
+	DefaultRedisTTL    = 90 * 24 * time.Hour
+)
+
+// maxWatchRetries bounds optimistic-lock retries when concurrent writers keep
+// touching the same keys.
+const maxWatchRetries = 16
+
+// RedisOptions configures a Redis store. Zero values pick the defaults.
+type RedisOptions struct {
+	Prefix string        // prepended to every key; default DefaultRedisPrefix
+	TTL    time.Duration // lifetime of transactions and entity history; negative never expires
+}
+
+// Redis is a Store kept entirely in Redis, so any number of API replicas
+// can share one history.
+//
+// Entity history uses one sorted set per entity value, scored by the
+// transaction's Unix time in milliseconds: SaveTransaction ZADDs the ID to
+// the email, IP, device and BIN sets, and the windowed lookups are a
+// ZRANGEBYSCORE from `since` followed by an MGET of the records. The
+// distinct BINs per IP are a plain set, so GetUniqueCardsByIP is an exact
+// SCARD rather than a HyperLogLog estimate. The emails seen with each IP,
+// device and BIN are a sorted set scored by when each was last seen (ZADD
+// GT), so CountDistinctEmails is an exact ZCOUNT rather than a walk over the
+// window. Each entity's links are three
+// sorted sets keyed by the linked entity, scored by weight (ZINCRBY), first
+// seen (ZADD LT) and last seen (ZADD GT).
+//
+// Transaction records, entity and email sets, BIN sets, link sets and the
+// all-transactions set expire TTL after their last write. On each save they
+// also drop members older than TTL before now, so history ages out without a
+// sweeper. The cutoff is the server clock, never the saved event's own
+// timestamp, so a client-supplied future timestamp cannot evict an entity's
+// history. Like BIN sets, link sets are not trimmed: a link's weight counts
+// every transaction since its entity's sets were created. IDs left in an
+// index by an expired record are skipped on read and removed then. Idempotency keys
+// expire with their own TTL. Lists, webhooks, thresholds, settings, API keys
+// and the audit log never expire.
+//
+// Read-modify-write methods (outcomes, review claims and decisions, list and
+// threshold changes, settings, API key updates and audit appends) use WATCH/MULTI and
+// retry on conflict, so concurrent replicas cannot lose each other's updates.
+// The Store methods that cannot return an error log it and report "not found"
+// or an empty result instead.
+type Redis struct {
+	c    redis.UniversalClient
+	opts RedisOptions
+}
+
+// Redis satisfies the full Store interface.
+var _ Store = (*Redis)(nil)
+
+// NewRedis returns a store using client. The caller owns the client and
+// closes it.
+func NewRedis(client redis.UniversalClient, opts RedisOptions) *Redis {
+	if opts.Prefix == "" {
+		opts.Prefix = DefaultRedisPrefix
+	}
+	if opts.TTL == 0 {
+		opts.TTL = DefaultRedisTTL
+	}
+	return &Redis{c: client, opts: opts}
+}
+
+// ─── Keys ─────────────────────────────────────────────────────────────────────
+
+func (r *Redis) key(parts ...string) string {
+	return r.opts.Prefix + strings.Join(parts, ":")
+}
+
+func (r *Redis) txKey(id string) string { return r.key("tx", id) }
+
+// entityKey names the sorted set of transaction IDs for one entity value.
+func (r *Redis) entityKey(entityType, value string) string {
+	return r.key("entity", entityType, value)
+}
+
+func (r *Redis) cardsKey(ip string) string { return r.key("cards", ip) }
+
+// emailsKey names the sorted set of the emails seen with an IP, device or
+// BIN, each scored by when it was last seen with it.
+func (r *Redis) emailsKey(entityType, value string) string {
+	return r.key("emails", entityType, value)
+}
+
+// linksKey names one of the sorted sets holding an entity's links, keyed by
+// the linked entity's linkMember: its weight, first seen or last seen.
+func (r *Redis) linksKey(field string, n domain.EntityNode) string {
+	return r.key("links", field, n.Type, n.Value)
+}
+
+func linkMember(n domain.EntityNode) string { return n.Type + ":" + n.Value }
+
+// fraudKey is the sorted set of an email's transactions whose outcomes label
+// them fraud, scored by transaction time.
+func (r *Redis) fraudKey(email string) string { return r.key("fraud", email) }
+
+func (r *Redis) blocklistEntityKey(entityType, value string) string {
+	return r.key("blocklist", "entity", entityType, value)
+}
+
+// ttl is the expiry to set on history keys; 0 means none.
+func (r *Redis) ttl() time.Duration {
+	if r.opts.TTL < 0 {
+		return 0
+	}
+	return r.opts.TTL
+}
+
+// score is the sorted-set score of a time. Milliseconds keep scores exact in
+// a float64; lookups re-check the precise timestamp after loading.
+func score(t time.Time) float64 {
+	return float64(t.UnixMilli())
+}
+
+// ─── Transactions ─────────────────────────────────────────────────────────────
+
+// SaveTransaction stores a transaction and adds it to every entity index.
+// Returns ErrDuplicateTransaction if the ID already exists.
+func (r *Redis) SaveTransaction(tx *domain.Transaction) error {
+	return r.SaveTransactions([]*domain.Transaction{tx})
+}
+
+// SaveTransactions stores a batch. Every record is claimed with SETNX first,
+// so a taken ID releases the claims made so far and saves nothing; the
+// indexes of the whole batch are then added in one MULTI/EXEC, so scoring
+// sees all of it or none of it.
+func (r *Redis) SaveTransactions(txs []*domain.Transaction) error {
+	ctx := context.Background()
+	keys := make([]string, 0, len(txs))
+	release := func() {
+		if len(keys) > 0 {
+			r.c.Del(ctx, keys...)
+		}
+	}
+	seen := make(map[string]bool, len(txs))
+	for _, tx := range txs {
+		if seen[tx.TransactionID] {
+			release()
+			return ErrDuplicateTransaction
+		}
+		seen[tx.TransactionID] = true
+		data, err := json.Marshal(tx)
+		if err != nil {
+			release()
+			return err
+		}
+		ok, err := r.c.SetNX(ctx, r.txKey(tx.TransactionID), data, r.ttl()).Result()
+		if err != nil {
+			release()
+			return err
+		}
+		if !ok {
+			release()
+			return ErrDuplicateTransaction
+		}
+		keys = append(keys, r.txKey(tx.TransactionID))
+	}
+
+	expired := "(" + formatScore(score(time.Now().Add(-r.ttl())))
+	_, err := r.c.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		for _, tx := range txs {
+			r.indexTransaction(ctx, p, tx, expired)
+		}
+		return nil
+	})
+	if err != nil {
+		// Without their indexes the records would be invisible to scoring;
+		// drop them so the caller can retry the whole save.
+		release()
+		return err
+	}
+	return nil
+}
+
+// indexTransaction queues the index updates for a newly stored transaction.
+// Members scored at or before expired are trimmed from the sets it touches.
+func (r *Redis) indexTransaction(ctx context.Context, p redis.Pipeliner, tx *domain.Transaction, expired string) {
+	member := redis.Z{Score: score(tx.Timestamp), Member: tx.TransactionID}
+	all := r.key("tx", "all")
+	p.ZAdd(ctx, all, member)
+	if ttl := r.ttl(); ttl > 0 {
+		p.ZRemRangeByScore(ctx, all, "-inf", expired)
+		p.Expire(ctx, all, ttl)
+	}
+	for _, e := range []struct{ typ, value string }{
+		{domain.EntityEmail, tx.UserEmail},
+		{domain.EntityIP, tx.IPAddress},
+		{domain.EntityDevice, tx.DeviceFingerprint},
+		{domain.EntityBIN, tx.CardBIN},
+	} {
+		keys := []string{r.entityKey(e.typ, e.value)}
+		p.ZAdd(ctx, keys[0], member)
+		if e.typ != domain.EntityEmail {
+			keys = append(keys, r.emailsKey(e.typ, e.value))
+			p.ZAddGT(ctx, keys[1], redis.Z{Score: member.Score, Member: tx.UserEmail})
+		}
+		if ttl := r.ttl(); ttl > 0 {
+			for _, k := range keys {
+				p.ZRemRangeByScore(ctx, k, "-inf", expired)
+				p.Expire(ctx, k, ttl)
+			}
+		}
+	}
+	p.SAdd(ctx, r.cardsKey(tx.IPAddress), tx.CardBIN)
+	if ttl := r.ttl(); ttl > 0 {
+		p.Expire(ctx, r.cardsKey(tx.IPAddress), ttl)
+	}
+	r.linkEntities(ctx, p, tx)
+	r.flagFraud(ctx, p, tx)
+	if tx.FinalStatus == domain.StatusPendingReview {
+		p.SAdd(ctx, r.key("review", "pending"), tx.TransactionID)
+	}
+}
+
+// GetTransaction retrieves a single transaction by ID.
+func (r *Redis) GetTransaction(id string) (*domain.Transaction, bool) {
+	tx, err := r.getTransaction(context.Background(), r.c, id)
+	if err != nil {
+		if !errors.Is(err, ErrTransactionNotFound) {
+			logRedisError("get transaction", err)
+		}
+		return nil, false
+	}
+	return tx, true
+}
+
+// GetTransactionsByEmail returns the email's transactions at or after since.
+func (r *Redis) GetTransactionsByEmail(email string, since time.Time) []*domain.Transaction {
+	return r.window(r.entityKey(domain.EntityEmail, email), since)
+}
+
+// GetTransactionsByIP returns the IP's transactions at or after since.
+func (r *Redis) GetTransactionsByIP(ip string, since time.Time) []*domain.Transaction {
+	return r.window(r.entityKey(domain.EntityIP, ip), since)
+}
+
+// GetTransactionsByDevice returns the device's transactions at or after since.
+func (r *Redis) GetTransactionsByDevice(device string, since time.Time) []*domain.Transaction {
+	return r.window(r.entityKey(domain.EntityDevice, device), since)
+}
+
+// GetTransactionsByBIN returns the BIN's transactions at or after since.
+func (r *Redis) GetTransactionsByBIN(bin string, since time.Time) []*domain.Transaction {
+	return r.window(r.entityKey(domain.EntityBIN, bin), since)
+}
+
+// GetUniqueCardsByIP returns how many distinct card BINs have been used from
+// the IP within the TTL.
+func (r *Redis) GetUniqueCardsByIP(ip string) int {
+	n, err := r.c.SCard(context.Background(), r.cardsKey(ip)).Result()
+	if err != nil {
+		logRedisError("unique cards by ip", err)
+	}
+	return int(n)
+}
+
+// CountTransactions counts the entity's transactions at or after since with
+// ZCOUNT. Scores are truncated to the millisecond, so only members sharing
+// since's millisecond are loaded to compare their precise timestamp.
+func (r *Redis) CountTransactions(entityType, value string, since time.Time) int {
+	ctx := context.Background()
+	key := r.entityKey(entityType, value)
+	edge := formatScore(score(since))
+	n, err := r.c.ZCount(ctx, key, "("+edge, "+inf").Result()
+	if err != nil {
+		logRedisError("count "+key, err)
+		return 0
+	}
+	ids, err := r.c.ZRangeByScore(ctx, key, &redis.ZRangeBy{Min: edge, Max: edge}).Result()
+	if err != nil {
+		logRedisError("count "+key, err)
+		return 0
+	}
+	txs, _, err := r.loadTransactions(ctx, ids)
+	if err != nil {
+		logRedisError("count "+key, err)
+		return 0
+	}
+	for _, tx := range txs {
+		if !tx.Timestamp.Before(since) {
+			n++
+		}
+	}
+	return int(n)
+}
+
+// CountDistinctEmails counts the distinct emails among the entity's
+// transactions at or after since: the emails last seen with it since then,
+// counted with ZCOUNT on its emails set. As in CountTransactions, only the
+// transactions in since's millisecond are loaded, to settle the emails last
+// seen in it.
+func (r *Redis) CountDistinctEmails(entityType, value string, since time.Time) int {
+	if entityType == domain.EntityEmail {
+		return min(r.CountTransactions(entityType, value, since), 1)
+	}
+	ctx := context.Background()
+	key := r.emailsKey(entityType, value)
+	edge := formatScore(score(since))
+	n, err := r.c.ZCount(ctx, key, "("+edge, "+inf").Result()
+	if err != nil {
+		logRedisError("count "+key, err)
+		return 0
+	}
+	ids, err := r.c.ZRangeByScore(ctx, r.entityKey(entityType, value), &redis.ZRangeBy{Min: edge, Max: edge}).Result()
+	if err != nil {
+		logRedisError("count "+key, err)
+		return 0
+	}
+	txs, _, err := r.loadTransactions(ctx, ids)
+	if err != nil {
+		logRedisError("count "+key, err)
+		return 0
+	}
+	var emails []string
+	for _, tx := range txs {
+		if !tx.Timestamp.Before(since) && !slices.Contains(emails, tx.UserEmail) {
+			emails = append(emails, tx.UserEmail)
+		}
+	}
+	if len(emails) == 0 {
+		return int(n)
+	}
+	// An email seen at the edge and again later is already counted.
+	last, err := r.c.ZMScore(ctx, key, emails...).Result()
+	if err != nil {
+		logRedisError("count "+key, err)
+		return 0
+	}
+	for _, at := range last {
+		if at == score(since) {
+			n++
+		}
+	}
+	return int(n)
+}
+
+// GetAllTransactions returns every transaction at or after since.
+func (r *Redis) GetAllTransactions(since time.Time) []*domain.Transaction {
+	return r.window(r.key("tx", "all"), since)
+}
+
+// searchBatch is how many IDs SearchTransactions loads per round trip.
+const searchBatch = 256
+
+// SearchTransactions pages through the all-transactions sorted set over the
+// query's window, in the query's time direction, loading records in batches
+// and filtering them here. A time-sorted page stops once it is full; sorting
+// by score or amount reads the whole window. Scores are in milliseconds, so
+// ties are settled within each millisecond after loading.
+func (r *Redis) SearchTransactions(q TransactionQuery) (TransactionPage, error) {
+	ctx := context.Background()
+	all := r.key("tx", "all")
+	from, to := q.window()
+	by := &redis.ZRangeBy{Min: "-inf", Max: "+inf", Count: searchBatch}
+	if !from.IsZero() {
+		by.Min = formatScore(score(from))
+	}
+	if !to.IsZero() {
+		by.Max = formatScore(score(to))
+	}
+	sameMilli := func(a, b time.Time) bool { return a.UnixMilli() == b.UnixMilli() }
+
+	b := newPageBuilder(&q, sameMilli)
+	var expired []any
+	defer func() {
+		if len(expired) > 0 {
+			r.c.ZRem(ctx, all, expired...)
+		}
+	}()
+	for ; ; by.Offset += searchBatch {
+		var ids []string
+		var err error
+		if q.Desc {
+			ids, err = r.c.ZRevRangeByScore(ctx, all, by).Result()
+		} else {
+			ids, err = r.c.ZRangeByScore(ctx, all, by).Result()
+		}
+		if err != nil {
+			return TransactionPage{}, err
+		}
+		txs, missing, err := r.loadTransactions(ctx, ids)
+		if err != nil {
+			return TransactionPage{}, err
+		}
+		expired = append(expired, missing...)
+		for _, tx := range txs {
+			if b.offer(tx) {
+				return b.page(), nil
+			}
+		}
+		if len(ids) < searchBatch {
+			return b.page(), nil
+		}
+	}
+}
+
+// window loads the transactions in a time-scored index at or after since,
+// oldest first.
+func (r *Redis) window(indexKey string, since time.Time) []*domain.Transaction {
+	ctx := context.Background()
+	ids, err := r.c.ZRangeByScore(ctx, indexKey, &redis.ZRangeBy{Min: formatScore(score(since)), Max: "+inf"}).Result()
+	if err != nil {
+		logRedisError("window "+indexKey, err)
+		return nil
+	}
+	txs, missing, err := r.loadTransactions(ctx, ids)
+	if err != nil {
+		logRedisError("window "+indexKey, err)
+		return nil
+	}
+	if len(missing) > 0 {
+		r.c.ZRem(ctx, indexKey, missing...)
+	}
+	// Scores are truncated to the millisecond.
+	result := txs[:0]
+	for _, tx := range txs {
+		if !tx.Timestamp.Before(since) {
+			result = append(result, tx)
+		}
+	}
+	return result
+}
+
+// loadTransactions fetches records by ID, returning the IDs whose record has
+// expired separately.
+func (r *Redis) loadTransactions(ctx context.Context, ids []string) ([]*domain.Transaction, []any, error) {
+	if len(ids) == 0 {
+		return nil, nil, nil
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = r.txKey(id)
+	}
+	vals, err := r.c.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, nil, err
+	}
+	var txs []*domain.Transaction
+	var missing []any
+	for i, v := range vals {
+		s, ok := v.(string)
+		if !ok {
+			missing = append(missing, ids[i])
+			continue
+		}
+		var tx domain.Transaction
+		if err := json.Unmarshal([]byte(s), &tx); err != nil {
+			return nil, nil, fmt.Errorf("decode %s: %w", ids[i], err)
+		}
+		txs = append(txs, &tx)
+	}
+	return txs, missing, nil
+}
+
+func (r *Redis) getTransaction(ctx context.Context, c redis.Cmdable, id string) (*domain.Transaction, error) {
+	data, err := c.Get(ctx, r.txKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrTransactionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var tx domain.Transaction
+	if err := json.Unmarshal(data, &tx); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+// replaceTransaction queues an overwrite of a stored transaction that keeps
+// its remaining TTL.
+func (r *Redis) replaceTransaction(ctx context.Context, p redis.Pipeliner, tx *domain.Transaction) error {
+	data, err := json.Marshal(tx)
+	if err != nil {
+		return err
+	}
+	p.SetArgs(ctx, r.txKey(tx.TransactionID), data, redis.SetArgs{KeepTTL: true})
+	return nil
+}
+
+// ─── Outcomes ─────────────────────────────────────────────────────────────────
+
+// AddOutcome appends an outcome to its transaction and returns the updated
+// transaction, or ErrTransactionNotFound.
+func (r *Redis) AddOutcome(o domain.Outcome) (*domain.Transaction, error) {
+	ctx := context.Background()
+	outcomes := r.key("outcomes")
+	var updated *domain.Transaction
+	err := r.watch(ctx, func(t *redis.Tx) error {
+		tx, err := r.getTransaction(ctx, t, o.TransactionID)
+		if err != nil {
+			return err
+		}
+		tx.Outcomes = append(tx.Outcomes, o)
+		data, err := json.Marshal(o)
+		if err != nil {
+			return err
+		}
+		_, err = t.TxPipelined(ctx, func(p redis.Pipeliner) error {
+			if err := r.replaceTransaction(ctx, p, tx); err != nil {
+				return err
+			}
+			p.ZAdd(ctx, outcomes, redis.Z{Score: score(o.OccurredAt), Member: data})
+			if ttl := r.ttl(); ttl > 0 {
+				p.ZRemRangeByScore(ctx, outcomes, "-inf", "("+formatScore(score(time.Now().Add(-ttl))))
+			}
+			r.flagFraud(ctx, p, tx)
+			return nil
+		})
+		updated = tx
+		return err
+	}, r.txKey(o.TransactionID))
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// flagFraud queues adding tx to its email's fraud set if its outcomes label
+// it fraud, or removing it if they no longer do.
+func (r *Redis) flagFraud(ctx context.Context, p redis.Pipeliner, tx *domain.Transaction) {
+	k := r.fraudKey(tx.UserEmail)
+	if fraud, known := domain.FraudLabel(tx.Outcomes); !fraud || !known {
+		p.ZRem(ctx, k, tx.TransactionID)
+		return
+	}
+	p.ZAdd(ctx, k, redis.Z{Score: score(tx.Timestamp), Member: tx.TransactionID})
+	if ttl := r.ttl(); ttl > 0 {
+		p.ZRemRangeByScore(ctx, k, "-inf", "("+formatScore(score(time.Now().Add(-ttl))))
+		p.Expire(ctx, k, ttl)
+	}
+}
+
+// FirstFraudLabelled returns the index of the first of emails with a
+// fraud-labelled transaction, and that email's earliest one, reading every
+// email's fraud set in one round trip.
+func (r *Redis) FirstFraudLabelled(emails []string) (int, string, bool) {
+	ctx := context.Background()
+	min := "-inf"
+	if ttl := r.ttl(); ttl > 0 {
+		min = formatScore(score(time.Now().Add(-ttl)))
+	}
+	cmds := make([]*redis.StringSliceCmd, len(emails))
+	_, err := r.c.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for i, email := range emails {
+			cmds[i] = p.ZRangeByScore(ctx, r.fraudKey(email), &redis.ZRangeBy{Min: min, Max: "+inf", Count: 1})
+		}
+		return nil
+	})
+	if err != nil {
+		logRedisError("first fraud labelled", err)
+		return 0, "", false
+	}
+	for i, cmd := range cmds {
+		if ids := cmd.Val(); len(ids) > 0 {
+			return i, ids[0], true
+		}
+	}
+	return 0, "", false
+}
+
+// ListOutcomes returns outcomes that occurred at or after since, newest
+// first. An empty outcomeType matches every type.
+func (r *Redis) ListOutcomes(outcomeType string, since time.Time) []domain.Outcome {
+	result := []domain.Outcome{}
+	vals, err := r.c.ZRevRangeByScore(context.Background(), r.key("outcomes"),
+		&redis.ZRangeBy{Min: formatScore(score(since)), Max: "+inf"}).Result()
+	if err != nil {
+		logRedisError("list outcomes", err)
+		return result
+	}
+	for _, v := range vals {
+		var o domain.Outcome
+		if err := json.Unmarshal([]byte(v), &o); err != nil {
+			logRedisError("list outcomes", err)
+			continue
+		}
+		if (outcomeType == "" || o.Type == outcomeType) && !o.OccurredAt.Before(since) {
+			result = append(result, o)
+		}
+	}
+	return result
+}
+
+// ─── Review queue ─────────────────────────────────────────────────────────────
+
+// PendingReviews returns every transaction still awaiting review.
+func (r *Redis) PendingReviews() []*domain.Transaction {
+	ctx := context.Background()
+	pending := r.key("review", "pending")
+	ids, err := r.c.SMembers(ctx, pending).Result()
+	if err != nil {
+		logRedisError("pending reviews", err)
+		return nil
+	}
+	txs, missing, err := r.loadTransactions(ctx, ids)
+	if err != nil {
+		logRedisError("pending reviews", err)
+		return nil
+	}
+	if len(missing) > 0 {
+		r.c.SRem(ctx, pending, missing...)
+	}
+	result := txs[:0]
+	for _, tx := range txs {
+		if tx.FinalStatus == domain.StatusPendingReview {
+			result = append(result, tx)
+		}
+	}
+	return result
+}
+
+// ActiveReviewClaims returns the claims that have not lapsed at now, keyed
+// by transaction ID.
+func (r *Redis) ActiveReviewClaims(now time.Time) map[string]domain.ReviewClaim {
+	result := make(map[string]domain.ReviewClaim)
+	vals, err := r.c.HVals(context.Background(), r.key("review", "claims")).Result()
+	if err != nil {
+		logRedisError("active review claims", err)
+		return result
+	}
+	for _, v := range vals {
+		var c domain.ReviewClaim
+		if err := json.Unmarshal([]byte(v), &c); err != nil {
+			logRedisError("active review claims", err)
+			continue
+		}
+		if now.Before(c.ExpiresAt) {
+			result[c.TransactionID] = c
+		}
+	}
+	return result
+}
+
+// ClaimReview assigns a queued case to analyst until now+ttl. Re-claiming
+// one's own case extends it; a lapsed claim by someone else is taken over.
+func (r *Redis) ClaimReview(txID, analyst string, now time.Time, ttl time.Duration) (domain.ReviewClaim, error) {
+	ctx := context.Background()
+	claims := r.key("review", "claims")
+	claim := domain.ReviewClaim{TransactionID: txID, Analyst: analyst, ClaimedAt: now, ExpiresAt: now.Add(ttl)}
+	err := r.watch(ctx, func(t *redis.Tx) error {
+		if _, err := r.pendingTransaction(ctx, t, txID); err != nil {
+			return err
+		}
+		held, err := r.getClaim(ctx, t, txID)
+		if err != nil {
+			return err
+		}
+		if held != nil && held.Analyst != analyst && now.Before(held.ExpiresAt) {
+			return ErrReviewClaimed
+		}
+		data, err := json.Marshal(claim)
+		if err != nil {
+			return err
+		}
+		_, err = t.TxPipelined(ctx, func(p redis.Pipeliner) error {
+			p.HSet(ctx, claims, txID, data)
+			return nil
+		})
+		return err
+	}, r.txKey(txID), claims)
+	if err != nil {
+		return domain.ReviewClaim{}, err
+	}
+	return claim, nil
+}
+
+// ReleaseReview returns a claimed case to the pool. Only the analyst holding
+// an active claim may release it.
+func (r *Redis) ReleaseReview(txID, analyst string, now time.Time) error {
+	ctx := context.Background()
+	claims := r.key("review", "claims")
+	return r.watch(ctx, func(t *redis.Tx) error {
+		if _, err := r.checkClaim(ctx, t, txID, analyst, now); err != nil {
+			return err
+		}
+		_, err := t.TxPipelined(ctx, func(p redis.Pipeliner) error {
+			p.HDel(ctx, claims, txID)
+			return nil
+		})
+		return err
+	}, r.txKey(txID), claims)
+}
+
+// DecideReview closes a queued case with the analyst's decision. The analyst
+// must hold an active claim at d.DecidedAt.
+func (r *Redis) DecideReview(txID string, d domain.ReviewDecision) (*domain.Transaction, error) {
+	ctx := context.Background()
+	claims := r.key("review", "claims")
+	var updated *domain.Transaction
+	err := r.watch(ctx, func(t *redis.Tx) error {
+		tx, err := r.checkClaim(ctx, t, txID, d.Analyst, d.DecidedAt)
+		if err != nil {
+			return err
+		}
+		tx.FinalStatus = domain.StatusApproved
+		if d.Decision == domain.ActionDecline {
+			tx.FinalStatus = domain.StatusDeclined
+		}
+		tx.Review = &d
+		_, err = t.TxPipelined(ctx, func(p redis.Pipeliner) error {
+			if err := r.replaceTransaction(ctx, p, tx); err != nil {
+				return err
+			}
+			p.SRem(ctx, r.key("review", "pending"), txID)
+			p.HDel(ctx, claims, txID)
+			return nil
+		})
+		updated = tx
+		return err
+	}, r.txKey(txID), claims)
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (r *Redis) pendingTransaction(ctx context.Context, c redis.Cmdable, txID string) (*domain.Transaction, error) {
+	tx, err := r.getTransaction(ctx, c, txID)
+	if err != nil {
+		return nil, err
+	}
+	if tx.FinalStatus != domain.StatusPendingReview {
+		return nil, ErrNotInReview
+	}
+	return tx, nil
+}
+
+// getClaim returns the stored claim on a case, lapsed or not, or nil.
+func (r *Redis) getClaim(ctx context.Context, c redis.Cmdable, txID string) (*domain.ReviewClaim, error) {
+	data, err := c.HGet(ctx, r.key("review", "claims"), txID).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var claim domain.ReviewClaim
+	if err := json.Unmarshal(data, &claim); err != nil {
+		return nil, err
+	}
+	return &claim, nil
+}
+
+// checkClaim verifies that analyst holds an active claim on a queued case
+// and returns the case.
+func (r *Redis) checkClaim(ctx context.Context, c redis.Cmdable, txID, analyst string, now time.Time) (*domain.Transaction, error) {
+	tx, err := r.pendingTransaction(ctx, c, txID)
+	if err != nil {
+		return nil, err
+	}
+	claim, err := r.getClaim(ctx, c, txID)
+	if err != nil {
+		return nil, err
+	}
+	if claim == nil || !now.Before(claim.ExpiresAt) {
+		return nil, ErrReviewNotClaimed
+	}
+	if claim.Analyst != analyst {
+		return nil, ErrReviewClaimed
+	}
+	return tx, nil
+}
+
+// ─── Entity links ─────────────────────────────────────────────────────────────
+
+// linkEntities queues the commands that add tx to the links between each
+// ordered pair of its entities.
+func (r *Redis) linkEntities(ctx context.Context, p redis.Pipeliner, tx *domain.Transaction) {
+	at := score(tx.Timestamp)
+	nodes := tx.Entities()
+	for _, from := range nodes {
+		weight, first, last := r.linksKey("weight", from), r.linksKey("first", from), r.linksKey("last", from)
+		for _, to := range nodes {
+			if from == to {
+				continue
+			}
+			member := linkMember(to)
+			p.ZIncrBy(ctx, weight, 1, member)
+			p.ZAddLT(ctx, first, redis.Z{Score: at, Member: member})
+			p.ZAddGT(ctx, last, redis.Z{Score: at, Member: member})
+		}
+		if ttl := r.ttl(); ttl > 0 {
+			p.Expire(ctx, weight, ttl)
+			p.Expire(ctx, first, ttl)
+			p.Expire(ctx, last, ttl)
+		}
+	}
+}
+
+// GetEntityLinks returns the entities that shared a transaction with the
+// given one, read from its three link sets in one round trip.
+func (r *Redis) GetEntityLinks(entityType, value string) []domain.EntityLink {
+	ctx := context.Background()
+	node := domain.EntityNode{Type: entityType, Value: value}
+	var weight, first, last *redis.ZSliceCmd
+	_, err := r.c.Pipelined(ctx, func(p redis.Pipeliner) error {
+		weight = p.ZRangeWithScores(ctx, r.linksKey("weight", node), 0, -1)
+		first = p.ZRangeWithScores(ctx, r.linksKey("first", node), 0, -1)
+		last = p.ZRangeWithScores(ctx, r.linksKey("last", node), 0, -1)
+		return nil
+	})
+	if err != nil {
+		logRedisError("entity links", err)
+		return nil
+	}
+
+	seen := make(map[string][2]float64)
+	for _, z := range first.Val() {
+		at := seen[z.Member.(string)]
+		at[0] = z.Score
+		seen[z.Member.(string)] = at
+	}
+	for _, z := range last.Val() {
+		at := seen[z.Member.(string)]
+		at[1] = z.Score
+		seen[z.Member.(string)] = at
+	}
+	result := make([]domain.EntityLink, 0, len(weight.Val()))
+	for _, z := range weight.Val() {
+		member := z.Member.(string)
+		typ, val, ok := strings.Cut(member, ":")
+		if !ok {
+			continue
+		}
+		at := seen[member]
+		result = append(result, domain.EntityLink{
+			Node:      domain.EntityNode{Type: typ, Value: val},
+			Weight:    int(z.Score),
+			FirstSeen: time.UnixMilli(int64(at[0])).UTC(),
+			LastSeen:  time.UnixMilli(int64(at[1])).UTC(),
+		})
+	}
+	return result
+}
+
+// ─── Idempotency keys ─────────────────────────────────────────────────────────
+
+func (r *Redis) idempotencyKey(key string) string { return r.key("idempotency", key) }
+
+// SaveIdempotencyKey stores k unless its key is still bound at k.CreatedAt,
+// in which case the existing record is returned with ErrIdempotencyKeyExists.
+// The Redis key expires with the record.
+func (r *Redis) SaveIdempotencyKey(k domain.IdempotencyKey) (domain.IdempotencyKey, error) {
+	ctx := context.Background()
+	rk := r.idempotencyKey(k.Key)
+	ttl := k.ExpiresAt.Sub(k.CreatedAt)
+	if ttl <= 0 {
+		return k, nil // lapsed on arrival; nothing to remember
+	}
+	saved := k
+	err := r.watch(ctx, func(t *redis.Tx) error {
+		held, err := getJSONValue[domain.IdempotencyKey](ctx, t, rk)
+		if err != nil {
+			return err
+		}
+		if held != nil && k.CreatedAt.Before(held.ExpiresAt) {
+			saved = *held
+			return ErrIdempotencyKeyExists
+		}
+		data, err := json.Marshal(k)
+		if err != nil {
+			return err
+		}
+		_, err = t.TxPipelined(ctx, func(p redis.Pipeliner) error {
+			p.Set(ctx, rk, data, ttl)
+			return nil
+		})
+		return err
+	}, rk)
+	if err != nil && !errors.Is(err, ErrIdempotencyKeyExists) {
+		return domain.IdempotencyKey{}, err
+	}
+	return saved, err
+}
+
+// ReleaseIdempotencyKey deletes k's record if it is still the one stored.
+func (r *Redis) ReleaseIdempotencyKey(k domain.IdempotencyKey) error {
+	ctx := context.Background()
+	rk := r.idempotencyKey(k.Key)
+	return r.watch(ctx, func(t *redis.Tx) error {
+		held, err := getJSONValue[domain.IdempotencyKey](ctx, t, rk)
+		if err != nil || held == nil || !sameIdempotencyKey(*held, k) {
+			return err
+		}
+		_, err = t.TxPipelined(ctx, func(p redis.Pipeliner) error {
+			p.Del(ctx, rk)
+			return nil
+		})
+		return err
+	}, rk)
+}
+
+// GetIdempotencyKey returns the record for key unless it has expired at now.
+func (r *Redis) GetIdempotencyKey(key string, now time.Time) (domain.IdempotencyKey, bool) {
+	k, err := getJSONValue[domain.IdempotencyKey](context.Background(), r.c, r.idempotencyKey(key))
+	if err != nil {
+		logRedisError("get idempotency key", err)
+		return domain.IdempotencyKey{}, false
+	}
+	if k == nil || !now.Before(k.ExpiresAt) {
+		return domain.IdempotencyKey{}, false
+	}
+	return *k, true
+}
+
+// ─── API keys ─────────────────────────────────────────────────────────────────
+
+// API keys live in one hash by ID, with a second hash from each key's current
+// token hash to its ID. Neither expires.
+
+// CreateAPIKey stores a new key unless a key has its token hash.
+func (r *Redis) CreateAPIKey(k *domain.APIKey) error {
+	ctx := context.Background()
+	byHash := r.key("api_keys", "by_hash")
+	data, err := json.Marshal(k)
+	if err != nil {
+		return err
+	}
+	return r.watch(ctx, func(t *redis.Tx) error {
+		taken, err := t.HExists(ctx, byHash, k.TokenHash).Result()
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrAPIKeyExists
+		}
+		_, err = t.TxPipelined(ctx, func(p redis.Pipeliner) error {
+			r.putAPIKey(ctx, p, k, data)
+			return nil
+		})
+		return err
+	}, byHash)
+}
+
+// UpdateAPIKey replaces a stored key that has not been revoked.
+func (r *Redis) UpdateAPIKey(k *domain.APIKey) error {
+	ctx := context.Background()
+	keys, byHash := r.key("api_keys"), r.key("api_keys", "by_hash")
+	data, err := json.Marshal(k)
+	if err != nil {
+		return err
+	}
+	return r.watch(ctx, func(t *redis.Tx) error {
+		prev, err := getHashJSON[domain.APIKey](ctx, t, keys, k.ID)
+		if err != nil {
+			return err
+		}
+		switch {
+		case prev == nil:
+			return ErrAPIKeyNotFound
+		case prev.RevokedAt != nil:
+			return ErrAPIKeyRevoked
+		}
+		if id, err := t.HGet(ctx, byHash, k.TokenHash).Result(); err == nil && id != k.ID {
+			return ErrAPIKeyExists
+		} else if err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+		_, err = t.TxPipelined(ctx, func(p redis.Pipeliner) error {
+			if prev.TokenHash != k.TokenHash {
+				p.HDel(ctx, byHash, prev.TokenHash)
+			}
+			r.putAPIKey(ctx, p, k, data)
+			return nil
+		})
+		return err
+	}, keys, byHash)
+}
+
+// putAPIKey queues storing k and indexing its token hash.
+func (r *Redis) putAPIKey(ctx context.Context, p redis.Pipeliner, k *domain.APIKey, data []byte) {
+	p.HSet(ctx, r.key("api_keys"), k.ID, data)
+	p.HSet(ctx, r.key("api_keys", "by_hash"), k.TokenHash, k.ID)
+}
+
+// GetAPIKey returns a key by ID.
+func (r *Redis) GetAPIKey(id string) (*domain.APIKey, bool) {
+	k, err := getHashJSON[domain.APIKey](context.Background(), r.c, r.key("api_keys"), id)
+	if err != nil {
+		logRedisError("get api key", err)
+	}
+	return k, k != nil
+}
+
+// GetAPIKeyByHash returns the key whose current token hashes to hash.
+func (r *Redis) GetAPIKeyByHash(hash string) (*domain.APIKey, bool) {
+	id, err := r.c.HGet(context.Background(), r.key("api_keys", "by_hash"), hash).Result()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			logRedisError("get api key by hash", err)
+		}
+		return nil, false
+	}
+	return r.GetAPIKey(id)
+}
+
+// ListAPIKeys returns every key, revoked ones included, oldest first.
+func (r *Redis) ListAPIKeys() []*domain.APIKey {
+	result, err := hashValues[domain.APIKey](context.Background(), r.c, r.key("api_keys"))
+	if err != nil {
+		logRedisError("list api keys", err)
+		return nil
+	}
+	sortAPIKeys(result)
+	return result
+}
+
+// ─── Audit log ────────────────────────────────────────────────────────────────
+
+// The audit chain is one list in append order, so entry Seq n is at index
+// n-1. It never expires.
+
+// AppendAuditEntry stores e if it directly follows the last stored entry.
+func (r *Redis) AppendAuditEntry(e domain.AuditEntry) error {
+	ctx := context.Background()
+	key := r.key("audit")
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return r.watch(ctx, func(t *redis.Tx) error {
+		n, err := t.LLen(ctx, key).Result()
+		if err != nil {
+			return err
+		}
+		if e.Seq != n+1 {
+			return ErrAuditConflict
+		}
+		_, err = t.TxPipelined(ctx, func(p redis.Pipeliner) error {
+			p.RPush(ctx, key, data)
+			return nil
+		})
+		return err
+	}, key)
+}
+
+// AuditEntriesAfter returns the entries after seq, in append order.
+func (r *Redis) AuditEntriesAfter(seq int64) ([]domain.AuditEntry, error) {
+	if seq < 0 {
+		seq = 0
+	}
+	values, err := r.c.LRange(context.Background(), r.key("audit"), seq, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]domain.AuditEntry, len(values))
+	for i, v := range values {
+		if err := json.Unmarshal([]byte(v), &result[i]); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// ─── Blocklist / Allowlist ────────────────────────────────────────────────────
+
+// SaveBlocklistEntry upserts a blocklist or allowlist rule.
+func (r *Redis) SaveBlocklistEntry(entry *domain.BlocklistEntry) {
+	ctx := context.Background()
+	blocklist := r.key("blocklist")
+	data, err := json.Marshal(entry)
+	if err != nil {
+		logRedisError("save blocklist entry", err)
+		return
+	}
+	err = r.watch(ctx, func(t *redis.Tx) error {
+		prev, err := getHashJSON[domain.BlocklistEntry](ctx, t, blocklist, entry.ID)
+		if err != nil {
+			return err
+		}
+		_, err = t.TxPipelined(ctx, func(p redis.Pipeliner) error {
+			if prev != nil {
+				p.SRem(ctx, r.blocklistEntityKey(prev.Type, prev.Value), prev.ID)
+			}
+			p.HSet(ctx, blocklist, entry.ID, data)
+			p.SAdd(ctx, r.blocklistEntityKey(entry.Type, entry.Value), entry.ID)
+			return nil
+		})
+		return err
+	}, blocklist)
+	if err != nil {
+		logRedisError("save blocklist entry", err)
+	}
+}
+
+// GetBlocklistEntry retrieves a single entry by ID, expired or not.
+func (r *Redis) GetBlocklistEntry(id string) (*domain.BlocklistEntry, bool) {
+	entry, err := getHashJSON[domain.BlocklistEntry](context.Background(), r.c, r.key("blocklist"), id)
+	if err != nil {
+		logRedisError("get blocklist entry", err)
+	}
+	return entry, entry != nil
+}
+
+// DeleteBlocklistEntry removes an entry by ID. Returns false if not found.
+func (r *Redis) DeleteBlocklistEntry(id string) bool {
+	ctx := context.Background()
+	blocklist := r.key("blocklist")
+	var found bool
+	err := r.watch(ctx, func(t *redis.Tx) error {
+		prev, err := getHashJSON[domain.BlocklistEntry](ctx, t, blocklist, id)
+		if err != nil || prev == nil {
+			return err
+		}
+		_, err = t.TxPipelined(ctx, func(p redis.Pipeliner) error {
+			p.HDel(ctx, blocklist, id)
+			p.SRem(ctx, r.blocklistEntityKey(prev.Type, prev.Value), id)
+			return nil
+		})
+		found = err == nil
+		return err
+	}, blocklist)
+	if err != nil {
+		logRedisError("delete blocklist entry", err)
+	}
+	return found
+}
+
+// CheckBlocklist looks up whether an entity is on the block or allow list.
+// Expired entries are skipped.
+func (r *Redis) CheckBlocklist(entityType, value string) (*domain.BlocklistEntry, bool) {
+	ctx := context.Background()
+	ids, err := r.c.SMembers(ctx, r.blocklistEntityKey(entityType, value)).Result()
+	if err != nil || len(ids) == 0 {
+		if err != nil {
+			logRedisError("check blocklist", err)
+		}
+		return nil, false
+	}
+	vals, err := r.c.HMGet(ctx, r.key("blocklist"), ids...).Result()
+	if err != nil {
+		logRedisError("check blocklist", err)
+		return nil, false
+	}
+	now := time.Now()
+	for _, v := range vals {
+		entry, ok := decodeValue[domain.BlocklistEntry](v)
+		if !ok {
+			continue
+		}
+		if entry.ExpiresAt != nil && entry.ExpiresAt.Before(now) {
+			continue // expired
+		}
+		return entry, true
+	}
+	return nil, false
+}
+
+// FirstBlocked returns the index of the first of nodes with an active block
+// entry, and the entry, in two round trips however many nodes are asked.
+func (r *Redis) FirstBlocked(nodes []domain.EntityNode) (int, *domain.BlocklistEntry, bool) {
+	ctx := context.Background()
+	cmds := make([]*redis.StringSliceCmd, len(nodes))
+	_, err := r.c.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for i, n := range nodes {
+			cmds[i] = p.SMembers(ctx, r.blocklistEntityKey(n.Type, n.Value))
+		}
+		return nil
+	})
+	if err != nil {
+		logRedisError("first blocked", err)
+		return 0, nil, false
+	}
+	var ids []string
+	for _, cmd := range cmds {
+		ids = append(ids, cmd.Val()...)
+	}
+	if len(ids) == 0 {
+		return 0, nil, false
+	}
+	vals, err := r.c.HMGet(ctx, r.key("blocklist"), ids...).Result()
+	if err != nil {
+		logRedisError("first blocked", err)
+		return 0, nil, false
+	}
+	now := time.Now()
+	blocked := make(map[domain.EntityNode]*domain.BlocklistEntry)
+	for _, v := range vals {
+		entry, ok := decodeValue[domain.BlocklistEntry](v)
+		if !ok || entry.ListType != domain.ListBlock || (entry.ExpiresAt != nil && entry.ExpiresAt.Before(now)) {
+			continue
+		}
+		blocked[domain.EntityNode{Type: entry.Type, Value: entry.Value}] = entry
+	}
+	for i, n := range nodes {
+		if entry, ok := blocked[n]; ok {
+			return i, entry, true
+		}
+	}
+	return 0, nil, false
+}
+
+// ListBlocklistEntries returns all non-expired entries.
+func (r *Redis) ListBlocklistEntries() []*domain.BlocklistEntry {
+	entries, err := hashValues[domain.BlocklistEntry](context.Background(), r.c, r.key("blocklist"))
+	if err != nil {
+		logRedisError("list blocklist entries", err)
+		return nil
+	}
+	now := time.Now()
+	var result []*domain.BlocklistEntry
+	for _, entry := range entries {
+		if entry.ExpiresAt == nil || entry.ExpiresAt.After(now) {
+			result = append(result, entry)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// ─── Webhooks ─────────────────────────────────────────────────────────────────
+
+// SaveWebhook upserts a webhook configuration.
+func (r *Redis) SaveWebhook(wh *domain.WebhookConfig) {
+	data, err := json.Marshal(wh)
+	if err == nil {
+		err = r.c.HSet(context.Background(), r.key("webhooks"), wh.ID, data).Err()
+	}
+	if err != nil {
+		logRedisError("save webhook", err)
+	}
+}
+
+// GetWebhook retrieves a single webhook by ID.
+func (r *Redis) GetWebhook(id string) (*domain.WebhookConfig, bool) {
+	wh, err := getHashJSON[domain.WebhookConfig](context.Background(), r.c, r.key("webhooks"), id)
+	if err != nil {
+		logRedisError("get webhook", err)
+	}
+	return wh, wh != nil
+}
+
+// DeleteWebhook removes a webhook by ID. Returns false if not found.
+func (r *Redis) DeleteWebhook(id string) bool {
+	n, err := r.c.HDel(context.Background(), r.key("webhooks"), id).Result()
+	if err != nil {
+		logRedisError("delete webhook", err)
+	}
+	return n > 0
+}
+
+// ListActiveWebhooks returns all webhooks that are currently active.
+func (r *Redis) ListActiveWebhooks() []*domain.WebhookConfig {
+	hooks, err := hashValues[domain.WebhookConfig](context.Background(), r.c, r.key("webhooks"))
+	if err != nil {
+		logRedisError("list webhooks", err)
+		return nil
+	}
+	var result []*domain.WebhookConfig
+	for _, wh := range hooks {
+		if wh.Active {
+			result = append(result, wh)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// ─── Thresholds ───────────────────────────────────────────────────────────────
+
+// SetThresholds upserts a threshold override and records the change.
+func (r *Redis) SetThresholds(cfg *domain.ThresholdConfig, actor string) domain.ThresholdChange {
+	ctx := context.Background()
+	thresholds := r.key("thresholds")
+	field := thresholdKey(cfg.Scope, cfg.Key)
+	var change domain.ThresholdChange
+	err := r.watch(ctx, func(t *redis.Tx) error {
+		prev, err := getHashJSON[domain.ThresholdConfig](ctx, t, thresholds, field)
+		if err != nil {
+			return err
+		}
+		var before *domain.Thresholds
+		if prev != nil {
+			before = &prev.Thresholds
+		}
+		after := cfg.Thresholds
+		change = newThresholdChange(cfg.Scope, cfg.Key, before, &after, actor, cfg.UpdatedAt)
+		return r.writeThresholds(ctx, t, field, cfg, change)
+	}, thresholds)
+	if err != nil {
+		logRedisError("set thresholds", err)
+	}
+	return change
+}
+
+// DeleteThresholds removes an override and records the change.
+// Returns false if no override exists for the scope and key.
+func (r *Redis) DeleteThresholds(scope, key, actor string) (domain.ThresholdChange, bool) {
+	ctx := context.Background()
+	thresholds := r.key("thresholds")
+	field := thresholdKey(scope, key)
+	var change domain.ThresholdChange
+	var found bool
+	err := r.watch(ctx, func(t *redis.Tx) error {
+		prev, err := getHashJSON[domain.ThresholdConfig](ctx, t, thresholds, field)
+		if err != nil || prev == nil {
+			return err
+		}
+		before := prev.Thresholds
+		change = newThresholdChange(scope, key, &before, nil, actor, time.Now().UTC())
+		if err := r.writeThresholds(ctx, t, field, nil, change); err != nil {
+			return err
+		}
+		found = true
+		return nil
+	}, thresholds)
+	if err != nil {
+		logRedisError("delete thresholds", err)
+		return domain.ThresholdChange{}, false
+	}
+	return change, found
+}
+
+// writeThresholds sets (or, with a nil cfg, deletes) an override and appends
+// its change record in one MULTI.
+func (r *Redis) writeThresholds(ctx context.Context, t *redis.Tx, field string, cfg *domain.ThresholdConfig, change domain.ThresholdChange) error {
+	changeData, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	var cfgData []byte
+	if cfg != nil {
+		if cfgData, err = json.Marshal(cfg); err != nil {
+			return err
+		}
+	}
+	_, err = t.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		if cfg != nil {
+			p.HSet(ctx, r.key("thresholds"), field, cfgData)
+		} else {
+			p.HDel(ctx, r.key("thresholds"), field)
+		}
+		p.RPush(ctx, r.key("thresholds", "changes"), changeData)
+		return nil
+	})
+	return err
+}
+
+func newThresholdChange(scope, key string, before, after *domain.Thresholds, actor string, at time.Time) domain.ThresholdChange {
+	return domain.ThresholdChange{
+		ID:        uuid.NewString(),
+		Scope:     scope,
+		Key:       key,
+		Before:    before,
+		After:     after,
+		Actor:     actor,
+		ChangedAt: at,
+	}
+}
+
+// ListThresholds returns every threshold override.
+func (r *Redis) ListThresholds() []*domain.ThresholdConfig {
+	result, err := hashValues[domain.ThresholdConfig](context.Background(), r.c, r.key("thresholds"))
+	if err != nil {
+		logRedisError("list thresholds", err)
+	}
+	if result == nil {
+		result = []*domain.ThresholdConfig{}
+	}
+	return result
+}
+
+// ListThresholdChanges returns the threshold change history, oldest first.
+func (r *Redis) ListThresholdChanges() []domain.ThresholdChange {
+	result := []domain.ThresholdChange{}
+	vals, err := r.c.LRange(context.Background(), r.key("thresholds", "changes"), 0, -1).Result()
+	if err != nil {
+		logRedisError("list threshold changes", err)
+		return result
+	}
+	for _, v := range vals {
+		var ch domain.ThresholdChange
+		if err := json.Unmarshal([]byte(v), &ch); err != nil {
+			logRedisError("list threshold changes", err)
+			continue
+		}
+		result = append(result, ch)
+	}
+	return result
+}
+
+// ResolveThresholds returns the most specific thresholds for a merchant, in
+// the same order as Memory.ResolveThresholds, with one HMGET.
+func (r *Redis) ResolveThresholds(merchantID, merchantCountry string) (domain.Thresholds, string) {
+	candidates := []struct{ scope, key string }{
+		{domain.ScopeMerchant, merchantID},
+		{domain.ScopeCountry, strings.ToUpper(merchantCountry)},
+		{domain.ScopeGlobal, ""},
+	}
+	fields := make([]string, len(candidates))
+	for i, c := range candidates {
+		fields[i] = thresholdKey(c.scope, c.key)
+	}
+	vals, err := r.c.HMGet(context.Background(), r.key("thresholds"), fields...).Result()
+	if err != nil {
+		logRedisError("resolve thresholds", err)
+		return domain.DefaultThresholds, domain.ScopeDefault
+	}
+	for i, c := range candidates {
+		if c.scope != domain.ScopeGlobal && c.key == "" {
+			continue
+		}
+		if cfg, ok := decodeValue[domain.ThresholdConfig](vals[i]); ok {
+			if c.key == "" {
+				return cfg.Thresholds, c.scope
+			}
+			return cfg.Thresholds, fields[i]
+		}
+	}
+	return domain.DefaultThresholds, domain.ScopeDefault
+}
+
+// ─── Settings ─────────────────────────────────────────────────────────────────
+
+// PutSetting stores a setting in the settings hash, one revision past the one
+// it replaces, if st.Revision is still the stored one. The check and the
+// write are one WATCHed transaction.
+func (r *Redis) PutSetting(st domain.Setting) (domain.Setting, error) {
+	ctx := context.Background()
+	settings := r.key("settings")
+	err := r.watch(ctx, func(t *redis.Tx) error {
+		prev, err := getHashJSON[domain.Setting](ctx, t, settings, st.Name)
+		if err != nil {
+			return err
+		}
+		var stored int64
+		if prev != nil {
+			stored = prev.Revision
+		}
+		if stored != st.Revision {
+			return ErrSettingConflict
+		}
+		st.Revision++
+		data, err := json.Marshal(st)
+		if err != nil {
+			return err
+		}
+		_, err = t.TxPipelined(ctx, func(p redis.Pipeliner) error {
+			p.HSet(ctx, settings, st.Name, data)
+			return nil
+		})
+		return err
+	}, settings)
+	return st, err
+}
+
+// GetSetting returns the setting stored under name.
+func (r *Redis) GetSetting(name string) (domain.Setting, bool) {
+	st, err := getHashJSON[domain.Setting](context.Background(), r.c, r.key("settings"), name)
+	if err != nil {
+		logRedisError("get setting", err)
+	}
+	if st == nil {
+		return domain.Setting{}, false
+	}
+	return *st, true
+}
+
+// ─── Helpers ──────────────────────────────────────────────────────────────────
+
+// watch runs fn as an optimistic transaction over keys, retrying when
+// another client changes one of them before the MULTI executes.
+func (r *Redis) watch(ctx context.Context, fn func(t *redis.Tx) error, keys ...string) error {
+	for i := 0; i < maxWatchRetries; i++ {
+		err := r.c.Watch(ctx, fn, keys...)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return fmt.Errorf("redis: %d conflicting writes on %v", maxWatchRetries, keys)
+}
+
+// getJSONValue decodes a string key, returning nil if it does not exist.
+func getJSONValue[T any](ctx context.Context, c redis.Cmdable, key string) (*T, error) {
+	data, err := c.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	v := new(T)
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// getHashJSON decodes one hash field, returning nil if it does not exist.
+func getHashJSON[T any](ctx context.Context, c redis.Cmdable, key, field string) (*T, error) {
+	data, err := c.HGet(ctx, key, field).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	v := new(T)
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func hashValues[T any](ctx context.Context, c redis.Cmdable, key string) ([]*T, error) {
+	vals, err := c.HVals(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*T, 0, len(vals))
+	for _, s := range vals {
+		v := new(T)
+		if err := json.Unmarshal([]byte(s), v); err != nil {
+			return result, err
+		}
+		result = append(result, v)
+	}
+	return result, nil
+}
+
+// decodeValue decodes one MGET/HMGET reply; missing values decode as false.
+func decodeValue[T any](reply any) (*T, bool) {
+	s, ok := reply.(string)
+	if !ok {
+		return nil, false
+	}
+	v := new(T)
+	if err := json.Unmarshal([]byte(s), v); err != nil {
+		logRedisError("decode", err)
+		return nil, false
+	}
+	return v, true
+}
+
+func formatScore(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func logRedisError(op string, err error) {
+	slog.Error("redis store command failed", "op", op, "error", err)
+}
@@ -0,0 +1,122 @@
+package store_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"lumina/fraud-api/internal/domain"
+	"lumina/fraud-api/internal/store"
+	"lumina/fraud-api/internal/store/storetest"
+)
+
+func newRedis(t *testing.T, mr *miniredis.Miniredis, opts store.RedisOptions) *store.Redis {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return store.NewRedis(client, opts)
+}
+
+func TestRedis_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		return newRedis(t, miniredis.RunT(t), store.RedisOptions{})
+	})
+}
+
+func TestRedis_ReplicasShareState(t *testing.T) {
+	mr := miniredis.RunT(t)
+	a := newRedis(t, mr, store.RedisOptions{})
+	b := newRedis(t, mr, store.RedisOptions{})
+	now := time.Now().UTC().Truncate(time.Second)
+
+	populate(t, a, now)
+	if got, want := observe(t, b, now), observe(t, a, now); got != want {
+		t.Errorf("replicas disagree\na: %s\nb: %s", want, got)
+	}
+	if err := b.SaveTransaction(durableTx("dur-a", "u@x.com", "7.7.7.7", "411111", now)); err != store.ErrDuplicateTransaction {
+		t.Errorf("expected IDs saved by one replica to be enforced on another, got %v", err)
+	}
+}
+
+func TestRedis_HistoryExpiresAfterTTL(t *testing.T) {
+	mr := miniredis.RunT(t)
+	s := newRedis(t, mr, store.RedisOptions{TTL: time.Hour})
+	now := time.Now().UTC()
+
+	if err := s.SaveTransaction(durableTx("ttl-1", "u@x.com", "7.7.7.7", "411111", now)); err != nil {
+		t.Fatal(err)
+	}
+	mr.FastForward(61 * time.Minute)
+
+	if _, ok := s.GetTransaction("ttl-1"); ok {
+		t.Error("expected the transaction to expire")
+	}
+	if got := s.GetTransactionsByIP("7.7.7.7", time.Time{}); len(got) != 0 {
+		t.Errorf("expected no IP history, got %d", len(got))
+	}
+	if n := s.GetUniqueCardsByIP("7.7.7.7"); n != 0 {
+		t.Errorf("expected the card set to expire, got %d", n)
+	}
+	if got := s.GetAllTransactions(time.Time{}); len(got) != 0 {
+		t.Errorf("expected expired IDs to be skipped, got %d", len(got))
+	}
+}
+
+func TestRedis_EntityHistoryTrimmedToTTL(t *testing.T) {
+	mr := miniredis.RunT(t)
+	s := newRedis(t, mr, store.RedisOptions{TTL: time.Hour})
+	now := time.Now().UTC()
+
+	// Events older than the TTL before now leave the entity's window on the
+	// next save.
+	_ = s.SaveTransaction(durableTx("old", "u@x.com", "7.7.7.7", "411111", now.Add(-2*time.Hour)))
+	_ = s.SaveTransaction(durableTx("new", "u@x.com", "7.7.7.7", "411111", now))
+
+	got := s.GetTransactionsByEmail("u@x.com", time.Time{})
+	if len(got) != 1 || got[0].TransactionID != "new" {
+		t.Errorf("expected only the recent transaction, got %d", len(got))
+	}
+}
+
+func TestRedis_FutureTimestamp_KeepsEntityHistory(t *testing.T) {
+	mr := miniredis.RunT(t)
+	s := newRedis(t, mr, store.RedisOptions{TTL: time.Hour})
+	now := time.Now().UTC()
+
+	// A client-supplied timestamp far ahead must not age out the entity's
+	// real history and so reset its velocity counts.
+	for i := 0; i < 3; i++ {
+		id := "recent-" + string(rune('a'+i))
+		_ = s.SaveTransaction(durableTx(id, "u@x.com", "7.7.7.7", "411111", now.Add(-time.Duration(i)*time.Minute)))
+	}
+	_ = s.SaveTransaction(durableTx("future", "u@x.com", "7.7.7.7", "411111", now.Add(48*time.Hour)))
+
+	if got := s.GetTransactionsByEmail("u@x.com", now.Add(-10*time.Minute)); len(got) != 4 {
+		t.Errorf("expected the 3 recent transactions to survive a future-dated one, got %d", len(got))
+	}
+	if n := s.CountTransactions(domain.EntityIP, "7.7.7.7", now.Add(-10*time.Minute)); n != 4 {
+		t.Errorf("expected the IP's velocity count to keep the recent history, got %d", n)
+	}
+}
+
+func TestRedis_AllTransactionsTrimmedToTTL(t *testing.T) {
+	mr := miniredis.RunT(t)
+	s := newRedis(t, mr, store.RedisOptions{TTL: time.Hour})
+	now := time.Now().UTC()
+
+	_ = s.SaveTransaction(durableTx("old", "a@x.com", "7.7.7.7", "411111", now.Add(-2*time.Hour)))
+	_ = s.SaveTransaction(durableTx("new", "b@x.com", "8.8.8.8", "522222", now))
+
+	members, err := mr.ZMembers(store.DefaultRedisPrefix + "tx:all")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 1 || members[0] != "new" {
+		t.Errorf("expected only the recent ID in the global index, got %v", members)
+	}
+	if ttl := mr.TTL(store.DefaultRedisPrefix + "tx:all"); ttl <= 0 || ttl > time.Hour {
+		t.Errorf("expected the global index to expire within the TTL, got %v", ttl)
+	}
+}
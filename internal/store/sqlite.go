@@ -0,0 +1,1455 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mattn/go-sqlite3"
+
+	"lumina/fraud-api/internal/domain"
+)
+
+// SQLite is a Store backed by an embedded SQLite database, for single-node
+// deployments that want state on disk without running a database server.
+//
+// Each record is kept whole as JSON in a data column, next to the columns
+// the queries filter and order on. Every read is a SQL query: the entity
+// lookups the scoring engine makes on each request are served by composite
+// (entity, timestamp) indexes, so a window query touches only the rows it
+// returns. Times are stored as Unix nanoseconds.
+//
+// The Store methods that cannot return an error log it and report "not
+// found" or an empty result instead.
+type SQLite struct {
+	db *sql.DB
+}
+
+// SQLite satisfies the full Store interface.
+var _ Store = (*SQLite)(nil)
+
+// migrations are applied in order; version N is migrations[N-1]. Applied
+// migrations are never edited: schema changes are appended as new entries.
+var migrations = []string{
+	// 1: initial schema.
+	`CREATE TABLE transactions (
+		id                 TEXT PRIMARY KEY,
+		timestamp          INTEGER NOT NULL,
+		user_email         TEXT NOT NULL,
+		ip_address         TEXT NOT NULL,
+		device_fingerprint TEXT NOT NULL,
+		card_bin           TEXT NOT NULL,
+		final_status       TEXT NOT NULL,
+		data               TEXT NOT NULL
+	);
+	CREATE INDEX transactions_email_ts  ON transactions (user_email, timestamp);
+	CREATE INDEX transactions_ip_ts     ON transactions (ip_address, timestamp);
+	CREATE INDEX transactions_device_ts ON transactions (device_fingerprint, timestamp);
+	CREATE INDEX transactions_bin_ts    ON transactions (card_bin, timestamp);
+	CREATE INDEX transactions_ts        ON transactions (timestamp);
+	CREATE INDEX transactions_status    ON transactions (final_status);
+
+	-- Distinct card BINs seen per IP, all-time, mirroring Memory.cardsByIP.
+	CREATE TABLE ip_cards (
+		ip_address TEXT NOT NULL,
+		card_bin   TEXT NOT NULL,
+		PRIMARY KEY (ip_address, card_bin)
+	) WITHOUT ROWID;
+
+	CREATE TABLE outcomes (
+		id             TEXT NOT NULL,
+		transaction_id TEXT NOT NULL REFERENCES transactions (id),
+		type           TEXT NOT NULL,
+		occurred_at    INTEGER NOT NULL,
+		data           TEXT NOT NULL
+	);
+	CREATE INDEX outcomes_occurred_at ON outcomes (occurred_at);
+
+	CREATE TABLE review_claims (
+		transaction_id TEXT PRIMARY KEY REFERENCES transactions (id),
+		analyst        TEXT NOT NULL,
+		expires_at     INTEGER NOT NULL,
+		data           TEXT NOT NULL
+	);
+
+	CREATE TABLE blocklist (
+		id          TEXT PRIMARY KEY,
+		entity_type TEXT NOT NULL,
+		value       TEXT NOT NULL,
+		expires_at  INTEGER, -- NULL never expires
+		data        TEXT NOT NULL
+	);
+	CREATE INDEX blocklist_entity ON blocklist (entity_type, value);
+
+	CREATE TABLE webhooks (
+		id     TEXT PRIMARY KEY,
+		active INTEGER NOT NULL,
+		data   TEXT NOT NULL
+	);
+
+	CREATE TABLE thresholds (
+		scope TEXT NOT NULL,
+		key   TEXT NOT NULL,
+		data  TEXT NOT NULL,
+		PRIMARY KEY (scope, key)
+	);
+
+	CREATE TABLE threshold_changes (
+		seq  INTEGER PRIMARY KEY AUTOINCREMENT,
+		data TEXT NOT NULL
+	);
+
+	CREATE TABLE api_keys (
+		id         TEXT PRIMARY KEY,
+		token_hash TEXT NOT NULL UNIQUE,
+		created_at INTEGER NOT NULL,
+		data       TEXT NOT NULL
+	);
+
+	-- The audit hash chain, by position.
+	CREATE TABLE audit_log (
+		seq  INTEGER PRIMARY KEY,
+		data TEXT NOT NULL
+	);`,
+
+	// 2: date IP/BIN pairs so retention can forget stale ones.
+	`ALTER TABLE ip_cards ADD COLUMN last_seen INTEGER NOT NULL DEFAULT 0;
+	UPDATE ip_cards SET last_seen = COALESCE((
+		SELECT MAX(t.timestamp) FROM transactions t
+		WHERE t.ip_address = ip_cards.ip_address AND t.card_bin = ip_cards.card_bin
+	), 0);
+	CREATE INDEX ip_cards_last_seen ON ip_cards (last_seen);`,
+
+	// 3: client idempotency keys.
+	`CREATE TABLE idempotency_keys (
+		key            TEXT PRIMARY KEY,
+		transaction_id TEXT NOT NULL,
+		expires_at     INTEGER NOT NULL,
+		data           TEXT NOT NULL
+	);
+	CREATE INDEX idempotency_keys_expires_at ON idempotency_keys (expires_at);`,
+
+	// 4: columns for transaction search, filled from the stored records.
+	`ALTER TABLE transactions ADD COLUMN risk_score       INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE transactions ADD COLUMN risk_level       TEXT NOT NULL DEFAULT '';
+	ALTER TABLE transactions ADD COLUMN recommendation   TEXT NOT NULL DEFAULT '';
+	ALTER TABLE transactions ADD COLUMN currency         TEXT NOT NULL DEFAULT '';
+	ALTER TABLE transactions ADD COLUMN ip_country       TEXT NOT NULL DEFAULT '';
+	ALTER TABLE transactions ADD COLUMN card_country     TEXT NOT NULL DEFAULT '';
+	ALTER TABLE transactions ADD COLUMN merchant_country TEXT NOT NULL DEFAULT '';
+	ALTER TABLE transactions ADD COLUMN reporting_amount REAL NOT NULL DEFAULT 0;
+	UPDATE transactions SET
+		risk_score       = COALESCE(json_extract(data, '$.risk_score'), 0),
+		risk_level       = COALESCE(json_extract(data, '$.risk_level'), ''),
+		recommendation   = COALESCE(json_extract(data, '$.recommendation'), ''),
+		currency         = COALESCE(json_extract(data, '$.currency'), ''),
+		ip_country       = COALESCE(json_extract(data, '$.ip_country'), ''),
+		card_country     = COALESCE(json_extract(data, '$.card_country'), ''),
+		merchant_country = COALESCE(json_extract(data, '$.merchant_country'), ''),
+		reporting_amount = CASE COALESCE(json_extract(data, '$.reporting_currency'), '')
+			WHEN '' THEN COALESCE(json_extract(data, '$.amount'), 0)
+			ELSE COALESCE(json_extract(data, '$.normalized_amount'), 0)
+		END;
+	DROP INDEX transactions_ts;
+	CREATE INDEX transactions_ts_id  ON transactions (timestamp, id);
+	CREATE INDEX transactions_score  ON transactions (risk_score, id);
+	CREATE INDEX transactions_amount ON transactions (reporting_amount, id);`,
+
+	// 5: entity link graph, filled from the stored transactions.
+	`CREATE TABLE entity_links (
+		from_type  TEXT NOT NULL,
+		from_value TEXT NOT NULL,
+		to_type    TEXT NOT NULL,
+		to_value   TEXT NOT NULL,
+		weight     INTEGER NOT NULL,
+		first_seen INTEGER NOT NULL,
+		last_seen  INTEGER NOT NULL,
+		PRIMARY KEY (from_type, from_value, to_type, to_value)
+	) WITHOUT ROWID;
+	` + rebuildEntityLinks + `;`,
+
+	// 6: look up a transaction's outcomes by type, for fraud history checks.
+	`CREATE INDEX outcomes_transaction_type ON outcomes (transaction_id, type);`,
+
+	// 7: runtime settings such as the active rule set.
+	`CREATE TABLE settings (
+		name TEXT PRIMARY KEY,
+		data BLOB NOT NULL
+	);`,
+}
+
+// rebuildEntityLinks fills entity_links from the transactions table: one row
+// per ordered pair of entities that share a transaction, the way
+// SaveTransaction maintains it.
+const rebuildEntityLinks = `INSERT INTO entity_links (from_type, from_value, to_type, to_value, weight, first_seen, last_seen)
+	SELECT from_type, from_value, to_type, to_value, COUNT(*), MIN(timestamp), MAX(timestamp) FROM (
+		          SELECT 'email' AS from_type, user_email AS from_value, 'ip' AS to_type, ip_address AS to_value, timestamp FROM transactions
+		UNION ALL SELECT 'email', user_email, 'device', device_fingerprint, timestamp FROM transactions
+		UNION ALL SELECT 'email', user_email, 'bin', card_bin, timestamp FROM transactions
+		UNION ALL SELECT 'ip', ip_address, 'email', user_email, timestamp FROM transactions
+		UNION ALL SELECT 'ip', ip_address, 'device', device_fingerprint, timestamp FROM transactions
+		UNION ALL SELECT 'ip', ip_address, 'bin', card_bin, timestamp FROM transactions
+		UNION ALL SELECT 'device', device_fingerprint, 'email', user_email, timestamp FROM transactions
+		UNION ALL SELECT 'device', device_fingerprint, 'ip', ip_address, timestamp FROM transactions
+		UNION ALL SELECT 'device', device_fingerprint, 'bin', card_bin, timestamp FROM transactions
+		UNION ALL SELECT 'bin', card_bin, 'email', user_email, timestamp FROM transactions
+		UNION ALL SELECT 'bin', card_bin, 'ip', ip_address, timestamp FROM transactions
+		UNION ALL SELECT 'bin', card_bin, 'device', device_fingerprint, timestamp FROM transactions
+	)
+	WHERE from_value != '' AND to_value != ''
+	GROUP BY from_type, from_value, to_type, to_value`
+
+// OpenSQLite opens the database at path, creating it if missing, and brings
+// its schema up to date. A database written by a newer build is refused.
+func OpenSQLite(path string) (*SQLite, error) {
+	if path == "" {
+		return nil, errors.New("database path is required")
+	}
+	// WAL journaling lets readers run alongside the single writer; immediate
+	// transactions take the write lock up front so read-then-write methods
+	// wait on busy_timeout instead of failing with SQLITE_BUSY.
+	dsn := "file:" + path + "?_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate&_foreign_keys=on&_synchronous=NORMAL"
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	s := &SQLite{db: db}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Close closes the database.
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
+
+// SchemaVersion returns the number of migrations applied to the database.
+func (s *SQLite) SchemaVersion() (int, error) {
+	var v int
+	err := s.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&v)
+	return v, err
+}
+
+// migrate applies every migration the database has not seen yet, each in
+// its own transaction together with its schema_migrations row.
+func (s *SQLite) migrate() error {
+	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at INTEGER NOT NULL
+	)`); err != nil {
+		return err
+	}
+	current, err := s.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if current > len(migrations) {
+		return fmt.Errorf("database schema version %d is newer than this build supports (%d)", current, len(migrations))
+	}
+	for v := current + 1; v <= len(migrations); v++ {
+		err := s.inTx(func(tx *sql.Tx) error {
+			if _, err := tx.Exec(migrations[v-1]); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, v, time.Now().UnixNano())
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d: %w", v, err)
+		}
+		slog.Info("sqlite migration applied", "version", v)
+	}
+	return nil
+}
+
+// ─── Transactions ─────────────────────────────────────────────────────────────
+
+// SaveTransaction inserts a transaction, records when its IP/BIN pair was
+// last seen and links its entities.
+// Returns ErrDuplicateTransaction if the ID already exists.
+func (s *SQLite) SaveTransaction(t *domain.Transaction) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		return saveTransaction(tx, t)
+	})
+}
+
+// SaveTransactions saves a batch in one database transaction: a duplicate ID
+// rolls back every insert before it.
+func (s *SQLite) SaveTransactions(txs []*domain.Transaction) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		for _, t := range txs {
+			if err := saveTransaction(tx, t); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// saveTransaction inserts t, its IP/BIN pair and its entity links within tx.
+func saveTransaction(tx *sql.Tx, t *domain.Transaction) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO transactions
+		(id, timestamp, user_email, ip_address, device_fingerprint, card_bin, final_status, data,
+		 risk_score, risk_level, recommendation, currency, ip_country, card_country, merchant_country, reporting_amount)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.TransactionID, unixNano(t.Timestamp), t.UserEmail, t.IPAddress, t.DeviceFingerprint, t.CardBIN, t.FinalStatus, data,
+		t.RiskScore, t.RiskLevel, t.Recommendation, t.Currency, t.IPCountry, t.CardCountry, t.MerchantCountry, t.ReportingAmount())
+	if isUniqueViolation(err) {
+		return ErrDuplicateTransaction
+	}
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO ip_cards (ip_address, card_bin, last_seen) VALUES (?, ?, ?)
+		ON CONFLICT (ip_address, card_bin) DO UPDATE SET last_seen = MAX(last_seen, excluded.last_seen)`,
+		t.IPAddress, t.CardBIN, unixNano(t.Timestamp))
+	if err != nil {
+		return err
+	}
+	return linkEntities(tx, t)
+}
+
+// linkEntities adds t to the links between each ordered pair of its
+// entities.
+func linkEntities(tx *sql.Tx, t *domain.Transaction) error {
+	at := unixNano(t.Timestamp)
+	nodes := t.Entities()
+	for _, from := range nodes {
+		for _, to := range nodes {
+			if from == to {
+				continue
+			}
+			_, err := tx.Exec(`INSERT INTO entity_links (from_type, from_value, to_type, to_value, weight, first_seen, last_seen)
+				VALUES (?, ?, ?, ?, 1, ?, ?)
+				ON CONFLICT (from_type, from_value, to_type, to_value) DO UPDATE SET
+					weight     = weight + 1,
+					first_seen = MIN(first_seen, excluded.first_seen),
+					last_seen  = MAX(last_seen, excluded.last_seen)`,
+				from.Type, from.Value, to.Type, to.Value, at, at)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// GetTransaction retrieves a single transaction by ID.
+func (s *SQLite) GetTransaction(id string) (*domain.Transaction, bool) {
+	t, err := getTransaction(s.db, id)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			logSQLiteError("get transaction", err)
+		}
+		return nil, false
+	}
+	return t, true
+}
+
+// GetTransactionsByEmail returns the email's transactions at or after since.
+func (s *SQLite) GetTransactionsByEmail(email string, since time.Time) []*domain.Transaction {
+	return s.queryTransactions("by email", `SELECT data FROM transactions WHERE user_email = ? AND timestamp >= ? ORDER BY timestamp`, email, unixNano(since))
+}
+
+// GetTransactionsByIP returns the IP's transactions at or after since.
+func (s *SQLite) GetTransactionsByIP(ip string, since time.Time) []*domain.Transaction {
+	return s.queryTransactions("by ip", `SELECT data FROM transactions WHERE ip_address = ? AND timestamp >= ? ORDER BY timestamp`, ip, unixNano(since))
+}
+
+// GetTransactionsByDevice returns the device's transactions at or after since.
+func (s *SQLite) GetTransactionsByDevice(device string, since time.Time) []*domain.Transaction {
+	return s.queryTransactions("by device", `SELECT data FROM transactions WHERE device_fingerprint = ? AND timestamp >= ? ORDER BY timestamp`, device, unixNano(since))
+}
+
+// GetTransactionsByBIN returns the BIN's transactions at or after since.
+func (s *SQLite) GetTransactionsByBIN(bin string, since time.Time) []*domain.Transaction {
+	return s.queryTransactions("by bin", `SELECT data FROM transactions WHERE card_bin = ? AND timestamp >= ? ORDER BY timestamp`, bin, unixNano(since))
+}
+
+// GetUniqueCardsByIP returns how many distinct card BINs have been used from
+// the IP since the pairs last pruned.
+func (s *SQLite) GetUniqueCardsByIP(ip string) int {
+	var n int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM ip_cards WHERE ip_address = ?`, ip).Scan(&n); err != nil {
+		logSQLiteError("unique cards by ip", err)
+	}
+	return n
+}
+
+// FirstFraudLabelled returns the index of the first of emails with a
+// fraud-labelled transaction, and that email's earliest one. Only
+// transactions with a fraud outcome are loaded; a later false positive can
+// still clear them, so the label is derived in Go.
+func (s *SQLite) FirstFraudLabelled(emails []string) (int, string, bool) {
+	if len(emails) == 0 {
+		return 0, "", false
+	}
+	candidates := s.queryTransactions("fraud labelled", `SELECT t.data FROM transactions t
+		WHERE t.user_email IN (`+placeholders(len(emails))+`)
+		AND EXISTS (SELECT 1 FROM outcomes o WHERE o.transaction_id = t.id AND o.type IN (?, ?))
+		ORDER BY t.timestamp, t.id`,
+		append(anySlice(emails), domain.OutcomeChargeback, domain.OutcomeConfirmedFraud)...)
+	first := make(map[string]string)
+	for _, tx := range candidates {
+		if fraud, known := domain.FraudLabel(tx.Outcomes); fraud && known && first[tx.UserEmail] == "" {
+			first[tx.UserEmail] = tx.TransactionID
+		}
+	}
+	for i, email := range emails {
+		if id, ok := first[email]; ok {
+			return i, id, true
+		}
+	}
+	return 0, "", false
+}
+
+// entityColumns maps entity types to the transaction columns indexed with
+// the timestamp.
+var entityColumns = map[string]string{
+	domain.EntityEmail:  "user_email",
+	domain.EntityIP:     "ip_address",
+	domain.EntityDevice: "device_fingerprint",
+	domain.EntityBIN:    "card_bin",
+}
+
+// CountTransactions counts the entity's transactions at or after since.
+// The count is answered from the (entity, timestamp) index alone.
+func (s *SQLite) CountTransactions(entityType, value string, since time.Time) int {
+	return s.countEntity("count transactions", "COUNT(*)", entityType, value, since)
+}
+
+// CountDistinctEmails counts the distinct emails among the entity's
+// transactions at or after since.
+func (s *SQLite) CountDistinctEmails(entityType, value string, since time.Time) int {
+	return s.countEntity("count distinct emails", "COUNT(DISTINCT user_email)", entityType, value, since)
+}
+
+func (s *SQLite) countEntity(op, aggregate, entityType, value string, since time.Time) int {
+	column, ok := entityColumns[entityType]
+	if !ok {
+		return 0
+	}
+	var n int
+	err := s.db.QueryRow(`SELECT `+aggregate+` FROM transactions WHERE `+column+` = ? AND timestamp >= ?`, value, unixNano(since)).Scan(&n)
+	if err != nil {
+		logSQLiteError(op, err)
+	}
+	return n
+}
+
+// GetAllTransactions returns every transaction at or after since.
+func (s *SQLite) GetAllTransactions(since time.Time) []*domain.Transaction {
+	return s.queryTransactions("all", `SELECT data FROM transactions WHERE timestamp >= ? ORDER BY timestamp`, unixNano(since))
+}
+
+// searchColumns maps sort keys to the column holding them.
+var searchColumns = map[string]string{
+	SortByTimestamp: "timestamp",
+	SortByScore:     "risk_score",
+	SortByAmount:    "reporting_amount",
+}
+
+// SearchTransactions runs the query as one keyset-paginated SELECT: the
+// filters become WHERE clauses on indexed columns, and the cursor a
+// (key, id) comparison, so a page reads only the rows it returns plus those
+// the filters skip.
+func (s *SQLite) SearchTransactions(q TransactionQuery) (TransactionPage, error) {
+	var where []string
+	var args []any
+	cond := func(clause string, a ...any) {
+		where = append(where, clause)
+		args = append(args, a...)
+	}
+	if !q.Since.IsZero() {
+		cond("timestamp >= ?", unixNano(q.Since))
+	}
+	if !q.Until.IsZero() {
+		cond("timestamp < ?", unixNano(q.Until))
+	}
+	for _, in := range []struct {
+		col    string
+		values []string
+	}{
+		{"risk_level", q.RiskLevels},
+		{"recommendation", q.Recommendations},
+		{"currency", q.Currencies},
+		{"ip_country", q.IPCountries},
+		{"card_country", q.CardCountries},
+		{"merchant_country", q.MerchantCountries},
+	} {
+		if len(in.values) > 0 {
+			cond(in.col+" IN ("+placeholders(len(in.values))+")", anySlice(in.values)...)
+		}
+	}
+	if q.MinScore != nil {
+		cond("risk_score >= ?", *q.MinScore)
+	}
+	if q.MaxScore != nil {
+		cond("risk_score <= ?", *q.MaxScore)
+	}
+	if q.MinAmount != nil {
+		cond("reporting_amount >= ?", *q.MinAmount)
+	}
+	if q.MaxAmount != nil {
+		cond("reporting_amount <= ?", *q.MaxAmount)
+	}
+	if q.Factor != "" {
+		cond(`EXISTS (SELECT 1 FROM json_each(data, '$.factors') f WHERE json_extract(f.value, '$.name') = ?)`, q.Factor)
+	}
+
+	col, dir, op := searchColumns[q.sortKey()], "ASC", ">"
+	if q.Desc {
+		dir, op = "DESC", "<"
+	}
+	if c := q.After; c != nil {
+		var key any = unixNano(c.Timestamp)
+		switch q.sortKey() {
+		case SortByScore:
+			key = c.Score
+		case SortByAmount:
+			key = c.Amount
+		}
+		cond(fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", col, op), key, key, c.ID)
+	}
+
+	query := `SELECT data FROM transactions`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += fmt.Sprintf(` ORDER BY %[1]s %[2]s, id %[2]s LIMIT ?`, col, dir)
+	args = append(args, q.limit()+1)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return TransactionPage{}, err
+	}
+	txs, err := scanJSON[domain.Transaction](rows)
+	if err != nil {
+		return TransactionPage{}, err
+	}
+	if len(txs) > q.limit() {
+		return TransactionPage{Transactions: txs[:q.limit()], More: true}, nil
+	}
+	return TransactionPage{Transactions: txs}, nil
+}
+
+// placeholders returns n comma-separated query parameters.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func anySlice(values []string) []any {
+	result := make([]any, len(values))
+	for i, v := range values {
+		result[i] = v
+	}
+	return result
+}
+
+func (s *SQLite) queryTransactions(op, query string, args ...any) []*domain.Transaction {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		logSQLiteError("transactions "+op, err)
+		return nil
+	}
+	result, err := scanJSON[domain.Transaction](rows)
+	if err != nil {
+		logSQLiteError("transactions "+op, err)
+	}
+	return result
+}
+
+// ─── Entity links ─────────────────────────────────────────────────────────────
+
+// GetEntityLinks returns the entities that shared a transaction with the
+// given one, read from the entity_links primary key.
+func (s *SQLite) GetEntityLinks(entityType, value string) []domain.EntityLink {
+	rows, err := s.db.Query(`SELECT to_type, to_value, weight, first_seen, last_seen FROM entity_links
+		WHERE from_type = ? AND from_value = ?`, entityType, value)
+	if err != nil {
+		logSQLiteError("entity links", err)
+		return nil
+	}
+	defer rows.Close()
+
+	var result []domain.EntityLink
+	for rows.Next() {
+		var l domain.EntityLink
+		var first, last int64
+		if err := rows.Scan(&l.Node.Type, &l.Node.Value, &l.Weight, &first, &last); err != nil {
+			logSQLiteError("entity links", err)
+			return nil
+		}
+		l.FirstSeen, l.LastSeen = time.Unix(0, first).UTC(), time.Unix(0, last).UTC()
+		result = append(result, l)
+	}
+	if err := rows.Err(); err != nil {
+		logSQLiteError("entity links", err)
+		return nil
+	}
+	return result
+}
+
+// ─── Retention ────────────────────────────────────────────────────────────────
+
+// SQLite satisfies Pruner.
+var _ Pruner = (*SQLite)(nil)
+
+// Prune deletes transactions that occurred before cutoff, with their
+// outcomes, claims and idempotency keys, IP/BIN pairs last seen before
+// cutoff and keys expired before it, in one transaction. When transactions
+// go, the entity links are rebuilt from the ones that remain. Transactions
+// awaiting review are kept.
+func (s *SQLite) Prune(cutoff time.Time) (PruneStats, error) {
+	var stats PruneStats
+	c := unixNano(cutoff)
+	err := s.inTx(func(tx *sql.Tx) error {
+		const expired = `SELECT id FROM transactions WHERE timestamp < ? AND final_status != ?`
+		// Keys go with the transactions pruned here, not with every missing
+		// one: a key reserved by a request still scoring has no transaction
+		// yet.
+		res, err := tx.Exec(`DELETE FROM idempotency_keys
+			WHERE expires_at < ? OR transaction_id IN (`+expired+`)`, c, c, domain.StatusPendingReview)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		stats.IdempotencyKeys = int(n)
+
+		for _, q := range []string{
+			`DELETE FROM outcomes WHERE transaction_id IN (` + expired + `)`,
+			`DELETE FROM review_claims WHERE transaction_id IN (` + expired + `)`,
+		} {
+			if _, err := tx.Exec(q, c, domain.StatusPendingReview); err != nil {
+				return err
+			}
+		}
+		if res, err = tx.Exec(`DELETE FROM transactions WHERE timestamp < ? AND final_status != ?`, c, domain.StatusPendingReview); err != nil {
+			return err
+		}
+		if n, err = res.RowsAffected(); err != nil {
+			return err
+		}
+		stats.Transactions = int(n)
+		if n > 0 {
+			if _, err := tx.Exec(`DELETE FROM entity_links`); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(rebuildEntityLinks); err != nil {
+				return err
+			}
+		}
+
+		if res, err = tx.Exec(`DELETE FROM ip_cards WHERE last_seen < ?`, c); err != nil {
+			return err
+		}
+		if n, err = res.RowsAffected(); err != nil {
+			return err
+		}
+		stats.Cards = int(n)
+		return nil
+	})
+	if err != nil {
+		return PruneStats{}, err
+	}
+	return stats, nil
+}
+
+// ─── Outcomes ─────────────────────────────────────────────────────────────────
+
+// AddOutcome appends an outcome to its transaction and returns the updated
+// transaction, or ErrTransactionNotFound.
+func (s *SQLite) AddOutcome(o domain.Outcome) (*domain.Transaction, error) {
+	var updated *domain.Transaction
+	err := s.inTx(func(tx *sql.Tx) error {
+		t, err := getTransaction(tx, o.TransactionID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTransactionNotFound
+		}
+		if err != nil {
+			return err
+		}
+		t.Outcomes = append(t.Outcomes, o)
+		if err := replaceTransaction(tx, t); err != nil {
+			return err
+		}
+		data, err := json.Marshal(o)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO outcomes (id, transaction_id, type, occurred_at, data) VALUES (?, ?, ?, ?, ?)`,
+			o.ID, o.TransactionID, o.Type, unixNano(o.OccurredAt), data); err != nil {
+			return err
+		}
+		updated = t
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// ListOutcomes returns outcomes that occurred at or after since, newest
+// first. An empty outcomeType matches every type.
+func (s *SQLite) ListOutcomes(outcomeType string, since time.Time) []domain.Outcome {
+	rows, err := s.db.Query(`SELECT data FROM outcomes
+		WHERE occurred_at >= ? AND (? = '' OR type = ?)
+		ORDER BY occurred_at DESC`, unixNano(since), outcomeType, outcomeType)
+	if err != nil {
+		logSQLiteError("list outcomes", err)
+		return []domain.Outcome{}
+	}
+	ptrs, err := scanJSON[domain.Outcome](rows)
+	if err != nil {
+		logSQLiteError("list outcomes", err)
+	}
+	result := make([]domain.Outcome, 0, len(ptrs))
+	for _, o := range ptrs {
+		result = append(result, *o)
+	}
+	return result
+}
+
+// ─── Review queue ─────────────────────────────────────────────────────────────
+
+// PendingReviews returns every transaction still awaiting review.
+func (s *SQLite) PendingReviews() []*domain.Transaction {
+	return s.queryTransactions("pending review", `SELECT data FROM transactions WHERE final_status = ? ORDER BY timestamp`, domain.StatusPendingReview)
+}
+
+// ActiveReviewClaims returns the claims that have not lapsed at now, keyed
+// by transaction ID.
+func (s *SQLite) ActiveReviewClaims(now time.Time) map[string]domain.ReviewClaim {
+	result := make(map[string]domain.ReviewClaim)
+	rows, err := s.db.Query(`SELECT data FROM review_claims WHERE expires_at > ?`, unixNano(now))
+	if err != nil {
+		logSQLiteError("active review claims", err)
+		return result
+	}
+	claims, err := scanJSON[domain.ReviewClaim](rows)
+	if err != nil {
+		logSQLiteError("active review claims", err)
+	}
+	for _, c := range claims {
+		result[c.TransactionID] = *c
+	}
+	return result
+}
+
+// ClaimReview assigns a queued case to analyst until now+ttl. Re-claiming
+// one's own case extends it; a lapsed claim by someone else is taken over.
+func (s *SQLite) ClaimReview(txID, analyst string, now time.Time, ttl time.Duration) (domain.ReviewClaim, error) {
+	claim := domain.ReviewClaim{TransactionID: txID, Analyst: analyst, ClaimedAt: now, ExpiresAt: now.Add(ttl)}
+	err := s.inTx(func(tx *sql.Tx) error {
+		if err := checkPendingReviewTx(tx, txID); err != nil {
+			return err
+		}
+		held, err := getClaim(tx, txID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if err == nil && held.Analyst != analyst && now.Before(held.ExpiresAt) {
+			return ErrReviewClaimed
+		}
+		data, err := json.Marshal(claim)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT OR REPLACE INTO review_claims (transaction_id, analyst, expires_at, data) VALUES (?, ?, ?, ?)`,
+			txID, analyst, unixNano(claim.ExpiresAt), data)
+		return err
+	})
+	if err != nil {
+		return domain.ReviewClaim{}, err
+	}
+	return claim, nil
+}
+
+// ReleaseReview returns a claimed case to the pool. Only the analyst holding
+// an active claim may release it.
+func (s *SQLite) ReleaseReview(txID, analyst string, now time.Time) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		if err := checkClaimTx(tx, txID, analyst, now); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM review_claims WHERE transaction_id = ?`, txID)
+		return err
+	})
+}
+
+// DecideReview closes a queued case with the analyst's decision. The analyst
+// must hold an active claim at d.DecidedAt.
+func (s *SQLite) DecideReview(txID string, d domain.ReviewDecision) (*domain.Transaction, error) {
+	var updated *domain.Transaction
+	err := s.inTx(func(tx *sql.Tx) error {
+		if err := checkClaimTx(tx, txID, d.Analyst, d.DecidedAt); err != nil {
+			return err
+		}
+		t, err := getTransaction(tx, txID)
+		if err != nil {
+			return err
+		}
+		t.FinalStatus = domain.StatusApproved
+		if d.Decision == domain.ActionDecline {
+			t.FinalStatus = domain.StatusDeclined
+		}
+		t.Review = &d
+		if err := replaceTransaction(tx, t); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM review_claims WHERE transaction_id = ?`, txID); err != nil {
+			return err
+		}
+		updated = t
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func checkPendingReviewTx(tx *sql.Tx, txID string) error {
+	var status string
+	err := tx.QueryRow(`SELECT final_status FROM transactions WHERE id = ?`, txID).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrTransactionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if status != domain.StatusPendingReview {
+		return ErrNotInReview
+	}
+	return nil
+}
+
+// checkClaimTx verifies that analyst holds an active claim on a queued case.
+func checkClaimTx(tx *sql.Tx, txID, analyst string, now time.Time) error {
+	if err := checkPendingReviewTx(tx, txID); err != nil {
+		return err
+	}
+	c, err := getClaim(tx, txID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !now.Before(c.ExpiresAt)) {
+		return ErrReviewNotClaimed
+	}
+	if err != nil {
+		return err
+	}
+	if c.Analyst != analyst {
+		return ErrReviewClaimed
+	}
+	return nil
+}
+
+func getClaim(tx *sql.Tx, txID string) (*domain.ReviewClaim, error) {
+	return getJSON[domain.ReviewClaim](tx, `SELECT data FROM review_claims WHERE transaction_id = ?`, txID)
+}
+
+// ─── Idempotency keys ─────────────────────────────────────────────────────────
+
+// SaveIdempotencyKey stores k unless its key is still bound at k.CreatedAt,
+// in which case the existing record is returned with ErrIdempotencyKeyExists.
+func (s *SQLite) SaveIdempotencyKey(k domain.IdempotencyKey) (domain.IdempotencyKey, error) {
+	saved := k
+	err := s.inTx(func(tx *sql.Tx) error {
+		held, err := getJSON[domain.IdempotencyKey](tx, `SELECT data FROM idempotency_keys WHERE key = ?`, k.Key)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if err == nil && k.CreatedAt.Before(held.ExpiresAt) {
+			saved = *held
+			return ErrIdempotencyKeyExists
+		}
+		data, err := json.Marshal(k)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT OR REPLACE INTO idempotency_keys (key, transaction_id, expires_at, data) VALUES (?, ?, ?, ?)`,
+			k.Key, k.TransactionID, unixNano(k.ExpiresAt), data)
+		return err
+	})
+	if err != nil && !errors.Is(err, ErrIdempotencyKeyExists) {
+		return domain.IdempotencyKey{}, err
+	}
+	return saved, err
+}
+
+// ReleaseIdempotencyKey deletes k's record if it is still the one stored.
+func (s *SQLite) ReleaseIdempotencyKey(k domain.IdempotencyKey) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		held, err := getJSON[domain.IdempotencyKey](tx, `SELECT data FROM idempotency_keys WHERE key = ?`, k.Key)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil || !sameIdempotencyKey(*held, k) {
+			return err
+		}
+		_, err = tx.Exec(`DELETE FROM idempotency_keys WHERE key = ?`, k.Key)
+		return err
+	})
+}
+
+// GetIdempotencyKey returns the record for key unless it has expired at now.
+func (s *SQLite) GetIdempotencyKey(key string, now time.Time) (domain.IdempotencyKey, bool) {
+	k, found := getOne[domain.IdempotencyKey](s.db, "get idempotency key",
+		`SELECT data FROM idempotency_keys WHERE key = ? AND expires_at > ?`, key, unixNano(now))
+	if !found {
+		return domain.IdempotencyKey{}, false
+	}
+	return *k, true
+}
+
+// ─── API keys ─────────────────────────────────────────────────────────────────
+
+// CreateAPIKey stores a new key unless a key has its token hash.
+func (s *SQLite) CreateAPIKey(k *domain.APIKey) error {
+	data, err := json.Marshal(k)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO api_keys (id, token_hash, created_at, data) VALUES (?, ?, ?, ?)`,
+		k.ID, k.TokenHash, unixNano(k.CreatedAt), data)
+	if isUniqueViolation(err) {
+		return ErrAPIKeyExists
+	}
+	return err
+}
+
+// UpdateAPIKey replaces a stored key that has not been revoked.
+func (s *SQLite) UpdateAPIKey(k *domain.APIKey) error {
+	data, err := json.Marshal(k)
+	if err != nil {
+		return err
+	}
+	err = s.inTx(func(tx *sql.Tx) error {
+		prev, err := getJSON[domain.APIKey](tx, `SELECT data FROM api_keys WHERE id = ?`, k.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrAPIKeyNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if prev.RevokedAt != nil {
+			return ErrAPIKeyRevoked
+		}
+		_, err = tx.Exec(`UPDATE api_keys SET token_hash = ?, data = ? WHERE id = ?`, k.TokenHash, data, k.ID)
+		return err
+	})
+	if isUniqueViolation(err) {
+		return ErrAPIKeyExists
+	}
+	return err
+}
+
+// GetAPIKey returns a key by ID.
+func (s *SQLite) GetAPIKey(id string) (*domain.APIKey, bool) {
+	return getOne[domain.APIKey](s.db, "get api key", `SELECT data FROM api_keys WHERE id = ?`, id)
+}
+
+// GetAPIKeyByHash returns the key whose current token hashes to hash.
+func (s *SQLite) GetAPIKeyByHash(hash string) (*domain.APIKey, bool) {
+	return getOne[domain.APIKey](s.db, "get api key by hash", `SELECT data FROM api_keys WHERE token_hash = ?`, hash)
+}
+
+// ListAPIKeys returns every key, revoked ones included, oldest first.
+func (s *SQLite) ListAPIKeys() []*domain.APIKey {
+	rows, err := s.db.Query(`SELECT data FROM api_keys ORDER BY created_at, id`)
+	if err != nil {
+		logSQLiteError("list api keys", err)
+		return nil
+	}
+	result, err := scanJSON[domain.APIKey](rows)
+	if err != nil {
+		logSQLiteError("list api keys", err)
+	}
+	return result
+}
+
+// ─── Audit log ────────────────────────────────────────────────────────────────
+
+// AppendAuditEntry stores e if it directly follows the last stored entry.
+func (s *SQLite) AppendAuditEntry(e domain.AuditEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	err = s.inTx(func(tx *sql.Tx) error {
+		var last int64
+		if err := tx.QueryRow(`SELECT COALESCE(MAX(seq), 0) FROM audit_log`).Scan(&last); err != nil {
+			return err
+		}
+		if e.Seq != last+1 {
+			return ErrAuditConflict
+		}
+		_, err := tx.Exec(`INSERT INTO audit_log (seq, data) VALUES (?, ?)`, e.Seq, data)
+		return err
+	})
+	if isUniqueViolation(err) {
+		return ErrAuditConflict
+	}
+	return err
+}
+
+// AuditEntriesAfter returns the entries after seq, in append order.
+func (s *SQLite) AuditEntriesAfter(seq int64) ([]domain.AuditEntry, error) {
+	rows, err := s.db.Query(`SELECT data FROM audit_log WHERE seq > ? ORDER BY seq`, seq)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := scanJSON[domain.AuditEntry](rows)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]domain.AuditEntry, len(entries))
+	for i, e := range entries {
+		result[i] = *e
+	}
+	return result, nil
+}
+
+// ─── Blocklist / Allowlist ────────────────────────────────────────────────────
+
+// SaveBlocklistEntry upserts a blocklist or allowlist rule.
+func (s *SQLite) SaveBlocklistEntry(entry *domain.BlocklistEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		logSQLiteError("save blocklist entry", err)
+		return
+	}
+	var expiresAt any
+	if entry.ExpiresAt != nil {
+		expiresAt = unixNano(*entry.ExpiresAt)
+	}
+	if _, err := s.db.Exec(`INSERT OR REPLACE INTO blocklist (id, entity_type, value, expires_at, data) VALUES (?, ?, ?, ?, ?)`,
+		entry.ID, entry.Type, entry.Value, expiresAt, data); err != nil {
+		logSQLiteError("save blocklist entry", err)
+	}
+}
+
+// GetBlocklistEntry retrieves a single entry by ID, expired or not.
+func (s *SQLite) GetBlocklistEntry(id string) (*domain.BlocklistEntry, bool) {
+	return getOne[domain.BlocklistEntry](s.db, "get blocklist entry", `SELECT data FROM blocklist WHERE id = ?`, id)
+}
+
+// DeleteBlocklistEntry removes an entry by ID. Returns false if not found.
+func (s *SQLite) DeleteBlocklistEntry(id string) bool {
+	return s.deleteByID("delete blocklist entry", `DELETE FROM blocklist WHERE id = ?`, id)
+}
+
+// CheckBlocklist looks up whether an entity is on the block or allow list.
+// Expired entries are skipped.
+func (s *SQLite) CheckBlocklist(entityType, value string) (*domain.BlocklistEntry, bool) {
+	return getOne[domain.BlocklistEntry](s.db, "check blocklist", `SELECT data FROM blocklist
+		WHERE entity_type = ? AND value = ? AND (expires_at IS NULL OR expires_at >= ?)
+		LIMIT 1`, entityType, value, time.Now().UnixNano())
+}
+
+// FirstBlocked returns the index of the first of nodes with an active block
+// entry, and the entry, reading every node's entries in one query.
+func (s *SQLite) FirstBlocked(nodes []domain.EntityNode) (int, *domain.BlocklistEntry, bool) {
+	if len(nodes) == 0 {
+		return 0, nil, false
+	}
+	match := make([]string, len(nodes))
+	args := make([]any, 0, 2*len(nodes)+2)
+	for i, n := range nodes {
+		match[i] = "(entity_type = ? AND value = ?)"
+		args = append(args, n.Type, n.Value)
+	}
+	args = append(args, time.Now().UnixNano(), domain.ListBlock)
+	rows, err := s.db.Query(`SELECT data FROM blocklist
+		WHERE (`+strings.Join(match, " OR ")+`)
+		AND (expires_at IS NULL OR expires_at >= ?) AND json_extract(data, '$.list_type') = ?`, args...)
+	if err != nil {
+		logSQLiteError("first blocked", err)
+		return 0, nil, false
+	}
+	entries, err := scanJSON[domain.BlocklistEntry](rows)
+	if err != nil {
+		logSQLiteError("first blocked", err)
+	}
+	blocked := make(map[domain.EntityNode]*domain.BlocklistEntry, len(entries))
+	for _, entry := range entries {
+		blocked[domain.EntityNode{Type: entry.Type, Value: entry.Value}] = entry
+	}
+	for i, n := range nodes {
+		if entry, ok := blocked[n]; ok {
+			return i, entry, true
+		}
+	}
+	return 0, nil, false
+}
+
+// ListBlocklistEntries returns all non-expired entries.
+func (s *SQLite) ListBlocklistEntries() []*domain.BlocklistEntry {
+	rows, err := s.db.Query(`SELECT data FROM blocklist WHERE expires_at IS NULL OR expires_at > ? ORDER BY id`, time.Now().UnixNano())
+	if err != nil {
+		logSQLiteError("list blocklist entries", err)
+		return nil
+	}
+	result, err := scanJSON[domain.BlocklistEntry](rows)
+	if err != nil {
+		logSQLiteError("list blocklist entries", err)
+	}
+	return result
+}
+
+// ─── Webhooks ─────────────────────────────────────────────────────────────────
+
+// SaveWebhook upserts a webhook configuration.
+func (s *SQLite) SaveWebhook(wh *domain.WebhookConfig) {
+	data, err := json.Marshal(wh)
+	if err != nil {
+		logSQLiteError("save webhook", err)
+		return
+	}
+	if _, err := s.db.Exec(`INSERT OR REPLACE INTO webhooks (id, active, data) VALUES (?, ?, ?)`, wh.ID, wh.Active, data); err != nil {
+		logSQLiteError("save webhook", err)
+	}
+}
+
+// GetWebhook retrieves a single webhook by ID.
+func (s *SQLite) GetWebhook(id string) (*domain.WebhookConfig, bool) {
+	return getOne[domain.WebhookConfig](s.db, "get webhook", `SELECT data FROM webhooks WHERE id = ?`, id)
+}
+
+// DeleteWebhook removes a webhook by ID. Returns false if not found.
+func (s *SQLite) DeleteWebhook(id string) bool {
+	return s.deleteByID("delete webhook", `DELETE FROM webhooks WHERE id = ?`, id)
+}
+
+// ListActiveWebhooks returns all webhooks that are currently active.
+func (s *SQLite) ListActiveWebhooks() []*domain.WebhookConfig {
+	rows, err := s.db.Query(`SELECT data FROM webhooks WHERE active ORDER BY id`)
+	if err != nil {
+		logSQLiteError("list webhooks", err)
+		return nil
+	}
+	result, err := scanJSON[domain.WebhookConfig](rows)
+	if err != nil {
+		logSQLiteError("list webhooks", err)
+	}
+	return result
+}
+
+// ─── Thresholds ───────────────────────────────────────────────────────────────
+
+// SetThresholds upserts a threshold override and records the change.
+func (s *SQLite) SetThresholds(cfg *domain.ThresholdConfig, actor string) domain.ThresholdChange {
+	var change domain.ThresholdChange
+	err := s.inTx(func(tx *sql.Tx) error {
+		var before *domain.Thresholds
+		prev, err := getJSON[domain.ThresholdConfig](tx, `SELECT data FROM thresholds WHERE scope = ? AND key = ?`, cfg.Scope, cfg.Key)
+		switch {
+		case err == nil:
+			before = &prev.Thresholds
+		case !errors.Is(err, sql.ErrNoRows):
+			return err
+		}
+		data, err := json.Marshal(cfg)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO thresholds (scope, key, data) VALUES (?, ?, ?)`, cfg.Scope, cfg.Key, data); err != nil {
+			return err
+		}
+		after := cfg.Thresholds
+		change, err = recordThresholdChangeTx(tx, cfg.Scope, cfg.Key, before, &after, actor, cfg.UpdatedAt)
+		return err
+	})
+	if err != nil {
+		logSQLiteError("set thresholds", err)
+	}
+	return change
+}
+
+// DeleteThresholds removes an override and records the change.
+// Returns false if no override exists for the scope and key.
+func (s *SQLite) DeleteThresholds(scope, key, actor string) (domain.ThresholdChange, bool) {
+	var change domain.ThresholdChange
+	err := s.inTx(func(tx *sql.Tx) error {
+		prev, err := getJSON[domain.ThresholdConfig](tx, `SELECT data FROM thresholds WHERE scope = ? AND key = ?`, scope, key)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM thresholds WHERE scope = ? AND key = ?`, scope, key); err != nil {
+			return err
+		}
+		before := prev.Thresholds
+		change, err = recordThresholdChangeTx(tx, scope, key, &before, nil, actor, time.Now().UTC())
+		return err
+	})
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			logSQLiteError("delete thresholds", err)
+		}
+		return domain.ThresholdChange{}, false
+	}
+	return change, true
+}
+
+func recordThresholdChangeTx(tx *sql.Tx, scope, key string, before, after *domain.Thresholds, actor string, at time.Time) (domain.ThresholdChange, error) {
+	ch := domain.ThresholdChange{
+		ID:        uuid.NewString(),
+		Scope:     scope,
+		Key:       key,
+		Before:    before,
+		After:     after,
+		Actor:     actor,
+		ChangedAt: at,
+	}
+	data, err := json.Marshal(ch)
+	if err != nil {
+		return ch, err
+	}
+	_, err = tx.Exec(`INSERT INTO threshold_changes (data) VALUES (?)`, data)
+	return ch, err
+}
+
+// ListThresholds returns every threshold override.
+func (s *SQLite) ListThresholds() []*domain.ThresholdConfig {
+	rows, err := s.db.Query(`SELECT data FROM thresholds ORDER BY scope, key`)
+	if err != nil {
+		logSQLiteError("list thresholds", err)
+		return []*domain.ThresholdConfig{}
+	}
+	result, err := scanJSON[domain.ThresholdConfig](rows)
+	if err != nil {
+		logSQLiteError("list thresholds", err)
+	}
+	if result == nil {
+		result = []*domain.ThresholdConfig{}
+	}
+	return result
+}
+
+// ListThresholdChanges returns the threshold change history, oldest first.
+func (s *SQLite) ListThresholdChanges() []domain.ThresholdChange {
+	rows, err := s.db.Query(`SELECT data FROM threshold_changes ORDER BY seq`)
+	if err != nil {
+		logSQLiteError("list threshold changes", err)
+		return []domain.ThresholdChange{}
+	}
+	ptrs, err := scanJSON[domain.ThresholdChange](rows)
+	if err != nil {
+		logSQLiteError("list threshold changes", err)
+	}
+	result := make([]domain.ThresholdChange, 0, len(ptrs))
+	for _, ch := range ptrs {
+		result = append(result, *ch)
+	}
+	return result
+}
+
+// ResolveThresholds returns the most specific thresholds for a merchant, in
+// the same order as Memory.ResolveThresholds, with one query.
+func (s *SQLite) ResolveThresholds(merchantID, merchantCountry string) (domain.Thresholds, string) {
+	country := strings.ToUpper(merchantCountry)
+	var scope, key string
+	var data []byte
+	err := s.db.QueryRow(`SELECT scope, key, data FROM thresholds
+		WHERE (scope = ? AND key = ? AND key != '')
+		   OR (scope = ? AND key = ? AND key != '')
+		   OR (scope = ? AND key = '')
+		ORDER BY CASE scope WHEN ? THEN 0 WHEN ? THEN 1 ELSE 2 END
+		LIMIT 1`,
+		domain.ScopeMerchant, merchantID,
+		domain.ScopeCountry, country,
+		domain.ScopeGlobal,
+		domain.ScopeMerchant, domain.ScopeCountry,
+	).Scan(&scope, &key, &data)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			logSQLiteError("resolve thresholds", err)
+		}
+		return domain.DefaultThresholds, domain.ScopeDefault
+	}
+	var cfg domain.ThresholdConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		logSQLiteError("resolve thresholds", err)
+		return domain.DefaultThresholds, domain.ScopeDefault
+	}
+	if key == "" {
+		return cfg.Thresholds, scope
+	}
+	return cfg.Thresholds, thresholdKey(scope, key)
+}
+
+// ─── Settings ─────────────────────────────────────────────────────────────────
+
+// PutSetting stores a setting, one revision past the one it replaces, if
+// st.Revision is still the stored one.
+func (s *SQLite) PutSetting(st domain.Setting) (domain.Setting, error) {
+	err := s.inTx(func(tx *sql.Tx) error {
+		var stored int64
+		prev, err := getJSON[domain.Setting](tx, `SELECT data FROM settings WHERE name = ?`, st.Name)
+		switch {
+		case err == nil:
+			stored = prev.Revision
+		case !errors.Is(err, sql.ErrNoRows):
+			return err
+		}
+		if stored != st.Revision {
+			return ErrSettingConflict
+		}
+		st.Revision++
+		data, err := json.Marshal(st)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT OR REPLACE INTO settings (name, data) VALUES (?, ?)`, st.Name, data)
+		return err
+	})
+	return st, err
+}
+
+// GetSetting returns the setting stored under name.
+func (s *SQLite) GetSetting(name string) (domain.Setting, bool) {
+	st, ok := getOne[domain.Setting](s.db, "get setting", `SELECT data FROM settings WHERE name = ?`, name)
+	if !ok {
+		return domain.Setting{}, false
+	}
+	return *st, true
+}
+
+// ─── Helpers ──────────────────────────────────────────────────────────────────
+
+// queryer is the read surface shared by *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// inTx runs fn in a transaction, committing if it returns nil.
+func (s *SQLite) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLite) deleteByID(op, query, id string) bool {
+	res, err := s.db.Exec(query, id)
+	if err != nil {
+		logSQLiteError(op, err)
+		return false
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		logSQLiteError(op, err)
+	}
+	return n > 0
+}
+
+func getTransaction(q queryer, id string) (*domain.Transaction, error) {
+	return getJSON[domain.Transaction](q, `SELECT data FROM transactions WHERE id = ?`, id)
+}
+
+// replaceTransaction overwrites a stored transaction with an updated copy.
+func replaceTransaction(tx *sql.Tx, t *domain.Transaction) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`UPDATE transactions SET final_status = ?, data = ? WHERE id = ?`, t.FinalStatus, data, t.TransactionID)
+	return err
+}
+
+// getJSON decodes the single data column returned by query. It returns
+// sql.ErrNoRows when nothing matches.
+func getJSON[T any](q queryer, query string, args ...any) (*T, error) {
+	var data []byte
+	if err := q.QueryRow(query, args...).Scan(&data); err != nil {
+		return nil, err
+	}
+	v := new(T)
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// getOne is getJSON for Store methods that report absence as false.
+func getOne[T any](q queryer, op, query string, args ...any) (*T, bool) {
+	v, err := getJSON[T](q, query, args...)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			logSQLiteError(op, err)
+		}
+		return nil, false
+	}
+	return v, true
+}
+
+// scanJSON decodes the data column of every row and closes rows. On error it
+// returns the rows decoded so far.
+func scanJSON[T any](rows *sql.Rows) ([]*T, error) {
+	defer rows.Close()
+	var result []*T
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return result, err
+		}
+		v := new(T)
+		if err := json.Unmarshal(data, v); err != nil {
+			return result, err
+		}
+		result = append(result, v)
+	}
+	return result, rows.Err()
+}
+
+// Bounds of the times representable in Unix nanoseconds (years 1678–2262).
+var (
+	minUnixNano = time.Unix(0, math.MinInt64)
+	maxUnixNano = time.Unix(0, math.MaxInt64)
+)
+
+// unixNano converts t for storage and comparison, clamping times outside the
+// int64 range so that e.g. time.Time{} still means "since the beginning".
+func unixNano(t time.Time) int64 {
+	switch {
+	case t.Before(minUnixNano):
+		return math.MinInt64
+	case t.After(maxUnixNano):
+		return math.MaxInt64
+	}
+	return t.UnixNano()
+}
+
+func isUniqueViolation(err error) bool {
+	var se sqlite3.Error
+	return errors.As(err, &se) &&
+		(se.ExtendedCode == sqlite3.ErrConstraintPrimaryKey || se.ExtendedCode == sqlite3.ErrConstraintUnique)
+}
+
+func logSQLiteError(op string, err error) {
+	slog.Error("sqlite store query failed", "op", op, "error", err)
+}
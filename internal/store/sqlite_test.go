@@ -0,0 +1,175 @@
+package store_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"lumina/fraud-api/internal/domain"
+	"lumina/fraud-api/internal/store"
+	"lumina/fraud-api/internal/store/storetest"
+)
+
+func openSQLite(t *testing.T, path string) *store.SQLite {
+	t.Helper()
+	s, err := store.OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	return s
+}
+
+func TestSQLite_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		s := openSQLite(t, filepath.Join(t.TempDir(), "fraud.db"))
+		t.Cleanup(func() { _ = s.Close() })
+		return s
+	})
+}
+
+func TestSQLite_StateSurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fraud.db")
+	now := time.Now().UTC().Truncate(time.Second)
+
+	s := openSQLite(t, path)
+	populate(t, s, now)
+	want := observe(t, s, now)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := openSQLite(t, path)
+	defer r.Close()
+	if got := observe(t, r, now); got != want {
+		t.Errorf("reopened state differs\nwant %s\ngot  %s", want, got)
+	}
+	if v, err := r.SchemaVersion(); err != nil || v < 1 {
+		t.Errorf("expected migrations to be recorded once, got version %d, %v", v, err)
+	}
+}
+
+func TestSQLite_RefusesNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fraud.db")
+	s := openSQLite(t, path)
+	_ = s.Close()
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (999, 0)`); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.Close()
+
+	if _, err := store.OpenSQLite(path); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("expected a database from a newer build to be refused, got %v", err)
+	}
+}
+
+func TestSQLite_WindowQueriesUseEntityIndexes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fraud.db")
+	s := openSQLite(t, path)
+	_ = s.Close()
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for column, index := range map[string]string{
+		"user_email":         "transactions_email_ts",
+		"ip_address":         "transactions_ip_ts",
+		"device_fingerprint": "transactions_device_ts",
+		"card_bin":           "transactions_bin_ts",
+	} {
+		rows, err := db.Query(`EXPLAIN QUERY PLAN SELECT data FROM transactions WHERE `+column+` = ? AND timestamp >= ? ORDER BY timestamp`, "x", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var plan strings.Builder
+		for rows.Next() {
+			var id, parent, notused int
+			var detail string
+			if err := rows.Scan(&id, &parent, &notused, &detail); err != nil {
+				t.Fatal(err)
+			}
+			plan.WriteString(detail + "\n")
+		}
+		rows.Close()
+		if !strings.Contains(plan.String(), index) || strings.Contains(plan.String(), "TEMP B-TREE") {
+			t.Errorf("%s lookup should use %s without sorting, plan:\n%s", column, index, plan.String())
+		}
+	}
+}
+
+func TestSQLite_SearchSortsUseIndexes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fraud.db")
+	s := openSQLite(t, path)
+	_ = s.Close()
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for column, index := range map[string]string{
+		"timestamp":        "transactions_ts_id",
+		"risk_score":       "transactions_score",
+		"reporting_amount": "transactions_amount",
+	} {
+		for _, dir := range []string{"ASC", "DESC"} {
+			rows, err := db.Query(`EXPLAIN QUERY PLAN SELECT data FROM transactions ORDER BY ` + column + ` ` + dir + `, id ` + dir + ` LIMIT 51`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var plan strings.Builder
+			for rows.Next() {
+				var id, parent, notused int
+				var detail string
+				if err := rows.Scan(&id, &parent, &notused, &detail); err != nil {
+					t.Fatal(err)
+				}
+				plan.WriteString(detail + "\n")
+			}
+			rows.Close()
+			if !strings.Contains(plan.String(), index) || strings.Contains(plan.String(), "TEMP B-TREE") {
+				t.Errorf("sorting by %s %s should walk %s, plan:\n%s", column, dir, index, plan.String())
+			}
+		}
+	}
+}
+
+func TestSQLite_LinkMigrationBackfillsExistingTransactions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fraud.db")
+	s := openSQLite(t, path)
+	now := time.Now().UTC().Truncate(time.Second)
+	populate(t, s, now)
+	want := observe(t, s, now)
+	_ = s.Close()
+
+	// Roll the database back to before migration 5.
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`DROP TABLE settings; DROP INDEX outcomes_transaction_type; DROP TABLE entity_links; DELETE FROM schema_migrations WHERE version >= 5`); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.Close()
+
+	r := openSQLite(t, path)
+	defer r.Close()
+	// Settings came later than links; put back the one populate stored.
+	if _, err := r.PutSetting(domain.Setting{Name: "rules", Document: json.RawMessage(`{"version":"v2"}`), UpdatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	if got := observe(t, r, now); got != want {
+		t.Errorf("migrated links differ from the ones maintained on save\nwant %s\ngot  %s", want, got)
+	}
+}
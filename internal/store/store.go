@@ -0,0 +1,260 @@
+// Package store defines the persistence interfaces of the fraud API and the
+// in-memory implementation used by default.
+//
+// The scoring engine, webhook notifier, review queue and HTTP handlers depend
+// only on the Store interface, so a backend can be swapped without touching
+// them. Every backend must pass the conformance suite in package storetest.
+package store
+
+import (
+	"errors"
+	"time"
+
+	"lumina/fraud-api/internal/domain"
+)
+
+// ErrDuplicateTransaction is returned when a transaction ID is submitted twice.
+var ErrDuplicateTransaction = errors.New("transaction already exists")
+
+// ErrTransactionNotFound is returned when updating a transaction that does not exist.
+var ErrTransactionNotFound = errors.New("transaction not found")
+
+// Review queue errors.
+var (
+	ErrNotInReview      = errors.New("transaction is not awaiting review")
+	ErrReviewClaimed    = errors.New("case is claimed by another analyst")
+	ErrReviewNotClaimed = errors.New("case must be claimed by the analyst first")
+)
+
+// ErrIdempotencyKeyExists is returned when saving an idempotency key that is
+// still bound to an earlier request.
+var ErrIdempotencyKeyExists = errors.New("idempotency key already in use")
+
+// API key errors.
+var (
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	ErrAPIKeyRevoked  = errors.New("API key is revoked")
+	ErrAPIKeyExists   = errors.New("token is already registered")
+)
+
+// ErrSettingConflict is returned when storing a setting based on a revision
+// that another writer has since replaced.
+var ErrSettingConflict = errors.New("setting has changed")
+
+// ErrAuditConflict is returned when appending an audit entry that does not
+// directly follow the last stored one, because another writer got there first.
+var ErrAuditConflict = errors.New("audit log has moved on")
+
+// Store is the full persistence surface of the API.
+type Store interface {
+	TransactionRepository
+	TransactionSearch
+	EntityHistory
+	VelocityCounters
+	EntityLinks
+	ListRepository
+	WebhookRepository
+	ThresholdRepository
+	SettingsRepository
+	ReviewRepository
+	IdempotencyRepository
+	APIKeyRepository
+	AuditRepository
+}
+
+// TransactionRepository stores scored transactions and their outcomes.
+//
+// Transactions returned by any method must not change underneath the caller:
+// updates replace the stored record rather than mutating a shared one.
+type TransactionRepository interface {
+	// SaveTransaction persists a transaction and indexes it for entity
+	// lookups. Returns ErrDuplicateTransaction if the ID already exists.
+	SaveTransaction(tx *domain.Transaction) error
+	// SaveTransactions saves every transaction of a batch or none of them.
+	// If any ID already exists or appears twice, it returns
+	// ErrDuplicateTransaction and nothing is saved.
+	SaveTransactions(txs []*domain.Transaction) error
+	GetTransaction(id string) (*domain.Transaction, bool)
+	// GetAllTransactions returns every transaction at or after since.
+	GetAllTransactions(since time.Time) []*domain.Transaction
+
+	// AddOutcome appends an outcome to its transaction and returns the
+	// updated transaction, or ErrTransactionNotFound.
+	AddOutcome(o domain.Outcome) (*domain.Transaction, error)
+	// ListOutcomes returns outcomes that occurred at or after since, newest
+	// first. An empty outcomeType matches every type.
+	ListOutcomes(outcomeType string, since time.Time) []domain.Outcome
+}
+
+// TransactionSearch filters, sorts and pages through stored transactions.
+type TransactionSearch interface {
+	// SearchTransactions returns the first q.Limit matches after q.After in
+	// q's order. See TransactionQuery.
+	SearchTransactions(q TransactionQuery) (TransactionPage, error)
+}
+
+// EntityHistory answers the windowed per-entity queries the scoring engine
+// and entity summaries are built on. Results are in arbitrary order.
+type EntityHistory interface {
+	GetTransactionsByEmail(email string, since time.Time) []*domain.Transaction
+	GetTransactionsByIP(ip string, since time.Time) []*domain.Transaction
+	GetTransactionsByDevice(device string, since time.Time) []*domain.Transaction
+	GetTransactionsByBIN(bin string, since time.Time) []*domain.Transaction
+	// GetUniqueCardsByIP counts the distinct card BINs seen from an IP.
+	GetUniqueCardsByIP(ip string) int
+	// FirstFraudLabelled returns the index of the first of emails with a
+	// stored transaction whose outcomes label it fraud (see
+	// domain.FraudLabel), and that email's earliest such transaction. It
+	// answers for a whole entity cluster in one lookup.
+	FirstFraudLabelled(emails []string) (i int, txID string, ok bool)
+}
+
+// VelocityCounters count an entity's recent activity without loading the
+// transactions, for the scoring engine's velocity rules. entityType is one of
+// the domain.Entity* constants; any other type counts nothing.
+type VelocityCounters interface {
+	// CountTransactions counts the entity's transactions at or after since.
+	CountTransactions(entityType, value string, since time.Time) int
+	// CountDistinctEmails counts the distinct emails among the entity's
+	// transactions at or after since, e.g. accounts sharing a card BIN.
+	CountDistinctEmails(entityType, value string, since time.Time) int
+}
+
+// EntityLinks is the entity link graph: which emails, IPs, devices and card
+// BINs have appeared together in a transaction. Saving a transaction links
+// each pair of its entities; pruning it unlinks them.
+type EntityLinks interface {
+	// GetEntityLinks returns the entities that shared a transaction with the
+	// given one, in arbitrary order. entityType is one of the domain.Entity*
+	// constants.
+	GetEntityLinks(entityType, value string) []domain.EntityLink
+}
+
+// ListRepository stores blocklist and allowlist entries.
+type ListRepository interface {
+	SaveBlocklistEntry(entry *domain.BlocklistEntry)
+	GetBlocklistEntry(id string) (*domain.BlocklistEntry, bool)
+	DeleteBlocklistEntry(id string) bool
+	// CheckBlocklist returns the active entry matching an entity, if any.
+	CheckBlocklist(entityType, value string) (*domain.BlocklistEntry, bool)
+	// FirstBlocked returns the index of the first of nodes with an active
+	// block entry, and the entry. Allowlist entries are ignored.
+	FirstBlocked(nodes []domain.EntityNode) (i int, entry *domain.BlocklistEntry, ok bool)
+	// ListBlocklistEntries returns every entry that has not expired.
+	ListBlocklistEntries() []*domain.BlocklistEntry
+}
+
+// WebhookRepository stores webhook registrations.
+type WebhookRepository interface {
+	SaveWebhook(wh *domain.WebhookConfig)
+	GetWebhook(id string) (*domain.WebhookConfig, bool)
+	DeleteWebhook(id string) bool
+	ListActiveWebhooks() []*domain.WebhookConfig
+}
+
+// ThresholdRepository stores recommendation threshold overrides and the
+// history of changes made to them.
+type ThresholdRepository interface {
+	SetThresholds(cfg *domain.ThresholdConfig, actor string) domain.ThresholdChange
+	DeleteThresholds(scope, key, actor string) (domain.ThresholdChange, bool)
+	ListThresholds() []*domain.ThresholdConfig
+	// ListThresholdChanges returns the change history, oldest first.
+	ListThresholdChanges() []domain.ThresholdChange
+	// ResolveThresholds returns the most specific thresholds for a merchant
+	// and the scope they came from.
+	ResolveThresholds(merchantID, merchantCountry string) (domain.Thresholds, string)
+}
+
+// SettingsRepository stores the configuration documents changed at runtime
+// through the admin API, so every replica applies the same ones and they
+// outlive a restart. Retention never removes them.
+type SettingsRepository interface {
+	// PutSetting stores s.Document under s.Name if s.Revision is the
+	// revision stored now, zero when there is none, and returns the stored
+	// setting with its new revision. The check and the write are atomic:
+	// if another writer got there first it returns ErrSettingConflict and
+	// stores nothing.
+	PutSetting(s domain.Setting) (domain.Setting, error)
+	GetSetting(name string) (domain.Setting, bool)
+}
+
+// Pruner is implemented by backends that drop old data only when asked; see
+// package retention. Backends that expire data themselves, like Redis with
+// its TTLs, do not implement it.
+type Pruner interface {
+	// Prune deletes transactions that occurred before cutoff, together with
+	// their index entries, outcomes and review claims, and forgets card BINs
+	// last seen from an IP before cutoff. Idempotency keys that expired
+	// before cutoff, or whose transaction was pruned, go too. Transactions
+	// still awaiting manual review are kept until decided.
+	Prune(cutoff time.Time) (PruneStats, error)
+}
+
+// PruneStats counts what one Prune call removed.
+type PruneStats struct {
+	Transactions    int `json:"transactions"`
+	Cards           int `json:"cards"` // IP/BIN pairs
+	IdempotencyKeys int `json:"idempotency_keys"`
+}
+
+// ReviewRepository holds analyst claims on queued cases and their decisions.
+type ReviewRepository interface {
+	PendingReviews() []*domain.Transaction
+	ActiveReviewClaims(now time.Time) map[string]domain.ReviewClaim
+	ClaimReview(txID, analyst string, now time.Time, ttl time.Duration) (domain.ReviewClaim, error)
+	ReleaseReview(txID, analyst string, now time.Time) error
+	DecideReview(txID string, d domain.ReviewDecision) (*domain.Transaction, error)
+}
+
+// IdempotencyRepository remembers which transaction each client-supplied
+// idempotency key created, so a retried submission can be answered with the
+// original result instead of a duplicate error.
+type IdempotencyRepository interface {
+	// SaveIdempotencyKey stores k and returns it. If the key is still bound
+	// at k.CreatedAt, the existing record is returned with
+	// ErrIdempotencyKeyExists and nothing is changed. The check and the
+	// write are atomic, so a key can be reserved before its transaction is
+	// saved.
+	SaveIdempotencyKey(k domain.IdempotencyKey) (domain.IdempotencyKey, error)
+	// ReleaseIdempotencyKey deletes k's record if it is still the one
+	// stored, freeing a key reserved by a request that then failed.
+	ReleaseIdempotencyKey(k domain.IdempotencyKey) error
+	// GetIdempotencyKey returns the record for key unless it has expired at now.
+	GetIdempotencyKey(key string, now time.Time) (domain.IdempotencyKey, bool)
+}
+
+// APIKeyRepository stores API keys, so every replica authenticates the same
+// keys and they outlive a restart. Only token hashes are stored. Retention
+// never removes keys; revoked ones stay listed for the record.
+type APIKeyRepository interface {
+	// CreateAPIKey stores a new key. Returns ErrAPIKeyExists if a key has
+	// the same token hash, revoked ones included: a revoked token can never
+	// be registered again.
+	CreateAPIKey(k *domain.APIKey) error
+	// UpdateAPIKey replaces a stored key, e.g. to rotate or revoke it. The
+	// check that the stored key is not revoked and the write are atomic, so
+	// a revoked key cannot be brought back by a concurrent rotation. Returns
+	// ErrAPIKeyNotFound, ErrAPIKeyRevoked, or ErrAPIKeyExists if the new
+	// token hash belongs to another active key.
+	UpdateAPIKey(k *domain.APIKey) error
+	GetAPIKey(id string) (*domain.APIKey, bool)
+	// GetAPIKeyByHash returns the key whose current token hashes to hash,
+	// revoked or not.
+	GetAPIKeyByHash(hash string) (*domain.APIKey, bool)
+	// ListAPIKeys returns every key, revoked ones included, oldest first.
+	ListAPIKeys() []*domain.APIKey
+}
+
+// AuditRepository stores the audit hash chain, so it outlives a restart and
+// every replica extends the same chain. Entries are opaque to the store apart
+// from Seq; package audit builds and verifies them. Retention never removes
+// entries.
+type AuditRepository interface {
+	// AppendAuditEntry stores e if its Seq is one past the last stored
+	// entry, atomically. Otherwise it returns ErrAuditConflict and stores
+	// nothing, so concurrent writers cannot fork the chain.
+	AppendAuditEntry(e domain.AuditEntry) error
+	// AuditEntriesAfter returns the entries with Seq greater than seq, in
+	// append order.
+	AuditEntriesAfter(seq int64) ([]domain.AuditEntry, error)
+}
@@ -0,0 +1,1173 @@
+// Package storetest is the conformance suite every store.Store backend must
+// pass. A backend's own tests call Run with a constructor for empty stores.
+package storetest
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"lumina/fraud-api/internal/domain"
+	"lumina/fraud-api/internal/store"
+)
+
+// Run exercises a backend against the behaviour the engine, handlers and
+// review queue rely on. open must return a new, empty store on every call;
+// it may register cleanup with t.
+func Run(t *testing.T, open func(t *testing.T) store.Store) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s store.Store)
+	}{
+		{"Save_And_GetByID", testSave_And_GetByID},
+		{"Save_DuplicateID_ReturnsError", testSave_DuplicateID_ReturnsError},
+		{"Get_MissingID_ReturnsFalse", testGet_MissingID_ReturnsFalse},
+		{"SaveTransactions_AllOrNone", testSaveTransactions_AllOrNone},
+		{"GetByEmail_ReturnsOnlyWithinWindow", testGetByEmail_ReturnsOnlyWithinWindow},
+		{"GetByIP_ReturnsOnlyWithinWindow", testGetByIP_ReturnsOnlyWithinWindow},
+		{"GetByDevice_ReturnsOnlyWithinWindow", testGetByDevice_ReturnsOnlyWithinWindow},
+		{"GetByBIN_ReturnsOnlyWithinWindow", testGetByBIN_ReturnsOnlyWithinWindow},
+		{"GetByEmail_EmptyResult_WhenNoHistory", testGetByEmail_EmptyResult_WhenNoHistory},
+		{"Windows_IncludeBackfilledEvents", testWindows_IncludeBackfilledEvents},
+		{"VelocityCounters_MatchWindows", testVelocityCounters_MatchWindows},
+		{"GetUniqueCardsByIP_CountsDistinctBINs", testGetUniqueCardsByIP_CountsDistinctBINs},
+		{"GetUniqueCardsByIP_ZeroForUnseenIP", testGetUniqueCardsByIP_ZeroForUnseenIP},
+		{"EntityLinks_JoinEntitiesSharingTransactions", testEntityLinks_JoinEntitiesSharingTransactions},
+		{"GetAllTransactions_FiltersCorrectly", testGetAllTransactions_FiltersCorrectly},
+		{"Search_Filters", testSearch_Filters},
+		{"Search_PagesInEverySortOrder", testSearch_PagesInEverySortOrder},
+		{"Blocklist_SaveAndCheck", testBlocklist_SaveAndCheck},
+		{"Blocklist_ExpiredEntry_NotFound", testBlocklist_ExpiredEntry_NotFound},
+		{"Blocklist_FutureExpiry_IsFound", testBlocklist_FutureExpiry_IsFound},
+		{"Blocklist_Delete_RemovesEntry", testBlocklist_Delete_RemovesEntry},
+		{"Blocklist_DeleteMissing_ReturnsFalse", testBlocklist_DeleteMissing_ReturnsFalse},
+		{"Blocklist_ListEntries_ExcludesExpired", testBlocklist_ListEntries_ExcludesExpired},
+		{"Blocklist_FirstBlocked_SkipsAllowedAndExpired", testBlocklist_FirstBlocked_SkipsAllowedAndExpired},
+		{"Webhook_SaveAndList", testWebhook_SaveAndList},
+		{"Webhook_Delete", testWebhook_Delete},
+		{"Webhook_DeleteMissing_ReturnsFalse", testWebhook_DeleteMissing_ReturnsFalse},
+		{"Thresholds_NoOverride_ReturnsDefault", testThresholds_NoOverride_ReturnsDefault},
+		{"Thresholds_MostSpecificScopeWins", testThresholds_MostSpecificScopeWins},
+		{"Thresholds_ChangesAreRecordedWithBeforeAndAfter", testThresholds_ChangesAreRecordedWithBeforeAndAfter},
+		{"Settings_EachPutAdvancesTheRevision", testSettings_EachPutAdvancesTheRevision},
+		{"Settings_StaleRevision_Conflicts", testSettings_StaleRevision_Conflicts},
+		{"AddOutcome_AppendsWithoutMutatingPriorReads", testAddOutcome_AppendsWithoutMutatingPriorReads},
+		{"AddOutcome_UnknownTransaction_ReturnsError", testAddOutcome_UnknownTransaction_ReturnsError},
+		{"ListOutcomes_FiltersByTypeAndWindow", testListOutcomes_FiltersByTypeAndWindow},
+		{"FirstFraudLabelled_FollowsLatestLabel", testFirstFraudLabelled_FollowsLatestLabel},
+		{"Blocklist_GetByID", testBlocklist_GetByID},
+		{"Webhook_GetByID", testWebhook_GetByID},
+		{"Review_ClaimReleaseDecide", testReview_ClaimReleaseDecide},
+		{"Review_ClaimRules", testReview_ClaimRules},
+		{"Idempotency_KeyIsBoundUntilExpiry", testIdempotency_KeyIsBoundUntilExpiry},
+		{"Idempotency_ReleaseFreesOnlyTheSameBinding", testIdempotency_ReleaseFreesOnlyTheSameBinding},
+		{"APIKeys_RotateAndRevoke", testAPIKeys_RotateAndRevoke},
+		{"AuditLog_AppendsOnlyTheNextSeq", testAuditLog_AppendsOnlyTheNextSeq},
+		{"Store_ConcurrentWrites_NoRace", testStore_ConcurrentWrites_NoRace},
+		{"Prune_DropsHistoryBeforeCutoff", testPrune_DropsHistoryBeforeCutoff},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, open(t))
+		})
+	}
+}
+
+// ─── Helpers ──────────────────────────────────────────────────────────────────
+
+func newTx(id, email, ip, device, bin string, ts time.Time) *domain.Transaction {
+	return &domain.Transaction{
+		TransactionRequest: domain.TransactionRequest{
+			TransactionID:     id,
+			Timestamp:         ts,
+			Amount:            50.0,
+			Currency:          domain.BRL,
+			UserEmail:         email,
+			IPAddress:         ip,
+			DeviceFingerprint: device,
+			CardBIN:           bin,
+		},
+	}
+}
+
+var now = time.Now().UTC()
+
+// ─── SaveTransaction ──────────────────────────────────────────────────────────
+
+func testSave_And_GetByID(t *testing.T, s store.Store) {
+	tx := newTx("tx-001", "a@a.com", "1.1.1.1", "dev1", "111111", now)
+	if err := s.SaveTransaction(tx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, ok := s.GetTransaction("tx-001")
+	if !ok {
+		t.Fatal("expected to find tx-001")
+	}
+	if got.TransactionID != "tx-001" {
+		t.Errorf("expected tx-001, got %s", got.TransactionID)
+	}
+}
+
+func testSave_DuplicateID_ReturnsError(t *testing.T, s store.Store) {
+	tx := newTx("dup-001", "a@a.com", "1.1.1.1", "dev1", "111111", now)
+	_ = s.SaveTransaction(tx)
+	err := s.SaveTransaction(tx)
+	if err != store.ErrDuplicateTransaction {
+		t.Errorf("expected ErrDuplicateTransaction, got %v", err)
+	}
+}
+
+func testSaveTransactions_AllOrNone(t *testing.T, s store.Store) {
+	_ = s.SaveTransaction(newTx("batch-taken", "a@a.com", "1.1.1.1", "dev1", "111111", now))
+
+	// A taken ID late in the batch leaves no trace of the items before it.
+	err := s.SaveTransactions([]*domain.Transaction{
+		newTx("batch-1", "b@b.com", "2.2.2.2", "dev2", "222222", now),
+		newTx("batch-taken", "a@a.com", "1.1.1.1", "dev1", "111111", now),
+	})
+	if err != store.ErrDuplicateTransaction {
+		t.Fatalf("expected ErrDuplicateTransaction, got %v", err)
+	}
+	if _, ok := s.GetTransaction("batch-1"); ok {
+		t.Error("a failed batch must save nothing")
+	}
+	if n := s.CountTransactions(domain.EntityEmail, "b@b.com", now.Add(-time.Minute)); n != 0 {
+		t.Errorf("a failed batch must index nothing, got %d", n)
+	}
+
+	// So does an ID repeated within the batch.
+	err = s.SaveTransactions([]*domain.Transaction{
+		newTx("batch-2", "c@c.com", "3.3.3.3", "dev3", "333333", now),
+		newTx("batch-2", "c@c.com", "3.3.3.3", "dev3", "333333", now),
+	})
+	if err != store.ErrDuplicateTransaction {
+		t.Fatalf("expected ErrDuplicateTransaction for a repeated ID, got %v", err)
+	}
+	if _, ok := s.GetTransaction("batch-2"); ok {
+		t.Error("a batch repeating an ID must save nothing")
+	}
+
+	if err := s.SaveTransactions([]*domain.Transaction{
+		newTx("batch-1", "b@b.com", "2.2.2.2", "dev2", "222222", now.Add(-time.Second)),
+		newTx("batch-3", "b@b.com", "2.2.2.2", "dev2", "222222", now),
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := s.CountTransactions(domain.EntityEmail, "b@b.com", now.Add(-time.Minute)); n != 2 {
+		t.Errorf("expected both items indexed, got %d", n)
+	}
+}
+
+func testGet_MissingID_ReturnsFalse(t *testing.T, s store.Store) {
+	_, ok := s.GetTransaction("nonexistent")
+	if ok {
+		t.Error("expected ok=false for missing transaction")
+	}
+}
+
+// ─── Time-windowed lookups ────────────────────────────────────────────────────
+
+func testGetByEmail_ReturnsOnlyWithinWindow(t *testing.T, s store.Store) {
+	inside := newTx("e-in-1", "user@x.com", "1.1.1.1", "d1", "111", now.Add(-30*time.Minute))
+	outside := newTx("e-out-1", "user@x.com", "1.1.1.1", "d1", "111", now.Add(-2*time.Hour))
+	_ = s.SaveTransaction(inside)
+	_ = s.SaveTransaction(outside)
+
+	since := now.Add(-1 * time.Hour)
+	result := s.GetTransactionsByEmail("user@x.com", since)
+
+	if len(result) != 1 {
+		t.Errorf("expected 1 result, got %d", len(result))
+	}
+	if result[0].TransactionID != "e-in-1" {
+		t.Errorf("expected e-in-1, got %s", result[0].TransactionID)
+	}
+}
+
+func testGetByIP_ReturnsOnlyWithinWindow(t *testing.T, s store.Store) {
+	_ = s.SaveTransaction(newTx("ip-in-1", "a@a.com", "5.5.5.5", "d1", "111", now.Add(-20*time.Minute)))
+	_ = s.SaveTransaction(newTx("ip-out-1", "b@b.com", "5.5.5.5", "d2", "222", now.Add(-90*time.Minute)))
+
+	result := s.GetTransactionsByIP("5.5.5.5", now.Add(-1*time.Hour))
+	if len(result) != 1 {
+		t.Errorf("expected 1 result, got %d", len(result))
+	}
+}
+
+func testGetByDevice_ReturnsOnlyWithinWindow(t *testing.T, s store.Store) {
+	_ = s.SaveTransaction(newTx("dv-in-1", "a@a.com", "1.1.1.1", "shared-dev", "111", now.Add(-5*time.Minute)))
+	_ = s.SaveTransaction(newTx("dv-out-1", "b@b.com", "2.2.2.2", "shared-dev", "222", now.Add(-40*time.Minute)))
+
+	result := s.GetTransactionsByDevice("shared-dev", now.Add(-30*time.Minute))
+	if len(result) != 1 {
+		t.Errorf("expected 1, got %d", len(result))
+	}
+}
+
+func testGetByBIN_ReturnsOnlyWithinWindow(t *testing.T, s store.Store) {
+	_ = s.SaveTransaction(newTx("bin-in-1", "a@a.com", "1.1.1.1", "d1", "999999", now.Add(-10*time.Minute)))
+	_ = s.SaveTransaction(newTx("bin-out-1", "b@b.com", "2.2.2.2", "d2", "999999", now.Add(-2*time.Hour)))
+
+	result := s.GetTransactionsByBIN("999999", now.Add(-1*time.Hour))
+	if len(result) != 1 {
+		t.Errorf("expected 1, got %d", len(result))
+	}
+}
+
+func testGetByEmail_EmptyResult_WhenNoHistory(t *testing.T, s store.Store) {
+	result := s.GetTransactionsByEmail("nobody@nowhere.com", now.Add(-24*time.Hour))
+	if len(result) != 0 {
+		t.Errorf("expected empty slice, got %d", len(result))
+	}
+}
+
+// Late and backfilled events arrive after newer ones; windows must still see
+// exactly the events inside them, on every entity index.
+func testWindows_IncludeBackfilledEvents(t *testing.T, s store.Store) {
+	for _, tx := range []*domain.Transaction{
+		newTx("bf-3", "bf@x.com", "8.8.4.4", "bf-dev", "454545", now.Add(-10*time.Minute)),
+		newTx("bf-1", "bf@x.com", "8.8.4.4", "bf-dev", "454545", now.Add(-3*time.Hour)),
+		newTx("bf-4", "bf@x.com", "8.8.4.4", "bf-dev", "454545", now),
+		newTx("bf-2", "bf@x.com", "8.8.4.4", "bf-dev", "454545", now.Add(-50*time.Minute)),
+		newTx("bf-0", "bf@x.com", "8.8.4.4", "bf-dev", "454545", now.Add(-26*time.Hour)),
+	} {
+		if err := s.SaveTransaction(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lookups := map[string]func(time.Time) []*domain.Transaction{
+		"email":  func(since time.Time) []*domain.Transaction { return s.GetTransactionsByEmail("bf@x.com", since) },
+		"ip":     func(since time.Time) []*domain.Transaction { return s.GetTransactionsByIP("8.8.4.4", since) },
+		"device": func(since time.Time) []*domain.Transaction { return s.GetTransactionsByDevice("bf-dev", since) },
+		"bin":    func(since time.Time) []*domain.Transaction { return s.GetTransactionsByBIN("454545", since) },
+	}
+	windows := []struct {
+		since time.Duration
+		want  []string
+	}{
+		{time.Hour, []string{"bf-2", "bf-3", "bf-4"}},
+		{4 * time.Hour, []string{"bf-1", "bf-2", "bf-3", "bf-4"}},
+		{48 * time.Hour, []string{"bf-0", "bf-1", "bf-2", "bf-3", "bf-4"}},
+		{0, []string{"bf-4"}},
+	}
+	for name, lookup := range lookups {
+		for _, w := range windows {
+			var got []string
+			for _, tx := range lookup(now.Add(-w.since)) {
+				got = append(got, tx.TransactionID)
+			}
+			sort.Strings(got)
+			if strings.Join(got, ",") != strings.Join(w.want, ",") {
+				t.Errorf("%s since -%s: expected %v, got %v", name, w.since, w.want, got)
+			}
+		}
+	}
+}
+
+func testVelocityCounters_MatchWindows(t *testing.T, s store.Store) {
+	// A window boundary can fall inside a millisecond.
+	edge := now.Add(-time.Hour).Truncate(time.Millisecond).Add(500 * time.Microsecond)
+	for _, tx := range []*domain.Transaction{
+		newTx("vc-1", "a@vc.com", "6.6.6.6", "vc-dev", "767676", now.Add(-5*time.Minute)),
+		newTx("vc-2", "b@vc.com", "6.6.6.6", "vc-dev", "767676", now.Add(-20*time.Minute)),
+		newTx("vc-3", "a@vc.com", "6.6.6.6", "vc-dev", "767676", edge.Add(100*time.Microsecond)),
+		newTx("vc-4", "c@vc.com", "6.6.6.6", "vc-dev", "767676", edge.Add(-100*time.Microsecond)),
+		newTx("vc-5", "d@vc.com", "6.6.6.6", "vc-dev", "767676", now.Add(-3*time.Hour)),
+	} {
+		if err := s.SaveTransaction(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	values := map[string]string{
+		domain.EntityIP:     "6.6.6.6",
+		domain.EntityDevice: "vc-dev",
+		domain.EntityBIN:    "767676",
+	}
+	for entityType, value := range values {
+		if n := s.CountTransactions(entityType, value, edge); n != 3 {
+			t.Errorf("%s: expected 3 transactions since the edge, got %d", entityType, n)
+		}
+		if n := s.CountTransactions(entityType, value, now.Add(-24*time.Hour)); n != 5 {
+			t.Errorf("%s: expected 5 transactions in 24h, got %d", entityType, n)
+		}
+		if n := s.CountDistinctEmails(entityType, value, edge); n != 2 {
+			t.Errorf("%s: expected 2 distinct emails since the edge, got %d", entityType, n)
+		}
+		if n := s.CountDistinctEmails(entityType, value, now.Add(-24*time.Hour)); n != 4 {
+			t.Errorf("%s: expected 4 distinct emails in 24h, got %d", entityType, n)
+		}
+	}
+	if n := s.CountTransactions(domain.EntityEmail, "a@vc.com", edge); n != 2 {
+		t.Errorf("email: expected 2 transactions since the edge, got %d", n)
+	}
+	if n := s.CountDistinctEmails(domain.EntityEmail, "a@vc.com", edge); n != 1 {
+		t.Errorf("email: expected 1 distinct email, got %d", n)
+	}
+	// A newer sighting of an email moves it, without counting it twice.
+	if err := s.SaveTransaction(newTx("vc-6", "b@vc.com", "6.6.6.6", "vc-dev", "767676", now.Add(-time.Minute))); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.CountDistinctEmails(domain.EntityBIN, "767676", now.Add(-10*time.Minute)); n != 2 {
+		t.Errorf("expected 2 distinct emails in 10m, got %d", n)
+	}
+	if n := s.CountDistinctEmails(domain.EntityBIN, "767676", now.Add(-24*time.Hour)); n != 4 {
+		t.Errorf("expected still 4 distinct emails in 24h, got %d", n)
+	}
+	if n := s.CountTransactions(domain.EntityBIN, "000000", edge); n != 0 {
+		t.Errorf("expected no transactions for an unseen BIN, got %d", n)
+	}
+	if n := s.CountTransactions("merchant", "6.6.6.6", edge); n != 0 {
+		t.Errorf("expected an unknown entity type to count nothing, got %d", n)
+	}
+}
+
+// ─── GetUniqueCardsByIP ───────────────────────────────────────────────────────
+
+func testGetUniqueCardsByIP_CountsDistinctBINs(t *testing.T, s store.Store) {
+	_ = s.SaveTransaction(newTx("c1", "a@a.com", "9.9.9.9", "d1", "111111", now))
+	_ = s.SaveTransaction(newTx("c2", "b@b.com", "9.9.9.9", "d2", "222222", now))
+	_ = s.SaveTransaction(newTx("c3", "c@c.com", "9.9.9.9", "d3", "111111", now)) // repeat BIN
+
+	count := s.GetUniqueCardsByIP("9.9.9.9")
+	if count != 2 {
+		t.Errorf("expected 2 unique BINs, got %d", count)
+	}
+}
+
+func testGetUniqueCardsByIP_ZeroForUnseenIP(t *testing.T, s store.Store) {
+	if n := s.GetUniqueCardsByIP("0.0.0.0"); n != 0 {
+		t.Errorf("expected 0, got %d", n)
+	}
+}
+
+// ─── Entity links ─────────────────────────────────────────────────────────────
+
+func linkTo(links []domain.EntityLink, typ, value string) (domain.EntityLink, bool) {
+	for _, l := range links {
+		if l.Node == (domain.EntityNode{Type: typ, Value: value}) {
+			return l, true
+		}
+	}
+	return domain.EntityLink{}, false
+}
+
+func testEntityLinks_JoinEntitiesSharingTransactions(t *testing.T, s store.Store) {
+	t0 := now.Truncate(time.Second)
+	_ = s.SaveTransaction(newTx("ln-1", "ring1@x.com", "5.5.5.5", "ln-dev", "411111", t0.Add(-2*time.Hour)))
+	_ = s.SaveTransaction(newTx("ln-2", "ring2@x.com", "6.6.6.6", "ln-dev", "411111", t0))
+	_ = s.SaveTransaction(newTx("ln-3", "ring2@x.com", "6.6.6.6", "ln-dev", "422222", t0.Add(-time.Hour)))
+
+	links := s.GetEntityLinks(domain.EntityDevice, "ln-dev")
+	if len(links) != 6 {
+		t.Fatalf("expected the device linked to 2 emails, 2 IPs and 2 BINs, got %+v", links)
+	}
+	l, ok := linkTo(links, domain.EntityEmail, "ring2@x.com")
+	if !ok || l.Weight != 2 || !l.FirstSeen.Equal(t0.Add(-time.Hour)) || !l.LastSeen.Equal(t0) {
+		t.Errorf("expected weight 2 seen from -1h to now, got %+v", l)
+	}
+	l, ok = linkTo(links, domain.EntityBIN, "411111")
+	if !ok || l.Weight != 2 || !l.FirstSeen.Equal(t0.Add(-2*time.Hour)) || !l.LastSeen.Equal(t0) {
+		t.Errorf("expected weight 2 seen from -2h to now, got %+v", l)
+	}
+
+	back := s.GetEntityLinks(domain.EntityBIN, "422222")
+	if l, ok := linkTo(back, domain.EntityDevice, "ln-dev"); len(back) != 3 || !ok || l.Weight != 1 {
+		t.Errorf("expected links to be symmetric, got %+v", back)
+	}
+	if got := s.GetEntityLinks(domain.EntityIP, "0.0.0.0"); len(got) != 0 {
+		t.Errorf("expected no links for an unseen IP, got %+v", got)
+	}
+}
+
+// ─── GetAllTransactions ───────────────────────────────────────────────────────
+
+func testGetAllTransactions_FiltersCorrectly(t *testing.T, s store.Store) {
+	_ = s.SaveTransaction(newTx("all-1", "a@a.com", "1.1.1.1", "d1", "111", now.Add(-30*time.Minute)))
+	_ = s.SaveTransaction(newTx("all-2", "b@b.com", "2.2.2.2", "d2", "222", now.Add(-90*time.Minute)))
+	_ = s.SaveTransaction(newTx("all-3", "c@c.com", "3.3.3.3", "d3", "333", now.Add(-10*time.Minute)))
+
+	since := now.Add(-1 * time.Hour)
+	result := s.GetAllTransactions(since)
+	if len(result) != 2 {
+		t.Errorf("expected 2 transactions within window, got %d", len(result))
+	}
+}
+
+// ─── Search ───────────────────────────────────────────────────────────────────
+
+// saveScored stores a scored transaction for the search tests.
+func saveScored(t *testing.T, s store.Store, id string, ts time.Time, score int, amount float64, currency, country string, factors ...string) {
+	t.Helper()
+	tx := newTx(id, id+"@x.com", "9.9.9.9", "sd", "999999", ts)
+	tx.RiskScore, tx.Amount, tx.Currency = score, amount, currency
+	tx.IPCountry, tx.CardCountry, tx.MerchantCountry = country, country, "BR"
+	switch {
+	case score > 70:
+		tx.RiskLevel, tx.Recommendation = domain.RiskHigh, domain.ActionDecline
+	case score > 30:
+		tx.RiskLevel, tx.Recommendation = domain.RiskMedium, domain.ActionReview
+	default:
+		tx.RiskLevel, tx.Recommendation = domain.RiskLow, domain.ActionApprove
+	}
+	for _, f := range factors {
+		tx.Factors = append(tx.Factors, domain.RiskFactor{Name: f, ScoreDelta: 10})
+	}
+	if err := s.SaveTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func pageIDs(p store.TransactionPage) string {
+	ids := make([]string, len(p.Transactions))
+	for i, tx := range p.Transactions {
+		ids[i] = tx.TransactionID
+	}
+	return strings.Join(ids, ",")
+}
+
+func testSearch_Filters(t *testing.T, s store.Store) {
+	saveScored(t, s, "sf-1", now.Add(-3*time.Hour), 10, 20, domain.BRL, "BR")
+	saveScored(t, s, "sf-2", now.Add(-2*time.Hour), 50, 200, domain.MXN, "MX", "ip_velocity_1h")
+	saveScored(t, s, "sf-3", now.Add(-time.Hour), 90, 2000, domain.BRL, "AR", "ip_velocity_1h", "high_risk_bin")
+
+	score, amount := 50, 1000.0
+	for name, tt := range map[string]struct {
+		q    store.TransactionQuery
+		want string
+	}{
+		"all, newest first": {store.TransactionQuery{Desc: true}, "sf-3,sf-2,sf-1"},
+		"time range":        {store.TransactionQuery{Since: now.Add(-2 * time.Hour), Until: now.Add(-time.Hour)}, "sf-2"},
+		"risk level":        {store.TransactionQuery{RiskLevels: []string{domain.RiskLow, domain.RiskHigh}}, "sf-1,sf-3"},
+		"recommendation":    {store.TransactionQuery{Recommendations: []string{domain.ActionReview}}, "sf-2"},
+		"currency":          {store.TransactionQuery{Currencies: []string{domain.MXN}}, "sf-2"},
+		"ip country":        {store.TransactionQuery{IPCountries: []string{"AR", "MX"}}, "sf-2,sf-3"},
+		"card country":      {store.TransactionQuery{CardCountries: []string{"BR"}}, "sf-1"},
+		"merchant country":  {store.TransactionQuery{MerchantCountries: []string{"MX"}}, ""},
+		"factor":            {store.TransactionQuery{Factor: "ip_velocity_1h"}, "sf-2,sf-3"},
+		"min score":         {store.TransactionQuery{MinScore: &score}, "sf-2,sf-3"},
+		"max score":         {store.TransactionQuery{MaxScore: &score}, "sf-1,sf-2"},
+		"min amount":        {store.TransactionQuery{MinAmount: &amount}, "sf-3"},
+		"max amount":        {store.TransactionQuery{MaxAmount: &amount}, "sf-1,sf-2"},
+		"combined":          {store.TransactionQuery{Factor: "ip_velocity_1h", Currencies: []string{domain.BRL}}, "sf-3"},
+	} {
+		page, err := s.SearchTransactions(tt.q)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got := pageIDs(page); got != tt.want || page.More {
+			t.Errorf("%s: got [%s] more=%v, want [%s]", name, got, page.More, tt.want)
+		}
+	}
+}
+
+func testSearch_PagesInEverySortOrder(t *testing.T, s store.Store) {
+	// Ties on every key are broken by ID, including two events in the same
+	// instant.
+	saveScored(t, s, "sp-a", now.Add(-4*time.Minute), 40, 100, domain.BRL, "BR")
+	saveScored(t, s, "sp-c", now.Add(-2*time.Minute), 40, 300, domain.BRL, "BR")
+	saveScored(t, s, "sp-b", now.Add(-2*time.Minute), 80, 100, domain.BRL, "BR")
+	saveScored(t, s, "sp-d", now.Add(-time.Minute), 10, 200, domain.BRL, "BR")
+	saveScored(t, s, "sp-e", now.Add(-3*time.Minute), 80, 50, domain.BRL, "BR")
+
+	for _, tt := range []struct {
+		sort string
+		desc bool
+		want string
+	}{
+		{store.SortByTimestamp, false, "sp-a,sp-e,sp-b,sp-c,sp-d"},
+		{store.SortByTimestamp, true, "sp-d,sp-c,sp-b,sp-e,sp-a"},
+		{store.SortByScore, false, "sp-d,sp-a,sp-c,sp-b,sp-e"},
+		{store.SortByScore, true, "sp-e,sp-b,sp-c,sp-a,sp-d"},
+		{store.SortByAmount, false, "sp-e,sp-a,sp-b,sp-d,sp-c"},
+		{store.SortByAmount, true, "sp-c,sp-d,sp-b,sp-a,sp-e"},
+	} {
+		q := store.TransactionQuery{Sort: tt.sort, Desc: tt.desc, Limit: 2}
+		var got []string
+		for pages := 0; ; pages++ {
+			page, err := s.SearchTransactions(q)
+			if err != nil {
+				t.Fatalf("sort %s desc=%v: %v", tt.sort, tt.desc, err)
+			}
+			if pages > 3 || len(page.Transactions) == 0 {
+				t.Fatalf("sort %s desc=%v: paging did not end, got [%s]", tt.sort, tt.desc, strings.Join(got, ","))
+			}
+			got = append(got, pageIDs(page))
+			if !page.More {
+				break
+			}
+			c := store.CursorAt(page.Transactions[len(page.Transactions)-1])
+			q.After = &c
+		}
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("sort %s desc=%v: got [%s], want [%s]", tt.sort, tt.desc, strings.Join(got, ","), tt.want)
+		}
+	}
+}
+
+// ─── Blocklist ────────────────────────────────────────────────────────────────
+
+func testBlocklist_SaveAndCheck(t *testing.T, s store.Store) {
+	s.SaveBlocklistEntry(&domain.BlocklistEntry{
+		ID:       "bl-1",
+		Type:     domain.EntityEmail,
+		Value:    "bad@example.com",
+		ListType: domain.ListBlock,
+	})
+	entry, ok := s.CheckBlocklist(domain.EntityEmail, "bad@example.com")
+	if !ok {
+		t.Fatal("expected to find blocklist entry")
+	}
+	if entry.ListType != domain.ListBlock {
+		t.Errorf("expected block, got %s", entry.ListType)
+	}
+}
+
+func testBlocklist_ExpiredEntry_NotFound(t *testing.T, s store.Store) {
+	past := time.Now().Add(-1 * time.Hour)
+	s.SaveBlocklistEntry(&domain.BlocklistEntry{
+		ID:        "bl-exp",
+		Type:      domain.EntityIP,
+		Value:     "1.2.3.4",
+		ListType:  domain.ListBlock,
+		ExpiresAt: &past,
+	})
+	_, ok := s.CheckBlocklist(domain.EntityIP, "1.2.3.4")
+	if ok {
+		t.Error("expired entry should not be found")
+	}
+}
+
+func testBlocklist_FutureExpiry_IsFound(t *testing.T, s store.Store) {
+	future := time.Now().Add(24 * time.Hour)
+	s.SaveBlocklistEntry(&domain.BlocklistEntry{
+		ID:        "bl-fut",
+		Type:      domain.EntityIP,
+		Value:     "5.6.7.8",
+		ListType:  domain.ListBlock,
+		ExpiresAt: &future,
+	})
+	_, ok := s.CheckBlocklist(domain.EntityIP, "5.6.7.8")
+	if !ok {
+		t.Error("non-expired entry should be found")
+	}
+}
+
+func testBlocklist_Delete_RemovesEntry(t *testing.T, s store.Store) {
+	s.SaveBlocklistEntry(&domain.BlocklistEntry{ID: "del-1", Type: domain.EntityEmail, Value: "x@x.com", ListType: domain.ListBlock})
+	if !s.DeleteBlocklistEntry("del-1") {
+		t.Fatal("expected delete to return true")
+	}
+	_, ok := s.CheckBlocklist(domain.EntityEmail, "x@x.com")
+	if ok {
+		t.Error("deleted entry should not be found")
+	}
+}
+
+func testBlocklist_DeleteMissing_ReturnsFalse(t *testing.T, s store.Store) {
+	if s.DeleteBlocklistEntry("nonexistent") {
+		t.Error("deleting non-existent entry should return false")
+	}
+}
+
+func testBlocklist_ListEntries_ExcludesExpired(t *testing.T, s store.Store) {
+	past := time.Now().Add(-1 * time.Hour)
+	future := time.Now().Add(1 * time.Hour)
+
+	s.SaveBlocklistEntry(&domain.BlocklistEntry{ID: "live", Type: domain.EntityIP, Value: "1.1.1.1", ListType: domain.ListBlock, ExpiresAt: &future})
+	s.SaveBlocklistEntry(&domain.BlocklistEntry{ID: "dead", Type: domain.EntityIP, Value: "2.2.2.2", ListType: domain.ListBlock, ExpiresAt: &past})
+	s.SaveBlocklistEntry(&domain.BlocklistEntry{ID: "perm", Type: domain.EntityIP, Value: "3.3.3.3", ListType: domain.ListBlock})
+
+	entries := s.ListBlocklistEntries()
+	if len(entries) != 2 {
+		t.Errorf("expected 2 active entries (live + permanent), got %d", len(entries))
+	}
+}
+
+func testBlocklist_FirstBlocked_SkipsAllowedAndExpired(t *testing.T, s store.Store) {
+	past := time.Now().Add(-1 * time.Hour)
+	s.SaveBlocklistEntry(&domain.BlocklistEntry{ID: "fb-allow", Type: domain.EntityEmail, Value: "vip@x.com", ListType: domain.ListAllow})
+	s.SaveBlocklistEntry(&domain.BlocklistEntry{ID: "fb-dead", Type: domain.EntityIP, Value: "1.1.1.1", ListType: domain.ListBlock, ExpiresAt: &past})
+	s.SaveBlocklistEntry(&domain.BlocklistEntry{ID: "fb-dev", Type: domain.EntityDevice, Value: "dev-bad", ListType: domain.ListBlock})
+	s.SaveBlocklistEntry(&domain.BlocklistEntry{ID: "fb-bin", Type: domain.EntityBIN, Value: "411111", ListType: domain.ListBlock})
+
+	nodes := []domain.EntityNode{
+		{Type: domain.EntityEmail, Value: "vip@x.com"},
+		{Type: domain.EntityIP, Value: "1.1.1.1"},
+		{Type: domain.EntityDevice, Value: "dev-ok"},
+		{Type: domain.EntityBIN, Value: "411111"},
+		{Type: domain.EntityDevice, Value: "dev-bad"},
+	}
+	i, entry, ok := s.FirstBlocked(nodes)
+	if !ok || i != 3 || entry.ID != "fb-bin" {
+		t.Errorf("expected the BIN at index 3 to be the first blocked node, got %d %+v %v", i, entry, ok)
+	}
+	if _, _, ok := s.FirstBlocked(nodes[:3]); ok {
+		t.Error("allowlisted and expired entries must not count as blocked")
+	}
+	if _, _, ok := s.FirstBlocked(nil); ok {
+		t.Error("expected no match for no nodes")
+	}
+}
+
+// ─── Webhooks ─────────────────────────────────────────────────────────────────
+
+func testWebhook_SaveAndList(t *testing.T, s store.Store) {
+	s.SaveWebhook(&domain.WebhookConfig{ID: "wh-1", URL: "http://a.com", Threshold: 80, Active: true})
+	s.SaveWebhook(&domain.WebhookConfig{ID: "wh-2", URL: "http://b.com", Threshold: 90, Active: false})
+
+	hooks := s.ListActiveWebhooks()
+	if len(hooks) != 1 {
+		t.Errorf("expected 1 active webhook, got %d", len(hooks))
+	}
+	if hooks[0].ID != "wh-1" {
+		t.Errorf("expected wh-1, got %s", hooks[0].ID)
+	}
+}
+
+func testWebhook_Delete(t *testing.T, s store.Store) {
+	s.SaveWebhook(&domain.WebhookConfig{ID: "wh-del", URL: "http://x.com", Active: true})
+	if !s.DeleteWebhook("wh-del") {
+		t.Fatal("expected delete to return true")
+	}
+	if len(s.ListActiveWebhooks()) != 0 {
+		t.Error("expected no webhooks after delete")
+	}
+}
+
+func testWebhook_DeleteMissing_ReturnsFalse(t *testing.T, s store.Store) {
+	if s.DeleteWebhook("ghost") {
+		t.Error("deleting missing webhook should return false")
+	}
+}
+
+// ─── Thresholds ───────────────────────────────────────────────────────────────
+
+func testThresholds_NoOverride_ReturnsDefault(t *testing.T, s store.Store) {
+	th, scope := s.ResolveThresholds("acme", "BR")
+	if th != domain.DefaultThresholds || scope != domain.ScopeDefault {
+		t.Errorf("expected defaults, got %+v from %q", th, scope)
+	}
+}
+
+func testThresholds_MostSpecificScopeWins(t *testing.T, s store.Store) {
+	s.SetThresholds(&domain.ThresholdConfig{Scope: domain.ScopeGlobal, Thresholds: domain.Thresholds{Approve: 20, Review: 60}}, "ops")
+	s.SetThresholds(&domain.ThresholdConfig{Scope: domain.ScopeCountry, Key: "BR", Thresholds: domain.Thresholds{Approve: 25, Review: 65}}, "ops")
+	s.SetThresholds(&domain.ThresholdConfig{Scope: domain.ScopeMerchant, Key: "acme", Thresholds: domain.Thresholds{Approve: 40, Review: 80}}, "ops")
+
+	cases := []struct {
+		merchant, country string
+		want              int
+		scope             string
+	}{
+		{"acme", "BR", 40, "merchant:acme"},
+		{"other", "br", 25, "country:BR"},
+		{"", "MX", 20, domain.ScopeGlobal},
+	}
+	for _, c := range cases {
+		th, scope := s.ResolveThresholds(c.merchant, c.country)
+		if th.Approve != c.want || scope != c.scope {
+			t.Errorf("(%q, %q): expected approve=%d from %q, got %d from %q",
+				c.merchant, c.country, c.want, c.scope, th.Approve, scope)
+		}
+	}
+}
+
+func testThresholds_ChangesAreRecordedWithBeforeAndAfter(t *testing.T, s store.Store) {
+	s.SetThresholds(&domain.ThresholdConfig{Scope: domain.ScopeCountry, Key: "MX", Thresholds: domain.Thresholds{Approve: 30, Review: 70}}, "alice")
+	s.SetThresholds(&domain.ThresholdConfig{Scope: domain.ScopeCountry, Key: "MX", Thresholds: domain.Thresholds{Approve: 35, Review: 75}}, "bob")
+	if _, ok := s.DeleteThresholds(domain.ScopeCountry, "MX", "carol"); !ok {
+		t.Fatal("expected delete to find the MX override")
+	}
+
+	changes := s.ListThresholdChanges()
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes, got %d", len(changes))
+	}
+	if changes[0].Before != nil || changes[0].After.Approve != 30 {
+		t.Errorf("create should have no before and after=30, got %+v", changes[0])
+	}
+	if changes[1].Before.Approve != 30 || changes[1].After.Approve != 35 || changes[1].Actor != "bob" {
+		t.Errorf("update should go 30 → 35 by bob, got %+v", changes[1])
+	}
+	if changes[2].Before.Approve != 35 || changes[2].After != nil {
+		t.Errorf("delete should have before=35 and no after, got %+v", changes[2])
+	}
+}
+
+// ─── Settings ─────────────────────────────────────────────────────────────────
+
+func testSettings_EachPutAdvancesTheRevision(t *testing.T, s store.Store) {
+	if _, ok := s.GetSetting("rules"); ok {
+		t.Fatal("expected no setting in an empty store")
+	}
+	first, err := s.PutSetting(domain.Setting{Name: "rules", Document: []byte(`{"version":"a"}`), UpdatedAt: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.PutSetting(domain.Setting{Name: "rules", Document: []byte(`{"version":"b"}`), Revision: first.Revision, UpdatedAt: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Revision == first.Revision {
+		t.Errorf("expected a new revision, got %d twice", first.Revision)
+	}
+	_, _ = s.PutSetting(domain.Setting{Name: "other", Document: []byte(`null`), UpdatedAt: now})
+
+	got, ok := s.GetSetting("rules")
+	if !ok {
+		t.Fatal("expected the rules setting")
+	}
+	if got.Revision != second.Revision || string(got.Document) != `{"version":"b"}` {
+		t.Errorf("expected the second put, got revision %d and %s", got.Revision, got.Document)
+	}
+}
+
+func testSettings_StaleRevision_Conflicts(t *testing.T, s store.Store) {
+	first, err := s.PutSetting(domain.Setting{Name: "fx_rates", Document: []byte(`{"rates":[]}`), UpdatedAt: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.PutSetting(domain.Setting{Name: "fx_rates", Document: []byte(`{"rates":[1]}`), UpdatedAt: now}); !errors.Is(err, store.ErrSettingConflict) {
+		t.Fatalf("expected ErrSettingConflict for a put based on no setting, got %v", err)
+	}
+	if _, err := s.PutSetting(domain.Setting{Name: "fx_rates", Document: []byte(`{"rates":[2]}`), Revision: first.Revision, UpdatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.PutSetting(domain.Setting{Name: "fx_rates", Document: []byte(`{"rates":[3]}`), Revision: first.Revision, UpdatedAt: now}); !errors.Is(err, store.ErrSettingConflict) {
+		t.Fatalf("expected ErrSettingConflict for a put based on a replaced revision, got %v", err)
+	}
+	got, _ := s.GetSetting("fx_rates")
+	if string(got.Document) != `{"rates":[2]}` {
+		t.Errorf("expected the put based on the current revision to stick, got %s", got.Document)
+	}
+}
+
+// ─── Outcomes ─────────────────────────────────────────────────────────────────
+
+func testAddOutcome_AppendsWithoutMutatingPriorReads(t *testing.T, s store.Store) {
+	_ = s.SaveTransaction(newTx("oc-1", "a@x.com", "1.1.1.1", "d1", "411111", now))
+	before, _ := s.GetTransaction("oc-1")
+
+	updated, err := s.AddOutcome(domain.Outcome{ID: "o-1", TransactionID: "oc-1", Type: domain.OutcomeChargeback, OccurredAt: now})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(updated.Outcomes) != 1 || updated.Outcomes[0].ID != "o-1" {
+		t.Errorf("expected the outcome on the returned transaction, got %v", updated.Outcomes)
+	}
+	if len(before.Outcomes) != 0 {
+		t.Error("a previously read transaction must not change underneath its reader")
+	}
+	if got, _ := s.GetTransaction("oc-1"); len(got.Outcomes) != 1 {
+		t.Errorf("expected 1 stored outcome, got %d", len(got.Outcomes))
+	}
+	if got := s.GetTransactionsByEmail("a@x.com", now.Add(-time.Minute)); len(got) != 1 || len(got[0].Outcomes) != 1 {
+		t.Error("index lookups must return the updated transaction")
+	}
+}
+
+func testAddOutcome_UnknownTransaction_ReturnsError(t *testing.T, s store.Store) {
+	if _, err := s.AddOutcome(domain.Outcome{TransactionID: "missing"}); err != store.ErrTransactionNotFound {
+		t.Errorf("expected ErrTransactionNotFound, got %v", err)
+	}
+}
+
+func testListOutcomes_FiltersByTypeAndWindow(t *testing.T, s store.Store) {
+	_ = s.SaveTransaction(newTx("ol-1", "a@x.com", "1.1.1.1", "d1", "411111", now))
+	_, _ = s.AddOutcome(domain.Outcome{ID: "old", TransactionID: "ol-1", Type: domain.OutcomeChargeback, OccurredAt: now.Add(-48 * time.Hour)})
+	_, _ = s.AddOutcome(domain.Outcome{ID: "cb", TransactionID: "ol-1", Type: domain.OutcomeChargeback, OccurredAt: now})
+	_, _ = s.AddOutcome(domain.Outcome{ID: "rf", TransactionID: "ol-1", Type: domain.OutcomeRefund, OccurredAt: now})
+
+	got := s.ListOutcomes(domain.OutcomeChargeback, now.Add(-time.Hour))
+	if len(got) != 1 || got[0].ID != "cb" {
+		t.Errorf("expected only the recent chargeback, got %v", got)
+	}
+	if all := s.ListOutcomes("", now.Add(-72*time.Hour)); len(all) != 3 {
+		t.Errorf("expected 3 outcomes of any type, got %d", len(all))
+	}
+}
+
+func testFirstFraudLabelled_FollowsLatestLabel(t *testing.T, s store.Store) {
+	_ = s.SaveTransaction(newTx("ff-a1", "a@x.com", "1.1.1.1", "d1", "411111", now.Add(-2*time.Hour)))
+	_ = s.SaveTransaction(newTx("ff-b1", "b@x.com", "1.1.1.1", "d1", "411111", now.Add(-2*time.Hour)))
+	_ = s.SaveTransaction(newTx("ff-b2", "b@x.com", "1.1.1.1", "d1", "411111", now.Add(-time.Hour)))
+	_ = s.SaveTransaction(newTx("ff-b3", "b@x.com", "1.1.1.1", "d1", "411111", now))
+	_, _ = s.AddOutcome(domain.Outcome{ID: "ff-o1", TransactionID: "ff-a1", Type: domain.OutcomeChargeback, OccurredAt: now})
+	_, _ = s.AddOutcome(domain.Outcome{ID: "ff-o2", TransactionID: "ff-b3", Type: domain.OutcomeConfirmedFraud, OccurredAt: now})
+	_, _ = s.AddOutcome(domain.Outcome{ID: "ff-o3", TransactionID: "ff-b2", Type: domain.OutcomeChargeback, OccurredAt: now})
+	_, _ = s.AddOutcome(domain.Outcome{ID: "ff-o4", TransactionID: "ff-b1", Type: domain.OutcomeRefund, OccurredAt: now})
+
+	emails := []string{"none@x.com", "b@x.com", "a@x.com"}
+	if i, txID, ok := s.FirstFraudLabelled(emails); !ok || i != 1 || txID != "ff-b2" {
+		t.Errorf("expected b@x.com's earliest fraud transaction ff-b2, got %d %q %v", i, txID, ok)
+	}
+
+	// A later false positive clears the label.
+	_, _ = s.AddOutcome(domain.Outcome{ID: "ff-o5", TransactionID: "ff-b2", Type: domain.OutcomeFalsePositive, OccurredAt: now})
+	_, _ = s.AddOutcome(domain.Outcome{ID: "ff-o6", TransactionID: "ff-b3", Type: domain.OutcomeFalsePositive, OccurredAt: now})
+	if i, txID, ok := s.FirstFraudLabelled(emails); !ok || i != 2 || txID != "ff-a1" {
+		t.Errorf("expected false positives to clear b@x.com, leaving ff-a1, got %d %q %v", i, txID, ok)
+	}
+	if _, _, ok := s.FirstFraudLabelled(emails[:2]); ok {
+		t.Error("expected no fraud label once every transaction is cleared")
+	}
+}
+
+// ─── Lookups by ID ────────────────────────────────────────────────────────────
+
+func testBlocklist_GetByID(t *testing.T, s store.Store) {
+	s.SaveBlocklistEntry(&domain.BlocklistEntry{ID: "bl-get", Type: domain.EntityBIN, Value: "411111", ListType: domain.ListAllow, Reason: "vip"})
+	got, ok := s.GetBlocklistEntry("bl-get")
+	if !ok || got.Value != "411111" || got.ListType != domain.ListAllow || got.Reason != "vip" {
+		t.Errorf("unexpected entry: %+v (found=%v)", got, ok)
+	}
+	if _, ok := s.GetBlocklistEntry("ghost"); ok {
+		t.Error("expected ok=false for a missing entry")
+	}
+}
+
+func testWebhook_GetByID(t *testing.T, s store.Store) {
+	s.SaveWebhook(&domain.WebhookConfig{ID: "wh-get", URL: "http://a.com", Threshold: 75, Active: true})
+	got, ok := s.GetWebhook("wh-get")
+	if !ok || got.URL != "http://a.com" || got.Threshold != 75 {
+		t.Errorf("unexpected webhook: %+v (found=%v)", got, ok)
+	}
+	if _, ok := s.GetWebhook("ghost"); ok {
+		t.Error("expected ok=false for a missing webhook")
+	}
+}
+
+// ─── Review queue ─────────────────────────────────────────────────────────────
+
+func saveForReview(t *testing.T, s store.Store, id string) {
+	t.Helper()
+	tx := newTx(id, id+"@x.com", "1.1.1.1", "d1", "411111", now)
+	tx.FinalStatus = domain.StatusPendingReview
+	if err := s.SaveTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testReview_ClaimReleaseDecide(t *testing.T, s store.Store) {
+	saveForReview(t, s, "rv-1")
+	if pending := s.PendingReviews(); len(pending) != 1 || pending[0].TransactionID != "rv-1" {
+		t.Fatalf("expected rv-1 pending, got %v", pending)
+	}
+
+	if _, err := s.ClaimReview("rv-1", "ana", now, time.Hour); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if claims := s.ActiveReviewClaims(now); claims["rv-1"].Analyst != "ana" {
+		t.Errorf("expected ana's claim, got %v", claims)
+	}
+	if err := s.ReleaseReview("rv-1", "ana", now); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if claims := s.ActiveReviewClaims(now); len(claims) != 0 {
+		t.Errorf("expected no claims after release, got %v", claims)
+	}
+
+	_, _ = s.ClaimReview("rv-1", "ana", now, time.Hour)
+	before, _ := s.GetTransaction("rv-1")
+	tx, err := s.DecideReview("rv-1", domain.ReviewDecision{Decision: domain.ActionDecline, Analyst: "ana", DecidedAt: now.Add(time.Minute)})
+	if err != nil {
+		t.Fatalf("decide: %v", err)
+	}
+	if tx.FinalStatus != domain.StatusDeclined || tx.Review == nil || tx.Review.Analyst != "ana" {
+		t.Errorf("unexpected decided transaction: %+v", tx)
+	}
+	if before.FinalStatus != domain.StatusPendingReview {
+		t.Error("a previously read transaction must not change underneath its reader")
+	}
+	if got, _ := s.GetTransaction("rv-1"); got.FinalStatus != domain.StatusDeclined {
+		t.Errorf("expected the decision to be stored, got %s", got.FinalStatus)
+	}
+	if len(s.PendingReviews()) != 0 || len(s.ActiveReviewClaims(now)) != 0 {
+		t.Error("a decided case must leave the queue and drop its claim")
+	}
+}
+
+func testReview_ClaimRules(t *testing.T, s store.Store) {
+	saveForReview(t, s, "rv-2")
+	_ = s.SaveTransaction(newTx("rv-done", "d@x.com", "1.1.1.1", "d1", "411111", now))
+
+	if _, err := s.ClaimReview("missing", "ana", now, time.Hour); err != store.ErrTransactionNotFound {
+		t.Errorf("expected ErrTransactionNotFound, got %v", err)
+	}
+	if _, err := s.ClaimReview("rv-done", "ana", now, time.Hour); err != store.ErrNotInReview {
+		t.Errorf("expected ErrNotInReview, got %v", err)
+	}
+	if _, err := s.DecideReview("rv-2", domain.ReviewDecision{Decision: domain.ActionApprove, Analyst: "ana", DecidedAt: now}); err != store.ErrReviewNotClaimed {
+		t.Errorf("expected ErrReviewNotClaimed, got %v", err)
+	}
+
+	_, _ = s.ClaimReview("rv-2", "ana", now, time.Minute)
+	if _, err := s.ClaimReview("rv-2", "bruno", now, time.Minute); err != store.ErrReviewClaimed {
+		t.Errorf("expected ErrReviewClaimed, got %v", err)
+	}
+	if err := s.ReleaseReview("rv-2", "bruno", now); err != store.ErrReviewClaimed {
+		t.Errorf("expected ErrReviewClaimed releasing someone else's claim, got %v", err)
+	}
+
+	// A lapsed claim can be taken over.
+	later := now.Add(2 * time.Minute)
+	if c, err := s.ClaimReview("rv-2", "bruno", later, time.Minute); err != nil || c.Analyst != "bruno" {
+		t.Errorf("expected bruno to take over the lapsed claim, got %+v, %v", c, err)
+	}
+}
+
+// ─── Idempotency keys ─────────────────────────────────────────────────────────
+
+func testIdempotency_KeyIsBoundUntilExpiry(t *testing.T, s store.Store) {
+	first := domain.IdempotencyKey{Key: "key-1", TransactionID: "ik-1", Fingerprint: "f1", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	if saved, err := s.SaveIdempotencyKey(first); err != nil || saved.TransactionID != "ik-1" {
+		t.Fatalf("expected the key to be saved, got %+v, %v", saved, err)
+	}
+	if got, ok := s.GetIdempotencyKey("key-1", now.Add(time.Minute)); !ok || got.Fingerprint != "f1" {
+		t.Errorf("expected the saved key, got %+v, %v", got, ok)
+	}
+	if _, ok := s.GetIdempotencyKey("unknown", now); ok {
+		t.Error("expected ok=false for an unknown key")
+	}
+
+	// While bound, the key keeps pointing at the first transaction.
+	second := domain.IdempotencyKey{Key: "key-1", TransactionID: "ik-2", Fingerprint: "f2", CreatedAt: now.Add(time.Minute), ExpiresAt: now.Add(2 * time.Hour)}
+	held, err := s.SaveIdempotencyKey(second)
+	if err != store.ErrIdempotencyKeyExists || held.TransactionID != "ik-1" {
+		t.Errorf("expected ErrIdempotencyKeyExists with the first record, got %+v, %v", held, err)
+	}
+
+	// Once lapsed, it is invisible and can be bound again.
+	later := now.Add(90 * time.Minute)
+	if _, ok := s.GetIdempotencyKey("key-1", later); ok {
+		t.Error("expected the key to have expired")
+	}
+	second.CreatedAt, second.ExpiresAt = later, later.Add(time.Hour)
+	if _, err := s.SaveIdempotencyKey(second); err != nil {
+		t.Fatalf("expected an expired key to be reusable, got %v", err)
+	}
+	if got, ok := s.GetIdempotencyKey("key-1", later); !ok || got.TransactionID != "ik-2" {
+		t.Errorf("expected the key to point at ik-2, got %+v, %v", got, ok)
+	}
+}
+
+func testIdempotency_ReleaseFreesOnlyTheSameBinding(t *testing.T, s store.Store) {
+	first := domain.IdempotencyKey{Key: "key-r", TransactionID: "ir-1", Fingerprint: "f1", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	if _, err := s.SaveIdempotencyKey(first); err != nil {
+		t.Fatal(err)
+	}
+
+	// A different binding of the same key leaves the stored one alone.
+	other := first
+	other.Fingerprint = "f2"
+	if err := s.ReleaseIdempotencyKey(other); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if _, ok := s.GetIdempotencyKey("key-r", now); !ok {
+		t.Fatal("expected a mismatched release to keep the key")
+	}
+
+	if err := s.ReleaseIdempotencyKey(first); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if _, ok := s.GetIdempotencyKey("key-r", now); ok {
+		t.Error("expected the released key to be gone")
+	}
+	if _, err := s.SaveIdempotencyKey(other); err != nil {
+		t.Errorf("expected a released key to be reusable at once, got %v", err)
+	}
+	if err := s.ReleaseIdempotencyKey(domain.IdempotencyKey{Key: "unknown"}); err != nil {
+		t.Errorf("expected releasing an unknown key to be a no-op, got %v", err)
+	}
+}
+
+// ─── API keys ─────────────────────────────────────────────────────────────────
+
+func testAPIKeys_RotateAndRevoke(t *testing.T, s store.Store) {
+	first := &domain.APIKey{ID: "k-1", Name: "ops", TokenHash: "h-1", Scopes: []string{"admin"}, CreatedAt: now}
+	second := &domain.APIKey{ID: "k-2", Name: "ci", TokenHash: "h-2", Scopes: []string{"admin"}, CreatedAt: now.Add(time.Second)}
+	if err := s.CreateAPIKey(second); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateAPIKey(first); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateAPIKey(&domain.APIKey{ID: "k-3", TokenHash: "h-1", CreatedAt: now}); err != store.ErrAPIKeyExists {
+		t.Errorf("expected ErrAPIKeyExists for a taken token hash, got %v", err)
+	}
+	if got, ok := s.GetAPIKeyByHash("h-1"); !ok || got.ID != "k-1" {
+		t.Errorf("expected k-1 by its hash, got %+v", got)
+	}
+
+	rotated := *first
+	rotated.TokenHash = "h-1b"
+	if err := s.UpdateAPIKey(&rotated); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.GetAPIKeyByHash("h-1"); ok {
+		t.Error("expected the old token hash to stop resolving after rotation")
+	}
+	if got, ok := s.GetAPIKeyByHash("h-1b"); !ok || got.ID != "k-1" {
+		t.Errorf("expected k-1 by its new hash, got %+v", got)
+	}
+	taken := rotated
+	taken.TokenHash = "h-2"
+	if err := s.UpdateAPIKey(&taken); err != store.ErrAPIKeyExists {
+		t.Errorf("expected ErrAPIKeyExists when rotating onto another key's hash, got %v", err)
+	}
+
+	revoked := rotated
+	revokedAt := now
+	revoked.RevokedAt = &revokedAt
+	if err := s.UpdateAPIKey(&revoked); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdateAPIKey(&rotated); err != store.ErrAPIKeyRevoked {
+		t.Errorf("expected a revoked key to stay revoked, got %v", err)
+	}
+	if err := s.CreateAPIKey(&domain.APIKey{ID: "k-4", TokenHash: "h-1b", CreatedAt: now}); err != store.ErrAPIKeyExists {
+		t.Errorf("expected a revoked key's token to stay taken, got %v", err)
+	}
+	if err := s.UpdateAPIKey(&domain.APIKey{ID: "missing"}); err != store.ErrAPIKeyNotFound {
+		t.Errorf("expected ErrAPIKeyNotFound, got %v", err)
+	}
+
+	list := s.ListAPIKeys()
+	if len(list) != 2 || list[0].ID != "k-1" || list[0].RevokedAt == nil || list[1].ID != "k-2" {
+		t.Errorf("expected both keys oldest first, k-1 revoked, got %+v", list)
+	}
+}
+
+// ─── Audit log ────────────────────────────────────────────────────────────────
+
+func testAuditLog_AppendsOnlyTheNextSeq(t *testing.T, s store.Store) {
+	if got, err := s.AuditEntriesAfter(0); err != nil || len(got) != 0 {
+		t.Fatalf("expected an empty log, got %v, %v", got, err)
+	}
+	for seq := int64(1); seq <= 3; seq++ {
+		e := domain.AuditEntry{Seq: seq, Time: now, Actor: "key:ops", Action: "x.set", Hash: fmt.Sprintf("h-%d", seq)}
+		if err := s.AppendAuditEntry(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, seq := range []int64{1, 3, 5} {
+		if err := s.AppendAuditEntry(domain.AuditEntry{Seq: seq}); err != store.ErrAuditConflict {
+			t.Errorf("seq %d: expected ErrAuditConflict, got %v", seq, err)
+		}
+	}
+	got, err := s.AuditEntriesAfter(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Seq != 2 || got[0].Hash != "h-2" || got[1].Seq != 3 {
+		t.Errorf("expected entries 2 and 3 in order, got %+v", got)
+	}
+	if got, _ := s.AuditEntriesAfter(3); len(got) != 0 {
+		t.Errorf("expected nothing after the last entry, got %+v", got)
+	}
+}
+
+// ─── Retention ────────────────────────────────────────────────────────────────
+
+func testPrune_DropsHistoryBeforeCutoff(t *testing.T, s store.Store) {
+	p, ok := s.(store.Pruner)
+	if !ok {
+		t.Skip("backend expires data itself")
+	}
+	old := now.Add(-100 * 24 * time.Hour)
+	_ = s.SaveTransaction(newTx("pr-old", "p@x.com", "8.8.8.8", "pd", "111111", old))
+	_ = s.SaveTransaction(newTx("pr-new", "p@x.com", "8.8.8.8", "pd", "222222", now))
+	_, _ = s.AddOutcome(domain.Outcome{ID: "pr-o", TransactionID: "pr-old", Type: domain.OutcomeChargeback, OccurredAt: old})
+	queued := newTx("pr-queued", "q@x.com", "8.8.8.8", "qd", "222222", old)
+	queued.FinalStatus = domain.StatusPendingReview
+	_ = s.SaveTransaction(queued)
+	_, _ = s.SaveIdempotencyKey(domain.IdempotencyKey{Key: "pr-key", TransactionID: "pr-old", CreatedAt: now, ExpiresAt: now.Add(time.Hour)})
+	_, _ = s.SaveIdempotencyKey(domain.IdempotencyKey{Key: "pr-key-new", TransactionID: "pr-new", CreatedAt: now, ExpiresAt: now.Add(time.Hour)})
+	_, _ = s.SaveIdempotencyKey(domain.IdempotencyKey{Key: "pr-key-pending", TransactionID: "pr-pending", CreatedAt: now, ExpiresAt: now.Add(time.Hour)})
+	_ = s.AppendAuditEntry(domain.AuditEntry{Seq: 1, Time: old, Action: "blocklist.add"})
+
+	stats, err := p.Prune(now.Add(-90 * 24 * time.Hour))
+	if err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	if stats.Transactions != 1 || stats.Cards != 1 || stats.IdempotencyKeys != 1 {
+		t.Errorf("expected 1 transaction, 1 card pair and 1 idempotency key pruned, got %+v", stats)
+	}
+	if _, ok := s.GetIdempotencyKey("pr-key", now); ok {
+		t.Error("expected the pruned transaction's idempotency key to go")
+	}
+	if _, ok := s.GetIdempotencyKey("pr-key-new", now); !ok {
+		t.Error("expected the live transaction's idempotency key to be kept")
+	}
+	if _, ok := s.GetIdempotencyKey("pr-key-pending", now); !ok {
+		t.Error("expected a key reserved for a transaction not yet saved to be kept")
+	}
+	if _, ok := s.GetTransaction("pr-old"); ok {
+		t.Error("expected the old transaction to be pruned")
+	}
+	if _, ok := s.GetTransaction("pr-queued"); !ok {
+		t.Error("a transaction awaiting review must be kept")
+	}
+	if got := s.GetTransactionsByEmail("p@x.com", time.Time{}); len(got) != 1 || got[0].TransactionID != "pr-new" {
+		t.Errorf("expected only pr-new in the email index, got %d", len(got))
+	}
+	if got := s.GetAllTransactions(time.Time{}); len(got) != 2 {
+		t.Errorf("expected 2 transactions left, got %d", len(got))
+	}
+	if n := s.GetUniqueCardsByIP("8.8.8.8"); n != 1 {
+		t.Errorf("expected the stale BIN to be forgotten, leaving 1, got %d", n)
+	}
+	if n := s.CountDistinctEmails(domain.EntityIP, "8.8.8.8", time.Time{}); n != 2 {
+		t.Errorf("expected both emails still seen from the IP, got %d", n)
+	}
+	if n := s.CountDistinctEmails(domain.EntityBIN, "111111", time.Time{}); n != 0 {
+		t.Errorf("expected no emails left on the pruned BIN, got %d", n)
+	}
+	links := s.GetEntityLinks(domain.EntityDevice, "pd")
+	if len(links) != 3 {
+		t.Errorf("expected the pruned transaction's BIN to be unlinked, got %+v", links)
+	}
+	if l, ok := linkTo(links, domain.EntityIP, "8.8.8.8"); !ok || l.Weight != 1 || !l.FirstSeen.Equal(now) {
+		t.Errorf("expected the surviving IP link re-dated to pr-new alone, got %+v", l)
+	}
+	if got := s.ListOutcomes("", time.Time{}); len(got) != 0 {
+		t.Errorf("expected the pruned transaction's outcome to go, got %v", got)
+	}
+	if _, txID, ok := s.FirstFraudLabelled([]string{"p@x.com"}); ok {
+		t.Errorf("expected the pruned transaction's fraud label to go, got %q", txID)
+	}
+	if got, _ := s.AuditEntriesAfter(0); len(got) != 1 {
+		t.Errorf("retention must never remove audit entries, got %d", len(got))
+	}
+}
+
+// ─── Concurrency (race detector) ─────────────────────────────────────────────
+
+func testStore_ConcurrentWrites_NoRace(t *testing.T, s store.Store) {
+	done := make(chan struct{})
+
+	for i := 0; i < 20; i++ {
+		go func(n int) {
+			tx := newTx(
+				"conc-"+string(rune('A'+n)),
+				"user@concurrent.com",
+				"10.0.0.1",
+				"dev-conc",
+				"111111",
+				now.Add(-time.Duration(n)*time.Minute),
+			)
+			_ = s.SaveTransaction(tx)
+			done <- struct{}{}
+		}(i)
+	}
+
+	for i := 0; i < 20; i++ {
+		<-done
+	}
+}
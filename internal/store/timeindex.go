@@ -0,0 +1,99 @@
+package store
+
+import (
+	"sort"
+	"time"
+)
+
+// timeIndex holds one entity's transaction IDs sorted by transaction time, so
+// a window query binary-searches its start instead of scanning the entity's
+// whole history. Ties keep insertion order.
+//
+// Live traffic arrives roughly in time order and appends; a late or
+// backfilled event is inserted at its position, which costs a copy of the
+// newer entries only.
+type timeIndex []indexEntry
+
+type indexEntry struct {
+	at time.Time
+	id string
+}
+
+// insert adds id at its timestamp and returns the updated index.
+func (idx timeIndex) insert(id string, at time.Time) timeIndex {
+	e := indexEntry{at: at, id: id}
+	if len(idx) == 0 || !at.Before(idx[len(idx)-1].at) {
+		return append(idx, e)
+	}
+	i := sort.Search(len(idx), func(i int) bool { return idx[i].at.After(at) })
+	idx = append(idx, indexEntry{})
+	copy(idx[i+1:], idx[i:])
+	idx[i] = e
+	return idx
+}
+
+// since returns the entries at or after t, oldest first. The result shares
+// the index's backing array and must not be modified.
+func (idx timeIndex) since(t time.Time) timeIndex {
+	i := sort.Search(len(idx), func(i int) bool { return !idx[i].at.Before(t) })
+	return idx[i:]
+}
+
+// between returns the entries at or after from and before to, oldest first.
+// A zero to leaves the range open. Like since, the result shares the index's
+// backing array.
+func (idx timeIndex) between(from, to time.Time) timeIndex {
+	idx = idx.since(from)
+	if to.IsZero() {
+		return idx
+	}
+	return idx[:sort.Search(len(idx), func(i int) bool { return !idx[i].at.Before(to) })]
+}
+
+// ids returns the index's transaction IDs, oldest first.
+func (idx timeIndex) ids() []string {
+	ids := make([]string, len(idx))
+	for i, e := range idx {
+		ids[i] = e.id
+	}
+	return ids
+}
+
+// remove deletes id's entry at at and returns the updated index.
+func (idx timeIndex) remove(id string, at time.Time) timeIndex {
+	for i := sort.Search(len(idx), func(i int) bool { return !idx[i].at.Before(at) }); i < len(idx) && idx[i].at.Equal(at); i++ {
+		if idx[i].id == id {
+			return append(idx[:i], idx[i+1:]...)
+		}
+	}
+	return idx
+}
+
+// recencyIndex holds keys, like the emails seen with one card BIN, sorted by
+// the last time each was seen, so the keys seen at or after any time are
+// counted with one binary search.
+type recencyIndex struct {
+	last  map[string]time.Time
+	order timeIndex // one entry per key, at its last sighting
+}
+
+func newRecencyIndex() *recencyIndex {
+	return &recencyIndex{last: make(map[string]time.Time)}
+}
+
+// see records key at at, unless it was already seen later.
+func (r *recencyIndex) see(key string, at time.Time) {
+	if prev, ok := r.last[key]; ok {
+		if !at.After(prev) {
+			return
+		}
+		r.order = r.order.remove(key, prev)
+	}
+	r.last[key] = at
+	r.order = r.order.insert(key, at)
+}
+
+// countSince counts the keys last seen at or after t.
+func (r *recencyIndex) countSince(t time.Time) int {
+	return len(r.order.since(t))
+}
@@ -0,0 +1,172 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SyncPolicy controls when WAL writes are fsynced to disk.
+type SyncPolicy string
+
+const (
+	// SyncAlways fsyncs after every record: no acknowledged write is lost,
+	// at the cost of one fsync per mutation.
+	SyncAlways SyncPolicy = "always"
+	// SyncInterval fsyncs on a timer. A crash of the machine (not just the
+	// process) can lose up to one interval of writes.
+	SyncInterval SyncPolicy = "interval"
+	// SyncNever leaves flushing to the operating system.
+	SyncNever SyncPolicy = "never"
+)
+
+// ParseSyncPolicy validates a policy name from a flag or config file.
+func ParseSyncPolicy(s string) (SyncPolicy, error) {
+	switch p := SyncPolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case SyncAlways, SyncInterval, SyncNever:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown sync policy %q: must be always, interval or never", s)
+}
+
+// errCorruptWAL reports a damaged record that is not a torn tail.
+var errCorruptWAL = errors.New("corrupt WAL record")
+
+// walRecord is one logged mutation. Data holds the op's payload.
+type walRecord struct {
+	Seq  uint64          `json:"seq"`
+	Op   string          `json:"op"`
+	Data json.RawMessage `json:"data"`
+}
+
+// ─── Segment files ────────────────────────────────────────────────────────────
+//
+// The log is a series of segment files named wal-<first seq>.log. Each line is
+// "<crc32 hex> <json record>\n"; the checksum covers the JSON. A new segment
+// is started on every open and on every snapshot, so compaction only ever
+// deletes whole files.
+
+const (
+	walPrefix = "wal-"
+	walSuffix = ".log"
+)
+
+func segmentName(firstSeq uint64) string {
+	return fmt.Sprintf("%s%020d%s", walPrefix, firstSeq, walSuffix)
+}
+
+// segment is a WAL file on disk and the first sequence number it may hold.
+type segment struct {
+	path     string
+	firstSeq uint64
+}
+
+// listSegments returns the WAL segments in dir, oldest first.
+func listSegments(dir string) ([]segment, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var segs []segment
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, walPrefix) || !strings.HasSuffix(name, walSuffix) {
+			continue
+		}
+		n, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, walPrefix), walSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		segs = append(segs, segment{path: filepath.Join(dir, name), firstSeq: n})
+	}
+	sort.Slice(segs, func(i, j int) bool { return segs[i].firstSeq < segs[j].firstSeq })
+	return segs, nil
+}
+
+func encodeRecord(r walRecord) ([]byte, error) {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	line := make([]byte, 0, len(body)+10)
+	line = fmt.Appendf(line, "%08x ", crc32.ChecksumIEEE(body))
+	line = append(line, body...)
+	return append(line, '\n'), nil
+}
+
+// readSegment calls fn for every record in a segment. When last is true a
+// torn or unterminated final record — what a crash mid-write leaves behind —
+// is cut off and the file truncated to its last good record. Damage anywhere
+// else is reported as errCorruptWAL.
+func readSegment(path string, last bool, fn func(walRecord) error) (torn bool, err error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var good int64
+	for {
+		line, readErr := r.ReadBytes('\n')
+		if len(line) == 0 && readErr == io.EOF {
+			return false, nil
+		}
+		rec, decodeErr := decodeRecord(line, readErr)
+		if decodeErr != nil {
+			if _, peekErr := r.Peek(1); last && peekErr == io.EOF {
+				return true, f.Truncate(good)
+			}
+			return false, fmt.Errorf("%s at offset %d: %w", filepath.Base(path), good, decodeErr)
+		}
+		if err := fn(rec); err != nil {
+			return false, fmt.Errorf("%s at offset %d: %w", filepath.Base(path), good, err)
+		}
+		good += int64(len(line))
+		if readErr == io.EOF {
+			return false, nil
+		}
+	}
+}
+
+func decodeRecord(line []byte, readErr error) (walRecord, error) {
+	if readErr != nil {
+		if readErr != io.EOF {
+			return walRecord{}, readErr
+		}
+		return walRecord{}, fmt.Errorf("%w: unterminated record", errCorruptWAL)
+	}
+	sum, body, found := bytes.Cut(bytes.TrimSuffix(line, []byte("\n")), []byte(" "))
+	want, hexErr := hex.DecodeString(string(sum))
+	if !found || hexErr != nil || len(want) != 4 {
+		return walRecord{}, fmt.Errorf("%w: bad checksum field", errCorruptWAL)
+	}
+	if got := crc32.ChecksumIEEE(body); got != uint32(want[0])<<24|uint32(want[1])<<16|uint32(want[2])<<8|uint32(want[3]) {
+		return walRecord{}, fmt.Errorf("%w: checksum mismatch", errCorruptWAL)
+	}
+	var rec walRecord
+	if err := json.Unmarshal(body, &rec); err != nil {
+		return walRecord{}, fmt.Errorf("%w: %v", errCorruptWAL, err)
+	}
+	return rec, nil
+}
+
+// syncDir fsyncs a directory so renames and new files in it are durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -20,12 +20,12 @@ import (
 
 // Notifier sends webhook payloads to all registered, active endpoints.
 type Notifier struct {
-	store  *store.Store
+	store  store.Store
 	client *http.Client
 }
 
 // New creates a Notifier with a sensible default HTTP client timeout.
-func New(s *store.Store) *Notifier {
+func New(s store.Store) *Notifier {
 	return &Notifier{
 		store: s,
 		client: &http.Client{